@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/m3talux/goauth/model"
+)
+
+// Account calls the account endpoints of an end-user, authenticated with their session.
+type Account struct {
+	client  *Client
+	session string
+}
+
+// Account returns the account endpoints of the end-user logged in with the given session, which is the
+// value of the session cookie of goauth.
+func (c *Client) Account(session string) *Account {
+	return &Account{client: c, session: session}
+}
+
+// Identities returns the upstream identities linked to the account.
+func (a *Account) Identities(ctx context.Context) ([]model.Identity, error) {
+	var res []model.Identity
+	err := a.client.api(ctx, a.request(http.MethodGet, "/identities", nil), &res)
+
+	return res, err
+}
+
+// UnlinkIdentity unlinks an upstream identity from the account.
+func (a *Account) UnlinkIdentity(ctx context.Context, identityID string) error {
+	return a.client.api(ctx, a.request(http.MethodDelete, "/identities"+pathEscape(identityID), nil), nil)
+}
+
+// Tokens returns the personal access tokens of the end-user.
+func (a *Account) Tokens(ctx context.Context) ([]model.PersonalAccessToken, error) {
+	var res []model.PersonalAccessToken
+	err := a.client.api(ctx, a.request(http.MethodGet, "/tokens", nil), &res)
+
+	return res, err
+}
+
+// CreateToken mints a personal access token for the end-user. Its value is only returned once.
+func (a *Account) CreateToken(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (*model.PersonalAccessTokenCreation, error) {
+	body := map[string]any{"name": name, "scopes": scopes, "expires_at": expiresAt}
+
+	res := new(model.PersonalAccessTokenCreation)
+	if err := a.client.api(ctx, a.request(http.MethodPost, "/tokens", body), res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// RevokeToken revokes a personal access token of the end-user.
+func (a *Account) RevokeToken(ctx context.Context, tokenID string) error {
+	return a.client.api(ctx, a.request(http.MethodDelete, "/tokens"+pathEscape(tokenID), nil), nil)
+}
+
+func (a *Account) request(method, path string, body any) request {
+	return request{method: method, path: accountPath + path, body: body, session: a.session}
+}
@@ -0,0 +1,391 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/m3talux/goauth/model"
+)
+
+// Roles returns every role of the platform.
+func (c *Client) Roles(ctx context.Context) ([]model.Role, error) {
+	return get[[]model.Role](ctx, c, apiPath+"/roles")
+}
+
+// Role returns a role.
+func (c *Client) Role(ctx context.Context, roleID string) (*model.Role, error) {
+	return getOne[model.Role](ctx, c, apiPath+"/roles"+pathEscape(roleID))
+}
+
+// CreateRole creates a role.
+func (c *Client) CreateRole(ctx context.Context, role model.Role) (*model.Role, error) {
+	return call[model.Role](ctx, c, http.MethodPost, apiPath+"/roles", role)
+}
+
+// UpdateRole replaces the description and the permissions of a role.
+func (c *Client) UpdateRole(ctx context.Context, roleID string, role model.Role) (*model.Role, error) {
+	return call[model.Role](ctx, c, http.MethodPut, apiPath+"/roles"+pathEscape(roleID), role)
+}
+
+// DeleteRole deletes a role.
+func (c *Client) DeleteRole(ctx context.Context, roleID string) error {
+	return c.delete(ctx, apiPath+"/roles"+pathEscape(roleID))
+}
+
+// ClientRoles returns the roles assigned to a client.
+func (c *Client) ClientRoles(ctx context.Context, clientID string) ([]model.Role, error) {
+	return get[[]model.Role](ctx, c, apiPath+"/clients"+pathEscape(clientID, "roles"))
+}
+
+// AssignClientRole assigns a role to a client.
+func (c *Client) AssignClientRole(ctx context.Context, clientID, roleID string) error {
+	return c.api(ctx, request{method: http.MethodPut, path: apiPath + "/clients" + pathEscape(clientID, "roles", roleID), auth: authAPI}, nil)
+}
+
+// UnassignClientRole removes a role from a client.
+func (c *Client) UnassignClientRole(ctx context.Context, clientID, roleID string) error {
+	return c.delete(ctx, apiPath+"/clients"+pathEscape(clientID, "roles", roleID))
+}
+
+// Organizations returns every organization of the platform.
+func (c *Client) Organizations(ctx context.Context) ([]model.Organization, error) {
+	return get[[]model.Organization](ctx, c, apiPath+"/organizations")
+}
+
+// GetOrganization returns an organization of the platform.
+func (c *Client) GetOrganization(ctx context.Context, organizationID string) (*model.Organization, error) {
+	return getOne[model.Organization](ctx, c, apiPath+"/organizations"+pathEscape(organizationID))
+}
+
+// CreateOrganization creates an organization.
+func (c *Client) CreateOrganization(ctx context.Context, organization model.Organization) (*model.Organization, error) {
+	return call[model.Organization](ctx, c, http.MethodPost, apiPath+"/organizations", organization)
+}
+
+// UpdateOrganization replaces the attributes of an organization.
+func (c *Client) UpdateOrganization(ctx context.Context, organizationID string, organization model.Organization) (*model.Organization, error) {
+	return call[model.Organization](ctx, c, http.MethodPut, apiPath+"/organizations"+pathEscape(organizationID), organization)
+}
+
+// DeleteOrganization deletes an organization without members.
+func (c *Client) DeleteOrganization(ctx context.Context, organizationID string) error {
+	return c.delete(ctx, apiPath+"/organizations"+pathEscape(organizationID))
+}
+
+// Members returns the members of the organization of the issuer.
+func (c *Client) Members(ctx context.Context) ([]model.Member, error) {
+	return get[[]model.Member](ctx, c, apiPath+"/members")
+}
+
+// UpdateMember replaces the roles of a member of the organization of the issuer. Users join the
+// organization through invitations.
+func (c *Client) UpdateMember(ctx context.Context, userID string, roles []string) (*model.Member, error) {
+	return call[model.Member](ctx, c, http.MethodPut, apiPath+"/members"+pathEscape(userID), map[string][]string{"roles": roles})
+}
+
+// RemoveMember removes a user from the organization of the issuer.
+func (c *Client) RemoveMember(ctx context.Context, userID string) error {
+	return c.delete(ctx, apiPath+"/members"+pathEscape(userID))
+}
+
+// Invitations returns the pending invitations of the organization of the issuer.
+func (c *Client) Invitations(ctx context.Context) ([]model.Invitation, error) {
+	return get[[]model.Invitation](ctx, c, apiPath+"/invitations")
+}
+
+// CreateInvitation invites an email address to join the organization of the issuer with the given roles.
+func (c *Client) CreateInvitation(ctx context.Context, email string, roles []string) (*model.InvitationCreation, error) {
+	body := map[string]any{"email": email, "roles": roles}
+
+	return call[model.InvitationCreation](ctx, c, http.MethodPost, apiPath+"/invitations", body)
+}
+
+// RevokeInvitation revokes a pending invitation.
+func (c *Client) RevokeInvitation(ctx context.Context, invitationID string) error {
+	return c.delete(ctx, apiPath+"/invitations"+pathEscape(invitationID))
+}
+
+// Domains returns the domains claimed by the organization of the issuer.
+func (c *Client) Domains(ctx context.Context) ([]model.DomainClaim, error) {
+	return get[[]model.DomainClaim](ctx, c, apiPath+"/domains")
+}
+
+// ClaimDomain claims a domain for the organization of the issuer. It has to be verified afterwards.
+func (c *Client) ClaimDomain(ctx context.Context, claim model.DomainClaim) (*model.DomainClaim, error) {
+	return call[model.DomainClaim](ctx, c, http.MethodPost, apiPath+"/domains", claim)
+}
+
+// UpdateDomain replaces the settings of a domain claim.
+func (c *Client) UpdateDomain(ctx context.Context, domain string, claim model.DomainClaim) (*model.DomainClaim, error) {
+	return call[model.DomainClaim](ctx, c, http.MethodPut, apiPath+"/domains"+pathEscape(domain), claim)
+}
+
+// VerifyDomain checks the DNS record proving the ownership of a claimed domain.
+func (c *Client) VerifyDomain(ctx context.Context, domain string) (*model.DomainClaim, error) {
+	return call[model.DomainClaim](ctx, c, http.MethodPost, apiPath+"/domains"+pathEscape(domain, "verify"), nil)
+}
+
+// DeleteDomain deletes a domain claim.
+func (c *Client) DeleteDomain(ctx context.Context, domain string) error {
+	return c.delete(ctx, apiPath+"/domains"+pathEscape(domain))
+}
+
+// APIKeys returns the API keys of the organization of the issuer.
+func (c *Client) APIKeys(ctx context.Context) ([]model.APIKey, error) {
+	return get[[]model.APIKey](ctx, c, apiPath+"/api-keys")
+}
+
+// CreateAPIKey creates an API key granting the given scopes. Its value is only returned once.
+func (c *Client) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (*model.APIKeyCreation, error) {
+	body := map[string]any{"name": name, "scopes": scopes, "expires_at": expiresAt}
+
+	return call[model.APIKeyCreation](ctx, c, http.MethodPost, apiPath+"/api-keys", body)
+}
+
+// RevokeAPIKey revokes an API key.
+func (c *Client) RevokeAPIKey(ctx context.Context, keyID string) error {
+	return c.delete(ctx, apiPath+"/api-keys"+pathEscape(keyID))
+}
+
+// Groups returns the groups of the organization of the issuer.
+func (c *Client) Groups(ctx context.Context) ([]model.Group, error) {
+	return get[[]model.Group](ctx, c, apiPath+"/groups")
+}
+
+// Group returns a group.
+func (c *Client) Group(ctx context.Context, groupID string) (*model.Group, error) {
+	return getOne[model.Group](ctx, c, apiPath+"/groups"+pathEscape(groupID))
+}
+
+// CreateGroup creates a group.
+func (c *Client) CreateGroup(ctx context.Context, group model.Group) (*model.Group, error) {
+	return call[model.Group](ctx, c, http.MethodPost, apiPath+"/groups", group)
+}
+
+// UpdateGroup replaces the display name, the members, the member groups and the roles of a group.
+func (c *Client) UpdateGroup(ctx context.Context, groupID string, group model.Group) (*model.Group, error) {
+	return call[model.Group](ctx, c, http.MethodPut, apiPath+"/groups"+pathEscape(groupID), group)
+}
+
+// DeleteGroup deletes a group.
+func (c *Client) DeleteGroup(ctx context.Context, groupID string) error {
+	return c.delete(ctx, apiPath+"/groups"+pathEscape(groupID))
+}
+
+// Policies returns the policies of the organization of the issuer.
+func (c *Client) Policies(ctx context.Context) ([]model.Policy, error) {
+	return get[[]model.Policy](ctx, c, apiPath+"/policies")
+}
+
+// Policy returns a policy.
+func (c *Client) Policy(ctx context.Context, policyID string) (*model.Policy, error) {
+	return getOne[model.Policy](ctx, c, apiPath+"/policies"+pathEscape(policyID))
+}
+
+// CreatePolicy creates a policy.
+func (c *Client) CreatePolicy(ctx context.Context, policy model.Policy) (*model.Policy, error) {
+	return call[model.Policy](ctx, c, http.MethodPost, apiPath+"/policies", policy)
+}
+
+// UpdatePolicy replaces a policy.
+func (c *Client) UpdatePolicy(ctx context.Context, policyID string, policy model.Policy) (*model.Policy, error) {
+	return call[model.Policy](ctx, c, http.MethodPut, apiPath+"/policies"+pathEscape(policyID), policy)
+}
+
+// DeletePolicy deletes a policy.
+func (c *Client) DeletePolicy(ctx context.Context, policyID string) error {
+	return c.delete(ctx, apiPath+"/policies"+pathEscape(policyID))
+}
+
+// Authorize returns the decision of the policies of the organization of the issuer on a request.
+func (c *Client) Authorize(ctx context.Context, req model.DecisionRequest) (*model.Decision, error) {
+	return call[model.Decision](ctx, c, http.MethodPost, apiPath+"/authorize", req)
+}
+
+// AuthorizeBatch returns the decisions on several requests, in the order of the requests.
+func (c *Client) AuthorizeBatch(ctx context.Context, reqs []model.DecisionRequest) ([]model.Decision, error) {
+	res, err := call[struct {
+		Decisions []model.Decision `json:"decisions"`
+	}](ctx, c, http.MethodPost, apiPath+"/authorize/batch", map[string]any{"requests": reqs})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Decisions, nil
+}
+
+// RelationNamespaces returns the relation namespaces of the organization of the issuer.
+func (c *Client) RelationNamespaces(ctx context.Context) ([]model.RelationNamespace, error) {
+	return get[[]model.RelationNamespace](ctx, c, apiPath+"/relations/namespaces")
+}
+
+// SaveRelationNamespace creates or replaces a relation namespace.
+func (c *Client) SaveRelationNamespace(ctx context.Context, namespace model.RelationNamespace) (*model.RelationNamespace, error) {
+	return call[model.RelationNamespace](ctx, c, http.MethodPut, apiPath+"/relations/namespaces"+pathEscape(namespace.Name), namespace)
+}
+
+// DeleteRelationNamespace deletes a relation namespace.
+func (c *Client) DeleteRelationNamespace(ctx context.Context, name string) error {
+	return c.delete(ctx, apiPath+"/relations/namespaces"+pathEscape(name))
+}
+
+// WriteRelations writes and deletes relation tuples, in their string form (namespace:object#relation@subject).
+func (c *Client) WriteRelations(ctx context.Context, writes, deletes []string) (*model.RelationWrite, error) {
+	body := map[string][]string{"writes": writes, "deletes": deletes}
+
+	return call[model.RelationWrite](ctx, c, http.MethodPost, apiPath+"/relations/write", body)
+}
+
+// CheckRelation checks a relation tuple, at least as fresh as the consistency token if any.
+func (c *Client) CheckRelation(ctx context.Context, tuple, consistencyToken string) (*model.RelationCheck, error) {
+	body := map[string]string{"tuple": tuple, "consistency_token": consistencyToken}
+
+	return call[model.RelationCheck](ctx, c, http.MethodPost, apiPath+"/relations/check", body)
+}
+
+// ExpandRelation returns the tree of the subjects of a userset.
+func (c *Client) ExpandRelation(ctx context.Context, userset, consistencyToken string) (*model.RelationExpansion, error) {
+	body := map[string]string{"userset": userset, "consistency_token": consistencyToken}
+
+	return call[model.RelationExpansion](ctx, c, http.MethodPost, apiPath+"/relations/expand", body)
+}
+
+// ListRelationObjects returns the objects of a namespace the subject has a relation with.
+func (c *Client) ListRelationObjects(ctx context.Context, namespace, relation, subject, consistencyToken string) (*model.RelationObjects, error) {
+	body := map[string]string{"namespace": namespace, "relation": relation, "subject": subject, "consistency_token": consistencyToken}
+
+	return call[model.RelationObjects](ctx, c, http.MethodPost, apiPath+"/relations/list-objects", body)
+}
+
+// SCIMTokens returns the tokens of the provisioning clients of the organization of the issuer.
+func (c *Client) SCIMTokens(ctx context.Context) ([]model.SCIMToken, error) {
+	return get[[]model.SCIMToken](ctx, c, apiPath+"/scim/tokens")
+}
+
+// CreateSCIMToken creates a token for a provisioning client. Its value is only returned once.
+func (c *Client) CreateSCIMToken(ctx context.Context, name string) (*model.SCIMTokenCreation, error) {
+	return call[model.SCIMTokenCreation](ctx, c, http.MethodPost, apiPath+"/scim/tokens", map[string]string{"name": name})
+}
+
+// RevokeSCIMToken revokes the token of a provisioning client.
+func (c *Client) RevokeSCIMToken(ctx context.Context, tokenID string) error {
+	return c.delete(ctx, apiPath+"/scim/tokens"+pathEscape(tokenID))
+}
+
+// AuditEvents returns a page of the audit events of the organization of the issuer matching the query,
+// from the most recent. The cursor of the query is the NextCursor of the previous page.
+func (c *Client) AuditEvents(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+	params := url.Values{}
+
+	for name, value := range map[string]string{
+		"type":      query.Type,
+		"outcome":   query.Outcome,
+		"subject":   query.Subject,
+		"client_id": query.Client,
+		"target":    query.Target,
+		"ip":        query.IP,
+		"cursor":    query.Cursor,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+
+	if query.Since != nil {
+		params.Set("since", query.Since.Format(time.RFC3339))
+	}
+
+	if query.Until != nil {
+		params.Set("until", query.Until.Format(time.RFC3339))
+	}
+
+	if query.Limit > 0 {
+		params.Set("limit", strconv.Itoa(query.Limit))
+	}
+
+	page := new(model.AuditPage)
+	if err := c.api(ctx, request{method: http.MethodGet, path: apiPath + "/audit/events", query: params, auth: authAPI}, page); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Webhooks returns the webhooks of the organization of the issuer.
+func (c *Client) Webhooks(ctx context.Context) ([]model.Webhook, error) {
+	return get[[]model.Webhook](ctx, c, apiPath+"/webhooks")
+}
+
+// Webhook returns a webhook.
+func (c *Client) Webhook(ctx context.Context, webhookID string) (*model.Webhook, error) {
+	return getOne[model.Webhook](ctx, c, apiPath+"/webhooks"+pathEscape(webhookID))
+}
+
+// CreateWebhook creates a webhook. Its secret, signing the deliveries, is only returned once.
+func (c *Client) CreateWebhook(ctx context.Context, webhook model.Webhook) (*model.WebhookCreation, error) {
+	return call[model.WebhookCreation](ctx, c, http.MethodPost, apiPath+"/webhooks", webhook)
+}
+
+// UpdateWebhook replaces the URL, the description, the events and the state of a webhook.
+func (c *Client) UpdateWebhook(ctx context.Context, webhookID string, webhook model.Webhook) (*model.Webhook, error) {
+	return call[model.Webhook](ctx, c, http.MethodPut, apiPath+"/webhooks"+pathEscape(webhookID), webhook)
+}
+
+// DeleteWebhook deletes a webhook and its deliveries.
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID string) error {
+	return c.delete(ctx, apiPath+"/webhooks"+pathEscape(webhookID))
+}
+
+// WebhookDeliveries returns the most recent deliveries of a webhook, filtered by status unless it is empty.
+func (c *Client) WebhookDeliveries(ctx context.Context, webhookID, status string) ([]model.WebhookDelivery, error) {
+	var query url.Values
+	if status != "" {
+		query = url.Values{"status": {status}}
+	}
+
+	var deliveries []model.WebhookDelivery
+	err := c.api(ctx, request{method: http.MethodGet, path: apiPath + "/webhooks" + pathEscape(webhookID, "deliveries"), query: query, auth: authAPI}, &deliveries)
+
+	return deliveries, err
+}
+
+// ReplayWebhookDelivery queues the event of a delivery again, as a new delivery.
+func (c *Client) ReplayWebhookDelivery(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDelivery, error) {
+	return call[model.WebhookDelivery](ctx, c, http.MethodPost, apiPath+"/webhooks"+pathEscape(webhookID, "deliveries", deliveryID, "replay"), nil)
+}
+
+// get sends a GET request to the API, authenticated as the client.
+func get[T any](ctx context.Context, c *Client, path string) (T, error) {
+	var res T
+	err := c.api(ctx, request{method: http.MethodGet, path: path, auth: authAPI}, &res)
+
+	return res, err
+}
+
+// getOne sends a GET request to the API returning a single resource.
+func getOne[T any](ctx context.Context, c *Client, path string) (*T, error) {
+	res := new(T)
+	if err := c.api(ctx, request{method: http.MethodGet, path: path, auth: authAPI}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// call sends a request with a JSON body to the API, authenticated as the client.
+func call[T any](ctx context.Context, c *Client, method, path string, body any) (*T, error) {
+	res := new(T)
+	if err := c.api(ctx, request{method: method, path: path, body: body, auth: authAPI}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// delete sends a DELETE request to the API, authenticated as the client.
+func (c *Client) delete(ctx context.Context, path string) error {
+	return c.api(ctx, request{method: http.MethodDelete, path: path, auth: authAPI}, nil)
+}
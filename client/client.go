@@ -0,0 +1,332 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/m3talux/goauth/model"
+)
+
+// Default values of the configuration.
+const (
+	defaultMaxRetries  = 3
+	defaultMinBackoff  = 200 * time.Millisecond
+	defaultMaxBackoff  = 5 * time.Second
+	defaultHTTPTimeout = 10 * time.Second
+)
+
+// Name of the session cookie of goauth.
+const defaultSessionCookieName = "goauth_session"
+
+// Paths of the endpoints of goauth, relative to the issuer.
+const (
+	oauthPath   = "/oauth2"
+	apiPath     = "/api/v1"
+	accountPath = "/account"
+)
+
+// Config configures a client of the goauth API.
+type Config struct {
+	// Issuer is the issuer of goauth, or of the organization the client calls. The endpoints of the
+	// platform, like the roles and the organizations, are served by the issuer of the default organization.
+	Issuer string
+	// ClientID and ClientSecret authenticate the client to the token endpoint. The API is called with the
+	// access tokens obtained with the client credentials grant, requesting Scopes.
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// APIKey authenticates the calls to the API instead of the client credentials, when set.
+	APIKey string
+	// SessionCookieName is the name of the session cookie sent to the account endpoints.
+	SessionCookieName string
+	// MaxRetries is the number of retries of the requests failing with a network error or a transient
+	// status, with an exponential backoff between MinBackoff and MaxBackoff. Set it to -1 to disable them.
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// HTTPClient sends the requests. Defaults to a client with a 10 seconds timeout.
+	HTTPClient *http.Client
+}
+
+// Client calls the goauth API. It is safe for concurrent use.
+type Client struct {
+	config Config
+	mutex  sync.Mutex
+	// token is the cached access token of the client credentials grant.
+	token          string
+	tokenExpiresAt time.Time
+}
+
+// request describes a request to goauth.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	// body is sent as JSON, form as an URL encoded form.
+	body any
+	form url.Values
+	// auth is the authentication of the request.
+	auth authentication
+	// session is the session cookie of the account endpoints.
+	session string
+}
+
+type authentication int
+
+const (
+	authNone authentication = iota
+	authAPI
+	authClient
+)
+
+// api sends a request to the API, and decodes the data of its model.APIResponse envelope into out.
+func (c *Client) api(ctx context.Context, req request, out any) error {
+	res, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	response := model.APIResponse{Data: out}
+	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+		// Errors that are not sent by goauth, like the ones of proxies, have no envelope
+		if res.StatusCode >= http.StatusBadRequest {
+			return &APIError{StatusCode: res.StatusCode}
+		}
+
+		return err
+	}
+
+	response.StatusCode = res.StatusCode
+
+	if response.Status == model.APIResponseStatusError || res.StatusCode >= http.StatusBadRequest {
+		return newAPIError(response)
+	}
+
+	return nil
+}
+
+// oauth sends a request to an OAuth endpoint, or another endpoint answering without the model.APIResponse
+// envelope, and decodes its response into out. Errors are returned as model.OAuthError.
+func (c *Client) oauth(ctx context.Context, req request, out any) error {
+	res, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		oauthErr := model.OAuthError{StatusCode: res.StatusCode}
+		if err = json.NewDecoder(res.Body).Decode(&oauthErr); err != nil || oauthErr.Code == "" {
+			oauthErr.Code = model.OAuthErrorServerError
+		}
+
+		return oauthErr
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// send sends a request, retrying it on network errors and transient statuses. The access token of the
+// client is renewed once if the API rejects it, since it may have been revoked.
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var body []byte
+
+	switch {
+	case req.form != nil:
+		body = []byte(req.form.Encode())
+	case req.body != nil:
+		encoded, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, err
+		}
+
+		body = encoded
+	}
+
+	renewed := false
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := c.newRequest(ctx, req, body)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := c.config.HTTPClient.Do(httpReq)
+
+		if err == nil && res.StatusCode == http.StatusUnauthorized && req.auth == authAPI && c.config.APIKey == "" && !renewed {
+			res.Body.Close()
+			c.resetToken()
+			renewed = true
+			attempt--
+
+			continue
+		}
+
+		if attempt >= c.config.MaxRetries || !isRetryable(req.method, res, err) {
+			return res, err
+		}
+
+		delay := c.backoff(attempt, res)
+
+		if res != nil {
+			res.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// newRequest creates the HTTP request of an attempt.
+func (c *Client) newRequest(ctx context.Context, req request, body []byte) (*http.Request, error) {
+	uri := c.config.Issuer + req.path
+	if len(req.query) > 0 {
+		uri += "?" + req.query.Encode()
+	}
+
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, uri, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+
+	switch {
+	case req.form != nil:
+		httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	case req.body != nil:
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	switch req.auth {
+	case authAPI:
+		if c.config.APIKey != "" {
+			httpReq.Header.Set("Authorization", model.APIKeyScheme+" "+c.config.APIKey)
+
+			break
+		}
+
+		token, err := c.accessToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		httpReq.Header.Set("Authorization", model.TokenTypeBearer+" "+token)
+	case authClient:
+		httpReq.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+	case authNone:
+	}
+
+	if req.session != "" {
+		httpReq.AddCookie(&http.Cookie{Name: c.config.SessionCookieName, Value: req.session})
+	}
+
+	return httpReq, nil
+}
+
+// backoff returns the delay before the next attempt: the delay requested by the server with a
+// Retry-After header, or an exponential delay with jitter.
+func (c *Client) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, c.config.MaxBackoff)
+		}
+	}
+
+	delay := c.config.MinBackoff << attempt
+	if delay <= 0 || delay > c.config.MaxBackoff {
+		delay = c.config.MaxBackoff
+	}
+
+	// Full jitter spreads the retries of concurrent clients
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// isRetryable returns true if a failed attempt can be retried. The requests that were not processed by
+// goauth are always retried, the others only when they are idempotent.
+func isRetryable(method string, res *http.Response, err error) bool {
+	idempotent := method != http.MethodPost && method != http.MethodPatch
+
+	if err != nil {
+		return idempotent && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+
+	return false
+}
+
+// pathEscape escapes the identifiers inserted in the paths of the endpoints.
+func pathEscape(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = url.PathEscape(segment)
+	}
+
+	return "/" + strings.Join(escaped, "/")
+}
+
+// New returns a client of the goauth API.
+func New(config Config) (*Client, error) {
+	if config.Issuer == "" {
+		return nil, errors.New("the issuer is required")
+	}
+
+	if config.APIKey == "" && config.ClientID == "" {
+		return nil, errors.New("an API key or client credentials are required")
+	}
+
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+
+	if config.SessionCookieName == "" {
+		config.SessionCookieName = defaultSessionCookieName
+	}
+
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultMinBackoff
+	}
+
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return &Client{config: config}, nil
+}
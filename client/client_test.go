@@ -0,0 +1,315 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/m3talux/goauth/client"
+	"github.com/m3talux/goauth/internal/goauthtest"
+	"github.com/m3talux/goauth/model"
+)
+
+// Client of the test server, administrator of the platform.
+const (
+	clientID     = "admin-client"
+	clientSecret = "secret"
+)
+
+// counter counts the requests of the test server matching a method and a path.
+type counter struct {
+	method string
+	path   string
+	count  atomic.Int32
+}
+
+func (c *counter) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == c.method && r.URL.Path == c.path {
+			c.count.Add(1)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// failing answers the first requests to a path with a status, and lets the next ones through.
+type failing struct {
+	path       string
+	status     int
+	retryAfter string
+	failures   int32
+	attempts   atomic.Int32
+	times      []time.Time
+}
+
+func (f *failing) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != f.path {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		f.times = append(f.times, time.Now())
+
+		if f.attempts.Add(1) <= f.failures {
+			if f.retryAfter != "" {
+				w.Header().Set("Retry-After", f.retryAfter)
+			}
+
+			w.WriteHeader(f.status)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newServer(t *testing.T, wrappers ...func(http.Handler) http.Handler) *goauthtest.Server {
+	t.Helper()
+
+	srv := goauthtest.NewServer(t, wrappers...)
+	srv.AddClient(t, model.Client{
+		ID:         clientID,
+		GrantTypes: []string{model.GrantTypeClientCredentials},
+		Scopes:     []string{"read"},
+		Roles:      []string{model.RoleIDAdmin},
+	}, clientSecret)
+
+	return srv
+}
+
+func newClient(t *testing.T, srv *goauthtest.Server, cfg client.Config) *client.Client {
+	t.Helper()
+
+	cfg.Issuer = srv.Issuer
+	cfg.ClientID = clientID
+	cfg.ClientSecret = clientSecret
+
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = 20 * time.Millisecond
+	}
+
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = 100 * time.Millisecond
+	}
+
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestToken(t *testing.T) {
+	srv := newServer(t)
+	c := newClient(t, srv, client.Config{})
+
+	res, err := c.Token(context.Background(), "read")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.AccessToken == "" || res.TokenType != model.TokenTypeBearer || res.Scope != "read" || res.ExpiresIn <= 0 {
+		t.Fatalf("unexpected token response %+v", res)
+	}
+
+	introspection, err := c.Introspect(context.Background(), res.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !introspection.Active || introspection.ClientID != clientID {
+		t.Fatalf("unexpected introspection response %+v", introspection)
+	}
+
+	// The errors of the token endpoint are OAuth errors
+	_, err = c.Token(context.Background(), "write")
+
+	var oauthErr model.OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != model.OAuthErrorInvalidScope {
+		t.Fatalf("expected an %s error, got %v", model.OAuthErrorInvalidScope, err)
+	}
+}
+
+func TestAccessTokenCache(t *testing.T) {
+	tokens := &counter{method: http.MethodPost, path: "/oauth2/token"}
+	srv := newServer(t, tokens.wrap)
+	c := newClient(t, srv, client.Config{Scopes: []string{"read"}})
+
+	for i := 0; i < 3; i++ {
+		roles, err := c.Roles(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(roles) != 1 || roles[0].ID != model.RoleIDAdmin {
+			t.Fatalf("unexpected roles %+v", roles)
+		}
+	}
+
+	if count := tokens.count.Load(); count != 1 {
+		t.Fatalf("expected a single token request, got %d", count)
+	}
+}
+
+func TestAccessTokenRenewal(t *testing.T) {
+	// The access tokens expire within the renewal margin of the client
+	t.Setenv("OAUTH_ACCESS_TOKEN_LIFETIME", "10")
+
+	tokens := &counter{method: http.MethodPost, path: "/oauth2/token"}
+	srv := newServer(t, tokens.wrap)
+	c := newClient(t, srv, client.Config{})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Roles(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count := tokens.count.Load(); count != 2 {
+		t.Fatalf("expected 2 token requests, got %d", count)
+	}
+}
+
+func TestAccessTokenRejected(t *testing.T) {
+	// The cached token is rejected once, as if it had been revoked
+	tokens := &counter{method: http.MethodPost, path: "/oauth2/token"}
+	rejected := &failing{path: "/api/v1/roles", status: http.StatusUnauthorized, failures: 1}
+	srv := newServer(t, tokens.wrap, rejected.wrap)
+	c := newClient(t, srv, client.Config{})
+
+	if _, err := c.Roles(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := tokens.count.Load(); count != 2 {
+		t.Fatalf("expected 2 token requests, got %d", count)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		failures   int32
+		maxRetries int
+		// attempts is the number of requests sent, and err the error of the call, if any.
+		attempts int32
+		err      error
+	}{
+		{name: "unavailable", status: http.StatusServiceUnavailable, failures: 2, attempts: 3},
+		{name: "bad gateway", status: http.StatusBadGateway, failures: 1, attempts: 2},
+		{name: "retry after", status: http.StatusTooManyRequests, retryAfter: "1", failures: 1, attempts: 2},
+		{name: "exhausted", status: http.StatusServiceUnavailable, failures: 5, maxRetries: 2, attempts: 3, err: client.ErrServer},
+		{name: "disabled", status: http.StatusServiceUnavailable, failures: 1, maxRetries: -1, attempts: 1, err: client.ErrServer},
+		{name: "not transient", status: http.StatusInternalServerError, failures: 1, attempts: 1, err: client.ErrServer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &failing{path: "/api/v1/roles", status: tt.status, retryAfter: tt.retryAfter, failures: tt.failures}
+			srv := newServer(t, f.wrap)
+			c := newClient(t, srv, client.Config{MaxRetries: tt.maxRetries})
+
+			_, err := c.Roles(context.Background())
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected the error %v, got %v", tt.err, err)
+			}
+
+			if attempts := f.attempts.Load(); attempts != tt.attempts {
+				t.Fatalf("expected %d attempts, got %d", tt.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	f := &failing{path: "/api/v1/roles", status: http.StatusServiceUnavailable, failures: 3}
+	srv := newServer(t, f.wrap)
+	c := newClient(t, srv, client.Config{MinBackoff: 40 * time.Millisecond, MaxBackoff: time.Second})
+
+	if _, err := c.Roles(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The delays double at each attempt, with a jitter of up to half of the delay
+	for i := 1; i < len(f.times); i++ {
+		delay := f.times[i].Sub(f.times[i-1])
+		if minimum := (40 * time.Millisecond << (i - 1)) / 2; delay < minimum {
+			t.Errorf("expected the retry %d after at least %s, got %s", i, minimum, delay)
+		}
+	}
+
+	// The delay requested by the server is capped by the maximum backoff
+	f = &failing{path: "/api/v1/roles", status: http.StatusTooManyRequests, retryAfter: "60", failures: 1}
+	srv = newServer(t, f.wrap)
+	c = newClient(t, srv, client.Config{MaxBackoff: 50 * time.Millisecond})
+
+	if _, err := c.Roles(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if delay := f.times[1].Sub(f.times[0]); delay >= time.Second {
+		t.Errorf("expected the Retry-After delay to be capped, the retry came after %s", delay)
+	}
+}
+
+func TestRetryNotIdempotent(t *testing.T) {
+	f := &failing{path: "/api/v1/roles", status: http.StatusBadGateway, failures: 1}
+	srv := newServer(t, f.wrap)
+	c := newClient(t, srv, client.Config{})
+
+	// A request that may have been processed is not sent again
+	_, err := c.CreateRole(context.Background(), model.Role{ID: "reader", Permissions: []string{model.PermissionRolesRead}})
+	if !errors.Is(err, client.ErrServer) {
+		t.Fatalf("expected a server error, got %v", err)
+	}
+
+	if attempts := f.attempts.Load(); attempts != 1 {
+		t.Fatalf("expected a single attempt, got %d", attempts)
+	}
+
+	// A request rejected without being processed is
+	f = &failing{path: "/api/v1/roles", status: http.StatusServiceUnavailable, failures: 1}
+	srv = newServer(t, f.wrap)
+	c = newClient(t, srv, client.Config{})
+
+	role, err := c.CreateRole(context.Background(), model.Role{ID: "reader", Permissions: []string{model.PermissionRolesRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if role.ID != "reader" || f.attempts.Load() != 2 {
+		t.Fatalf("unexpected role %+v after %d attempts", role, f.attempts.Load())
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	// The rate limits of goauth answer with a Retry-After delay longer than the maximum backoff
+	t.Setenv("RATE_LIMIT_API", "client:2/1m")
+
+	srv := newServer(t)
+	c := newClient(t, srv, client.Config{MaxRetries: -1})
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Roles(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := c.Roles(context.Background())
+
+	var apiErr *client.APIError
+	if !errors.Is(err, client.ErrRateLimited) || !errors.As(err, &apiErr) || !strings.Contains(apiErr.Message, "too many requests") {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+}
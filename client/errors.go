@@ -0,0 +1,63 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/m3talux/goauth/model"
+)
+
+// Errors matched by the API errors with errors.Is, according to their HTTP status.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+)
+
+// APIError is returned when the API responds with the error status of the model.APIResponse envelope.
+// The errors of the OAuth endpoints are returned as model.OAuthError.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return http.StatusText(e.StatusCode)
+	}
+
+	return e.Message
+}
+
+// Is matches the error with the sentinel error of its HTTP status.
+func (e *APIError) Is(target error) bool {
+	switch {
+	case e.StatusCode >= http.StatusInternalServerError:
+		return errors.Is(target, ErrServer)
+	case e.StatusCode == http.StatusBadRequest:
+		return errors.Is(target, ErrBadRequest)
+	case e.StatusCode == http.StatusUnauthorized:
+		return errors.Is(target, ErrUnauthorized)
+	case e.StatusCode == http.StatusForbidden:
+		return errors.Is(target, ErrForbidden)
+	case e.StatusCode == http.StatusNotFound:
+		return errors.Is(target, ErrNotFound)
+	case e.StatusCode == http.StatusConflict:
+		return errors.Is(target, ErrConflict)
+	case e.StatusCode == http.StatusTooManyRequests:
+		return errors.Is(target, ErrRateLimited)
+	}
+
+	return false
+}
+
+func newAPIError(response model.APIResponse) *APIError {
+	return &APIError{
+		StatusCode: response.StatusCode,
+		Message:    response.Message,
+	}
+}
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/m3talux/goauth/model"
+)
+
+// Access tokens are renewed when they expire within this delay.
+const tokenExpiryMargin = 30 * time.Second
+
+// Token requests an access token with the client credentials grant, for the given scopes.
+func (c *Client) Token(ctx context.Context, scopes ...string) (*model.TokenResponse, error) {
+	form := url.Values{"grant_type": {model.GrantTypeClientCredentials}}
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+
+	return c.tokenRequest(ctx, form)
+}
+
+// ExchangeCode exchanges an authorization code for tokens. The code verifier is the PKCE verifier of
+// the authorization request, if any.
+func (c *Client) ExchangeCode(ctx context.Context, code, redirectURI, codeVerifier string) (*model.TokenResponse, error) {
+	form := url.Values{
+		"grant_type":   {model.GrantTypeAuthorizationCode},
+		"code":         {code},
+		"redirect_uri": {redirectURI},
+	}
+
+	if codeVerifier != "" {
+		form.Set("code_verifier", codeVerifier)
+	}
+
+	return c.tokenRequest(ctx, form)
+}
+
+// Refresh exchanges a refresh token for new tokens.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*model.TokenResponse, error) {
+	return c.tokenRequest(ctx, url.Values{"grant_type": {model.GrantTypeRefreshToken}, "refresh_token": {refreshToken}})
+}
+
+// Introspect returns the state of a token (RFC 7662). Inactive tokens are not an error.
+func (c *Client) Introspect(ctx context.Context, token string) (*model.IntrospectionResponse, error) {
+	res := new(model.IntrospectionResponse)
+
+	err := c.oauth(ctx, request{
+		method: http.MethodPost,
+		path:   oauthPath + "/introspect",
+		form:   url.Values{"token": {token}},
+		auth:   authClient,
+	}, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Metadata returns the authorization server metadata of the issuer (RFC 8414).
+func (c *Client) Metadata(ctx context.Context) (*model.ServerMetadata, error) {
+	res := new(model.ServerMetadata)
+	if err := c.oauth(ctx, request{method: http.MethodGet, path: "/.well-known/openid-configuration"}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// JWKS returns the public keys verifying the JWTs signed by goauth.
+func (c *Client) JWKS(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	res := new(jose.JSONWebKeySet)
+	if err := c.oauth(ctx, request{method: http.MethodGet, path: oauthPath + "/jwks"}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Organization returns the public information of the organization of the issuer.
+func (c *Client) Organization(ctx context.Context) (*model.PublicOrganization, error) {
+	res := new(model.PublicOrganization)
+	if err := c.oauth(ctx, request{method: http.MethodGet, path: "/organization"}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) tokenRequest(ctx context.Context, form url.Values) (*model.TokenResponse, error) {
+	res := new(model.TokenResponse)
+
+	err := c.oauth(ctx, request{
+		method: http.MethodPost,
+		path:   oauthPath + "/token",
+		form:   form,
+		auth:   authClient,
+	}, res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// accessToken returns the cached access token of the client, or requests a new one when it expires.
+// Concurrent calls wait for the same token request.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.token != "" && time.Until(c.tokenExpiresAt) > tokenExpiryMargin {
+		return c.token, nil
+	}
+
+	res, err := c.Token(ctx, c.config.Scopes...)
+	if err != nil {
+		return "", err
+	}
+
+	c.token = res.AccessToken
+	c.tokenExpiresAt = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+
+	return c.token, nil
+}
+
+// resetToken drops the cached access token of the client.
+func (c *Client) resetToken() {
+	c.mutex.Lock()
+	c.token = ""
+	c.mutex.Unlock()
+}
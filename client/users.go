@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/m3talux/goauth/model"
+)
+
+// UserRoles returns the roles assigned to a user on the whole platform.
+func (c *Client) UserRoles(ctx context.Context, userID string) ([]model.Role, error) {
+	return get[[]model.Role](ctx, c, apiPath+"/users"+pathEscape(userID, "roles"))
+}
+
+// AssignUserRole assigns a role to a user on the whole platform.
+func (c *Client) AssignUserRole(ctx context.Context, userID, roleID string) error {
+	return c.api(ctx, request{method: http.MethodPut, path: apiPath + "/users" + pathEscape(userID, "roles", roleID), auth: authAPI}, nil)
+}
+
+// UnassignUserRole removes a role from a user.
+func (c *Client) UnassignUserRole(ctx context.Context, userID, roleID string) error {
+	return c.delete(ctx, apiPath+"/users"+pathEscape(userID, "roles", roleID))
+}
+
+// UserGroups returns the effective groups of a user in the organization of the issuer, direct or nested.
+// It is the source of the groups of the access tokens holding the groups_overage claim.
+func (c *Client) UserGroups(ctx context.Context, userID string) ([]model.Group, error) {
+	return get[[]model.Group](ctx, c, apiPath+"/users"+pathEscape(userID, "groups"))
+}
+
+// UserTokens returns the personal access tokens of a user.
+func (c *Client) UserTokens(ctx context.Context, userID string) ([]model.PersonalAccessToken, error) {
+	return get[[]model.PersonalAccessToken](ctx, c, apiPath+"/users"+pathEscape(userID, "tokens"))
+}
+
+// RevokeUserToken revokes a personal access token of a user.
+func (c *Client) RevokeUserToken(ctx context.Context, userID, tokenID string) error {
+	return c.delete(ctx, apiPath+"/users"+pathEscape(userID, "tokens", tokenID))
+}
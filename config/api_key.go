@@ -0,0 +1,24 @@
+package config
+
+import (
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var apiKeyEnvs apiKey
+
+type apiKey struct {
+	Prefix string `env:"API_KEY_PREFIX,default=gak"`
+}
+
+func initAPIKeyVariables() {
+	_, err := env.UnmarshalFromEnviron(&apiKeyEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load API key environment variables")
+	}
+}
+
+// APIKeyPrefix returns the prefix of the API keys, making them recognizable by secret scanners.
+func APIKeyPrefix() string {
+	return apiKeyEnvs.Prefix
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var auditEnvs audit
+
+type audit struct {
+	Retention int `env:"AUDIT_RETENTION,default=7776000"`
+	PageSize  int `env:"AUDIT_PAGE_SIZE,default=100"`
+}
+
+func initAuditVariables() {
+	_, err := env.UnmarshalFromEnviron(&auditEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load audit environment variables")
+	}
+}
+
+// AuditRetention returns how long the audit events are kept. Events are kept forever when it is zero.
+func AuditRetention() time.Duration {
+	return time.Duration(auditEnvs.Retention) * time.Second
+}
+
+// AuditPageSize returns the default, and maximum, number of audit events returned at a time.
+func AuditPageSize() int {
+	return auditEnvs.PageSize
+}
@@ -20,6 +20,7 @@ const (
 var baseEnvs base
 
 type base struct {
+	Port    string `env:"PORT,default=8080"`
 	GinMode string `env:"GIN_MODE"`
 }
 
@@ -34,6 +35,10 @@ func AppName() string {
 	return appName
 }
 
+func Port() string {
+	return baseEnvs.Port
+}
+
 func GinMode() string {
 	return baseEnvs.GinMode
 }
@@ -4,12 +4,34 @@ func Initialize() {
 	initBaseVariables()
 	initCORSVariables()
 	initMongoVariables()
+	initOAuthVariables()
+	initDPoPVariables()
+	initTLSVariables()
+	initSecurityVariables()
+	initSessionVariables()
+	initSocialVariables()
+	initSAMLVariables()
+	initLDAPVariables()
+	initSCIMVariables()
+	initRBACVariables()
+	initOrganizationVariables()
+	initInvitationVariables()
+	initAPIKeyVariables()
+	initPersonalAccessTokenVariables()
+	initPolicyVariables()
+	initRelationVariables()
+	initGroupVariables()
+	initRateLimitVariables()
+	initAuditVariables()
+	initWebhookVariables()
+	initMetricsVariables()
 }
 
 func Check() []error {
 	errs := make([]error, 0)
 
 	errs = append(errs, checkMongoEnvs()...)
+	errs = append(errs, checkOAuthEnvs()...)
 
 	return errs
 }
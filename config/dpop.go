@@ -0,0 +1,36 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var dpopEnvs dpop
+
+type dpop struct {
+	ProofMaxAge   int    `env:"DPOP_PROOF_MAX_AGE,default=60"`
+	NonceSecret   string `env:"DPOP_NONCE_SECRET"`
+	NonceLifetime int    `env:"DPOP_NONCE_LIFETIME,default=300"`
+}
+
+func initDPoPVariables() {
+	_, err := env.UnmarshalFromEnviron(&dpopEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load DPoP environment variables")
+	}
+}
+
+func DPoPProofMaxAge() time.Duration {
+	return time.Duration(dpopEnvs.ProofMaxAge) * time.Second
+}
+
+// DPoPNonceSecret returns the secret used to sign DPoP nonces. Nonces are not required when it is empty.
+func DPoPNonceSecret() string {
+	return dpopEnvs.NonceSecret
+}
+
+func DPoPNonceLifetime() time.Duration {
+	return time.Duration(dpopEnvs.NonceLifetime) * time.Second
+}
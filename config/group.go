@@ -0,0 +1,25 @@
+package config
+
+import (
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var groupEnvs group
+
+type group struct {
+	ClaimLimit int `env:"GROUP_CLAIM_LIMIT,default=150"`
+}
+
+func initGroupVariables() {
+	_, err := env.UnmarshalFromEnviron(&groupEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load group environment variables")
+	}
+}
+
+// GroupClaimLimit returns the maximum number of groups added to an access token. The tokens of the
+// subjects in more groups hold an overage indicator instead, so that they don't grow too large.
+func GroupClaimLimit() int {
+	return groupEnvs.ClaimLimit
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	invitationsPath = "/invitations"
+
+	// domainVerificationRecordPrefix prefixes the name of the DNS TXT record proving the ownership of an
+	// email domain claimed by an organization.
+	domainVerificationRecordPrefix = "_goauth-challenge."
+)
+
+var invitationEnvs invitation
+
+type invitation struct {
+	Lifetime int `env:"INVITATION_LIFETIME,default=604800"`
+}
+
+func initInvitationVariables() {
+	_, err := env.UnmarshalFromEnviron(&invitationEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load invitation environment variables")
+	}
+}
+
+func InvitationsPath() string {
+	return invitationsPath
+}
+
+// InvitationURL returns the link of an invitation, opened by the invitee to accept it.
+func InvitationURL(token string) string {
+	return OAuthIssuer() + invitationsPath + "/" + token
+}
+
+func InvitationLifetime() time.Duration {
+	return time.Duration(invitationEnvs.Lifetime) * time.Second
+}
+
+// DomainVerificationRecord returns the name of the DNS TXT record holding the verification token of a
+// claimed email domain.
+func DomainVerificationRecord(domain string) string {
+	return domainVerificationRecordPrefix + domain
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var ldapEnvs ldap
+
+type ldap struct {
+	PoolSize int `env:"LDAP_POOL_SIZE,default=5"`
+	Timeout  int `env:"LDAP_TIMEOUT,default=10"`
+}
+
+func initLDAPVariables() {
+	_, err := env.UnmarshalFromEnviron(&ldapEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load LDAP environment variables")
+	}
+}
+
+// LDAPPoolSize returns how many idle connections are kept open to each directory.
+func LDAPPoolSize() int {
+	return ldapEnvs.PoolSize
+}
+
+// LDAPTimeout returns the timeout of the connections and requests to the directories.
+func LDAPTimeout() time.Duration {
+	return time.Duration(ldapEnvs.Timeout) * time.Second
+}
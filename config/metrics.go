@@ -0,0 +1,32 @@
+package config
+
+import (
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var metricsEnvs metrics
+
+type metrics struct {
+	Address string `env:"METRICS_ADDRESS,default=127.0.0.1:9090"`
+	Path    string `env:"METRICS_PATH,default=/metrics"`
+}
+
+func initMetricsVariables() {
+	_, err := env.UnmarshalFromEnviron(&metricsEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load metrics environment variables")
+	}
+}
+
+// MetricsAddress returns the address the Prometheus metrics are served on, apart from the public port.
+// It is the loopback interface by default, for the metrics not to be public: scrapers on other hosts
+// need a private address like 10.0.0.5:9090. The metrics are not served when it is empty.
+func MetricsAddress() string {
+	return metricsEnvs.Address
+}
+
+// MetricsPath returns the path of the Prometheus metrics.
+func MetricsPath() string {
+	return metricsEnvs.Path
+}
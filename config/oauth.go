@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	oauthBasePath   = "/oauth2"
+	accountBasePath = "/account"
+
+	parRequestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+)
+
+var oauthEnvs oauth
+
+type oauth struct {
+	Issuer                       string `env:"OAUTH_ISSUER,required=true"`
+	LoginURL                     string `env:"OAUTH_LOGIN_URL,required=true"`
+	AccountURL                   string `env:"OAUTH_ACCOUNT_URL"`
+	PARLifetime                  int    `env:"OAUTH_PAR_LIFETIME,default=60"`
+	AuthorizationRequestLifetime int    `env:"OAUTH_AUTHORIZATION_REQUEST_LIFETIME,default=600"`
+	AuthorizationCodeLifetime    int    `env:"OAUTH_AUTHORIZATION_CODE_LIFETIME,default=60"`
+	AccessTokenLifetime          int    `env:"OAUTH_ACCESS_TOKEN_LIFETIME,default=3600"`
+	RefreshTokenLifetime         int    `env:"OAUTH_REFRESH_TOKEN_LIFETIME,default=2592000"`
+	IDTokenLifetime              int    `env:"OAUTH_ID_TOKEN_LIFETIME,default=3600"`
+	SigningKeyFile               string `env:"OAUTH_SIGNING_KEY_FILE"`
+}
+
+func initOAuthVariables() {
+	_, err := env.UnmarshalFromEnviron(&oauthEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load OAuth environment variables")
+	}
+}
+
+func checkOAuthEnvs() []error {
+	errs := make([]error, 0)
+
+	if oauthEnvs.Issuer == "" {
+		details := "the OAuth issuer is not set"
+		errs = append(errs, errors.New(details))
+	}
+
+	if oauthEnvs.LoginURL == "" {
+		details := "the OAuth login URL is not set"
+		errs = append(errs, errors.New(details))
+	}
+
+	return errs
+}
+
+func OAuthIssuer() string {
+	return oauthEnvs.Issuer
+}
+
+func OAuthPath() string {
+	return oauthBasePath
+}
+
+// OAuthEndpoint returns the absolute URL of an OAuth endpoint, as advertised to clients.
+func OAuthEndpoint(path string) string {
+	return oauthEnvs.Issuer + oauthBasePath + path
+}
+
+func OAuthLoginURL() string {
+	return oauthEnvs.LoginURL
+}
+
+// AccountPath returns the path of the endpoints letting logged-in end-users manage their account.
+func AccountPath() string {
+	return accountBasePath
+}
+
+// OAuthAccountURL returns the page where end-users manage their account, where they are redirected after
+// linking an identity. It defaults to the login page.
+func OAuthAccountURL() string {
+	if oauthEnvs.AccountURL == "" {
+		return oauthEnvs.LoginURL
+	}
+
+	return oauthEnvs.AccountURL
+}
+
+func PARRequestURIPrefix() string {
+	return parRequestURIPrefix
+}
+
+func PARLifetime() time.Duration {
+	return time.Duration(oauthEnvs.PARLifetime) * time.Second
+}
+
+func AuthorizationRequestLifetime() time.Duration {
+	return time.Duration(oauthEnvs.AuthorizationRequestLifetime) * time.Second
+}
+
+func AuthorizationCodeLifetime() time.Duration {
+	return time.Duration(oauthEnvs.AuthorizationCodeLifetime) * time.Second
+}
+
+func AccessTokenLifetime() time.Duration {
+	return time.Duration(oauthEnvs.AccessTokenLifetime) * time.Second
+}
+
+func RefreshTokenLifetime() time.Duration {
+	return time.Duration(oauthEnvs.RefreshTokenLifetime) * time.Second
+}
+
+func IDTokenLifetime() time.Duration {
+	return time.Duration(oauthEnvs.IDTokenLifetime) * time.Second
+}
+
+// OAuthSigningKeyFile returns the PEM file of the private key signing the JWTs issued by goauth.
+// An ephemeral key is generated when it is empty.
+func OAuthSigningKeyFile() string {
+	return oauthEnvs.SigningKeyFile
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+const organizationsPath = "/o"
+
+var organizationEnvs organization
+
+type organization struct {
+	DefaultID string `env:"ORGANIZATION_DEFAULT_ID,default=default"`
+}
+
+func initOrganizationVariables() {
+	_, err := env.UnmarshalFromEnviron(&organizationEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load organization environment variables")
+	}
+}
+
+// OrganizationsPath returns the path under which the endpoints of every organization are served, the
+// organization being resolved from the next path segment.
+func OrganizationsPath() string {
+	return organizationsPath
+}
+
+// DefaultOrganizationID returns the organization of the requests that don't target one, which also
+// holds the documents created before organizations were introduced.
+func DefaultOrganizationID() string {
+	return organizationEnvs.DefaultID
+}
+
+// OrganizationPath returns the path prefix of the endpoints of an organization, empty for the default
+// organization served at the root.
+func OrganizationPath(organizationID string) string {
+	if organizationID == "" || organizationID == organizationEnvs.DefaultID {
+		return ""
+	}
+
+	return organizationsPath + "/" + organizationID
+}
+
+// OrganizationIssuer returns the issuer identifier of an organization.
+func OrganizationIssuer(organizationID string) string {
+	return OAuthIssuer() + OrganizationPath(organizationID)
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var personalAccessTokenEnvs personalAccessToken
+
+type personalAccessToken struct {
+	Prefix      string `env:"PAT_PREFIX,default=gpat"`
+	MaxLifetime int    `env:"PAT_MAX_LIFETIME,default=31536000"`
+}
+
+func initPersonalAccessTokenVariables() {
+	_, err := env.UnmarshalFromEnviron(&personalAccessTokenEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load personal access token environment variables")
+	}
+}
+
+// PersonalAccessTokenPrefix returns the prefix of the personal access tokens, telling them apart from the
+// access tokens issued to clients.
+func PersonalAccessTokenPrefix() string {
+	return personalAccessTokenEnvs.Prefix
+}
+
+// PersonalAccessTokenMaxLifetime returns the longest lifetime of a personal access token, also used when
+// the end-user does not choose an expiration date.
+func PersonalAccessTokenMaxLifetime() time.Duration {
+	return time.Duration(personalAccessTokenEnvs.MaxLifetime) * time.Second
+}
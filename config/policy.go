@@ -0,0 +1,31 @@
+package config
+
+import (
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var policyEnvs policy
+
+type policy struct {
+	CostLimit  uint64 `env:"POLICY_COST_LIMIT,default=10000"`
+	BatchLimit int    `env:"POLICY_BATCH_LIMIT,default=100"`
+}
+
+func initPolicyVariables() {
+	_, err := env.UnmarshalFromEnviron(&policyEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load policy environment variables")
+	}
+}
+
+// PolicyCostLimit returns the maximum cost of the evaluation of a policy condition, stopping the
+// conditions iterating over large attributes.
+func PolicyCostLimit() uint64 {
+	return policyEnvs.CostLimit
+}
+
+// PolicyBatchLimit returns the maximum number of authorization decisions requested at once.
+func PolicyBatchLimit() int {
+	return policyEnvs.BatchLimit
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+// Backends of the rate limiter.
+const (
+	RateLimitBackendMemory = "memory"
+	RateLimitBackendMongo  = "mongo"
+)
+
+var rateLimitEnvs rateLimit
+
+type rateLimit struct {
+	Backend        string `env:"RATE_LIMIT_BACKEND,default=memory"`
+	Login          string `env:"RATE_LIMIT_LOGIN,default=ip:20/1m"`
+	OAuth          string `env:"RATE_LIMIT_OAUTH,default=ip:120/1m"`
+	Account        string `env:"RATE_LIMIT_ACCOUNT,default=user:60/1m"`
+	SCIM           string `env:"RATE_LIMIT_SCIM,default=ip:600/1m"`
+	API            string `env:"RATE_LIMIT_API,default=user:600/1m"`
+	Authentication string `env:"RATE_LIMIT_AUTHENTICATION,default=ip:600/1m"`
+}
+
+func initRateLimitVariables() {
+	_, err := env.UnmarshalFromEnviron(&rateLimitEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load rate limit environment variables")
+	}
+}
+
+// RateLimitBackend returns where the buckets of the rate limiter are kept: in memory for a single
+// instance, or in MongoDB so that the instances share them.
+func RateLimitBackend() string {
+	return rateLimitEnvs.Backend
+}
+
+// RateLimitPolicies returns the rate limit policies of the route groups, by name, written as
+// key:capacity/period. The route groups without policy are not rate limited.
+func RateLimitPolicies() map[string]string {
+	policies := map[string]string{
+		"login":          rateLimitEnvs.Login,
+		"oauth":          rateLimitEnvs.OAuth,
+		"account":        rateLimitEnvs.Account,
+		"scim":           rateLimitEnvs.SCIM,
+		"api":            rateLimitEnvs.API,
+		"authentication": rateLimitEnvs.Authentication,
+	}
+
+	for name, policy := range policies {
+		if policy == "" || policy == "none" {
+			delete(policies, name)
+		}
+	}
+
+	return policies
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var rbacEnvs rbac
+
+type rbac struct {
+	AdminUsers   string `env:"RBAC_ADMIN_USERS"`
+	AdminClients string `env:"RBAC_ADMIN_CLIENTS"`
+}
+
+func initRBACVariables() {
+	_, err := env.UnmarshalFromEnviron(&rbacEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load RBAC environment variables")
+	}
+}
+
+// RBACAdminUsers returns the identifiers of the users granted the admin role at startup, giving access
+// to the administration API before any role is assigned.
+func RBACAdminUsers() []string {
+	return splitList(rbacEnvs.AdminUsers)
+}
+
+// RBACAdminClients returns the identifiers of the clients granted the admin role at startup.
+func RBACAdminClients() []string {
+	return splitList(rbacEnvs.AdminClients)
+}
+
+// splitList splits a comma-separated environment variable, ignoring empty values.
+func splitList(value string) []string {
+	values := make([]string, 0)
+
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
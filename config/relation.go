@@ -0,0 +1,39 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var relationEnvs relation
+
+type relation struct {
+	MaxDepth           int `env:"RELATION_MAX_DEPTH,default=25"`
+	CheckCacheLifetime int `env:"RELATION_CHECK_CACHE_LIFETIME,default=10"`
+	CheckCacheSize     int `env:"RELATION_CHECK_CACHE_SIZE,default=10000"`
+}
+
+func initRelationVariables() {
+	_, err := env.UnmarshalFromEnviron(&relationEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load relation environment variables")
+	}
+}
+
+// RelationMaxDepth returns the maximum number of usersets followed when evaluating a relation.
+func RelationMaxDepth() int {
+	return relationEnvs.MaxDepth
+}
+
+// RelationCheckCacheLifetime returns how long the result of a relationship check is reused by the checks
+// without consistency token, or with an older one.
+func RelationCheckCacheLifetime() time.Duration {
+	return time.Duration(relationEnvs.CheckCacheLifetime) * time.Second
+}
+
+// RelationCheckCacheSize returns the maximum number of relationship checks cached by the server.
+func RelationCheckCacheSize() int {
+	return relationEnvs.CheckCacheSize
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var samlEnvs saml
+
+type saml struct {
+	KeyFile         string `env:"SAML_KEY_FILE"`
+	CertificateFile string `env:"SAML_CERTIFICATE_FILE"`
+	RequestLifetime int    `env:"SAML_REQUEST_LIFETIME,default=600"`
+}
+
+func initSAMLVariables() {
+	_, err := env.UnmarshalFromEnviron(&samlEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load SAML environment variables")
+	}
+}
+
+// SAMLPath returns the path of the SAML identity provider endpoints.
+func SAMLPath() string {
+	return "/saml"
+}
+
+// SAMLEndpoint returns the URL of a SAML identity provider endpoint. The metadata URL is also the
+// entity ID of the identity provider.
+func SAMLEndpoint(path string) string {
+	return OAuthIssuer() + SAMLPath() + path
+}
+
+// SAMLServiceProviderMetadataURL returns the metadata URL of the service provider registered on the
+// upstream SAML identity provider of a connection, which is also its entity ID.
+func SAMLServiceProviderMetadataURL(connectionID string) string {
+	return OAuthIssuer() + SocialLoginPath() + "/" + connectionID + "/metadata"
+}
+
+// SAMLKeyFile returns the PEM file of the RSA key signing the SAML messages.
+func SAMLKeyFile() string {
+	return samlEnvs.KeyFile
+}
+
+// SAMLCertificateFile returns the PEM file of the certificate of the SAML signing key, published in the metadata.
+func SAMLCertificateFile() string {
+	return samlEnvs.CertificateFile
+}
+
+// SAMLRequestLifetime returns how long a SAML authentication request waits for the end-user to log in.
+func SAMLRequestLifetime() time.Duration {
+	return time.Duration(samlEnvs.RequestLifetime) * time.Second
+}
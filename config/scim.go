@@ -0,0 +1,36 @@
+package config
+
+import (
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+const scimPath = "/scim/v2"
+
+var scimEnvs scim
+
+type scim struct {
+	MaxResults int `env:"SCIM_MAX_RESULTS,default=200"`
+}
+
+func initSCIMVariables() {
+	_, err := env.UnmarshalFromEnviron(&scimEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load SCIM environment variables")
+	}
+}
+
+func SCIMPath() string {
+	return scimPath
+}
+
+// SCIMEndpoint returns the absolute URL of a SCIM endpoint, used as location of the SCIM resources.
+func SCIMEndpoint(path string) string {
+	return OAuthIssuer() + scimPath + path
+}
+
+// SCIMMaxResults returns the maximum number of resources returned by a SCIM query, also used when the
+// client does not ask for a page size.
+func SCIMMaxResults() int {
+	return scimEnvs.MaxResults
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var securityEnvs security
+
+type security struct {
+	SecretsEncryptionKey string `env:"SECRETS_ENCRYPTION_KEY"`
+	JWKSCacheLifetime    int    `env:"JWKS_CACHE_LIFETIME,default=300"`
+}
+
+func initSecurityVariables() {
+	_, err := env.UnmarshalFromEnviron(&securityEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load security environment variables")
+	}
+}
+
+// SecretsEncryptionKey returns the AES-256 key used to encrypt the secrets that have to be retrieved
+// later, like the secrets of client_secret_jwt clients. It returns nil if the key is not set or invalid.
+func SecretsEncryptionKey() []byte {
+	key, err := base64.StdEncoding.DecodeString(securityEnvs.SecretsEncryptionKey)
+	if err != nil || len(key) == 0 {
+		return nil
+	}
+
+	return key
+}
+
+// JWKSCacheLifetime returns how long the key sets fetched from remote URIs are cached.
+func JWKSCacheLifetime() time.Duration {
+	return time.Duration(securityEnvs.JWKSCacheLifetime) * time.Second
+}
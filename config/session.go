@@ -0,0 +1,46 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var sessionEnvs session
+
+type session struct {
+	Lifetime                     int    `env:"SESSION_LIFETIME,default=86400"`
+	CookieName                   string `env:"SESSION_COOKIE_NAME,default=goauth_session"`
+	CookieSecure                 bool   `env:"SESSION_COOKIE_SECURE,default=true"`
+	LogoutTokenLifetime          int    `env:"SESSION_LOGOUT_TOKEN_LIFETIME,default=120"`
+	BackchannelLogoutMaxAttempts int    `env:"SESSION_BACKCHANNEL_LOGOUT_MAX_ATTEMPTS,default=5"`
+}
+
+func initSessionVariables() {
+	_, err := env.UnmarshalFromEnviron(&sessionEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load session environment variables")
+	}
+}
+
+func SessionLifetime() time.Duration {
+	return time.Duration(sessionEnvs.Lifetime) * time.Second
+}
+
+func SessionCookieName() string {
+	return sessionEnvs.CookieName
+}
+
+func SessionCookieSecure() bool {
+	return sessionEnvs.CookieSecure
+}
+
+func LogoutTokenLifetime() time.Duration {
+	return time.Duration(sessionEnvs.LogoutTokenLifetime) * time.Second
+}
+
+// BackchannelLogoutMaxAttempts returns how many times a logout token is sent to a client before giving up.
+func BackchannelLogoutMaxAttempts() int {
+	return sessionEnvs.BackchannelLogoutMaxAttempts
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var socialEnvs social
+
+type social struct {
+	LoginStateLifetime     int    `env:"SOCIAL_LOGIN_STATE_LIFETIME,default=600"`
+	StateCookieName        string `env:"SOCIAL_STATE_COOKIE_NAME,default=goauth_login_state"`
+	DiscoveryCacheLifetime int    `env:"SOCIAL_DISCOVERY_CACHE_LIFETIME,default=3600"`
+}
+
+func initSocialVariables() {
+	_, err := env.UnmarshalFromEnviron(&socialEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load social login environment variables")
+	}
+}
+
+// SocialLoginPath returns the path of the endpoints handling logins with upstream providers.
+func SocialLoginPath() string {
+	return "/login"
+}
+
+// SocialLoginCallbackURL returns the redirect URI registered on the upstream provider of a connection.
+func SocialLoginCallbackURL(connectionID string) string {
+	return OAuthIssuer() + SocialLoginPath() + "/" + connectionID + "/callback"
+}
+
+// SocialLoginStateLifetime returns how long the end-user has to log in on the upstream provider.
+func SocialLoginStateLifetime() time.Duration {
+	return time.Duration(socialEnvs.LoginStateLifetime) * time.Second
+}
+
+func SocialStateCookieName() string {
+	return socialEnvs.StateCookieName
+}
+
+// SocialDiscoveryCacheLifetime returns how long the metadata of upstream OpenID providers are cached.
+func SocialDiscoveryCacheLifetime() time.Duration {
+	return time.Duration(socialEnvs.DiscoveryCacheLifetime) * time.Second
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var tlsEnvs tlsConfig
+
+type tlsConfig struct {
+	CertFile                string `env:"TLS_CERT_FILE"`
+	KeyFile                 string `env:"TLS_KEY_FILE"`
+	ClientCAFile            string `env:"TLS_CLIENT_CA_FILE"`
+	ClientCertificateHeader string `env:"TLS_CLIENT_CERTIFICATE_HEADER"`
+	TrustedProxies          string `env:"TLS_TRUSTED_PROXIES"`
+}
+
+func initTLSVariables() {
+	_, err := env.UnmarshalFromEnviron(&tlsEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load TLS environment variables")
+	}
+}
+
+// TLSEnabled returns true if the server has to terminate TLS itself.
+func TLSEnabled() bool {
+	return tlsEnvs.CertFile != "" && tlsEnvs.KeyFile != ""
+}
+
+func TLSCertFile() string {
+	return tlsEnvs.CertFile
+}
+
+func TLSKeyFile() string {
+	return tlsEnvs.KeyFile
+}
+
+// TLSClientCAFile returns the PEM file of the certificate authorities trusted for tls_client_auth.
+func TLSClientCAFile() string {
+	return tlsEnvs.ClientCAFile
+}
+
+// TLSClientCertificateHeader returns the header in which a TLS terminating proxy forwards the client
+// certificate. Forwarded certificates are ignored when it is empty.
+func TLSClientCertificateHeader() string {
+	return tlsEnvs.ClientCertificateHeader
+}
+
+// TLSTrustedProxies returns the IPs and CIDRs of the proxies allowed to forward client certificates.
+func TLSTrustedProxies() []string {
+	if tlsEnvs.TrustedProxies == "" {
+		return nil
+	}
+
+	proxies := strings.Split(tlsEnvs.TrustedProxies, ",")
+
+	for i, proxy := range proxies {
+		proxies[i] = strings.TrimSpace(proxy)
+	}
+
+	return proxies
+}
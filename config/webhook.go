@@ -0,0 +1,57 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/rs/zerolog/log"
+)
+
+var webhookEnvs webhook
+
+type webhook struct {
+	MaxAttempts       int  `env:"WEBHOOK_MAX_ATTEMPTS,default=8"`
+	RetryDelay        int  `env:"WEBHOOK_RETRY_DELAY,default=30"`
+	PollInterval      int  `env:"WEBHOOK_POLL_INTERVAL,default=5"`
+	DeliveryRetention int  `env:"WEBHOOK_DELIVERY_RETENTION,default=2592000"`
+	AllowInsecureURLs bool `env:"WEBHOOK_ALLOW_INSECURE_URLS,default=false"`
+	AllowPrivateIPs   bool `env:"WEBHOOK_ALLOW_PRIVATE_IPS,default=false"`
+}
+
+func initWebhookVariables() {
+	_, err := env.UnmarshalFromEnviron(&webhookEnvs)
+	if err != nil {
+		log.Err(err).Msg("Could not load webhook environment variables")
+	}
+}
+
+// WebhookMaxAttempts returns how many times an event is sent to a webhook before the delivery is dead.
+func WebhookMaxAttempts() int {
+	return webhookEnvs.MaxAttempts
+}
+
+// WebhookRetryDelay returns the delay before the first retry of a failed delivery, doubled after each attempt.
+func WebhookRetryDelay() time.Duration {
+	return time.Duration(webhookEnvs.RetryDelay) * time.Second
+}
+
+// WebhookPollInterval returns how often the queue of the deliveries is polled.
+func WebhookPollInterval() time.Duration {
+	return time.Duration(webhookEnvs.PollInterval) * time.Second
+}
+
+// WebhookDeliveryRetention returns how long the deliveries, and the log of their attempts, are kept.
+func WebhookDeliveryRetention() time.Duration {
+	return time.Duration(webhookEnvs.DeliveryRetention) * time.Second
+}
+
+// WebhookAllowInsecureURLs returns true if webhooks may post events over plain HTTP, for development.
+func WebhookAllowInsecureURLs() bool {
+	return webhookEnvs.AllowInsecureURLs
+}
+
+// WebhookAllowPrivateIPs returns true if webhooks may post events to loopback, private and link-local
+// addresses, for development. Otherwise, the tenants could reach the internal network of goauth.
+func WebhookAllowPrivateIPs() bool {
+	return webhookEnvs.AllowPrivateIPs
+}
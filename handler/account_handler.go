@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+)
+
+// AccountHandler exposes the endpoints letting logged-in end-users manage their account.
+type AccountHandler struct {
+	UserManager        manager.UserManager
+	SocialLoginManager manager.SocialLoginManager
+}
+
+// Identities handler returns the upstream accounts linked to the account of the end-user.
+func (h *AccountHandler) Identities(c *gin.Context) {
+	identities, err := h.UserManager.Identities(c.Request.Context(), middleware.GetSession(c).Subject)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, identities)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// LinkIdentity handler redirects the end-user to the upstream provider of a connection, to link the
+// upstream account to their account.
+func (h *AccountHandler) LinkIdentity(c *gin.Context) {
+	authorizationURL, state, err := h.SocialLoginManager.Start(c.Request.Context(), c.Param("connection"), "", middleware.GetSession(c).Subject)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	setSocialStateCookie(c, state)
+	c.Redirect(http.StatusFound, authorizationURL)
+}
+
+// UnlinkIdentity handler removes an upstream account from the account of the end-user.
+func (h *AccountHandler) UnlinkIdentity(c *gin.Context) {
+	if err := h.UserManager.Unlink(c.Request.Context(), middleware.GetSession(c).Subject, c.Param("identity")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func NewAccountHandler(userManager manager.UserManager, socialLoginManager manager.SocialLoginManager) *AccountHandler {
+	return &AccountHandler{
+		UserManager:        userManager,
+		SocialLoginManager: socialLoginManager,
+	}
+}
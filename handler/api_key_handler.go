@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+)
+
+// APIKeyHandler exposes the administration endpoints managing the API keys of an organization.
+type APIKeyHandler struct {
+	APIKeyManager manager.APIKeyManager
+	AuditManager  manager.AuditManager
+}
+
+// apiKeyBody is the request body of the creation of an API key.
+type apiKeyBody struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeys handler returns the API keys of the organization.
+func (h *APIKeyHandler) APIKeys(c *gin.Context) {
+	apiKeys, err := h.APIKeyManager.APIKeys(c.Request.Context())
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, apiKeys)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// CreateAPIKey handler creates an API key on behalf of the subject, or the client, of the access token.
+// Its value is only returned once.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var body apiKeyBody
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token := middleware.GetAccessToken(c)
+
+	apiKey, err := h.APIKeyManager.Create(c.Request.Context(), model.APIKey{
+		Name:      body.Name,
+		Subject:   token.Subject,
+		ClientID:  token.ClientID,
+		Scopes:    body.Scopes,
+		ExpiresAt: body.ExpiresAt,
+	}, token.Permissions)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	response := model.NewAPIResponseSuccess(http.StatusCreated, apiKey)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// RevokeAPIKey handler deletes an API key.
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	err := h.APIKeyManager.Revoke(c.Request.Context(), c.Param("key"))
+	auditTokenRevocation(c, h.AuditManager, model.AuditTargetAPIKey, c.Param("key"), err)
+
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func NewAPIKeyHandler(apiKeyManager manager.APIKeyManager, auditManager manager.AuditManager) *APIKeyHandler {
+	return &APIKeyHandler{
+		APIKeyManager: apiKeyManager,
+		AuditManager:  auditManager,
+	}
+}
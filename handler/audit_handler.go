@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+)
+
+// AuditHandler exposes the administration endpoint querying the audit log of an organization.
+type AuditHandler struct {
+	AuditManager manager.AuditManager
+}
+
+// Events handler returns the audit events matching the filters of the request, from the most recent, a page
+// at a time. The since and until filters are RFC 3339 times, and the page size is capped.
+func (h *AuditHandler) Events(c *gin.Context) {
+	query := model.AuditQuery{
+		Type:    c.Query("type"),
+		Outcome: c.Query("outcome"),
+		Subject: c.Query("subject"),
+		Client:  c.Query("client_id"),
+		Target:  c.Query("target"),
+		IP:      c.Query("ip"),
+		Cursor:  c.Query("cursor"),
+		Limit:   config.AuditPageSize(),
+	}
+
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		query.Limit = min(max(limit, 1), config.AuditPageSize())
+	}
+
+	var ok bool
+
+	if query.Since, ok = queryTime(c, "since"); !ok {
+		return
+	}
+
+	if query.Until, ok = queryTime(c, "until"); !ok {
+		return
+	}
+
+	page, err := h.AuditManager.Events(c.Request.Context(), query)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, page)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// queryTime reads an optional RFC 3339 time from the query of a request. The request is aborted when the
+// time is invalid.
+func queryTime(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the "+name+" parameter is not an RFC 3339 time"))
+
+		return nil, false
+	}
+
+	return &t, true
+}
+
+func NewAuditHandler(auditManager manager.AuditManager) *AuditHandler {
+	return &AuditHandler{
+		AuditManager: auditManager,
+	}
+}
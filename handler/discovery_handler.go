@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/tenant"
+)
+
+// DiscoveryHandler exposes the metadata clients use to discover the authorization server.
+type DiscoveryHandler struct {
+	KeyManager manager.KeyManager
+}
+
+// Metadata handler returns the authorization server metadata (RFC 8414). The same document is
+// served as the OpenID Provider configuration (OpenID Connect Discovery 1.0).
+func (h *DiscoveryHandler) Metadata(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	c.JSON(http.StatusOK, model.ServerMetadata{
+		Issuer:                             tenant.Issuer(ctx),
+		AuthorizationEndpoint:              tenant.URL(ctx, config.OAuthEndpoint("/authorize")),
+		TokenEndpoint:                      tenant.URL(ctx, config.OAuthEndpoint("/token")),
+		IntrospectionEndpoint:              tenant.URL(ctx, config.OAuthEndpoint("/introspect")),
+		PushedAuthorizationRequestEndpoint: tenant.URL(ctx, config.OAuthEndpoint("/par")),
+		RequirePushedAuthorizationRequests: false,
+		ResponseTypesSupported:             []string{model.ResponseTypeCode},
+		GrantTypesSupported: []string{
+			model.GrantTypeAuthorizationCode,
+			model.GrantTypeRefreshToken,
+			model.GrantTypeClientCredentials,
+		},
+		CodeChallengeMethodsSupported: []string{model.CodeChallengeMethodS256},
+		TokenEndpointAuthMethodsSupported: []string{
+			model.ClientAuthMethodSecretBasic,
+			model.ClientAuthMethodSecretPost,
+			model.ClientAuthMethodPrivateKeyJWT,
+			model.ClientAuthMethodSecretJWT,
+			model.ClientAuthMethodTLS,
+			model.ClientAuthMethodSelfSignedTLS,
+			model.ClientAuthMethodNone,
+		},
+		TokenEndpointAuthSigningAlgValuesSupported: manager.ClientAssertionSignatureAlgorithms(),
+		TLSClientCertificateBoundAccessTokens:      true,
+		DPoPSigningAlgValuesSupported:              manager.DPoPSignatureAlgorithms(),
+		JWKSURI:                                    tenant.URL(ctx, config.OAuthEndpoint("/jwks")),
+		EndSessionEndpoint:                         tenant.URL(ctx, config.OAuthEndpoint("/logout")),
+		SubjectTypesSupported:                      []string{"public"},
+		IDTokenSigningAlgValuesSupported:           h.signingAlgorithms(),
+		BackchannelLogoutSupported:                 true,
+		BackchannelLogoutSessionSupported:          true,
+		FrontchannelLogoutSupported:                true,
+		FrontchannelLogoutSessionSupported:         true,
+	})
+}
+
+// JWKS handler returns the public keys used to verify the JWTs signed by the authorization server.
+func (h *DiscoveryHandler) JWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, h.KeyManager.KeySet())
+}
+
+// signingAlgorithms returns the algorithms of the signing keys of the authorization server.
+func (h *DiscoveryHandler) signingAlgorithms() []string {
+	keys := h.KeyManager.KeySet().Keys
+
+	algorithms := make([]string, 0, len(keys))
+	for _, key := range keys {
+		algorithms = append(algorithms, key.Algorithm)
+	}
+
+	return algorithms
+}
+
+func NewDiscoveryHandler(keyManager manager.KeyManager) *DiscoveryHandler {
+	return &DiscoveryHandler{
+		KeyManager: keyManager,
+	}
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+)
+
+// DomainHandler exposes the administration endpoints managing the email domains claimed by an organization,
+// and the discovery of the organization of an email by the login pages.
+type DomainHandler struct {
+	DomainManager manager.DomainManager
+}
+
+// Domains handler returns the email domains claimed by the organization.
+func (h *DomainHandler) Domains(c *gin.Context) {
+	domains, err := h.DomainManager.Domains(c.Request.Context())
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, domains)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// ClaimDomain handler claims an email domain for the organization. The response holds the token to publish
+// in the DNS TXT record of the domain to verify it.
+func (h *DomainHandler) ClaimDomain(c *gin.Context) {
+	var body model.DomainClaim
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token := middleware.GetAccessToken(c)
+
+	claim, err := h.DomainManager.Claim(c.Request.Context(), body, token.Permissions)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusCreated, claim)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// UpdateDomain handler replaces the connection, the automatic join and the roles of a claimed domain.
+func (h *DomainHandler) UpdateDomain(c *gin.Context) {
+	var body model.DomainClaim
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token := middleware.GetAccessToken(c)
+
+	claim, err := h.DomainManager.Update(c.Request.Context(), c.Param("domain"), body, token.Permissions)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, claim)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// DeleteDomain handler removes the claim of a domain.
+func (h *DomainHandler) DeleteDomain(c *gin.Context) {
+	if err := h.DomainManager.Delete(c.Request.Context(), c.Param("domain")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyDomain handler verifies a claimed domain from its DNS TXT record.
+func (h *DomainHandler) VerifyDomain(c *gin.Context) {
+	claim, err := h.DomainManager.Verify(c.Request.Context(), c.Param("domain"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, claim)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// Discover handler returns the organization that verified the domain of an email, and its connection, so
+// that the login page can route the end-user to it.
+func (h *DomainHandler) Discover(c *gin.Context) {
+	discovery, err := h.DomainManager.Discover(c.Request.Context(), c.Query("email"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, discovery)
+}
+
+func NewDomainHandler(domainManager manager.DomainManager) *DomainHandler {
+	return &DomainHandler{
+		DomainManager: domainManager,
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+)
+
+// GroupHandler exposes the administration endpoints managing the groups of an organization, their nested
+// members and their roles.
+type GroupHandler struct {
+	GroupManager manager.GroupManager
+}
+
+// Groups handler returns the groups of the organization.
+func (h *GroupHandler) Groups(c *gin.Context) {
+	groups, err := h.GroupManager.Groups(c.Request.Context())
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, groups)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// Group handler returns a group.
+func (h *GroupHandler) Group(c *gin.Context) {
+	group, err := h.GroupManager.Get(c.Request.Context(), c.Param("group"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, group)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// CreateGroup handler creates a group.
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	var body model.Group
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token := middleware.GetAccessToken(c)
+
+	group, err := h.GroupManager.Create(c.Request.Context(), body, token.Permissions)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusCreated, group)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// UpdateGroup handler replaces the display name, the members and the roles of a group.
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	var body model.Group
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token := middleware.GetAccessToken(c)
+
+	group, err := h.GroupManager.Update(c.Request.Context(), c.Param("group"), body, token.Permissions)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, group)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// DeleteGroup handler deletes a group.
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	if err := h.GroupManager.Delete(c.Request.Context(), c.Param("group")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UserGroups handler returns the effective groups of a user, direct or nested.
+func (h *GroupHandler) UserGroups(c *gin.Context) {
+	groups, err := h.GroupManager.UserGroups(c.Request.Context(), c.Param("user"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, groups)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+func NewGroupHandler(groupManager manager.GroupManager) *GroupHandler {
+	return &GroupHandler{
+		GroupManager: groupManager,
+	}
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// abortWithOAuthError sends an OAuth error response. Errors that are not OAuth errors are logged and
+// hidden behind a server_error.
+func abortWithOAuthError(c *gin.Context, err error) {
+	var oauthErr model.OAuthError
+	if !errors.As(err, &oauthErr) {
+		log.Err(err).Str("path", c.FullPath()).Msg("Unexpected error while processing an OAuth request")
+
+		oauthErr = model.NewOAuthServerError()
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	if oauthErr.StatusCode == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", "Basic")
+	}
+
+	c.AbortWithStatusJSON(oauthErr.HTTPStatus(), oauthErr)
+}
+
+// abortWithAPIError sends an API error response. OAuth errors are converted to API responses, other
+// errors are logged and hidden behind an internal server error.
+func abortWithAPIError(c *gin.Context, err error) {
+	var response model.APIResponse
+
+	var oauthErr model.OAuthError
+	if errors.As(err, &oauthErr) {
+		response = model.NewAPIResponseError(oauthErr.HTTPStatus(), oauthErr.Description)
+	} else if !errors.As(err, &response) {
+		log.Err(err).Str("path", c.FullPath()).Msg("Unexpected error while processing an API request")
+
+		response = model.NewAPIResponseError(http.StatusInternalServerError, "an unexpected error occurred")
+	}
+
+	c.AbortWithStatusJSON(response.HTTPStatus(), response)
+}
+
+// auditTokenRevocation records the revocation of a token by the end-user, or the client, of the request in
+// the audit log.
+func auditTokenRevocation(c *gin.Context, auditManager manager.AuditManager, targetType, tokenID string, err error) {
+	event := model.AuditEvent{
+		Type:    model.AuditEventTokenRevoke,
+		Outcome: model.AuditOutcomeSuccess,
+		Target:  &model.AuditTarget{Type: targetType, ID: tokenID},
+	}
+
+	if token := middleware.GetAccessToken(c); token != nil {
+		event.Actor = model.AuditActor{Subject: token.Subject, ClientID: token.ClientID}
+	} else if session := middleware.GetSession(c); session != nil {
+		event.Actor = model.AuditActor{Subject: session.Subject}
+	}
+
+	if err != nil {
+		event.Outcome = model.AuditOutcomeFailure
+		event.Reason = err.Error()
+	}
+
+	auditManager.Record(c.Request.Context(), event)
+}
+
+// abortWithSCIMError sends a SCIM error response. API and OAuth errors are converted to SCIM errors,
+// other errors are logged and hidden behind an internal server error.
+func abortWithSCIMError(c *gin.Context, err error) {
+	var scimErr model.SCIMError
+
+	var response model.APIResponse
+
+	var oauthErr model.OAuthError
+
+	switch {
+	case errors.As(err, &scimErr):
+	case errors.As(err, &response):
+		scimErr = model.NewSCIMError(response.HTTPStatus(), "", response.Message)
+	case errors.As(err, &oauthErr):
+		scimErr = model.NewSCIMError(oauthErr.HTTPStatus(), "", oauthErr.Description)
+	default:
+		log.Err(err).Str("path", c.FullPath()).Msg("Unexpected error while processing a SCIM request")
+
+		scimErr = model.NewSCIMError(http.StatusInternalServerError, "", "an unexpected error occurred")
+	}
+
+	c.Header("Content-Type", model.SCIMContentType)
+	c.AbortWithStatusJSON(scimErr.HTTPStatus(), scimErr)
+}
+
+// redirectOrAbortWithOAuthError sends an OAuth error to the client redirect URI when it is known,
+// as described in RFC 6749, section 4.1.2.1. Otherwise, the error is returned to the user-agent.
+func redirectOrAbortWithOAuthError(c *gin.Context, err error) {
+	var oauthErr model.OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.RedirectURI == "" {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	params := url.Values{"error": {oauthErr.Code}}
+
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+
+	if oauthErr.State != "" {
+		params.Set("state", oauthErr.State)
+	}
+
+	c.Redirect(http.StatusFound, withQuery(oauthErr.RedirectURI, params))
+	c.Abort()
+}
+
+// withQuery adds the given parameters to the query of a URL, keeping the existing ones.
+func withQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := u.Query()
+
+	for name, values := range params {
+		query[name] = values
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// loginURL returns the login page of the organization of the request, with the login challenge. The
+// organization is given to the login pages shared by several organizations.
+func loginURL(c *gin.Context, loginChallenge string) string {
+	params := url.Values{"login_challenge": {loginChallenge}}
+
+	if organizationID := tenant.OrganizationID(c.Request.Context()); organizationID != config.DefaultOrganizationID() {
+		params.Set("organization", organizationID)
+	}
+
+	if organization := middleware.GetOrganization(c); organization != nil && organization.Settings.LoginURL != "" {
+		return withQuery(organization.Settings.LoginURL, params)
+	}
+
+	return withQuery(config.OAuthLoginURL(), params)
+}
+
+// setSessionCookie binds a login session to the user-agent. The cookie is restricted to the path of the
+// organization, so that the user-agent can be logged in several organizations.
+func setSessionCookie(c *gin.Context, session *model.Session) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(config.SessionCookieName(), session.ID, int(config.SessionLifetime().Seconds()), sessionCookiePath(c), "", config.SessionCookieSecure(), true)
+}
+
+// setSocialStateCookie binds the state of a login on an upstream provider to the user-agent. An empty
+// state removes it. Secure cookies are also sent with cross-site posts, like the responses of SAML
+// identity providers.
+func setSocialStateCookie(c *gin.Context, state string) {
+	maxAge := int(config.SocialLoginStateLifetime().Seconds())
+	if state == "" {
+		maxAge = -1
+	}
+
+	if config.SessionCookieSecure() {
+		c.SetSameSite(http.SameSiteNoneMode)
+	} else {
+		c.SetSameSite(http.SameSiteLaxMode)
+	}
+
+	c.SetCookie(config.SocialStateCookieName(), state, maxAge, tenant.Path(c.Request.Context(), config.SocialLoginPath()), "", config.SessionCookieSecure(), true)
+}
+
+// clearSessionCookie removes the login session from the user-agent.
+func clearSessionCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(config.SessionCookieName(), "", -1, sessionCookiePath(c), "", config.SessionCookieSecure(), true)
+}
+
+// sessionCookiePath returns the path of the session cookie of the organization of the request.
+func sessionCookiePath(c *gin.Context) string {
+	return tenant.Path(c.Request.Context(), "/")
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+)
+
+// InvitationHandler exposes the administration endpoints inviting people to join an organization, and the
+// invitation links opened by the invitees.
+type InvitationHandler struct {
+	InvitationManager    manager.InvitationManager
+	AuthorizationManager manager.AuthorizationManager
+}
+
+// invitationBody is the request body of an invitation.
+type invitationBody struct {
+	Email string   `json:"email"`
+	Roles []string `json:"roles"`
+}
+
+// Invitations handler returns the pending invitations of the organization.
+func (h *InvitationHandler) Invitations(c *gin.Context) {
+	invitations, err := h.InvitationManager.Invitations(c.Request.Context())
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, invitations)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// CreateInvitation handler invites an email to join the organization. The invitation link is only
+// returned once.
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var body invitationBody
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	// The invitations sent by clients on their own behalf are attributed to the client
+	token := middleware.GetAccessToken(c)
+	invitedBy := token.Subject
+
+	if invitedBy == "" {
+		invitedBy = token.ClientID
+	}
+
+	invitation, err := h.InvitationManager.Create(c.Request.Context(), body.Email, body.Roles, invitedBy, token.Permissions)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	response := model.NewAPIResponseSuccess(http.StatusCreated, invitation)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// RevokeInvitation handler deletes a pending invitation.
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	if err := h.InvitationManager.Revoke(c.Request.Context(), c.Param("invitation")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Open handler is the invitation link. It redirects the invitee to the login page, to accept the invitation
+// once logged in with the invited email.
+func (h *InvitationHandler) Open(c *gin.Context) {
+	invitation, err := h.InvitationManager.Open(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	req, err := h.AuthorizationManager.RequestInvitationLogin(c.Request.Context(), invitation.ID)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	c.Redirect(http.StatusFound, loginURL(c, req.ID))
+}
+
+func NewInvitationHandler(invitationManager manager.InvitationManager, authorizationManager manager.AuthorizationManager) *InvitationHandler {
+	return &InvitationHandler{
+		InvitationManager:    invitationManager,
+		AuthorizationManager: authorizationManager,
+	}
+}
@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+)
+
+// LoginHandler exposes the endpoints logging end-users in with upstream identity providers.
+type LoginHandler struct {
+	SocialLoginManager   manager.SocialLoginManager
+	AuthorizationManager manager.AuthorizationManager
+	UserManager          manager.UserManager
+	SessionManager       manager.SessionManager
+	LDAPManager          manager.LDAPManager
+	InvitationManager    manager.InvitationManager
+	AuditManager         manager.AuditManager
+}
+
+// Connections handler returns the upstream providers end-users can log in with.
+func (h *LoginHandler) Connections(c *gin.Context) {
+	connections, err := h.SocialLoginManager.Connections(c.Request.Context())
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	c.JSON(http.StatusOK, connections)
+}
+
+// Start handler redirects the end-user to the upstream provider of a connection, to resume the
+// authorization request of the login challenge once logged in.
+func (h *LoginHandler) Start(c *gin.Context) {
+	loginChallenge := c.Query("login_challenge")
+
+	if !h.resumeLogin(c, loginChallenge) {
+		return
+	}
+
+	authorizationURL, state, err := h.SocialLoginManager.Start(c.Request.Context(), c.Param("connection"), loginChallenge, "")
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	setSocialStateCookie(c, state)
+	c.Redirect(http.StatusFound, authorizationURL)
+}
+
+// Callback handler is the redirect URI of the upstream providers. It logs the end-user in, creating
+// the user on the first login, then redirects to the client with an authorization code. When the login
+// was started to link an identity, the upstream account is linked to the logged-in end-user instead.
+// It is also the assertion consumer service of the SAML connections, receiving posted responses.
+func (h *LoginHandler) Callback(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		abortWithOAuthError(c, model.NewOAuthInvalidRequestError("the request could not be parsed"))
+
+		return
+	}
+
+	state, _ := c.Cookie(config.SocialStateCookieName())
+	setSocialStateCookie(c, "")
+
+	profile, loginState, err := h.SocialLoginManager.Callback(c.Request.Context(), c.Param("connection"), c.Request.Form, state)
+
+	loginChallenge := ""
+	if loginState != nil {
+		loginChallenge = loginState.LoginChallenge
+	}
+
+	if loginState != nil && loginState.UserID != "" {
+		h.link(c, loginState.UserID, profile, err)
+
+		return
+	}
+
+	if err != nil {
+		h.auditLogin(c, "", err)
+		h.abortLogin(c, loginChallenge, err)
+
+		return
+	}
+
+	h.login(c, loginChallenge, profile)
+}
+
+// Authenticate handler logs the end-user in with the username and password posted by the login page,
+// verified against the directory of an LDAP connection, then redirects to the client with an
+// authorization code.
+func (h *LoginHandler) Authenticate(c *gin.Context) {
+	loginChallenge := c.PostForm("login_challenge")
+
+	if !h.resumeLogin(c, loginChallenge) {
+		return
+	}
+
+	profile, err := h.LDAPManager.Authenticate(c.Request.Context(), c.Param("connection"), c.PostForm("username"), c.PostForm("password"))
+	if err != nil {
+		h.auditLogin(c, "", err)
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	h.login(c, loginChallenge, profile)
+}
+
+// login logs the end-user of an upstream profile in, creating the user on the first login, opens the
+// session, then redirects to the client of the login challenge. When the login challenge accepts an
+// invitation, the end-user joins the organization first.
+func (h *LoginHandler) login(c *gin.Context, loginChallenge string, profile model.ExternalProfile) {
+	user, err := h.loginUser(c.Request.Context(), loginChallenge, profile)
+	if err != nil {
+		h.auditLogin(c, "", err)
+		h.abortLogin(c, loginChallenge, err)
+
+		return
+	}
+
+	session, err := h.SessionManager.Create(c.Request.Context(), user.ID)
+	if err != nil {
+		h.auditLogin(c, user.ID, err)
+		h.abortLogin(c, loginChallenge, err)
+
+		return
+	}
+
+	h.auditLogin(c, user.ID, nil)
+	setSessionCookie(c, session)
+
+	redirectURI, err := h.AuthorizationManager.Approve(c.Request.Context(), loginChallenge, session)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURI)
+}
+
+// Metadata handler returns the SAML metadata of the service provider of a SAML connection, to be imported
+// by its identity provider.
+func (h *LoginHandler) Metadata(c *gin.Context) {
+	metadata, err := h.SocialLoginManager.ServiceProviderMetadata(c.Request.Context(), c.Param("connection"))
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// loginUser returns the user of an upstream profile, once the invitation of the login challenge, if any,
+// is accepted.
+func (h *LoginHandler) loginUser(ctx context.Context, loginChallenge string, profile model.ExternalProfile) (*model.User, error) {
+	req, err := h.AuthorizationManager.Get(ctx, loginChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if req != nil && req.InvitationID != "" {
+		return h.InvitationManager.Accept(ctx, req.InvitationID, profile)
+	}
+
+	return h.UserManager.Login(ctx, profile)
+}
+
+// resumeLogin checks the login challenge of a login, and moves the request to the organization of the
+// challenge: the login pages may be shared by several organizations. The request is aborted when the
+// challenge is invalid.
+func (h *LoginHandler) resumeLogin(c *gin.Context, loginChallenge string) bool {
+	req, err := h.AuthorizationManager.Get(c.Request.Context(), loginChallenge)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return false
+	}
+
+	if req == nil {
+		abortWithOAuthError(c, model.NewOAuthInvalidRequestError("the login challenge is invalid or expired"))
+
+		return false
+	}
+
+	if !middleware.ResumeOrganization(c, req.OrganizationID) {
+		abortWithOAuthError(c, model.NewOAuthInvalidRequestError("the login challenge belongs to another organization"))
+
+		return false
+	}
+
+	return true
+}
+
+// link completes the link of an upstream account to the logged-in end-user, then redirects to the account page.
+func (h *LoginHandler) link(c *gin.Context, userID string, profile model.ExternalProfile, err error) {
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	// The end-user must still be logged in as the user who started the link. The session cookie is not
+	// sent with the responses posted by SAML identity providers, from another site: the link is then bound
+	// to the user-agent that started it by the login state only.
+	if sessionID, _ := c.Cookie(config.SessionCookieName()); sessionID != "" || c.Request.Method != http.MethodPost {
+		session, err := h.SessionManager.Get(c.Request.Context(), sessionID)
+		if err != nil {
+			abortWithAPIError(c, err)
+
+			return
+		}
+
+		if session == nil || session.Subject != userID {
+			abortWithAPIError(c, model.NewAPIResponseError(http.StatusUnauthorized, "the session is invalid or expired"))
+
+			return
+		}
+	}
+
+	if _, err = h.UserManager.Link(c.Request.Context(), userID, profile); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Redirect(http.StatusFound, config.OAuthAccountURL())
+}
+
+// abortLogin ends a failed login. When the end-user denied the login on the upstream provider, the
+// authorization request is denied and the client is notified.
+func (h *LoginHandler) abortLogin(c *gin.Context, loginChallenge string, err error) {
+	var oauthErr model.OAuthError
+	if loginChallenge == "" || !errors.As(err, &oauthErr) || oauthErr.Code != model.OAuthErrorAccessDenied {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	redirectOrAbortWithOAuthError(c, h.AuthorizationManager.Deny(c.Request.Context(), loginChallenge, oauthErr.Description))
+}
+
+// auditLogin records a login through the connection of the request in the audit log. The username of the
+// failed logins with a username and a password is recorded along with the reason of the failure.
+func (h *LoginHandler) auditLogin(c *gin.Context, subject string, err error) {
+	event := model.AuditEvent{
+		Type:    model.AuditEventLogin,
+		Outcome: model.AuditOutcomeSuccess,
+		Actor:   model.AuditActor{Subject: subject},
+		Target:  &model.AuditTarget{Type: model.AuditTargetConnection, ID: c.Param("connection")},
+	}
+
+	if err != nil {
+		event.Outcome = model.AuditOutcomeFailure
+		event.Reason = err.Error()
+
+		if username := c.PostForm("username"); username != "" {
+			event.Details = map[string]string{"username": username}
+		}
+	}
+
+	h.AuditManager.Record(c.Request.Context(), event)
+}
+
+func NewLoginHandler(
+	socialLoginManager manager.SocialLoginManager,
+	authorizationManager manager.AuthorizationManager,
+	userManager manager.UserManager,
+	sessionManager manager.SessionManager,
+	ldapManager manager.LDAPManager,
+	invitationManager manager.InvitationManager,
+	auditManager manager.AuditManager,
+) *LoginHandler {
+	return &LoginHandler{
+		SocialLoginManager:   socialLoginManager,
+		AuthorizationManager: authorizationManager,
+		UserManager:          userManager,
+		SessionManager:       sessionManager,
+		LDAPManager:          ldapManager,
+		InvitationManager:    invitationManager,
+		AuditManager:         auditManager,
+	}
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// OAuthHandler exposes the OAuth 2.0 endpoints of the authorization server.
+type OAuthHandler struct {
+	ClientManager        manager.ClientManager
+	AuthorizationManager manager.AuthorizationManager
+	TokenManager         manager.TokenManager
+	DPoPManager          manager.DPoPManager
+	SessionManager       manager.SessionManager
+}
+
+// Authorize handler is the authorization endpoint. It validates the authorization request, then
+// redirects the end-user to the login page with a login challenge. End-users who already have an
+// active session are redirected to the client right away, unless the client asks for a new login.
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		abortWithOAuthError(c, model.NewOAuthInvalidRequestError("the request could not be parsed"))
+
+		return
+	}
+
+	req, err := h.AuthorizationManager.Authorize(c.Request.Context(), c.Request.Form)
+	if err != nil {
+		redirectOrAbortWithOAuthError(c, err)
+
+		return
+	}
+
+	if c.Request.Form.Get("prompt") != "login" {
+		if session := h.activeSession(c); session != nil {
+			redirectURI, approveErr := h.AuthorizationManager.Approve(c.Request.Context(), req.ID, session)
+			if approveErr != nil {
+				redirectOrAbortWithOAuthError(c, approveErr)
+
+				return
+			}
+
+			c.Redirect(http.StatusFound, redirectURI)
+
+			return
+		}
+	}
+
+	c.Redirect(http.StatusFound, loginURL(c, req.ID))
+}
+
+// PushedAuthorizationRequest handler is the pushed authorization request endpoint (RFC 9126).
+// It lets authenticated clients send the authorization parameters through a back-channel.
+func (h *OAuthHandler) PushedAuthorizationRequest(c *gin.Context) {
+	h.setDPoPNonce(c)
+
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	jkt, err := h.verifyDPoPProof(c, tenant.URL(c.Request.Context(), config.OAuthEndpoint("/par")))
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	res, err := h.AuthorizationManager.Push(c.Request.Context(), client, c.Request.PostForm, jkt)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, res)
+}
+
+// Token handler is the token endpoint. Tokens are bound to the key of the DPoP proof sent with
+// the request (RFC 9449), and to the client certificate (RFC 8705), if any.
+func (h *OAuthHandler) Token(c *gin.Context) {
+	h.setDPoPNonce(c)
+
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	jkt, err := h.verifyDPoPProof(c, tenant.URL(c.Request.Context(), config.OAuthEndpoint("/token")))
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	cert, err := security.PeerCertificate(c.Request)
+	if err != nil {
+		abortWithOAuthError(c, model.NewOAuthInvalidRequestError("the client certificate could not be parsed"))
+
+		return
+	}
+
+	cnf := model.Confirmation{JKT: jkt}
+	if cert != nil {
+		cnf.X5TS256 = security.CertificateThumbprint(cert)
+	}
+
+	res, err := h.TokenManager.Exchange(c.Request.Context(), client, c.Request.PostForm, cnf)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, res)
+}
+
+// Introspect handler is the token introspection endpoint (RFC 7662). It is restricted to
+// confidential clients, typically resource servers.
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	client, ok := h.authenticateClient(c)
+	if !ok {
+		return
+	}
+
+	if client.IsPublic() {
+		abortWithOAuthError(c, model.NewOAuthInvalidClientError("public clients can't introspect tokens"))
+
+		return
+	}
+
+	value := c.Request.PostForm.Get("token")
+	if value == "" {
+		abortWithOAuthError(c, model.NewOAuthInvalidRequestError("the token is missing"))
+
+		return
+	}
+
+	token, err := h.TokenManager.Introspect(c.Request.Context(), value)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	// The tokens of other organizations are not disclosed to the clients of this one
+	if token == nil || !tenant.Matches(c.Request.Context(), token.OrganizationID) {
+		c.JSON(http.StatusOK, model.IntrospectionResponse{Active: false})
+
+		return
+	}
+
+	c.JSON(http.StatusOK, model.IntrospectionResponse{
+		Active:         true,
+		Scope:          strings.Join(token.Scopes, " "),
+		ClientID:       token.ClientID,
+		Subject:        token.Subject,
+		TokenType:      token.TokenType(),
+		IssuedAt:       token.IssuedAt.Unix(),
+		ExpiresAt:      token.ExpiresAt.Unix(),
+		Issuer:         tenant.Issuer(c.Request.Context()),
+		Audience:       token.Audience,
+		Confirmation:   token.Confirmation,
+		OrganizationID: token.OrganizationID,
+		Roles:          token.Roles,
+		Permissions:    token.Permissions,
+		Groups:         token.Groups,
+		GroupsOverage:  token.GroupsOverage,
+	})
+}
+
+// authenticateClient parses the request form and authenticates the calling client.
+// The request is aborted when the authentication fails.
+func (h *OAuthHandler) authenticateClient(c *gin.Context) (*model.Client, bool) {
+	if err := c.Request.ParseForm(); err != nil {
+		abortWithOAuthError(c, model.NewOAuthInvalidRequestError("the request could not be parsed"))
+
+		return nil, false
+	}
+
+	client, err := h.ClientManager.Authenticate(c.Request.Context(), c.Request)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return nil, false
+	}
+
+	return client, true
+}
+
+// verifyDPoPProof validates the DPoP proof of the request, if any, and returns its key thumbprint.
+func (h *OAuthHandler) verifyDPoPProof(c *gin.Context, uri string) (string, error) {
+	proofs := c.Request.Header.Values("DPoP")
+
+	switch len(proofs) {
+	case 0:
+		return "", nil
+	case 1:
+		return h.DPoPManager.VerifyProof(c.Request.Context(), proofs[0], c.Request.Method, uri, "")
+	default:
+		return "", model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidDPoPProof, "a single DPoP proof is allowed")
+	}
+}
+
+func (h *OAuthHandler) setDPoPNonce(c *gin.Context) {
+	if nonce := h.DPoPManager.Nonce(); nonce != "" {
+		c.Header("DPoP-Nonce", nonce)
+	}
+}
+
+// activeSession returns the active login session of the user-agent, if any.
+func (h *OAuthHandler) activeSession(c *gin.Context) *model.Session {
+	sessionID, err := c.Cookie(config.SessionCookieName())
+	if err != nil || sessionID == "" {
+		return nil
+	}
+
+	session, err := h.SessionManager.Get(c.Request.Context(), sessionID)
+	if err != nil {
+		log.Err(err).Msg("Could not get the session of an authorization request")
+
+		return nil
+	}
+
+	return session
+}
+
+func NewOAuthHandler(
+	clientManager manager.ClientManager,
+	authorizationManager manager.AuthorizationManager,
+	tokenManager manager.TokenManager,
+	dpopManager manager.DPoPManager,
+	sessionManager manager.SessionManager,
+) *OAuthHandler {
+	return &OAuthHandler{
+		ClientManager:        clientManager,
+		AuthorizationManager: authorizationManager,
+		TokenManager:         tokenManager,
+		DPoPManager:          dpopManager,
+		SessionManager:       sessionManager,
+	}
+}
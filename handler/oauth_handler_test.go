@@ -0,0 +1,67 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/m3talux/goauth/internal/goauthtest"
+	"github.com/m3talux/goauth/model"
+)
+
+func TestRefreshTokenDisabledUser(t *testing.T) {
+	srv := goauthtest.NewServer(t)
+	srv.AddClient(t, model.Client{
+		ID:         "web",
+		GrantTypes: []string{model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken},
+	}, "secret")
+	srv.AddUser(t, model.User{ID: "enabled"})
+	srv.AddUser(t, model.User{ID: "disabled", Disabled: true})
+
+	tests := []struct {
+		subject string
+		status  int
+	}{
+		{subject: "enabled", status: http.StatusOK},
+		{subject: "disabled", status: http.StatusBadRequest},
+		{subject: "deleted", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			refreshToken := srv.AddRefreshToken(t, model.Token{ClientID: "web", Subject: tt.subject})
+
+			form := url.Values{"grant_type": {model.GrantTypeRefreshToken}, "refresh_token": {refreshToken}}
+
+			req, err := http.NewRequest(http.MethodPost, srv.Issuer+"/oauth2/token", strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth("web", "secret")
+
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer res.Body.Close()
+
+			var body map[string]any
+			if err = json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+
+			if res.StatusCode != tt.status {
+				t.Fatalf("expected the status %d, got %d: %v", tt.status, res.StatusCode, body)
+			}
+
+			if tt.status != http.StatusOK && body["error"] != model.OAuthErrorInvalidGrant {
+				t.Fatalf("expected an invalid_grant error, got %v", body)
+			}
+		})
+	}
+}
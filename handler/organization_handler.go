@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/tenant"
+)
+
+// OrganizationHandler exposes the administration endpoints managing the organizations and their members,
+// and the public information of the organization of the request.
+type OrganizationHandler struct {
+	OrganizationManager manager.OrganizationManager
+}
+
+// memberBody is the request body setting the roles of a member.
+type memberBody struct {
+	Roles []string `json:"roles"`
+}
+
+// Organization handler returns the public information of the organization of the request, to customize
+// its login pages.
+func (h *OrganizationHandler) Organization(c *gin.Context) {
+	organization := middleware.GetOrganization(c)
+
+	c.JSON(http.StatusOK, model.PublicOrganization{
+		ID:       organization.ID,
+		Name:     organization.Name,
+		Issuer:   tenant.Issuer(c.Request.Context()),
+		Branding: organization.Branding,
+	})
+}
+
+// Organizations handler returns every organization.
+func (h *OrganizationHandler) Organizations(c *gin.Context) {
+	organizations, err := h.OrganizationManager.Organizations(c.Request.Context())
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, organizations)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// GetOrganization handler returns an organization.
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	organization, err := h.OrganizationManager.Get(c.Request.Context(), c.Param("org"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, organization)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// CreateOrganization handler creates an organization.
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var body model.Organization
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	organization, err := h.OrganizationManager.Create(c.Request.Context(), body)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusCreated, organization)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// UpdateOrganization handler replaces the name, domains, branding and settings of an organization.
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	var body model.Organization
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	organization, err := h.OrganizationManager.Update(c.Request.Context(), c.Param("org"), body)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, organization)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// DeleteOrganization handler deletes an organization.
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	if err := h.OrganizationManager.Delete(c.Request.Context(), c.Param("org")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Members handler returns the members of an organization.
+func (h *OrganizationHandler) Members(c *gin.Context) {
+	members, err := h.OrganizationManager.Members(c.Request.Context(), memberOrganizationID(c))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, members)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// SetMember handler makes a user a member of an organization of the path, or replaces the roles of a member.
+func (h *OrganizationHandler) SetMember(c *gin.Context) {
+	var body memberBody
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token := middleware.GetAccessToken(c)
+
+	member, err := h.OrganizationManager.SetMember(c.Request.Context(), c.Param("org"), c.Param("user"), body.Roles, token.Permissions)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, member)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// UpdateMember handler replaces the roles of a member of the organization of the request.
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	var body memberBody
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token := middleware.GetAccessToken(c)
+
+	member, err := h.OrganizationManager.UpdateMember(c.Request.Context(), c.Param("user"), body.Roles, token.Permissions)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, member)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// RemoveMember handler removes a user from an organization.
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	if err := h.OrganizationManager.RemoveMember(c.Request.Context(), memberOrganizationID(c), c.Param("user")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// memberOrganizationID returns the organization whose members are managed: the organization of the path
+// on the platform endpoints, the organization of the request otherwise.
+func memberOrganizationID(c *gin.Context) string {
+	if organizationID := c.Param("org"); organizationID != "" {
+		return organizationID
+	}
+
+	return tenant.OrganizationID(c.Request.Context())
+}
+
+func NewOrganizationHandler(organizationManager manager.OrganizationManager) *OrganizationHandler {
+	return &OrganizationHandler{
+		OrganizationManager: organizationManager,
+	}
+}
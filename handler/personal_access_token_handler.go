@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+)
+
+// PersonalAccessTokenHandler exposes the endpoints letting logged-in end-users manage their personal access
+// tokens, and the administration endpoints managing the tokens of the users of an organization.
+type PersonalAccessTokenHandler struct {
+	PersonalAccessTokenManager manager.PersonalAccessTokenManager
+	AuditManager               manager.AuditManager
+}
+
+// personalAccessTokenBody is the request body of the creation of a personal access token.
+type personalAccessTokenBody struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Tokens handler returns the personal access tokens of the end-user.
+func (h *PersonalAccessTokenHandler) Tokens(c *gin.Context) {
+	h.tokens(c, middleware.GetSession(c).Subject)
+}
+
+// CreateToken handler mints a personal access token for the end-user. Its value is only returned once.
+func (h *PersonalAccessTokenHandler) CreateToken(c *gin.Context) {
+	var body personalAccessTokenBody
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token := model.PersonalAccessToken{Name: body.Name, Scopes: body.Scopes}
+	if body.ExpiresAt != nil {
+		token.ExpiresAt = *body.ExpiresAt
+	}
+
+	creation, err := h.PersonalAccessTokenManager.Create(c.Request.Context(), middleware.GetSession(c).Subject, token)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	response := model.NewAPIResponseSuccess(http.StatusCreated, creation)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// RevokeToken handler deletes a personal access token of the end-user.
+func (h *PersonalAccessTokenHandler) RevokeToken(c *gin.Context) {
+	h.revoke(c, middleware.GetSession(c).Subject)
+}
+
+// UserTokens handler returns the personal access tokens of a user.
+func (h *PersonalAccessTokenHandler) UserTokens(c *gin.Context) {
+	h.tokens(c, c.Param("user"))
+}
+
+// RevokeUserToken handler deletes a personal access token of a user.
+func (h *PersonalAccessTokenHandler) RevokeUserToken(c *gin.Context) {
+	h.revoke(c, c.Param("user"))
+}
+
+func (h *PersonalAccessTokenHandler) tokens(c *gin.Context, subject string) {
+	tokens, err := h.PersonalAccessTokenManager.Tokens(c.Request.Context(), subject)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, tokens)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+func (h *PersonalAccessTokenHandler) revoke(c *gin.Context, subject string) {
+	err := h.PersonalAccessTokenManager.Revoke(c.Request.Context(), subject, c.Param("token"))
+	auditTokenRevocation(c, h.AuditManager, model.AuditTargetPersonalAccessToken, c.Param("token"), err)
+
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func NewPersonalAccessTokenHandler(
+	personalAccessTokenManager manager.PersonalAccessTokenManager,
+	auditManager manager.AuditManager,
+) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		PersonalAccessTokenManager: personalAccessTokenManager,
+		AuditManager:               auditManager,
+	}
+}
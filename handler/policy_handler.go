@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+)
+
+// PolicyHandler exposes the administration endpoints managing the policies of an organization, and the
+// authorization decision endpoints called by its services.
+type PolicyHandler struct {
+	PolicyManager manager.PolicyManager
+}
+
+// decisionBatchBody is the request body of a batch of decisions.
+type decisionBatchBody struct {
+	Requests []model.DecisionRequest `json:"requests"`
+}
+
+// decisionBatchResponse holds the decisions of a batch, in the order of the requests.
+type decisionBatchResponse struct {
+	Decisions []model.Decision `json:"decisions"`
+}
+
+// Policies handler returns the policies of the organization.
+func (h *PolicyHandler) Policies(c *gin.Context) {
+	policies, err := h.PolicyManager.Policies(c.Request.Context())
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, policies)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// Policy handler returns a policy.
+func (h *PolicyHandler) Policy(c *gin.Context) {
+	policy, err := h.PolicyManager.Get(c.Request.Context(), c.Param("policy"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, policy)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// CreatePolicy handler creates a policy.
+func (h *PolicyHandler) CreatePolicy(c *gin.Context) {
+	var body model.Policy
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	policy, err := h.PolicyManager.Create(c.Request.Context(), body)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusCreated, policy)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// UpdatePolicy handler replaces a policy.
+func (h *PolicyHandler) UpdatePolicy(c *gin.Context) {
+	var body model.Policy
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	policy, err := h.PolicyManager.Update(c.Request.Context(), c.Param("policy"), body)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, policy)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// DeletePolicy handler deletes a policy.
+func (h *PolicyHandler) DeletePolicy(c *gin.Context) {
+	if err := h.PolicyManager.Delete(c.Request.Context(), c.Param("policy")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Authorize handler returns the authorization decision of a request. The explain query parameter adds
+// the evaluation of the policies to the decision.
+func (h *PolicyHandler) Authorize(c *gin.Context) {
+	var body model.DecisionRequest
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	decisions, err := h.PolicyManager.Decide(c.Request.Context(), []model.DecisionRequest{body}, c.Query("explain") == "true")
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, decisions[0])
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// AuthorizeBatch handler returns the authorization decisions of several requests at once.
+func (h *PolicyHandler) AuthorizeBatch(c *gin.Context) {
+	var body decisionBatchBody
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	decisions, err := h.PolicyManager.Decide(c.Request.Context(), body.Requests, c.Query("explain") == "true")
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, decisionBatchResponse{Decisions: decisions})
+	c.JSON(response.HTTPStatus(), response)
+}
+
+func NewPolicyHandler(policyManager manager.PolicyManager) *PolicyHandler {
+	return &PolicyHandler{
+		PolicyManager: policyManager,
+	}
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+)
+
+// RelationHandler exposes the endpoints of the relationship-based authorization: the administration of
+// the namespaces, and the write, check, expand and list-objects endpoints called by the services of an
+// organization.
+type RelationHandler struct {
+	RelationManager manager.RelationManager
+}
+
+// relationWriteBody is the request body of a write of relation tuples.
+type relationWriteBody struct {
+	Writes  []string `json:"writes"`
+	Deletes []string `json:"deletes"`
+}
+
+// relationCheckBody is the request body of a relationship check.
+type relationCheckBody struct {
+	Tuple            string `json:"tuple"`
+	ConsistencyToken string `json:"consistency_token"`
+}
+
+// relationExpandBody is the request body of the expansion of a userset.
+type relationExpandBody struct {
+	Userset          string `json:"userset"`
+	ConsistencyToken string `json:"consistency_token"`
+}
+
+// relationListObjectsBody is the request body of the listing of the objects a subject has a relation with.
+type relationListObjectsBody struct {
+	Namespace        string `json:"namespace"`
+	Relation         string `json:"relation"`
+	Subject          string `json:"subject"`
+	ConsistencyToken string `json:"consistency_token"`
+}
+
+// Namespaces handler returns the namespaces of the organization.
+func (h *RelationHandler) Namespaces(c *gin.Context) {
+	namespaces, err := h.RelationManager.Namespaces(c.Request.Context())
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, namespaces)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// SaveNamespace handler creates a namespace, or replaces its relations.
+func (h *RelationHandler) SaveNamespace(c *gin.Context) {
+	var body model.RelationNamespace
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	body.Name = c.Param("namespace")
+
+	namespace, err := h.RelationManager.SaveNamespace(c.Request.Context(), body)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, namespace)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// DeleteNamespace handler deletes a namespace.
+func (h *RelationHandler) DeleteNamespace(c *gin.Context) {
+	if err := h.RelationManager.DeleteNamespace(c.Request.Context(), c.Param("namespace")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Write handler creates and deletes relation tuples.
+func (h *RelationHandler) Write(c *gin.Context) {
+	var body relationWriteBody
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	write, err := h.RelationManager.Write(c.Request.Context(), body.Writes, body.Deletes)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, write)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// Check handler checks whether a subject has a relation with an object.
+func (h *RelationHandler) Check(c *gin.Context) {
+	var body relationCheckBody
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	check, err := h.RelationManager.Check(c.Request.Context(), body.Tuple, body.ConsistencyToken)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, check)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// Expand handler returns the tree of the subjects of a userset.
+func (h *RelationHandler) Expand(c *gin.Context) {
+	var body relationExpandBody
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	expansion, err := h.RelationManager.Expand(c.Request.Context(), body.Userset, body.ConsistencyToken)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, expansion)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// ListObjects handler returns the objects of a namespace a subject has a relation with.
+func (h *RelationHandler) ListObjects(c *gin.Context) {
+	var body relationListObjectsBody
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	objects, err := h.RelationManager.ListObjects(c.Request.Context(), body.Namespace, body.Relation, body.Subject, body.ConsistencyToken)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, objects)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+func NewRelationHandler(relationManager manager.RelationManager) *RelationHandler {
+	return &RelationHandler{
+		RelationManager: relationManager,
+	}
+}
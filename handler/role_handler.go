@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+)
+
+// RoleHandler exposes the administration endpoints managing the roles, and their assignment to users
+// and clients.
+type RoleHandler struct {
+	RoleManager manager.RoleManager
+}
+
+// Roles handler returns every role.
+func (h *RoleHandler) Roles(c *gin.Context) {
+	roles, err := h.RoleManager.Roles(c.Request.Context())
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, roles)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// Role handler returns a role.
+func (h *RoleHandler) Role(c *gin.Context) {
+	role, err := h.RoleManager.Get(c.Request.Context(), c.Param("role"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, role)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// CreateRole handler creates a role.
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var body model.Role
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token := middleware.GetAccessToken(c)
+
+	role, err := h.RoleManager.Create(c.Request.Context(), body, token.Permissions)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusCreated, role)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// UpdateRole handler replaces the description and the permissions of a role.
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	var body model.Role
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token := middleware.GetAccessToken(c)
+
+	role, err := h.RoleManager.Update(c.Request.Context(), c.Param("role"), body, token.Permissions)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, role)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// DeleteRole handler deletes a role.
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	if err := h.RoleManager.Delete(c.Request.Context(), c.Param("role")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UserRoles handler returns the roles assigned to a user.
+func (h *RoleHandler) UserRoles(c *gin.Context) {
+	roles, err := h.RoleManager.UserRoles(c.Request.Context(), c.Param("user"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, roles)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// AssignUserRole handler assigns a role to a user.
+func (h *RoleHandler) AssignUserRole(c *gin.Context) {
+	token := middleware.GetAccessToken(c)
+
+	if err := h.RoleManager.AssignUserRole(c.Request.Context(), c.Param("user"), c.Param("role"), token.Permissions); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UnassignUserRole handler removes a role from a user.
+func (h *RoleHandler) UnassignUserRole(c *gin.Context) {
+	if err := h.RoleManager.UnassignUserRole(c.Request.Context(), c.Param("user"), c.Param("role")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ClientRoles handler returns the roles assigned to a client.
+func (h *RoleHandler) ClientRoles(c *gin.Context) {
+	roles, err := h.RoleManager.ClientRoles(c.Request.Context(), c.Param("client"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, roles)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// AssignClientRole handler assigns a role to a client.
+func (h *RoleHandler) AssignClientRole(c *gin.Context) {
+	token := middleware.GetAccessToken(c)
+
+	if err := h.RoleManager.AssignClientRole(c.Request.Context(), c.Param("client"), c.Param("role"), token.Permissions); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UnassignClientRole handler removes a role from a client.
+func (h *RoleHandler) UnassignClientRole(c *gin.Context) {
+	if err := h.RoleManager.UnassignClientRole(c.Request.Context(), c.Param("client"), c.Param("role")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func NewRoleHandler(roleManager manager.RoleManager) *RoleHandler {
+	return &RoleHandler{
+		RoleManager: roleManager,
+	}
+}
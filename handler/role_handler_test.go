@@ -0,0 +1,82 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/m3talux/goauth/client"
+	"github.com/m3talux/goauth/internal/goauthtest"
+	"github.com/m3talux/goauth/model"
+)
+
+// roleWriterID is a client holding the roles:write permission only.
+const roleWriterID = "role-writer"
+
+func newRoleWriter(t *testing.T) *client.Client {
+	t.Helper()
+
+	srv := goauthtest.NewServer(t)
+	srv.AddRole(t, model.Role{ID: "roles-writer", Permissions: []string{model.PermissionRolesWrite}})
+	srv.AddClient(t, model.Client{
+		ID:         roleWriterID,
+		GrantTypes: []string{model.GrantTypeClientCredentials},
+		Roles:      []string{"roles-writer"},
+	}, "secret")
+
+	c, err := client.New(client.Config{Issuer: srv.Issuer, ClientID: roleWriterID, ClientSecret: "secret", MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestRoleEscalation(t *testing.T) {
+	c := newRoleWriter(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "assign admin to client", call: func() error {
+			return c.AssignClientRole(ctx, roleWriterID, model.RoleIDAdmin)
+		}},
+		{name: "assign admin to user", call: func() error {
+			return c.AssignUserRole(ctx, "user", model.RoleIDAdmin)
+		}},
+		{name: "create role", call: func() error {
+			_, err := c.CreateRole(ctx, model.Role{ID: "root", Permissions: []string{model.PermissionAll}})
+
+			return err
+		}},
+		{name: "create role with other permissions", call: func() error {
+			_, err := c.CreateRole(ctx, model.Role{ID: "auditor", Permissions: []string{model.PermissionAuditRead}})
+
+			return err
+		}},
+		{name: "update role", call: func() error {
+			_, err := c.UpdateRole(ctx, "roles-writer", model.Role{Permissions: []string{"roles:*"}})
+
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, client.ErrForbidden) {
+				t.Fatalf("expected a forbidden error, got %v", err)
+			}
+		})
+	}
+
+	// The roles within the permissions of the caller can still be created and assigned
+	if _, err := c.CreateRole(ctx, model.Role{ID: "writer", Permissions: []string{model.PermissionRolesWrite}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.AssignClientRole(ctx, roleWriterID, "writer"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// samlResponsePage posts a SAML response to the assertion consumer service of a service provider, through
+// the user-agent (HTTP-POST binding).
+var samlResponsePage = template.Must(template.New("samlResponse").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Logging in</title></head>
+<body>
+<form method="post" action="{{.URL}}" id="SAMLResponseForm">
+<input type="hidden" name="SAMLResponse" value="{{.SAMLResponse}}">
+{{if .RelayState}}<input type="hidden" name="RelayState" value="{{.RelayState}}">
+{{end}}<noscript><button type="submit">Continue</button></noscript>
+</form>
+<script>document.getElementById("SAMLResponseForm").submit();</script>
+</body>
+</html>
+`))
+
+// SAMLHandler exposes the endpoints of goauth as a SAML 2.0 identity provider.
+type SAMLHandler struct {
+	SAMLManager          manager.SAMLManager
+	AuthorizationManager manager.AuthorizationManager
+	SessionManager       manager.SessionManager
+}
+
+// Metadata handler returns the SAML metadata of the identity provider.
+func (h *SAMLHandler) Metadata(c *gin.Context) {
+	metadata, err := h.SAMLManager.Metadata(c.Request.Context())
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// SSO handler is the single sign-on service of the identity provider. It receives the authentication
+// requests of the service providers, and answers them right away when the end-user is logged in.
+// Otherwise, the end-user is sent to the login page, and the request is resumed once logged in.
+func (h *SAMLHandler) SSO(c *gin.Context) {
+	req, err := h.SAMLManager.Receive(c.Request.Context(), c.Request)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	resumeURL := tenant.URL(c.Request.Context(), config.SAMLEndpoint("/sso/resume/"+req.ID))
+
+	session := h.activeSession(c)
+	if session == nil {
+		h.requestLogin(c, resumeURL)
+
+		return
+	}
+
+	form, err := h.SAMLManager.Respond(c.Request.Context(), c.Request, req.ID, session)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	renderSAMLResponse(c, form)
+}
+
+// Resume handler answers a pending authentication request, once the end-user is logged in.
+func (h *SAMLHandler) Resume(c *gin.Context) {
+	session := h.activeSession(c)
+	if session == nil {
+		abortWithOAuthError(c, model.NewOAuthError(http.StatusUnauthorized, model.OAuthErrorAccessDenied, "the end-user is not logged in"))
+
+		return
+	}
+
+	form, err := h.SAMLManager.Respond(c.Request.Context(), c.Request, c.Param("request"), session)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	renderSAMLResponse(c, form)
+}
+
+// IdPInitiated handler logs the end-user in to the service provider given by its entity ID, without
+// authentication request (IdP-initiated single sign-on). The RelayState parameter is forwarded to the
+// service provider, usually to tell the page to land on.
+func (h *SAMLHandler) IdPInitiated(c *gin.Context) {
+	session := h.activeSession(c)
+	if session == nil {
+		h.requestLogin(c, tenant.URL(c.Request.Context(), config.SAMLEndpoint("/idp?"+c.Request.URL.RawQuery)))
+
+		return
+	}
+
+	form, err := h.SAMLManager.RespondUnsolicited(c.Request.Context(), c.Request, c.Query("provider"), c.Query("RelayState"), session)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	renderSAMLResponse(c, form)
+}
+
+// requestLogin redirects the end-user to the login page, to come back to the resume URL once logged in.
+func (h *SAMLHandler) requestLogin(c *gin.Context, resumeURL string) {
+	req, err := h.AuthorizationManager.RequestLogin(c.Request.Context(), resumeURL)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	c.Redirect(http.StatusFound, loginURL(c, req.ID))
+}
+
+// activeSession returns the active login session of the user-agent, if any.
+func (h *SAMLHandler) activeSession(c *gin.Context) *model.Session {
+	sessionID, err := c.Cookie(config.SessionCookieName())
+	if err != nil || sessionID == "" {
+		return nil
+	}
+
+	session, err := h.SessionManager.Get(c.Request.Context(), sessionID)
+	if err != nil {
+		log.Err(err).Msg("Could not get the session of a SAML request")
+
+		return nil
+	}
+
+	return session
+}
+
+// renderSAMLResponse sends the page posting a SAML response to the service provider.
+func renderSAMLResponse(c *gin.Context, form model.SAMLResponseForm) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	if err := samlResponsePage.Execute(c.Writer, form); err != nil {
+		log.Err(err).Msg("Could not render the SAML response page")
+	}
+}
+
+func NewSAMLHandler(
+	samlManager manager.SAMLManager,
+	authorizationManager manager.AuthorizationManager,
+	sessionManager manager.SessionManager,
+) *SAMLHandler {
+	return &SAMLHandler{
+		SAMLManager:          samlManager,
+		AuthorizationManager: authorizationManager,
+		SessionManager:       sessionManager,
+	}
+}
@@ -0,0 +1,489 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+)
+
+// SCIMHandler exposes the SCIM 2.0 endpoints (RFC 7644) letting provisioning clients, like HR systems,
+// manage the users and groups, and the endpoints managing the tokens of these clients.
+type SCIMHandler struct {
+	SCIMManager  manager.SCIMManager
+	AuditManager manager.AuditManager
+}
+
+// ServiceProviderConfig handler returns the SCIM features supported by goauth.
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	renderSCIM(c, http.StatusOK, model.SCIMServiceProviderConfig{
+		Schemas:        []string{model.SCIMSchemaServiceProviderConfig},
+		Patch:          model.SCIMSupported{Supported: true},
+		Bulk:           model.SCIMBulkSupported{Supported: false},
+		Filter:         model.SCIMFilterSupported{Supported: true, MaxResults: config.SCIMMaxResults()},
+		ChangePassword: model.SCIMSupported{Supported: false},
+		Sort:           model.SCIMSupported{Supported: false},
+		ETag:           model.SCIMSupported{Supported: false},
+		AuthenticationSchemes: []model.SCIMAuthenticationScheme{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "OAuth Bearer Token",
+				Description: "Authentication with a bearer token created for the provisioning client",
+				Primary:     true,
+			},
+		},
+		Meta: model.SCIMMeta{ResourceType: "ServiceProviderConfig", Location: config.SCIMEndpoint("/ServiceProviderConfig")},
+	})
+}
+
+// ResourceTypes handler returns the SCIM resource types supported by goauth.
+func (h *SCIMHandler) ResourceTypes(c *gin.Context) {
+	resourceTypes := scimResourceTypes()
+
+	resources := make([]any, 0, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		resources = append(resources, resourceType)
+	}
+
+	renderSCIM(c, http.StatusOK, scimStaticList(resources))
+}
+
+// ResourceType handler returns a SCIM resource type.
+func (h *SCIMHandler) ResourceType(c *gin.Context) {
+	for _, resourceType := range scimResourceTypes() {
+		if resourceType.ID == c.Param("id") {
+			renderSCIM(c, http.StatusOK, resourceType)
+
+			return
+		}
+	}
+
+	abortWithSCIMError(c, model.NewSCIMError(http.StatusNotFound, "", "the resource type does not exist"))
+}
+
+// Schemas handler returns the SCIM schemas supported by goauth.
+func (h *SCIMHandler) Schemas(c *gin.Context) {
+	schemas := scimSchemas()
+
+	resources := make([]any, 0, len(schemas))
+	for _, schema := range schemas {
+		resources = append(resources, schema)
+	}
+
+	renderSCIM(c, http.StatusOK, scimStaticList(resources))
+}
+
+// Schema handler returns a SCIM schema.
+func (h *SCIMHandler) Schema(c *gin.Context) {
+	for _, schema := range scimSchemas() {
+		if schema.ID == c.Param("id") {
+			renderSCIM(c, http.StatusOK, schema)
+
+			return
+		}
+	}
+
+	abortWithSCIMError(c, model.NewSCIMError(http.StatusNotFound, "", "the schema does not exist"))
+}
+
+// Users handler returns the users matching the filter of the request, a page at a time.
+func (h *SCIMHandler) Users(c *gin.Context) {
+	list, err := h.SCIMManager.Users(c.Request.Context(), scimQuery(c))
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	renderSCIMList(c, list)
+}
+
+// User handler returns a user.
+func (h *SCIMHandler) User(c *gin.Context) {
+	user, err := h.SCIMManager.User(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	renderSCIMResource(c, http.StatusOK, user)
+}
+
+// CreateUser handler provisions a user.
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var resource model.SCIMUser
+	if !bindSCIM(c, &resource) {
+		return
+	}
+
+	user, err := h.SCIMManager.CreateUser(c.Request.Context(), resource)
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	c.Header("Location", user.Meta.Location)
+	renderSCIMResource(c, http.StatusCreated, user)
+}
+
+// ReplaceUser handler replaces the attributes of a user.
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	var resource model.SCIMUser
+	if !bindSCIM(c, &resource) {
+		return
+	}
+
+	user, err := h.SCIMManager.ReplaceUser(c.Request.Context(), c.Param("id"), resource)
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	renderSCIMResource(c, http.StatusOK, user)
+}
+
+// PatchUser handler modifies some attributes of a user.
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	var request model.SCIMPatchRequest
+	if !bindSCIM(c, &request) {
+		return
+	}
+
+	user, err := h.SCIMManager.PatchUser(c.Request.Context(), c.Param("id"), request)
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	renderSCIMResource(c, http.StatusOK, user)
+}
+
+// DeleteUser handler deprovisions a user.
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	if err := h.SCIMManager.DeleteUser(c.Request.Context(), c.Param("id")); err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Groups handler returns the groups matching the filter of the request, a page at a time.
+func (h *SCIMHandler) Groups(c *gin.Context) {
+	list, err := h.SCIMManager.Groups(c.Request.Context(), scimQuery(c))
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	renderSCIMList(c, list)
+}
+
+// Group handler returns a group.
+func (h *SCIMHandler) Group(c *gin.Context) {
+	group, err := h.SCIMManager.Group(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	renderSCIMResource(c, http.StatusOK, group)
+}
+
+// CreateGroup handler provisions a group.
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var resource model.SCIMGroup
+	if !bindSCIM(c, &resource) {
+		return
+	}
+
+	group, err := h.SCIMManager.CreateGroup(c.Request.Context(), resource)
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	c.Header("Location", group.Meta.Location)
+	renderSCIMResource(c, http.StatusCreated, group)
+}
+
+// ReplaceGroup handler replaces the attributes and the members of a group.
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	var resource model.SCIMGroup
+	if !bindSCIM(c, &resource) {
+		return
+	}
+
+	group, err := h.SCIMManager.ReplaceGroup(c.Request.Context(), c.Param("id"), resource)
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	renderSCIMResource(c, http.StatusOK, group)
+}
+
+// PatchGroup handler modifies some attributes or the members of a group.
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	var request model.SCIMPatchRequest
+	if !bindSCIM(c, &request) {
+		return
+	}
+
+	group, err := h.SCIMManager.PatchGroup(c.Request.Context(), c.Param("id"), request)
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	renderSCIMResource(c, http.StatusOK, group)
+}
+
+// DeleteGroup handler deletes a group.
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	if err := h.SCIMManager.DeleteGroup(c.Request.Context(), c.Param("id")); err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Tokens handler returns the tokens of the provisioning clients.
+func (h *SCIMHandler) Tokens(c *gin.Context) {
+	tokens, err := h.SCIMManager.Tokens(c.Request.Context())
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, tokens)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// CreateToken handler creates a token for a provisioning client. Its value is only returned once.
+func (h *SCIMHandler) CreateToken(c *gin.Context) {
+	var body struct {
+		Name string `json:"name"`
+	}
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	token, err := h.SCIMManager.CreateToken(c.Request.Context(), body.Name)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	response := model.NewAPIResponseSuccess(http.StatusCreated, token)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// RevokeToken handler deletes the token of a provisioning client.
+func (h *SCIMHandler) RevokeToken(c *gin.Context) {
+	err := h.SCIMManager.RevokeToken(c.Request.Context(), c.Param("token"))
+	auditTokenRevocation(c, h.AuditManager, model.AuditTargetSCIMToken, c.Param("token"), err)
+
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func scimResourceTypes() []model.SCIMResourceType {
+	return []model.SCIMResourceType{
+		{
+			Schemas:     []string{model.SCIMSchemaResourceType},
+			ID:          model.SCIMResourceTypeUser,
+			Name:        model.SCIMResourceTypeUser,
+			Endpoint:    "/Users",
+			Description: "User Account",
+			Schema:      model.SCIMSchemaUser,
+			Meta:        model.SCIMMeta{ResourceType: "ResourceType", Location: config.SCIMEndpoint("/ResourceTypes/" + model.SCIMResourceTypeUser)},
+		},
+		{
+			Schemas:     []string{model.SCIMSchemaResourceType},
+			ID:          model.SCIMResourceTypeGroup,
+			Name:        model.SCIMResourceTypeGroup,
+			Endpoint:    "/Groups",
+			Description: "Group",
+			Schema:      model.SCIMSchemaGroup,
+			Meta:        model.SCIMMeta{ResourceType: "ResourceType", Location: config.SCIMEndpoint("/ResourceTypes/" + model.SCIMResourceTypeGroup)},
+		},
+	}
+}
+
+func scimSchemas() []model.SCIMSchema {
+	schemas := []model.SCIMSchema{model.SCIMUserSchema(), model.SCIMGroupSchema()}
+
+	for i := range schemas {
+		schemas[i].Meta = model.SCIMMeta{ResourceType: "Schema", Location: config.SCIMEndpoint("/Schemas/" + schemas[i].ID)}
+	}
+
+	return schemas
+}
+
+// scimStaticList returns the list response of the discovery endpoints, which are not paginated.
+func scimStaticList(resources []any) model.SCIMListResponse {
+	return model.SCIMListResponse{
+		Schemas:      []string{model.SCIMSchemaListResponse},
+		TotalResults: int64(len(resources)),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// scimQuery reads the filter and the pagination parameters of a request. The page size is capped,
+// and defaults to the maximum.
+func scimQuery(c *gin.Context) model.SCIMQuery {
+	query := model.SCIMQuery{
+		Filter:     c.Query("filter"),
+		StartIndex: 1,
+		Count:      config.SCIMMaxResults(),
+	}
+
+	if startIndex, err := strconv.Atoi(c.Query("startIndex")); err == nil && startIndex > 1 {
+		query.StartIndex = startIndex
+	}
+
+	if count, err := strconv.Atoi(c.Query("count")); err == nil {
+		query.Count = min(max(count, 0), config.SCIMMaxResults())
+	}
+
+	return query
+}
+
+// bindSCIM decodes the body of a SCIM request, or sends an invalidSyntax error.
+func bindSCIM(c *gin.Context, v any) bool {
+	if err := json.NewDecoder(c.Request.Body).Decode(v); err != nil {
+		abortWithSCIMError(c, model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidSyntax, "the request body is invalid"))
+
+		return false
+	}
+
+	return true
+}
+
+// renderSCIMList sends a list of resources, keeping the attributes requested by the client.
+func renderSCIMList(c *gin.Context, list model.SCIMListResponse) {
+	for i, resource := range list.Resources {
+		projected, err := projectSCIM(c, resource)
+		if err != nil {
+			abortWithSCIMError(c, err)
+
+			return
+		}
+
+		list.Resources[i] = projected
+	}
+
+	renderSCIM(c, http.StatusOK, list)
+}
+
+// renderSCIMResource sends a resource, keeping the attributes requested by the client.
+func renderSCIMResource(c *gin.Context, status int, resource any) {
+	projected, err := projectSCIM(c, resource)
+	if err != nil {
+		abortWithSCIMError(c, err)
+
+		return
+	}
+
+	renderSCIM(c, status, projected)
+}
+
+func renderSCIM(c *gin.Context, status int, v any) {
+	c.Header("Content-Type", model.SCIMContentType)
+	c.JSON(status, v)
+}
+
+// projectSCIM keeps the attributes of a resource listed by the attributes parameter, or removes the
+// ones listed by the excludedAttributes parameter (RFC 7644, section 3.4.2.5). The id and schemas
+// attributes are always returned. Sub-attributes select their whole attribute.
+func projectSCIM(c *gin.Context, resource any) (any, error) {
+	attributes, excludedAttributes := scimAttributeNames(c.Query("attributes")), scimAttributeNames(c.Query("excludedAttributes"))
+	if len(attributes) == 0 && len(excludedAttributes) == 0 {
+		return resource, nil
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var document map[string]any
+	if err = json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	for name := range document {
+		lowerName := strings.ToLower(name)
+		if lowerName == "id" || lowerName == "schemas" {
+			continue
+		}
+
+		if (len(attributes) > 0 && !attributes[lowerName]) || excludedAttributes[lowerName] {
+			delete(document, name)
+		}
+	}
+
+	return document, nil
+}
+
+// scimAttributeNames returns the lowercased top-level attributes of a comma-separated attribute list.
+func scimAttributeNames(list string) map[string]bool {
+	names := make(map[string]bool)
+
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		for _, schema := range []string{model.SCIMSchemaUser, model.SCIMSchemaGroup} {
+			if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+				path = path[len(schema)+1:]
+			}
+		}
+
+		name, _, _ := strings.Cut(path, ".")
+		names[strings.ToLower(name)] = true
+	}
+
+	return names
+}
+
+func NewSCIMHandler(scimManager manager.SCIMManager, auditManager manager.AuditManager) *SCIMHandler {
+	return &SCIMHandler{
+		SCIMManager:  scimManager,
+		AuditManager: auditManager,
+	}
+}
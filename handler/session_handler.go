@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/rs/zerolog/log"
+)
+
+// logoutPage renders the front-channel logout URIs of the clients in hidden iframes, then redirects
+// the user-agent once they are loaded (OpenID Connect Front-Channel Logout 1.0).
+var logoutPage = template.Must(template.New("logout").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Logged out</title></head>
+<body>
+<p>You have been logged out.</p>
+{{range .FrontchannelLogoutURIs}}<iframe src="{{.}}" style="display:none"></iframe>
+{{end}}{{if .RedirectURI}}<script>window.addEventListener("load", function () { window.location.replace({{.RedirectURI}}); });</script>
+{{end}}</body>
+</html>
+`))
+
+// SessionHandler exposes the endpoints managing the login session of the end-user.
+type SessionHandler struct {
+	LogoutManager manager.LogoutManager
+}
+
+// Logout handler is the end session endpoint (OpenID Connect RP-Initiated Logout 1.0). It ends the
+// session of the end-user, notifies the clients, then redirects to the post logout redirect URI.
+func (h *SessionHandler) Logout(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	sessionID, _ := c.Cookie(config.SessionCookieName())
+
+	res, err := h.LogoutManager.EndSession(c.Request.Context(), c.Request.Form, sessionID)
+	if err != nil {
+		abortWithOAuthError(c, err)
+
+		return
+	}
+
+	clearSessionCookie(c)
+	c.Header("Cache-Control", "no-store")
+
+	if len(res.FrontchannelLogoutURIs) == 0 && res.RedirectURI != "" {
+		c.Redirect(http.StatusFound, res.RedirectURI)
+
+		return
+	}
+
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+
+	if err = logoutPage.Execute(c.Writer, res); err != nil {
+		log.Err(err).Msg("Could not render the logout page")
+	}
+}
+
+func NewSessionHandler(logoutManager manager.LogoutManager) *SessionHandler {
+	return &SessionHandler{
+		LogoutManager: logoutManager,
+	}
+}
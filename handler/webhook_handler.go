@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+)
+
+// WebhookHandler exposes the administration endpoints managing the webhooks of an organization, and the log
+// of their deliveries.
+type WebhookHandler struct {
+	WebhookManager manager.WebhookManager
+}
+
+// Webhooks handler returns the webhooks of the organization.
+func (h *WebhookHandler) Webhooks(c *gin.Context) {
+	webhooks, err := h.WebhookManager.Webhooks(c.Request.Context())
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, webhooks)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// Webhook handler returns a webhook.
+func (h *WebhookHandler) Webhook(c *gin.Context) {
+	webhook, err := h.WebhookManager.Get(c.Request.Context(), c.Param("webhook"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, webhook)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// CreateWebhook handler creates a webhook. Its secret is only returned once.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var body model.Webhook
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	webhook, err := h.WebhookManager.Create(c.Request.Context(), body)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	response := model.NewAPIResponseSuccess(http.StatusCreated, webhook)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// UpdateWebhook handler replaces the URL, the description, the events and the state of a webhook.
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var body model.Webhook
+
+	if err := c.ShouldBindJSON(&body); err != nil {
+		abortWithAPIError(c, model.NewAPIResponseError(http.StatusBadRequest, "the request body is invalid"))
+
+		return
+	}
+
+	webhook, err := h.WebhookManager.Update(c.Request.Context(), c.Param("webhook"), body)
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, webhook)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// DeleteWebhook handler deletes a webhook and its deliveries.
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.WebhookManager.Delete(c.Request.Context(), c.Param("webhook")); err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Deliveries handler returns the most recent deliveries of a webhook, with the log of their attempts,
+// optionally filtered by status.
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	deliveries, err := h.WebhookManager.Deliveries(c.Request.Context(), c.Param("webhook"), c.Query("status"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusOK, deliveries)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+// ReplayDelivery handler queues the event of a delivery again, dead or not.
+func (h *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	delivery, err := h.WebhookManager.Replay(c.Request.Context(), c.Param("webhook"), c.Param("delivery"))
+	if err != nil {
+		abortWithAPIError(c, err)
+
+		return
+	}
+
+	response := model.NewAPIResponseSuccess(http.StatusAccepted, delivery)
+	c.JSON(response.HTTPStatus(), response)
+}
+
+func NewWebhookHandler(webhookManager manager.WebhookManager) *WebhookHandler {
+	return &WebhookHandler{
+		WebhookManager: webhookManager,
+	}
+}
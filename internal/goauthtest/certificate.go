@@ -0,0 +1,115 @@
+package goauthtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// Certificate is a certificate generated for the tests, with its private key.
+type Certificate struct {
+	*x509.Certificate
+	Key *ecdsa.PrivateKey
+}
+
+// NewCA generates a certificate authority, signed by its parent, or self-signed when there is none.
+func NewCA(t testing.TB, commonName string, parent *Certificate) *Certificate {
+	t.Helper()
+
+	return newCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, parent)
+}
+
+// NewClientCertificate generates a TLS client certificate, issued by a certificate authority, or
+// self-signed when there is none.
+func NewClientCertificate(t testing.TB, commonName string, issuer *Certificate) *Certificate {
+	t.Helper()
+
+	return newCertificate(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, issuer)
+}
+
+// PEM returns the PEM encoding of the certificate.
+func (c *Certificate) PEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+}
+
+// TLSCertificate returns the certificate to present in TLS handshakes, followed by its chain.
+func (c *Certificate) TLSCertificate(chain ...*Certificate) tls.Certificate {
+	cert := tls.Certificate{Certificate: [][]byte{c.Raw}, PrivateKey: c.Key, Leaf: c.Certificate}
+
+	for _, intermediate := range chain {
+		cert.Certificate = append(cert.Certificate, intermediate.Raw)
+	}
+
+	return cert
+}
+
+// JWKS returns a JSON Web Key Set holding the certificate, as registered by self_signed_tls_client_auth
+// clients.
+func (c *Certificate) JWKS(t testing.TB) string {
+	t.Helper()
+
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:          &c.Key.PublicKey,
+		Certificates: []*x509.Certificate{c.Certificate},
+		Use:          "sig",
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(jwks)
+}
+
+func newCertificate(t testing.TB, template *x509.Certificate, issuer *Certificate) *Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template.SerialNumber = serialNumber
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parent, signer := template, key
+	if issuer != nil {
+		parent, signer = issuer.Certificate, issuer.Key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Certificate{Certificate: cert, Key: key}
+}
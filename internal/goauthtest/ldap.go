@@ -0,0 +1,312 @@
+package goauthtest
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPEntry is an entry of the directory of an LDAPServer. Entries with a password can be bound.
+type LDAPEntry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// LDAPServer is an in-process LDAP directory, answering simple binds and searches over ldap://. Like most
+// directories, it accepts the unauthenticated binds of a DN with an empty password.
+type LDAPServer struct {
+	URL string
+
+	listener net.Listener
+	entries  []LDAPEntry
+
+	mutex       sync.Mutex
+	connections []net.Conn
+	binds       []string
+	filters     []string
+}
+
+// NewLDAPServer starts a directory holding the given entries, stopped at the end of the test.
+func NewLDAPServer(t testing.TB, entries ...LDAPEntry) *LDAPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &LDAPServer{URL: "ldap://" + listener.Addr().String(), listener: listener, entries: entries}
+
+	go s.serve()
+
+	t.Cleanup(s.close)
+
+	return s
+}
+
+// Binds returns the DNs of the bind requests received by the directory.
+func (s *LDAPServer) Binds() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.binds...)
+}
+
+// Filters returns the filters of the search requests received by the directory.
+func (s *LDAPServer) Filters() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.filters...)
+}
+
+func (s *LDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.connections = append(s.connections, conn)
+		s.mutex.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *LDAPServer) close() {
+	_ = s.listener.Close()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, conn := range s.connections {
+		_ = conn.Close()
+	}
+}
+
+// handle answers the requests of a connection, until it is unbound or closed.
+func (s *LDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		request, err := ber.ReadPacket(conn)
+		if err != nil || len(request.Children) < 2 {
+			return
+		}
+
+		messageID := request.Children[0].Value
+		op := request.Children[1]
+
+		var responses []*ber.Packet
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			responses = []*ber.Packet{s.bind(op)}
+		case ldap.ApplicationSearchRequest:
+			responses = s.search(op)
+		case ldap.ApplicationUnbindRequest:
+			return
+		default:
+			responses = []*ber.Packet{ldapResult(ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform)}
+		}
+
+		for _, response := range responses {
+			message := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+			message.AppendChild(response)
+
+			if _, err = conn.Write(message.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind answers a simple bind: anonymous and unauthenticated binds succeed, other binds need the password
+// of the entry.
+func (s *LDAPServer) bind(op *ber.Packet) *ber.Packet {
+	if len(op.Children) < 3 || op.Children[2].Tag != 0 {
+		return ldapResult(ldap.ApplicationBindResponse, ldap.LDAPResultAuthMethodNotSupported)
+	}
+
+	dn := ber.DecodeString(op.Children[1].Data.Bytes())
+	password := ber.DecodeString(op.Children[2].Data.Bytes())
+
+	s.mutex.Lock()
+	s.binds = append(s.binds, dn)
+	s.mutex.Unlock()
+
+	if password == "" {
+		return ldapResult(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess)
+	}
+
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			return ldapResult(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess)
+		}
+	}
+
+	return ldapResult(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials)
+}
+
+// search returns the entries under the base DN matching the filter, up to the size limit.
+func (s *LDAPServer) search(op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return []*ber.Packet{ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError)}
+	}
+
+	base := ber.DecodeString(op.Children[0].Data.Bytes())
+	sizeLimit, _ := op.Children[3].Value.(int64)
+
+	filter, err := ldap.DecompileFilter(op.Children[6])
+	if err != nil {
+		return []*ber.Packet{ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError)}
+	}
+
+	s.mutex.Lock()
+	s.filters = append(s.filters, filter)
+	s.mutex.Unlock()
+
+	attributes := make([]string, 0, len(op.Children[7].Children))
+	for _, attribute := range op.Children[7].Children {
+		attributes = append(attributes, ber.DecodeString(attribute.Data.Bytes()))
+	}
+
+	var responses []*ber.Packet
+
+	for _, entry := range s.entries {
+		if !ldapInBase(entry.DN, base) || !entry.matches(op.Children[6]) {
+			continue
+		}
+
+		if sizeLimit > 0 && int64(len(responses)) == sizeLimit {
+			return append(responses, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded))
+		}
+
+		responses = append(responses, entry.packet(attributes))
+	}
+
+	return append(responses, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+}
+
+// values returns the values of an attribute of the entry.
+func (e LDAPEntry) values(name string) []string {
+	for attribute, values := range e.Attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+
+	return nil
+}
+
+// matches evaluates a search filter supporting the and, or, not, equality and presence filters.
+func (e LDAPEntry) matches(filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !e.matches(child) {
+				return false
+			}
+		}
+
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if e.matches(child) {
+				return true
+			}
+		}
+
+		return false
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !e.matches(filter.Children[0])
+	case ldap.FilterEqualityMatch:
+		if len(filter.Children) != 2 {
+			return false
+		}
+
+		value := ber.DecodeString(filter.Children[1].Data.Bytes())
+
+		for _, candidate := range e.values(ber.DecodeString(filter.Children[0].Data.Bytes())) {
+			if strings.EqualFold(candidate, value) {
+				return true
+			}
+		}
+
+		return false
+	case ldap.FilterPresent:
+		return len(e.values(ber.DecodeString(filter.Data.Bytes()))) > 0
+	}
+
+	return false
+}
+
+// packet encodes the entry as a search result entry, with the requested attributes.
+func (e LDAPEntry) packet(attributes []string) *ber.Packet {
+	entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "Object Name"))
+
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+
+	for name, values := range e.Attributes {
+		if !ldapRequested(attributes, name) {
+			continue
+		}
+
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+
+	entry.AppendChild(list)
+
+	return entry
+}
+
+// ldapRequested reports whether an attribute is returned for the attribute list of a search request:
+// an empty list requests all the attributes, and "1.1" none of them.
+func ldapRequested(attributes []string, name string) bool {
+	if len(attributes) == 0 {
+		return true
+	}
+
+	for _, attribute := range attributes {
+		if attribute == "*" || strings.EqualFold(attribute, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ldapInBase reports whether a DN is the base DN, or below it.
+func ldapInBase(dn, base string) bool {
+	dn, base = strings.ToLower(dn), strings.ToLower(base)
+
+	return base == "" || dn == base || strings.HasSuffix(dn, ","+base)
+}
+
+// ldapResult encodes an LDAP result, the response of most operations.
+func ldapResult(application ber.Tag, resultCode uint16) *ber.Packet {
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, application, nil, ldap.ApplicationMap[uint8(application)])
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(resultCode), "Result Code"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+
+	return result
+}
@@ -0,0 +1,711 @@
+package goauthtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// memoryDAO is an in-memory mongo.CrudDAO, evaluating the filters and the updates used by the managers.
+// The documents are stored in their BSON form, so that they are matched on their BSON field names. It
+// panics on the operators it does not support, and on aggregations.
+type memoryDAO[T mongo.Document] struct {
+	mutex     sync.Mutex
+	documents []bson.M
+	// tenantScoped is set for the documents of organizations, as in the MongoDB DAO.
+	tenantScoped bool
+}
+
+func (dao *memoryDAO[T]) GetCollection() *mongodriver.Collection {
+	return nil
+}
+
+func (dao *memoryDAO[T]) CreateIndexes(context.Context, []mongodriver.IndexModel) {}
+
+func (dao *memoryDAO[T]) Create(ctx context.Context, t *T) (bool, error) {
+	if dao.tenantScoped && !tenant.AllOrganizations(ctx) {
+		organizationID := tenant.OrganizationID(ctx)
+		if organizationID == "" {
+			return false, mongo.ErrMissingOrganization
+		}
+
+		any(t).(mongo.TenantDocument).SetTenant(organizationID)
+	}
+
+	document, err := toDocument(t)
+	if err != nil {
+		return false, err
+	}
+
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	if id, found := document["_id"]; found && dao.indexOf(bson.M{"_id": id}) >= 0 {
+		return false, nil
+	}
+
+	dao.documents = append(dao.documents, document)
+
+	return true, nil
+}
+
+func (dao *memoryDAO[T]) Update(ctx context.Context, filter bson.M, update bson.M, withUpsert bool) (mongo.UpdateResult, error) {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		return mongo.UpdateResult{}, err
+	}
+
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	if i := dao.indexOf(filter); i >= 0 {
+		applyUpdate(dao.documents[i], update, false)
+
+		return mongo.UpdateResult{}, nil
+	}
+
+	if !withUpsert {
+		return mongo.UpdateResult{NotFound: true}, nil
+	}
+
+	document := bson.M{}
+	setEqualities(document, filter)
+	applyUpdate(document, update, true)
+
+	dao.documents = append(dao.documents, document)
+
+	return mongo.UpdateResult{Inserted: true}, nil
+}
+
+func (dao *memoryDAO[T]) Exists(ctx context.Context, filter bson.M, _ *options.CountOptions) (bool, error) {
+	documents, err := dao.find(ctx, filter, nil)
+
+	return len(documents) > 0, err
+}
+
+func (dao *memoryDAO[T]) Count(ctx context.Context, filter bson.M) int64 {
+	documents, _ := dao.find(ctx, filter, nil)
+
+	return int64(len(documents))
+}
+
+func (dao *memoryDAO[T]) FindOne(ctx context.Context, filter bson.M, opts *options.FindOneOptions) (*T, error) {
+	findOptions := options.Find().SetLimit(1)
+	if opts != nil {
+		findOptions.Sort = opts.Sort
+		findOptions.Skip = opts.Skip
+	}
+
+	documents, err := dao.find(ctx, filter, findOptions)
+	if err != nil || len(documents) == 0 {
+		return nil, err
+	}
+
+	return &documents[0], nil
+}
+
+func (dao *memoryDAO[T]) FindMany(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]T, error) {
+	return dao.find(ctx, filter, opts)
+}
+
+// Aggregate only supports the pipelines whose first $match stage matches no document, like the lookups of
+// the groups of the users in no group: the other stages produce no document from no document.
+func (dao *memoryDAO[T]) Aggregate(ctx context.Context, pipeline interface{}) ([]T, error) {
+	if stages, ok := pipeline.(bson.A); ok && len(stages) > 0 {
+		if stage, ok := stages[0].(bson.M); ok {
+			if filter, ok := stage["$match"].(bson.M); ok {
+				documents, err := dao.find(ctx, filter, nil)
+				if err != nil || len(documents) == 0 {
+					return make([]T, 0), err
+				}
+			}
+		}
+	}
+
+	panic("goauthtest: aggregations are not supported by the in-memory DAO")
+}
+
+func (dao *memoryDAO[T]) Delete(ctx context.Context, filter bson.M) (bool, error) {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	i := dao.indexOf(filter)
+	if i < 0 {
+		return false, nil
+	}
+
+	dao.documents = slices.Delete(dao.documents, i, i+1)
+
+	return true, nil
+}
+
+func (dao *memoryDAO[T]) DeleteMany(ctx context.Context, filter bson.M) (int64, error) {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	count := len(dao.documents)
+	dao.documents = slices.DeleteFunc(dao.documents, func(document bson.M) bool {
+		return matches(document, filter)
+	})
+
+	return int64(count - len(dao.documents)), nil
+}
+
+// find returns the documents matching a filter, sorted and paginated with the options.
+func (dao *memoryDAO[T]) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]T, error) {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	dao.mutex.Lock()
+
+	found := make([]bson.M, 0)
+
+	for _, document := range dao.documents {
+		if matches(document, filter) {
+			found = append(found, document)
+		}
+	}
+
+	dao.mutex.Unlock()
+
+	if opts != nil {
+		if opts.Sort != nil {
+			sortDocuments(found, opts.Sort)
+		}
+
+		if opts.Skip != nil {
+			found = found[min(int(*opts.Skip), len(found)):]
+		}
+
+		if opts.Limit != nil && *opts.Limit > 0 {
+			found = found[:min(int(*opts.Limit), len(found))]
+		}
+	}
+
+	results := make([]T, 0, len(found))
+
+	for _, document := range found {
+		var t T
+		if err = fromDocument(document, &t); err != nil {
+			return nil, err
+		}
+
+		results = append(results, t)
+	}
+
+	return results, nil
+}
+
+// indexOf returns the index of the first document matching a filter, or -1. The mutex must be held.
+func (dao *memoryDAO[T]) indexOf(filter bson.M) int {
+	return slices.IndexFunc(dao.documents, func(document bson.M) bool {
+		return matches(document, filter)
+	})
+}
+
+// scope restricts a filter to the organization of the context, as the MongoDB DAO does.
+func (dao *memoryDAO[T]) scope(ctx context.Context, filter bson.M) (bson.M, error) {
+	if !dao.tenantScoped || tenant.AllOrganizations(ctx) {
+		return filter, nil
+	}
+
+	organizationID := tenant.OrganizationID(ctx)
+	if organizationID == "" {
+		return nil, mongo.ErrMissingOrganization
+	}
+
+	field := any(new(T)).(mongo.TenantDocument).TenantField()
+
+	tenantFilter := bson.M{field: organizationID}
+	if organizationID == config.DefaultOrganizationID() {
+		tenantFilter = bson.M{field: bson.M{"$in": bson.A{organizationID, nil}}}
+	}
+
+	return bson.M{"$and": bson.A{filter, tenantFilter}}, nil
+}
+
+// setEqualities sets the fields of the equality conditions of a filter on an upserted document.
+func setEqualities(document bson.M, filter bson.M) {
+	for field, condition := range filter {
+		switch {
+		case field == "$and":
+			for _, clause := range normalize(condition).(bson.A) {
+				setEqualities(document, clause.(bson.M))
+			}
+		case !strings.HasPrefix(field, "$") && !isOperatorDocument(condition):
+			setPath(document, strings.Split(field, "."), normalize(condition))
+		}
+	}
+}
+
+// toDocument converts a document to its BSON form.
+func toDocument(v any) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	document := bson.M{}
+
+	return document, bson.Unmarshal(data, &document)
+}
+
+// fromDocument decodes a document from its BSON form.
+func fromDocument(document bson.M, v any) error {
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return err
+	}
+
+	return bson.Unmarshal(data, v)
+}
+
+// normalize converts a value of a filter or an update to the type it has in the stored documents.
+func normalize(value any) any {
+	document, err := toDocument(bson.M{"v": value})
+	if err != nil {
+		panic(fmt.Sprintf("goauthtest: could not encode %v: %s", value, err))
+	}
+
+	return document["v"]
+}
+
+// isOperatorDocument returns true if a condition is a document of query operators.
+func isOperatorDocument(condition any) bool {
+	operators, ok := condition.(bson.M)
+	if !ok || len(operators) == 0 {
+		return false
+	}
+
+	for operator := range operators {
+		if !strings.HasPrefix(operator, "$") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matches evaluates a filter on a document.
+func matches(document bson.M, filter bson.M) bool {
+	for field, condition := range filter {
+		switch field {
+		case "$and", "$or", "$nor":
+			clauses := normalize(condition).(bson.A)
+			matched := 0
+
+			for _, clause := range clauses {
+				if matches(document, clause.(bson.M)) {
+					matched++
+				}
+			}
+
+			if (field == "$and" && matched != len(clauses)) || (field == "$or" && matched == 0) || (field == "$nor" && matched > 0) {
+				return false
+			}
+
+			continue
+		}
+
+		values := lookup(document, strings.Split(field, "."))
+
+		if !isOperatorDocument(condition) {
+			if !equalsAny(values, normalize(condition)) {
+				return false
+			}
+
+			continue
+		}
+
+		for operator, operand := range condition.(bson.M) {
+			if !evaluate(values, operator, operand) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// evaluate applies a query operator to the values of a field.
+func evaluate(values []any, operator string, operand any) bool {
+	switch operator {
+	case "$eq":
+		return equalsAny(values, normalize(operand))
+	case "$ne":
+		return !equalsAny(values, normalize(operand))
+	case "$in", "$nin":
+		in := false
+
+		for _, candidate := range normalize(operand).(bson.A) {
+			if equalsAny(values, candidate) {
+				in = true
+
+				break
+			}
+		}
+
+		return in == (operator == "$in")
+	case "$all":
+		for _, candidate := range normalize(operand).(bson.A) {
+			if !equalsAny(values, candidate) {
+				return false
+			}
+		}
+
+		return true
+	case "$exists":
+		return (len(values) > 0) == operand.(bool)
+	case "$gt", "$gte", "$lt", "$lte":
+		bound := normalize(operand)
+
+		for _, value := range flatten(values) {
+			cmp, ok := compare(value, bound)
+			if !ok {
+				continue
+			}
+
+			switch {
+			case operator == "$gt" && cmp > 0, operator == "$gte" && cmp >= 0,
+				operator == "$lt" && cmp < 0, operator == "$lte" && cmp <= 0:
+				return true
+			}
+		}
+
+		return false
+	case "$elemMatch":
+		for _, value := range values {
+			elements, _ := value.(bson.A)
+
+			for _, element := range elements {
+				document, ok := element.(bson.M)
+
+				if ok && matches(document, operand.(bson.M)) || !ok && matches(bson.M{"v": element}, bson.M{"v": operand}) {
+					return true
+				}
+			}
+		}
+
+		return false
+	case "$not":
+		return !matches(bson.M{"v": valuesOf(values)}, bson.M{"v": operand})
+	}
+
+	panic("goauthtest: the " + operator + " operator is not supported by the in-memory DAO")
+}
+
+// valuesOf returns the value of a field from its looked up values, for nested evaluations.
+func valuesOf(values []any) any {
+	if len(values) == 1 {
+		return values[0]
+	}
+
+	return bson.A(values)
+}
+
+// lookup returns the values of a dotted field path in a document. The path is applied to each element
+// of the arrays it goes through, and returns no value when the field is missing.
+func lookup(value any, path []string) []any {
+	if len(path) == 0 {
+		return []any{value}
+	}
+
+	switch v := value.(type) {
+	case bson.M:
+		field, found := v[path[0]]
+		if !found {
+			return nil
+		}
+
+		return lookup(field, path[1:])
+	case bson.A:
+		if index, err := strconv.Atoi(path[0]); err == nil {
+			if index < 0 || index >= len(v) {
+				return nil
+			}
+
+			return lookup(v[index], path[1:])
+		}
+
+		values := make([]any, 0)
+		for _, element := range v {
+			values = append(values, lookup(element, path)...)
+		}
+
+		return values
+	}
+
+	return nil
+}
+
+// flatten expands the array values of a field, which match their elements.
+func flatten(values []any) []any {
+	flat := make([]any, 0, len(values))
+
+	for _, value := range values {
+		if elements, ok := value.(bson.A); ok {
+			flat = append(flat, elements...)
+		}
+
+		flat = append(flat, value)
+	}
+
+	return flat
+}
+
+// equalsAny returns true if one of the values of a field, or one of their elements, equals a value. A
+// null value matches the missing fields.
+func equalsAny(values []any, expected any) bool {
+	if expected == nil && len(values) == 0 {
+		return true
+	}
+
+	for _, value := range flatten(values) {
+		if equals(value, expected) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func equals(a, b any) bool {
+	if cmp, ok := compare(a, b); ok {
+		return cmp == 0
+	}
+
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	x, errA := toDocument(bson.M{"v": a})
+	y, errB := toDocument(bson.M{"v": b})
+
+	return errA == nil && errB == nil && fmt.Sprint(x) == fmt.Sprint(y)
+}
+
+// compare orders two scalar values of the same kind. It returns false when they can't be compared.
+func compare(a, b any) (int, bool) {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+
+			return 0, true
+		}
+
+		return 0, false
+	}
+
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			if x == y {
+				return 0, true
+			}
+
+			if y {
+				return -1, true
+			}
+
+			return 1, true
+		}
+	case primitive.DateTime:
+		if y, ok := b.(primitive.DateTime); ok {
+			return compareInts(int64(x), int64(y)), true
+		}
+	}
+
+	return 0, false
+}
+
+func compareInts(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+
+	return 0
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+// sortDocuments sorts documents with a sort specification.
+func sortDocuments(documents []bson.M, specification any) {
+	keys, ok := specification.(bson.D)
+	if !ok {
+		panic("goauthtest: the sort specifications must be a bson.D")
+	}
+
+	sort.SliceStable(documents, func(i, j int) bool {
+		for _, key := range keys {
+			a := valuesOf(lookup(documents[i], strings.Split(key.Key, ".")))
+			b := valuesOf(lookup(documents[j], strings.Split(key.Key, ".")))
+
+			cmp, _ := compare(a, b)
+			if cmp == 0 {
+				continue
+			}
+
+			if direction, _ := number(normalize(key.Value)); direction < 0 {
+				return cmp > 0
+			}
+
+			return cmp < 0
+		}
+
+		return false
+	})
+}
+
+// applyUpdate applies the update operators to a document. The $setOnInsert operator only applies to the
+// upserted documents.
+func applyUpdate(document bson.M, update bson.M, inserted bool) {
+	for operator, fields := range update {
+		for field, operand := range fields.(bson.M) {
+			path := strings.Split(field, ".")
+			if slices.Contains(path, "$") {
+				panic("goauthtest: the positional operator is not supported by the in-memory DAO")
+			}
+
+			value := normalize(operand)
+
+			switch operator {
+			case "$set":
+				setPath(document, path, value)
+			case "$setOnInsert":
+				if inserted {
+					setPath(document, path, value)
+				}
+			case "$unset":
+				unsetPath(document, path)
+			case "$inc":
+				current, _ := number(valuesOf(lookup(document, path)))
+				increment, _ := number(value)
+				setPath(document, path, normalize(current+increment))
+			case "$push", "$addToSet":
+				elements, _ := valuesOf(lookup(document, path)).(bson.A)
+
+				added := bson.A{value}
+				if each, ok := value.(bson.M); ok && each["$each"] != nil {
+					added = each["$each"].(bson.A)
+				}
+
+				for _, element := range added {
+					if operator == "$push" || !slices.ContainsFunc(elements, func(e any) bool { return equals(e, element) }) {
+						elements = append(elements, element)
+					}
+				}
+
+				setPath(document, path, elements)
+			case "$pull":
+				elements, _ := valuesOf(lookup(document, path)).(bson.A)
+
+				elements = slices.DeleteFunc(elements, func(element any) bool {
+					if document, ok := element.(bson.M); ok && isDocumentCondition(operand) {
+						return matches(document, operand.(bson.M))
+					}
+
+					return matches(bson.M{"v": element}, bson.M{"v": operand})
+				})
+
+				if elements != nil {
+					setPath(document, path, elements)
+				}
+			case "$currentDate":
+				setPath(document, path, primitive.NewDateTimeFromTime(time.Now()))
+			default:
+				panic("goauthtest: the " + operator + " update operator is not supported by the in-memory DAO")
+			}
+		}
+	}
+}
+
+// isDocumentCondition returns true if a $pull condition is a filter on the fields of the elements.
+func isDocumentCondition(condition any) bool {
+	fields, ok := condition.(bson.M)
+
+	return ok && !isOperatorDocument(fields)
+}
+
+func setPath(document bson.M, path []string, value any) {
+	for _, field := range path[:len(path)-1] {
+		next, ok := document[field].(bson.M)
+		if !ok {
+			next = bson.M{}
+			document[field] = next
+		}
+
+		document = next
+	}
+
+	document[path[len(path)-1]] = value
+}
+
+func unsetPath(document bson.M, path []string) {
+	for _, field := range path[:len(path)-1] {
+		next, ok := document[field].(bson.M)
+		if !ok {
+			return
+		}
+
+		document = next
+	}
+
+	delete(document, path[len(path)-1])
+}
+
+// NewMemoryDAO returns an empty in-memory DAO of a document, for the tests of the managers using DAOs
+// directly.
+func NewMemoryDAO[T mongo.Document]() mongo.CrudDAO[T] {
+	dao := &memoryDAO[T]{}
+	_, dao.tenantScoped = any(new(T)).(mongo.TenantDocument)
+
+	return dao
+}
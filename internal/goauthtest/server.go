@@ -0,0 +1,235 @@
+// Package goauthtest runs goauth in process for the tests of its clients. The server is the real
+// application of goauth, with its router, handlers, middlewares and managers, on top of in-memory DAOs
+// instead of MongoDB.
+package goauthtest
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/server"
+	"github.com/m3talux/goauth/tenant"
+)
+
+// Server is an in-process goauth, serving the endpoints of the default organization at its URL. The
+// configuration is read from the environment, like goauth does, with the issuer set to the URL of the
+// server: the tests using it can't run in parallel.
+type Server struct {
+	*httptest.Server
+	// Issuer is the issuer of the default organization, the URL of the server.
+	Issuer string
+	// KeyManager signs the JWTs of the server.
+	KeyManager manager.KeyManager
+
+	daos server.DAOs
+}
+
+// NewServer starts a goauth server, stopped at the end of the test. The wrappers wrap the router, to
+// observe or alter the requests and the responses of the server.
+func NewServer(t testing.TB, wrappers ...func(http.Handler) http.Handler) *Server {
+	t.Helper()
+
+	return newServer(t, false, wrappers)
+}
+
+// NewTLSServer starts a goauth server over TLS, requesting the client certificates like goauth does when
+// it terminates TLS itself. The clients of TLSClient trust its certificate.
+func NewTLSServer(t testing.TB, wrappers ...func(http.Handler) http.Handler) *Server {
+	t.Helper()
+
+	return newServer(t, true, wrappers)
+}
+
+func newServer(t testing.TB, withTLS bool, wrappers []func(http.Handler) http.Handler) *Server {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(nil)
+
+	issuer := "http://" + srv.Listener.Addr().String()
+	if withTLS {
+		issuer = "https://" + srv.Listener.Addr().String()
+	}
+
+	Configure(t, issuer)
+
+	daos := newDAOs(t)
+
+	app, err := server.NewApplication(context.Background(), daos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var h http.Handler = app.Router
+	for _, wrap := range wrappers {
+		h = wrap(h)
+	}
+
+	srv.Config.Handler = h
+
+	if withTLS {
+		srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+		srv.StartTLS()
+	} else {
+		srv.Start()
+	}
+
+	t.Cleanup(srv.Close)
+
+	return &Server{
+		Server:     srv,
+		Issuer:     issuer,
+		KeyManager: app.KeyManager,
+		daos:       daos,
+	}
+}
+
+// Configure initializes the configuration of goauth from the environment, with the required variables set
+// for the issuer. The other variables can be set with t.Setenv beforehand.
+func Configure(t testing.TB, issuer string) {
+	t.Helper()
+
+	t.Setenv("OAUTH_ISSUER", issuer)
+	t.Setenv("OAUTH_LOGIN_URL", issuer+"/login")
+	t.Setenv("MONGODB_HOST", "localhost")
+	t.Setenv("MONGODB_PORT", "27017")
+	config.Initialize()
+}
+
+// AddClient registers a client in the default organization. With a secret, the client is a confidential
+// client authenticating with the client secret, otherwise it authenticates with its own method.
+func (s *Server) AddClient(t testing.TB, client model.Client, secret string) {
+	t.Helper()
+
+	if secret != "" {
+		hash, err := security.HashSecret(secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client.SecretHash = hash
+		client.TokenEndpointAuthMethod = model.ClientAuthMethodSecretBasic
+	}
+
+	client.CreatedAt = time.Now()
+	client.UpdatedAt = client.CreatedAt
+
+	if _, err := s.daos.Client.Create(Context(), &client); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TLSClient returns an HTTP client of a TLS server, presenting the client certificates, if any.
+func (s *Server) TLSClient(certificates ...tls.Certificate) *http.Client {
+	transport := s.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.Certificates = certificates
+
+	return &http.Client{Transport: transport}
+}
+
+// AddRole creates a role of the platform.
+func (s *Server) AddRole(t testing.TB, role model.Role) {
+	t.Helper()
+
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = role.CreatedAt
+
+	if _, err := s.daos.Role.Create(Context(), &role); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AddUser creates an end-user.
+func (s *Server) AddUser(t testing.TB, user model.User) {
+	t.Helper()
+
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+
+	if _, err := s.daos.User.Create(Context(), &user); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AddRefreshToken issues a refresh token in the default organization, valid for an hour, and returns its
+// value.
+func (s *Server) AddRefreshToken(t testing.TB, token model.Token) string {
+	t.Helper()
+
+	value, err := security.RandomToken(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token.ID = security.HashToken(value)
+	token.Kind = model.TokenKindRefreshToken
+	token.OrganizationID = config.DefaultOrganizationID()
+	token.IssuedAt = time.Now()
+	token.ExpiresAt = token.IssuedAt.Add(time.Hour)
+
+	if _, err = s.daos.Token.Create(Context(), &token); err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+// Context returns a context in the default organization.
+func Context() context.Context {
+	return tenant.WithOrganization(context.Background(), config.DefaultOrganizationID())
+}
+
+// newDAOs returns in-memory DAOs for all the collections of goauth. A DAO added to server.DAOs without
+// its in-memory counterpart fails the tests, rather than panicking in the request using it.
+func newDAOs(t testing.TB) server.DAOs {
+	t.Helper()
+
+	daos := server.DAOs{
+		Client:                     NewMemoryDAO[model.Client](),
+		AuthorizationRequest:       NewMemoryDAO[model.AuthorizationRequest](),
+		PushedAuthorizationRequest: NewMemoryDAO[model.PushedAuthorizationRequest](),
+		AuthorizationCode:          NewMemoryDAO[model.AuthorizationCode](),
+		Token:                      NewMemoryDAO[model.Token](),
+		ReplayEntry:                NewMemoryDAO[model.ReplayEntry](),
+		Session:                    NewMemoryDAO[model.Session](),
+		User:                       NewMemoryDAO[model.User](),
+		Identity:                   NewMemoryDAO[model.Identity](),
+		Connection:                 NewMemoryDAO[model.Connection](),
+		SocialLoginState:           NewMemoryDAO[model.SocialLoginState](),
+		SAMLServiceProvider:        NewMemoryDAO[model.SAMLServiceProvider](),
+		SAMLAuthnRequest:           NewMemoryDAO[model.SAMLAuthnRequest](),
+		Group:                      NewMemoryDAO[model.Group](),
+		SCIMToken:                  NewMemoryDAO[model.SCIMToken](),
+		Role:                       NewMemoryDAO[model.Role](),
+		Organization:               NewMemoryDAO[model.Organization](),
+		Invitation:                 NewMemoryDAO[model.Invitation](),
+		DomainClaim:                NewMemoryDAO[model.DomainClaim](),
+		APIKey:                     NewMemoryDAO[model.APIKey](),
+		PersonalAccessToken:        NewMemoryDAO[model.PersonalAccessToken](),
+		Policy:                     NewMemoryDAO[model.Policy](),
+		RelationNamespace:          NewMemoryDAO[model.RelationNamespace](),
+		RelationTuple:              NewMemoryDAO[model.RelationTuple](),
+		RelationRevision:           NewMemoryDAO[model.RelationRevision](),
+		RateLimitBucket:            NewMemoryDAO[model.RateLimitBucket](),
+		AuditEvent:                 NewMemoryDAO[model.AuditEvent](),
+		Webhook:                    NewMemoryDAO[model.Webhook](),
+		WebhookDelivery:            NewMemoryDAO[model.WebhookDelivery](),
+	}
+
+	fields := reflect.ValueOf(daos)
+	for i := 0; i < fields.NumField(); i++ {
+		if fields.Field(i).IsNil() {
+			t.Fatalf("no in-memory DAO for %s", fields.Type().Field(i).Name)
+		}
+	}
+
+	return daos
+}
@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// accessTokenStrategy issues and validates the tokens of one format. The token manager picks the
+// strategy of each access token from the registration of the client.
+type accessTokenStrategy interface {
+	// Issue returns the value of a new token carrying the given token information.
+	Issue(ctx context.Context, token *model.Token, grant tokenGrant) (string, error)
+
+	// Validate returns the active token matching the given value, or nil if there is none, whatever its
+	// organization.
+	Validate(ctx context.Context, value string) (*model.Token, error)
+}
+
+// opaqueTokenStrategy issues random reference tokens, stored hashed. It is also used for refresh tokens.
+type opaqueTokenStrategy struct {
+	tokenDAO mongo.CrudDAO[model.Token]
+}
+
+func (s *opaqueTokenStrategy) Issue(ctx context.Context, token *model.Token, _ tokenGrant) (string, error) {
+	value, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	token.ID = security.HashToken(value)
+
+	if _, err = s.tokenDAO.Create(ctx, token); err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+func (s *opaqueTokenStrategy) Validate(ctx context.Context, value string) (*model.Token, error) {
+	// Tokens are looked up in every organization, the callers check the organization of the token
+	token, err := s.tokenDAO.FindOne(tenant.WithAllOrganizations(ctx), bson.M{"_id": security.HashToken(value)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if token == nil || !token.IsActive(time.Now()) {
+		//nolint:nilnil // An unknown or expired token is not an error
+		return nil, nil
+	}
+
+	return token, nil
+}
+
+// jwtAccessTokenStrategy issues self-contained JWT access tokens (RFC 9068), which resource servers can
+// validate with the public keys of goauth. They are not stored, so they remain valid until they expire.
+type jwtAccessTokenStrategy struct {
+	keyManager KeyManager
+}
+
+func (s *jwtAccessTokenStrategy) Issue(ctx context.Context, token *model.Token, grant tokenGrant) (string, error) {
+	jti, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	token.ID = jti
+
+	// The subject of client credentials tokens is the client itself (RFC 9068, section 2.2)
+	subject := token.Subject
+	if subject == "" {
+		subject = token.ClientID
+	}
+
+	audience := grant.Client.AccessTokenAudiences
+	if len(audience) == 0 {
+		audience = []string{tenant.Issuer(ctx)}
+	}
+
+	claims := model.AccessTokenClaims{
+		Claims: jwt.Claims{
+			ID:       jti,
+			Issuer:   tenant.Issuer(ctx),
+			Subject:  subject,
+			Audience: audience,
+			IssuedAt: jwt.NewNumericDate(token.IssuedAt),
+			Expiry:   jwt.NewNumericDate(token.ExpiresAt),
+		},
+		ClientID:       token.ClientID,
+		Scope:          strings.Join(token.Scopes, " "),
+		SessionID:      token.SessionID,
+		Confirmation:   token.Confirmation,
+		OrganizationID: token.OrganizationID,
+		Roles:          token.Roles,
+		Permissions:    token.Permissions,
+		Groups:         token.Groups,
+		GroupsOverage:  token.GroupsOverage,
+	}
+
+	if !grant.AuthTime.IsZero() {
+		claims.AuthTime = grant.AuthTime.Unix()
+	}
+
+	return s.keyManager.Sign(claims, model.JWTTypeAccessToken)
+}
+
+func (s *jwtAccessTokenStrategy) Validate(_ context.Context, value string) (*model.Token, error) {
+	var claims model.AccessTokenClaims
+
+	if err := s.keyManager.Verify(value, model.JWTTypeAccessToken, &claims); err != nil {
+		//nolint:nilnil // An invalid token is not an error
+		return nil, nil
+	}
+
+	// The issuer is the one of the organization of the token
+	expected := jwt.Expected{Issuer: config.OrganizationIssuer(claims.OrganizationID), Time: time.Now()}
+	if err := claims.ValidateWithLeeway(expected, 0); err != nil || claims.Expiry == nil {
+		//nolint:nilnil // An expired token is not an error
+		return nil, nil
+	}
+
+	token := &model.Token{
+		ID:             claims.ID,
+		Kind:           model.TokenKindAccessToken,
+		OrganizationID: claims.OrganizationID,
+		ClientID:       claims.ClientID,
+		Subject:        claims.Subject,
+		Scopes:         strings.Fields(claims.Scope),
+		Confirmation:   claims.Confirmation,
+		SessionID:      claims.SessionID,
+		Roles:          claims.Roles,
+		Permissions:    claims.Permissions,
+		Groups:         claims.Groups,
+		GroupsOverage:  claims.GroupsOverage,
+		Audience:       claims.Audience,
+		ExpiresAt:      claims.Expiry.Time(),
+	}
+
+	if claims.IssuedAt != nil {
+		token.IssuedAt = claims.IssuedAt.Time()
+	}
+
+	return token, nil
+}
+
+// isJWT returns true if the token value is a compact serialized JWS, rather than an opaque token.
+func isJWT(value string) bool {
+	return strings.Count(value, ".") == 2
+}
@@ -0,0 +1,165 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Size, in bytes, of the random part of the prefix of API keys and personal access tokens.
+	credentialPrefixSize = 6
+
+	// credentialUsageResolution is the interval between two updates of the last use of an API key or a
+	// personal access token, so that every authenticated request does not write to the database.
+	credentialUsageResolution = time.Minute
+)
+
+type APIKeyManager interface {
+	// Authenticate returns the API key of a key value, or nil if it is unknown or expired. Its last use is
+	// recorded, with the IP of the client.
+	Authenticate(ctx context.Context, key, ip string) (*model.APIKey, error)
+
+	// Create creates an API key in the organization of the context, on behalf of the subject or the client
+	// of the key. Its scopes must be granted by the permissions of its creator. The key value is only
+	// returned once.
+	Create(ctx context.Context, apiKey model.APIKey, permissions []string) (*model.APIKeyCreation, error)
+
+	// APIKeys returns the API keys of the organization of the context.
+	APIKeys(ctx context.Context) ([]model.APIKey, error)
+
+	// Revoke deletes an API key.
+	Revoke(ctx context.Context, keyID string) error
+}
+
+type apiKeyManager struct {
+	apiKeyDAO mongo.CrudDAO[model.APIKey]
+}
+
+func (m *apiKeyManager) Authenticate(ctx context.Context, key, ip string) (*model.APIKey, error) {
+	// The prefix identifies the key, the secret is only compared to the stored hash
+	prefix, secret, found := strings.Cut(key, ".")
+	if !found {
+		//nolint:nilnil // An invalid key is not an error
+		return nil, nil
+	}
+
+	ctx = tenant.WithAllOrganizations(ctx)
+
+	apiKey, err := m.apiKeyDAO.FindOne(ctx, bson.M{"prefix": prefix}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if apiKey == nil || !security.EqualTokens(apiKey.SecretHash, security.HashToken(secret)) || apiKey.IsExpired(now) {
+		//nolint:nilnil // An unknown or expired key is not an error
+		return nil, nil
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= credentialUsageResolution || apiKey.LastUsedIP != ip {
+		update := bson.M{"$set": bson.M{"lastUsedAt": now, "lastUsedIp": ip}}
+		if _, err = m.apiKeyDAO.Update(ctx, bson.M{"_id": apiKey.ID}, update, false); err != nil {
+			return nil, err
+		}
+
+		apiKey.LastUsedAt = &now
+		apiKey.LastUsedIP = ip
+	}
+
+	return apiKey, nil
+}
+
+func (m *apiKeyManager) Create(ctx context.Context, apiKey model.APIKey, permissions []string) (*model.APIKeyCreation, error) {
+	if strings.TrimSpace(apiKey.Name) == "" {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the name of the key is required")
+	}
+
+	if len(apiKey.Scopes) == 0 {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the scopes of the key are required")
+	}
+
+	for _, scope := range apiKey.Scopes {
+		if !model.GrantsPermission(permissions, scope) {
+			return nil, model.NewAPIResponseError(http.StatusForbidden, "the "+scope+" scope exceeds the permissions of the access token")
+		}
+	}
+
+	now := time.Now()
+
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now) {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the expiration date of the key is past")
+	}
+
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := security.RandomToken(credentialPrefixSize)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey = model.APIKey{
+		ID:         id,
+		Name:       apiKey.Name,
+		Prefix:     config.APIKeyPrefix() + "_" + prefix,
+		SecretHash: security.HashToken(secret),
+		Subject:    apiKey.Subject,
+		ClientID:   apiKey.ClientID,
+		Scopes:     apiKey.Scopes,
+		ExpiresAt:  apiKey.ExpiresAt,
+		CreatedAt:  now,
+	}
+
+	created, err := m.apiKeyDAO.Create(ctx, &apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// The random prefix collided with another key
+	if !created {
+		return nil, model.NewAPIResponseError(http.StatusConflict, "the key could not be created, please retry")
+	}
+
+	return &model.APIKeyCreation{APIKey: apiKey, Key: apiKey.Prefix + "." + secret}, nil
+}
+
+func (m *apiKeyManager) APIKeys(ctx context.Context) ([]model.APIKey, error) {
+	return m.apiKeyDAO.FindMany(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+}
+
+func (m *apiKeyManager) Revoke(ctx context.Context, keyID string) error {
+	deleted, err := m.apiKeyDAO.Delete(ctx, bson.M{"_id": keyID})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return model.NewAPIResponseError(http.StatusNotFound, "the API key does not exist")
+	}
+
+	return nil
+}
+
+func NewAPIKeyManager(apiKeyDAO mongo.CrudDAO[model.APIKey]) APIKeyManager {
+	return &apiKeyManager{
+		apiKeyDAO: apiKeyDAO,
+	}
+}
@@ -0,0 +1,198 @@
+package manager
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/metrics"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type auditContextKey int
+
+// auditSourceKey is the key of the source of the request in the context, recorded in its audit events.
+const auditSourceKey auditContextKey = iota
+
+// auditSource is the client IP and the user agent of a request.
+type auditSource struct {
+	IP        string
+	UserAgent string
+}
+
+type AuditManager interface {
+	// Record appends an event to the audit log of the organization of the context, with the source of the
+	// request of the context (see WithAuditSource), and publishes it to the webhooks. Failures are logged,
+	// and don't fail the audited operation.
+	Record(ctx context.Context, event model.AuditEvent)
+
+	// Events returns a page of the audit events of the organization of the context matching a query.
+	// It returns a 400 API error if the cursor of the query is invalid.
+	Events(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error)
+}
+
+type auditManager struct {
+	auditEventDAO  mongo.CrudDAO[model.AuditEvent]
+	webhookManager WebhookManager
+}
+
+// WithAuditSource returns a context recording the client IP and the user agent of a request in the audit
+// events of the request.
+func WithAuditSource(ctx context.Context, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, auditSourceKey, auditSource{IP: ip, UserAgent: userAgent})
+}
+
+func (m *auditManager) Record(ctx context.Context, event model.AuditEvent) {
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		log.Err(err).Str("type", event.Type).Msg("Could not record an audit event")
+
+		return
+	}
+
+	event.ID = id
+	event.CreatedAt = time.Now()
+
+	if source, ok := ctx.Value(auditSourceKey).(auditSource); ok {
+		event.IP, event.UserAgent = source.IP, source.UserAgent
+	}
+
+	if retention := config.AuditRetention(); retention > 0 {
+		expiresAt := event.CreatedAt.Add(retention)
+		event.ExpiresAt = &expiresAt
+	}
+
+	observeAuditEvent(event)
+
+	// The audited operation may be ending with the request
+	ctx = context.WithoutCancel(ctx)
+
+	if _, err = m.auditEventDAO.Create(ctx, &event); err != nil {
+		log.Err(err).Str("type", event.Type).Msg("Could not record an audit event")
+
+		return
+	}
+
+	m.webhookManager.Publish(ctx, event)
+}
+
+func (m *auditManager) Events(ctx context.Context, query model.AuditQuery) (*model.AuditPage, error) {
+	filter := bson.M{}
+
+	for field, value := range map[string]string{
+		"type":           query.Type,
+		"outcome":        query.Outcome,
+		"actor.subject":  query.Subject,
+		"actor.clientId": query.Client,
+		"target.id":      query.Target,
+		"ip":             query.IP,
+	} {
+		if value != "" {
+			filter[field] = value
+		}
+	}
+
+	createdAt := bson.M{}
+	if query.Since != nil {
+		createdAt["$gte"] = *query.Since
+	}
+
+	if query.Until != nil {
+		createdAt["$lt"] = *query.Until
+	}
+
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+
+	if query.Cursor != "" {
+		cursorAt, cursorID, err := decodeAuditCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		// The events are sorted from the most recent, and by identifier between events of the same time
+		filter["$or"] = bson.A{
+			bson.M{"createdAt": bson.M{"$lt": cursorAt}},
+			bson.M{"createdAt": cursorAt, "_id": bson.M{"$lt": cursorID}},
+		}
+	}
+
+	// One more event is fetched to know whether there is a next page
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(query.Limit + 1))
+
+	events, err := m.auditEventDAO.FindMany(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.AuditPage{Events: events}
+
+	if len(events) > query.Limit {
+		page.Events = events[:query.Limit]
+		last := page.Events[query.Limit-1]
+		page.NextCursor = encodeAuditCursor(last.CreatedAt, last.ID)
+	}
+
+	return page, nil
+}
+
+// observeAuditEvent records the logins and the token requests of the audit events in the metrics.
+func observeAuditEvent(event model.AuditEvent) {
+	switch event.Type {
+	case model.AuditEventLogin:
+		metrics.Logins.WithLabelValues(event.Outcome).Inc()
+	case model.AuditEventTokenIssue:
+		// The grant type of the failed requests comes from the client, and is not a label
+		if event.Outcome == model.AuditOutcomeSuccess {
+			metrics.TokensIssued.WithLabelValues(event.Details["grant_type"]).Inc()
+		} else {
+			metrics.TokenRequestFailures.Inc()
+		}
+	}
+}
+
+// encodeAuditCursor returns the cursor of the page following an event.
+func encodeAuditCursor(createdAt time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt.UnixMilli(), 10) + "." + id))
+}
+
+// decodeAuditCursor returns the creation time and the identifier of the event preceding the page of a cursor.
+func decodeAuditCursor(cursor string) (time.Time, string, error) {
+	invalidCursorError := model.NewAPIResponseError(http.StatusBadRequest, "the cursor is invalid")
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", invalidCursorError
+	}
+
+	millis, id, found := strings.Cut(string(raw), ".")
+	if !found || id == "" {
+		return time.Time{}, "", invalidCursorError
+	}
+
+	unixMilli, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return time.Time{}, "", invalidCursorError
+	}
+
+	return time.UnixMilli(unixMilli), id, nil
+}
+
+func NewAuditManager(auditEventDAO mongo.CrudDAO[model.AuditEvent], webhookManager WebhookManager) AuditManager {
+	return &auditManager{
+		auditEventDAO:  auditEventDAO,
+		webhookManager: webhookManager,
+	}
+}
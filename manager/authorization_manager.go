@@ -0,0 +1,419 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Size, in bytes, of the random identifiers of stored requests.
+const requestIDSize = 32
+
+// authorizationParameters lists the parameters kept from a pushed authorization request.
+var authorizationParameters = []string{
+	"response_type",
+	"redirect_uri",
+	"scope",
+	"state",
+	"nonce",
+	"code_challenge",
+	"code_challenge_method",
+	"dpop_jkt",
+}
+
+type AuthorizationManager interface {
+	// Push validates the authorization parameters pushed by an authenticated client, and stores
+	// them until the authorization endpoint is called with the returned request URI (RFC 9126).
+	// When the request came with a valid DPoP proof, jkt is the thumbprint of the proof key and
+	// the authorization code will be bound to it.
+	Push(ctx context.Context, client *model.Client, params url.Values, jkt string) (model.PushedAuthorizationResponse, error)
+
+	// Authorize validates a request sent to the authorization endpoint, and stores it until the
+	// end-user logs in. If the request references a pushed authorization request, its parameters
+	// are used instead of the query ones, and it can't be used again.
+	Authorize(ctx context.Context, params url.Values) (*model.AuthorizationRequest, error)
+
+	// RequestLogin stores a login request on behalf of another protocol, like SAML, until the end-user logs
+	// in. Its identifier is the login challenge to give to the login page, and the end-user is sent back to
+	// the resume URL once logged in.
+	RequestLogin(ctx context.Context, resumeURL string) (*model.AuthorizationRequest, error)
+
+	// RequestInvitationLogin stores a login request accepting an invitation once the end-user logs in. The
+	// end-user is then sent to the account page.
+	RequestInvitationLogin(ctx context.Context, invitationID string) (*model.AuthorizationRequest, error)
+
+	// Get returns the pending authorization request of the given login challenge, or nil if there is none.
+	// The request is returned whatever its organization.
+	Get(ctx context.Context, requestID string) (*model.AuthorizationRequest, error)
+
+	// Approve completes an authorization request once the end-user has logged in: an authorization code
+	// is issued within the session, and the redirection to the client is returned. Login requests of other
+	// protocols are redirected to their resume URL instead, without authorization code.
+	Approve(ctx context.Context, requestID string, session *model.Session) (string, error)
+
+	// Deny completes an authorization request that the end-user did not approve. It returns the
+	// access_denied error, to send to the client redirect URI.
+	Deny(ctx context.Context, requestID, description string) error
+}
+
+type authorizationManager struct {
+	clientManager                 ClientManager
+	authorizationRequestDAO       mongo.CrudDAO[model.AuthorizationRequest]
+	pushedAuthorizationRequestDAO mongo.CrudDAO[model.PushedAuthorizationRequest]
+	authorizationCodeDAO          mongo.CrudDAO[model.AuthorizationCode]
+}
+
+func (m *authorizationManager) Push(
+	ctx context.Context,
+	client *model.Client,
+	params url.Values,
+	jkt string,
+) (model.PushedAuthorizationResponse, error) {
+	if params.Has("request_uri") {
+		return model.PushedAuthorizationResponse{}, model.NewOAuthInvalidRequestError("the request_uri parameter can't be pushed")
+	}
+
+	if clientID := params.Get("client_id"); clientID != "" && clientID != client.ID {
+		return model.PushedAuthorizationResponse{}, model.NewOAuthInvalidRequestError("the client_id does not match the authenticated client")
+	}
+
+	if jkt != "" {
+		if dpopJKT := params.Get("dpop_jkt"); dpopJKT != "" && dpopJKT != jkt {
+			return model.PushedAuthorizationResponse{}, model.NewOAuthInvalidRequestError("the dpop_jkt does not match the DPoP proof key")
+		}
+
+		params.Set("dpop_jkt", jkt)
+	}
+
+	if _, err := validateAuthorizationRequest(client, params); err != nil {
+		return model.PushedAuthorizationResponse{}, err
+	}
+
+	id, err := security.RandomToken(requestIDSize)
+	if err != nil {
+		return model.PushedAuthorizationResponse{}, err
+	}
+
+	parameters := make(map[string][]string)
+
+	for _, name := range authorizationParameters {
+		if params.Has(name) {
+			parameters[name] = params[name]
+		}
+	}
+
+	now := time.Now()
+
+	par := &model.PushedAuthorizationRequest{
+		ID:         id,
+		ClientID:   client.ID,
+		Parameters: parameters,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(config.PARLifetime()),
+	}
+
+	if _, err = m.pushedAuthorizationRequestDAO.Create(ctx, par); err != nil {
+		return model.PushedAuthorizationResponse{}, err
+	}
+
+	return model.PushedAuthorizationResponse{
+		RequestURI: config.PARRequestURIPrefix() + id,
+		ExpiresIn:  int(config.PARLifetime().Seconds()),
+	}, nil
+}
+
+func (m *authorizationManager) Authorize(ctx context.Context, params url.Values) (*model.AuthorizationRequest, error) {
+	clientID := params.Get("client_id")
+	if clientID == "" {
+		return nil, model.NewOAuthInvalidRequestError("the client_id is missing")
+	}
+
+	client, err := m.clientManager.Get(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		return nil, model.NewOAuthInvalidRequestError("the client is unknown")
+	}
+
+	requestURI := params.Get("request_uri")
+
+	switch {
+	case requestURI != "":
+		params, err = m.consumePushedRequest(ctx, client, requestURI)
+		if err != nil {
+			return nil, err
+		}
+	case client.RequirePushedAuthorizationRequests:
+		return nil, model.NewOAuthInvalidRequestError("the client must use pushed authorization requests")
+	}
+
+	req, err := validateAuthorizationRequest(client, params)
+	if err != nil {
+		return nil, err
+	}
+
+	req.ID, err = security.RandomToken(requestIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	req.OrganizationID = tenant.OrganizationID(ctx)
+	req.Pushed = requestURI != ""
+	req.CreatedAt = now
+	req.ExpiresAt = now.Add(config.AuthorizationRequestLifetime())
+
+	if _, err = m.authorizationRequestDAO.Create(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func (m *authorizationManager) RequestLogin(ctx context.Context, resumeURL string) (*model.AuthorizationRequest, error) {
+	return m.requestLogin(ctx, &model.AuthorizationRequest{ResumeURL: resumeURL})
+}
+
+func (m *authorizationManager) RequestInvitationLogin(ctx context.Context, invitationID string) (*model.AuthorizationRequest, error) {
+	return m.requestLogin(ctx, &model.AuthorizationRequest{ResumeURL: tenant.URL(ctx, config.OAuthAccountURL()), InvitationID: invitationID})
+}
+
+func (m *authorizationManager) Get(ctx context.Context, requestID string) (*model.AuthorizationRequest, error) {
+	req, err := m.authorizationRequestDAO.FindOne(ctx, bson.M{"_id": requestID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if req == nil || req.IsExpired(time.Now()) {
+		//nolint:nilnil // An unknown or expired request is not an error
+		return nil, nil
+	}
+
+	return req, nil
+}
+
+func (m *authorizationManager) Approve(ctx context.Context, requestID string, session *model.Session) (string, error) {
+	req, err := m.consumeRequest(ctx, requestID)
+	if err != nil {
+		return "", err
+	}
+
+	if req.ResumeURL != "" {
+		return req.ResumeURL, nil
+	}
+
+	value, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	code := &model.AuthorizationCode{
+		ID:                  security.HashToken(value),
+		ClientID:            req.ClientID,
+		Subject:             session.Subject,
+		RedirectURI:         req.RedirectURI,
+		RedirectURIRequired: req.RedirectURIRequired,
+		Scopes:              req.Scopes,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		DPoPJKT:             req.DPoPJKT,
+		SessionID:           session.ID,
+		AuthTime:            session.AuthTime,
+		CreatedAt:           now,
+		ExpiresAt:           now.Add(config.AuthorizationCodeLifetime()),
+	}
+
+	if _, err = m.authorizationCodeDAO.Create(ctx, code); err != nil {
+		return "", err
+	}
+
+	redirectURI, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		return "", err
+	}
+
+	query := redirectURI.Query()
+	query.Set("code", value)
+
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+
+	redirectURI.RawQuery = query.Encode()
+
+	return redirectURI.String(), nil
+}
+
+func (m *authorizationManager) Deny(ctx context.Context, requestID, description string) error {
+	req, err := m.consumeRequest(ctx, requestID)
+	if err != nil {
+		return err
+	}
+
+	return model.NewOAuthError(http.StatusForbidden, model.OAuthErrorAccessDenied, description).WithRedirect(req.RedirectURI, req.State)
+}
+
+// requestLogin stores a login request of the organization of the context, without client.
+func (m *authorizationManager) requestLogin(ctx context.Context, req *model.AuthorizationRequest) (*model.AuthorizationRequest, error) {
+	id, err := security.RandomToken(requestIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	req.ID = id
+	req.OrganizationID = tenant.OrganizationID(ctx)
+	req.CreatedAt = now
+	req.ExpiresAt = now.Add(config.AuthorizationRequestLifetime())
+
+	if _, err = m.authorizationRequestDAO.Create(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// consumeRequest returns a pending authorization request and deletes it, so that it is completed only once.
+func (m *authorizationManager) consumeRequest(ctx context.Context, requestID string) (*model.AuthorizationRequest, error) {
+	invalidRequestError := model.NewOAuthInvalidRequestError("the login challenge is invalid or expired")
+
+	req, err := m.authorizationRequestDAO.FindOne(ctx, bson.M{"_id": requestID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The request must be completed in its organization, where the end-user logged in
+	if req == nil || !tenant.Matches(ctx, req.OrganizationID) {
+		return nil, invalidRequestError
+	}
+
+	deleted, err := m.authorizationRequestDAO.Delete(ctx, bson.M{"_id": requestID})
+	if err != nil {
+		return nil, err
+	}
+
+	if !deleted || req.IsExpired(time.Now()) {
+		return nil, invalidRequestError
+	}
+
+	return req, nil
+}
+
+// consumePushedRequest returns the parameters of a pushed authorization request and deletes it.
+func (m *authorizationManager) consumePushedRequest(ctx context.Context, client *model.Client, requestURI string) (url.Values, error) {
+	invalidRequestURIError := model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidRequestURI, "the request_uri is invalid or expired")
+
+	id, found := strings.CutPrefix(requestURI, config.PARRequestURIPrefix())
+	if !found {
+		return nil, invalidRequestURIError
+	}
+
+	par, err := m.pushedAuthorizationRequestDAO.FindOne(ctx, bson.M{"_id": id}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if par == nil || par.ClientID != client.ID {
+		return nil, invalidRequestURIError
+	}
+
+	// Whoever deletes the request first is the only one allowed to use it
+	deleted, err := m.pushedAuthorizationRequestDAO.Delete(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	if !deleted || par.IsExpired(time.Now()) {
+		return nil, invalidRequestURIError
+	}
+
+	params := url.Values(par.Parameters)
+	params.Set("client_id", client.ID)
+
+	return params, nil
+}
+
+// validateAuthorizationRequest checks the authorization parameters against the client registration.
+// Once the redirect URI is validated, errors are returned with the redirection to use.
+func validateAuthorizationRequest(client *model.Client, params url.Values) (*model.AuthorizationRequest, error) {
+	redirectURI := params.Get("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+
+	if !client.HasRedirectURI(redirectURI) {
+		return nil, model.NewOAuthInvalidRequestError("the redirect_uri is missing or not registered")
+	}
+
+	state := params.Get("state")
+
+	if params.Get("response_type") != model.ResponseTypeCode {
+		return nil, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorUnsupportedResponseType, "only the code response type is supported").
+			WithRedirect(redirectURI, state)
+	}
+
+	if !client.HasGrantType(model.GrantTypeAuthorizationCode) {
+		return nil, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorUnauthorizedClient, "the client can't use the authorization code grant").
+			WithRedirect(redirectURI, state)
+	}
+
+	scopes := strings.Fields(params.Get("scope"))
+	if !client.AllowsScopes(scopes) {
+		return nil, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidScope, "the requested scope is not allowed").
+			WithRedirect(redirectURI, state)
+	}
+
+	codeChallenge := params.Get("code_challenge")
+	codeChallengeMethod := params.Get("code_challenge_method")
+
+	if codeChallenge == "" && client.IsPublic() {
+		return nil, model.NewOAuthInvalidRequestError("PKCE is required for public clients").WithRedirect(redirectURI, state)
+	}
+
+	if codeChallenge != "" && codeChallengeMethod != model.CodeChallengeMethodS256 {
+		return nil, model.NewOAuthInvalidRequestError("only the S256 code challenge method is supported").WithRedirect(redirectURI, state)
+	}
+
+	return &model.AuthorizationRequest{
+		ClientID:            client.ID,
+		ResponseType:        model.ResponseTypeCode,
+		RedirectURI:         redirectURI,
+		RedirectURIRequired: params.Get("redirect_uri") != "",
+		Scopes:              scopes,
+		State:               state,
+		Nonce:               params.Get("nonce"),
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
+		DPoPJKT:             params.Get("dpop_jkt"),
+	}, nil
+}
+
+func NewAuthorizationManager(
+	clientManager ClientManager,
+	authorizationRequestDAO mongo.CrudDAO[model.AuthorizationRequest],
+	pushedAuthorizationRequestDAO mongo.CrudDAO[model.PushedAuthorizationRequest],
+	authorizationCodeDAO mongo.CrudDAO[model.AuthorizationCode],
+) AuthorizationManager {
+	return &authorizationManager{
+		clientManager:                 clientManager,
+		authorizationRequestDAO:       authorizationRequestDAO,
+		pushedAuthorizationRequestDAO: pushedAuthorizationRequestDAO,
+		authorizationCodeDAO:          authorizationCodeDAO,
+	}
+}
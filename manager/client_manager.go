@@ -0,0 +1,389 @@
+package manager
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type ClientManager interface {
+	// Get returns the client with the given identifier, or nil if it does not exist.
+	Get(ctx context.Context, clientID string) (*model.Client, error)
+
+	// Authenticate authenticates the client calling a back-channel endpoint, with the
+	// authentication method registered for the client. The request form must have been parsed.
+	Authenticate(ctx context.Context, r *http.Request) (*model.Client, error)
+}
+
+// Method of the credentials read from a request that carries a JWT client assertion.
+// It matches both the private_key_jwt and client_secret_jwt registered methods.
+const clientAssertionMethod = "client_assertion"
+
+// Clock skew tolerated when validating the time claims of client assertions.
+const clientAssertionLeeway = 30 * time.Second
+
+type clientManager struct {
+	clientDAO      mongo.CrudDAO[model.Client]
+	replayEntryDAO mongo.CrudDAO[model.ReplayEntry]
+	jwksManager    JWKSManager
+}
+
+type clientCredentials struct {
+	ID        string
+	Secret    string
+	Assertion string
+	Method    string
+}
+
+func (m *clientManager) Get(ctx context.Context, clientID string) (*model.Client, error) {
+	return m.clientDAO.FindOne(ctx, bson.M{"_id": clientID}, nil)
+}
+
+func (m *clientManager) Authenticate(ctx context.Context, r *http.Request) (*model.Client, error) {
+	credentials, err := readClientCredentials(r)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := m.Get(ctx, credentials.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		log.Warn().Str("clientId", credentials.ID).Msg("Authentication attempt of an unknown client")
+
+		return nil, model.NewOAuthInvalidClientError("the client authentication failed")
+	}
+
+	if err = m.authenticateClient(ctx, client, credentials, r); err != nil {
+		log.Warn().Err(err).Str("clientId", client.ID).Str("method", client.TokenEndpointAuthMethod).Msg("Client authentication failed")
+
+		return nil, model.NewOAuthInvalidClientError("the client authentication failed")
+	}
+
+	return client, nil
+}
+
+// authenticateClient verifies the credentials presented by the client, with its registered method.
+func (m *clientManager) authenticateClient(ctx context.Context, client *model.Client, credentials clientCredentials, r *http.Request) error {
+	switch client.TokenEndpointAuthMethod {
+	case model.ClientAuthMethodPrivateKeyJWT, model.ClientAuthMethodSecretJWT:
+		if credentials.Method != clientAssertionMethod {
+			return errors.New("a client assertion is required")
+		}
+
+		return m.verifyClientAssertion(ctx, client, credentials.Assertion, r)
+	case model.ClientAuthMethodSecretBasic, model.ClientAuthMethodSecretPost:
+		if credentials.Method != client.TokenEndpointAuthMethod || !security.CheckSecret(client.SecretHash, credentials.Secret) {
+			return errors.New("invalid client secret")
+		}
+
+		return nil
+	case model.ClientAuthMethodTLS, model.ClientAuthMethodSelfSignedTLS:
+		// Only the client identifier is sent in the request, the credentials are in the TLS layer
+		if credentials.Method != model.ClientAuthMethodNone {
+			return errors.New("unexpected client secret")
+		}
+
+		cert, err := security.PeerCertificate(r)
+		if err != nil {
+			return err
+		}
+
+		if cert == nil {
+			return errors.New("no client certificate was presented")
+		}
+
+		if client.TokenEndpointAuthMethod == model.ClientAuthMethodTLS {
+			return verifyPKICertificate(client, cert, security.PeerIntermediates(r))
+		}
+
+		return verifySelfSignedCertificate(client, cert)
+	case model.ClientAuthMethodNone:
+		if credentials.Method != model.ClientAuthMethodNone {
+			return errors.New("unexpected client secret")
+		}
+
+		return nil
+	default:
+		return errors.New("unsupported authentication method")
+	}
+}
+
+// verifyClientAssertion validates the JWT client assertion of a private_key_jwt or client_secret_jwt
+// client (RFC 7523, section 3). The assertion identifier is recorded so it can't be used twice.
+func (m *clientManager) verifyClientAssertion(ctx context.Context, client *model.Client, assertion string, r *http.Request) error {
+	algs := asymmetricSignatureAlgorithms
+	if client.TokenEndpointAuthMethod == model.ClientAuthMethodSecretJWT {
+		algs = hmacSignatureAlgorithms
+	}
+
+	token, err := jwt.ParseSigned(assertion, algs)
+	if err != nil {
+		return err
+	}
+
+	key, err := m.clientAssertionKey(ctx, client, token.Headers[0].KeyID)
+	if err != nil {
+		return err
+	}
+
+	var claims jwt.Claims
+	if err = token.Claims(key, &claims); err != nil {
+		return err
+	}
+
+	if claims.Expiry == nil || claims.ID == "" {
+		return errors.New("the client assertion must contain the exp and jti claims")
+	}
+
+	expected := jwt.Expected{Issuer: client.ID, Subject: client.ID, Time: time.Now()}
+	if err = claims.ValidateWithLeeway(expected, clientAssertionLeeway); err != nil {
+		return err
+	}
+
+	// The audience may be the issuer, the token endpoint, or the endpoint receiving the assertion
+	audiences := []string{tenant.Issuer(ctx), tenant.URL(ctx, config.OAuthEndpoint("/token")), config.OAuthIssuer() + r.URL.Path}
+	if !slices.ContainsFunc(audiences, claims.Audience.Contains) {
+		return errors.New("the client assertion audience is invalid")
+	}
+
+	entry := &model.ReplayEntry{
+		ID:        "client_assertion:" + client.ID + ":" + claims.ID,
+		ExpiresAt: claims.Expiry.Time().Add(clientAssertionLeeway),
+	}
+
+	created, err := m.replayEntryDAO.Create(ctx, entry)
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		return errors.New("the client assertion has already been used")
+	}
+
+	return nil
+}
+
+// clientAssertionKey returns the key verifying the assertions of a client: its decrypted secret for
+// client_secret_jwt clients, or the public key with the given identifier from its key set.
+func (m *clientManager) clientAssertionKey(ctx context.Context, client *model.Client, kid string) (interface{}, error) {
+	if client.TokenEndpointAuthMethod == model.ClientAuthMethodSecretJWT {
+		secret, err := security.Decrypt(client.SecretEncrypted)
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(secret), nil
+	}
+
+	var (
+		keySet *jose.JSONWebKeySet
+		err    error
+	)
+
+	if client.JWKSURI != "" {
+		if kid != "" {
+			key, keyErr := m.jwksManager.Key(ctx, client.JWKSURI, kid)
+			if keyErr != nil {
+				return nil, keyErr
+			}
+
+			if key == nil || !key.IsPublic() {
+				return nil, errors.New("the client assertion key is unknown")
+			}
+
+			return key.Key, nil
+		}
+
+		keySet, err = m.jwksManager.Get(ctx, client.JWKSURI)
+	} else {
+		keySet, err = client.KeySet()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := keySet.Keys
+	if kid != "" {
+		keys = keySet.Key(kid)
+	}
+
+	// Without key identifier, the key set must contain a single key
+	if len(keys) != 1 || !keys[0].IsPublic() {
+		return nil, errors.New("the client assertion key is unknown")
+	}
+
+	return keys[0].Key, nil
+}
+
+// verifyPKICertificate validates the certificate chain of a tls_client_auth client against the trusted
+// authorities, through the intermediate certificates presented with it, then checks that the certificate
+// matches the registered subject (RFC 8705, section 2.1).
+func verifyPKICertificate(client *model.Client, cert *x509.Certificate, intermediates []*x509.Certificate) error {
+	roots, err := security.ClientCertificateAuthorities()
+	if err != nil {
+		return err
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	for _, intermediate := range intermediates {
+		opts.Intermediates.AddCert(intermediate)
+	}
+
+	if _, err = cert.Verify(opts); err != nil {
+		return err
+	}
+
+	var matches bool
+
+	switch {
+	case client.TLSClientAuthSubjectDN != "":
+		matches = cert.Subject.String() == client.TLSClientAuthSubjectDN
+	case client.TLSClientAuthSANDNS != "":
+		matches = slices.Contains(cert.DNSNames, client.TLSClientAuthSANDNS)
+	case client.TLSClientAuthSANURI != "":
+		matches = slices.ContainsFunc(cert.URIs, func(u *url.URL) bool { return u.String() == client.TLSClientAuthSANURI })
+	case client.TLSClientAuthSANIP != "":
+		matches = slices.ContainsFunc(cert.IPAddresses, func(ip net.IP) bool { return ip.Equal(net.ParseIP(client.TLSClientAuthSANIP)) })
+	case client.TLSClientAuthSANEmail != "":
+		matches = slices.Contains(cert.EmailAddresses, client.TLSClientAuthSANEmail)
+	}
+
+	if !matches {
+		return errors.New("the client certificate does not match the registered subject")
+	}
+
+	return nil
+}
+
+// verifySelfSignedCertificate checks that the certificate of a self_signed_tls_client_auth client is one
+// of the certificates registered in its JWKS (RFC 8705, section 2.2).
+func verifySelfSignedCertificate(client *model.Client, cert *x509.Certificate) error {
+	keySet, err := client.KeySet()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keySet.Keys {
+		if len(key.Certificates) > 0 && key.Certificates[0].Equal(cert) {
+			return nil
+		}
+	}
+
+	return errors.New("the client certificate is not registered")
+}
+
+// readClientCredentials extracts the client credentials from the request, as described in RFC 6749, section 2.3.1.
+func readClientCredentials(r *http.Request) (clientCredentials, error) {
+	formClientID := r.PostForm.Get("client_id")
+	formSecret := r.PostForm.Get("client_secret")
+
+	basicID, basicSecret, hasBasic := r.BasicAuth()
+
+	if r.PostForm.Has("client_assertion_type") {
+		if hasBasic || formSecret != "" {
+			return clientCredentials{}, model.NewOAuthInvalidRequestError("the client must use a single authentication method")
+		}
+
+		return readClientAssertion(r.PostForm)
+	}
+
+	if !hasBasic {
+		if formClientID == "" {
+			return clientCredentials{}, model.NewOAuthInvalidClientError("the client authentication is missing")
+		}
+
+		method := model.ClientAuthMethodSecretPost
+		if formSecret == "" {
+			method = model.ClientAuthMethodNone
+		}
+
+		return clientCredentials{ID: formClientID, Secret: formSecret, Method: method}, nil
+	}
+
+	if formSecret != "" {
+		return clientCredentials{}, model.NewOAuthInvalidRequestError("the client must use a single authentication method")
+	}
+
+	// Credentials are form-urlencoded before being base64 encoded in the Authorization header
+	id, err := url.QueryUnescape(basicID)
+	if err != nil {
+		return clientCredentials{}, model.NewOAuthInvalidClientError("the client identifier is malformed")
+	}
+
+	secret, err := url.QueryUnescape(basicSecret)
+	if err != nil {
+		return clientCredentials{}, model.NewOAuthInvalidClientError("the client secret is malformed")
+	}
+
+	if formClientID != "" && formClientID != id {
+		return clientCredentials{}, model.NewOAuthInvalidRequestError("the client identifiers do not match")
+	}
+
+	return clientCredentials{ID: id, Secret: secret, Method: model.ClientAuthMethodSecretBasic}, nil
+}
+
+// readClientAssertion extracts the JWT client assertion from the request form (RFC 7523, section 2.2).
+// The client is identified by the assertion subject, which is verified later with the assertion.
+func readClientAssertion(form url.Values) (clientCredentials, error) {
+	if form.Get("client_assertion_type") != model.ClientAssertionTypeJWTBearer {
+		return clientCredentials{}, model.NewOAuthInvalidRequestError("the client_assertion_type is not supported")
+	}
+
+	assertion := form.Get("client_assertion")
+
+	algs := make([]jose.SignatureAlgorithm, 0, len(asymmetricSignatureAlgorithms)+len(hmacSignatureAlgorithms))
+	algs = append(algs, asymmetricSignatureAlgorithms...)
+	algs = append(algs, hmacSignatureAlgorithms...)
+
+	token, err := jwt.ParseSigned(assertion, algs)
+	if err != nil {
+		return clientCredentials{}, model.NewOAuthInvalidClientError("the client assertion is malformed")
+	}
+
+	var claims jwt.Claims
+	if err = token.UnsafeClaimsWithoutVerification(&claims); err != nil || claims.Subject == "" {
+		return clientCredentials{}, model.NewOAuthInvalidClientError("the client assertion is malformed")
+	}
+
+	if clientID := form.Get("client_id"); clientID != "" && clientID != claims.Subject {
+		return clientCredentials{}, model.NewOAuthInvalidRequestError("the client identifiers do not match")
+	}
+
+	return clientCredentials{ID: claims.Subject, Assertion: assertion, Method: clientAssertionMethod}, nil
+}
+
+func NewClientManager(
+	clientDAO mongo.CrudDAO[model.Client],
+	replayEntryDAO mongo.CrudDAO[model.ReplayEntry],
+	jwksManager JWKSManager,
+) ClientManager {
+	return &clientManager{
+		clientDAO:      clientDAO,
+		replayEntryDAO: replayEntryDAO,
+		jwksManager:    jwksManager,
+	}
+}
@@ -0,0 +1,131 @@
+package manager_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/m3talux/goauth/internal/goauthtest"
+	"github.com/m3talux/goauth/model"
+)
+
+// requestToken requests an access token with the client credentials grant, authenticating the client
+// with the certificate of the TLS connection, and returns the status of the response.
+func requestToken(t *testing.T, srv *goauthtest.Server, clientID string, certificates ...tls.Certificate) int {
+	t.Helper()
+
+	form := url.Values{"grant_type": {model.GrantTypeClientCredentials}, "client_id": {clientID}}
+
+	res, err := srv.TLSClient(certificates...).Post(srv.Issuer+"/oauth2/token", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	return res.StatusCode
+}
+
+func TestTLSClientAuth(t *testing.T) {
+	root := goauthtest.NewCA(t, "Root CA", nil)
+	intermediate := goauthtest.NewCA(t, "Intermediate CA", root)
+	untrusted := goauthtest.NewCA(t, "Untrusted CA", nil)
+
+	// The authorities are loaded once per process: no other test of the package may use them
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, root.PEM(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("TLS_CLIENT_CA_FILE", caFile)
+
+	srv := goauthtest.NewTLSServer(t)
+	srv.AddClient(t, model.Client{
+		ID:                      "pki-client",
+		GrantTypes:              []string{model.GrantTypeClientCredentials},
+		TokenEndpointAuthMethod: model.ClientAuthMethodTLS,
+		TLSClientAuthSubjectDN:  "CN=pki-client",
+	}, "")
+
+	tests := []struct {
+		name         string
+		certificates []tls.Certificate
+		status       int
+	}{
+		{
+			name:         "issued by a trusted authority",
+			certificates: []tls.Certificate{goauthtest.NewClientCertificate(t, "pki-client", root).TLSCertificate()},
+			status:       http.StatusOK,
+		},
+		{
+			name:         "issued by an intermediate authority",
+			certificates: []tls.Certificate{goauthtest.NewClientCertificate(t, "pki-client", intermediate).TLSCertificate(intermediate)},
+			status:       http.StatusOK,
+		},
+		{
+			name:         "without its intermediate authority",
+			certificates: []tls.Certificate{goauthtest.NewClientCertificate(t, "pki-client", intermediate).TLSCertificate()},
+			status:       http.StatusUnauthorized,
+		},
+		{
+			name:         "issued by an untrusted authority",
+			certificates: []tls.Certificate{goauthtest.NewClientCertificate(t, "pki-client", untrusted).TLSCertificate()},
+			status:       http.StatusUnauthorized,
+		},
+		{
+			name:         "issued to another subject",
+			certificates: []tls.Certificate{goauthtest.NewClientCertificate(t, "other-client", root).TLSCertificate()},
+			status:       http.StatusUnauthorized,
+		},
+		{
+			name:   "without certificate",
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := requestToken(t, srv, "pki-client", tt.certificates...); status != tt.status {
+				t.Fatalf("expected the status %d, got %d", tt.status, status)
+			}
+		})
+	}
+}
+
+func TestSelfSignedTLSClientAuth(t *testing.T) {
+	registered := goauthtest.NewClientCertificate(t, "self-signed-client", nil)
+
+	srv := goauthtest.NewTLSServer(t)
+	srv.AddClient(t, model.Client{
+		ID:                      "self-signed-client",
+		GrantTypes:              []string{model.GrantTypeClientCredentials},
+		TokenEndpointAuthMethod: model.ClientAuthMethodSelfSignedTLS,
+		JWKS:                    registered.JWKS(t),
+	}, "")
+
+	tests := []struct {
+		name         string
+		certificates []tls.Certificate
+		status       int
+	}{
+		{name: "registered certificate", certificates: []tls.Certificate{registered.TLSCertificate()}, status: http.StatusOK},
+		{
+			name:         "other certificate with the same subject",
+			certificates: []tls.Certificate{goauthtest.NewClientCertificate(t, "self-signed-client", nil).TLSCertificate()},
+			status:       http.StatusUnauthorized,
+		},
+		{name: "without certificate", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := requestToken(t, srv, "self-signed-client", tt.certificates...); status != tt.status {
+				t.Fatalf("expected the status %d, got %d", tt.status, status)
+			}
+		})
+	}
+}
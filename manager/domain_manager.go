@@ -0,0 +1,243 @@
+package manager
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// domainPattern matches the lowercased domain names that can be claimed.
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,61}[a-z0-9]$`)
+
+type DomainManager interface {
+	// Domains returns the email domains claimed by the organization of the context, sorted by domain.
+	Domains(ctx context.Context) ([]model.DomainClaim, error)
+
+	// Claim claims an email domain for the organization of the context. The claim is not verified until the
+	// verification token is published in the DNS TXT record of the domain (see Verify). The roles given to
+	// the users joining by their domain can't grant more than the permissions of the caller.
+	Claim(ctx context.Context, claim model.DomainClaim, permissions []string) (*model.DomainClaim, error)
+
+	// Update replaces the connection, the automatic join and the roles of a claimed domain. The roles can't
+	// grant more than the permissions of the caller.
+	Update(ctx context.Context, domain string, claim model.DomainClaim, permissions []string) (*model.DomainClaim, error)
+
+	// Delete removes the claim of a domain. The users who joined the organization by their domain remain
+	// members.
+	Delete(ctx context.Context, domain string) error
+
+	// Verify verifies a claimed domain, once the DNS TXT record of the domain holds the verification token
+	// of the claim. A domain can only be verified by one organization.
+	Verify(ctx context.Context, domain string) (*model.DomainClaim, error)
+
+	// Discover returns the organization that verified the domain of an email, and its connection, so that
+	// the login page can route the end-user to it. It returns a 404 API error if no organization claims it.
+	Discover(ctx context.Context, email string) (*model.DomainDiscovery, error)
+}
+
+type domainManager struct {
+	domainClaimDAO mongo.CrudDAO[model.DomainClaim]
+	connectionDAO  mongo.CrudDAO[model.Connection]
+	roleDAO        mongo.CrudDAO[model.Role]
+}
+
+func (m *domainManager) Domains(ctx context.Context) ([]model.DomainClaim, error) {
+	return m.domainClaimDAO.FindMany(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "domain", Value: 1}}))
+}
+
+func (m *domainManager) Claim(ctx context.Context, claim model.DomainClaim, permissions []string) (*model.DomainClaim, error) {
+	domain := strings.ToLower(strings.TrimSpace(claim.Domain))
+	if !domainPattern.MatchString(domain) {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the domain is invalid")
+	}
+
+	if err := m.validate(ctx, &claim, permissions); err != nil {
+		return nil, err
+	}
+
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	verificationToken, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	claim = model.DomainClaim{
+		ID:                id,
+		Domain:            domain,
+		VerificationToken: verificationToken,
+		ConnectionID:      claim.ConnectionID,
+		AutoJoin:          claim.AutoJoin,
+		Roles:             claim.Roles,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	created, err := m.domainClaimDAO.Create(ctx, &claim)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		return nil, model.NewAPIResponseError(http.StatusConflict, "the domain is already claimed by the organization")
+	}
+
+	return &claim, nil
+}
+
+func (m *domainManager) Update(ctx context.Context, domain string, claim model.DomainClaim, permissions []string) (*model.DomainClaim, error) {
+	if err := m.validate(ctx, &claim, permissions); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"connectionId": claim.ConnectionID,
+		"autoJoin":     claim.AutoJoin,
+		"roles":        claim.Roles,
+		"updatedAt":    time.Now(),
+	}}
+
+	res, err := m.domainClaimDAO.Update(ctx, bson.M{"domain": strings.ToLower(domain)}, update, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.NotFound {
+		return nil, domainClaimNotFoundError()
+	}
+
+	return m.get(ctx, domain)
+}
+
+func (m *domainManager) Delete(ctx context.Context, domain string) error {
+	deleted, err := m.domainClaimDAO.Delete(ctx, bson.M{"domain": strings.ToLower(domain)})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return domainClaimNotFoundError()
+	}
+
+	return nil
+}
+
+func (m *domainManager) Verify(ctx context.Context, domain string) (*model.DomainClaim, error) {
+	claim, err := m.get(ctx, domain)
+	if err != nil || claim.Verified {
+		return claim, err
+	}
+
+	record := config.DomainVerificationRecord(claim.Domain)
+
+	values, err := net.DefaultResolver.LookupTXT(ctx, record)
+	if err != nil || !slices.Contains(values, claim.VerificationToken) {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the TXT record "+record+" does not hold the verification token")
+	}
+
+	now := time.Now()
+
+	res, err := m.domainClaimDAO.Update(ctx, bson.M{"_id": claim.ID}, bson.M{"$set": bson.M{"verified": true, "verifiedAt": now, "updatedAt": now}}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.NotFound {
+		return nil, domainClaimNotFoundError()
+	}
+
+	if res.UniqueError {
+		return nil, model.NewAPIResponseError(http.StatusConflict, "the domain is verified by another organization")
+	}
+
+	return m.get(ctx, domain)
+}
+
+func (m *domainManager) Discover(ctx context.Context, email string) (*model.DomainDiscovery, error) {
+	domain := emailDomain(email)
+	if domain == "" {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the email is invalid")
+	}
+
+	claim, err := m.domainClaimDAO.FindOne(tenant.WithAllOrganizations(ctx), bson.M{"domain": domain, "verified": true}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if claim == nil {
+		return nil, model.NewAPIResponseError(http.StatusNotFound, "no organization claims the domain of the email")
+	}
+
+	return &model.DomainDiscovery{
+		OrganizationID: claim.OrganizationID,
+		Issuer:         config.OrganizationIssuer(claim.OrganizationID),
+		ConnectionID:   claim.ConnectionID,
+	}, nil
+}
+
+// get returns a domain claimed by the organization of the context, or a 404 API error if it is not claimed.
+func (m *domainManager) get(ctx context.Context, domain string) (*model.DomainClaim, error) {
+	claim, err := m.domainClaimDAO.FindOne(ctx, bson.M{"domain": strings.ToLower(domain)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if claim == nil {
+		return nil, domainClaimNotFoundError()
+	}
+
+	return claim, nil
+}
+
+// validate checks that the connection and the roles of a claim exist in the organization of the context,
+// and that the roles don't grant more than the permissions of the caller.
+func (m *domainManager) validate(ctx context.Context, claim *model.DomainClaim, permissions []string) error {
+	if claim.ConnectionID != "" {
+		exists, err := m.connectionDAO.Exists(ctx, bson.M{"_id": claim.ConnectionID}, nil)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return model.NewAPIResponseError(http.StatusBadRequest, "the connection does not exist")
+		}
+	}
+
+	roles, err := checkGrantableRoles(ctx, m.roleDAO, claim.Roles, permissions)
+	claim.Roles = roles
+
+	return err
+}
+
+func domainClaimNotFoundError() error {
+	return model.NewAPIResponseError(http.StatusNotFound, "the domain is not claimed by the organization")
+}
+
+func NewDomainManager(
+	domainClaimDAO mongo.CrudDAO[model.DomainClaim],
+	connectionDAO mongo.CrudDAO[model.Connection],
+	roleDAO mongo.CrudDAO[model.Role],
+) DomainManager {
+	return &domainManager{
+		domainClaimDAO: domainClaimDAO,
+		connectionDAO:  connectionDAO,
+		roleDAO:        roleDAO,
+	}
+}
@@ -0,0 +1,189 @@
+package manager
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+)
+
+const dpopProofType = "dpop+jwt"
+
+type DPoPManager interface {
+	// VerifyProof validates a DPoP proof sent with a request to the given method and URI, as
+	// described in RFC 9449, section 4.3, and returns the JWK SHA-256 thumbprint of the proof key.
+	// When the proof is sent along with an access token, the token hash must match the ath claim.
+	VerifyProof(ctx context.Context, proof, method, uri, accessToken string) (string, error)
+
+	// Nonce returns a fresh nonce to send to clients in the DPoP-Nonce header.
+	// It returns an empty string when nonces are not required.
+	Nonce() string
+}
+
+type dpopManager struct {
+	replayEntryDAO mongo.CrudDAO[model.ReplayEntry]
+}
+
+type dpopClaims struct {
+	ID          string           `json:"jti"`
+	HTTPMethod  string           `json:"htm"`
+	HTTPURI     string           `json:"htu"`
+	IssuedAt    *jwt.NumericDate `json:"iat"`
+	AccessToken string           `json:"ath,omitempty"`
+	Nonce       string           `json:"nonce,omitempty"`
+}
+
+func (m *dpopManager) VerifyProof(ctx context.Context, proof, method, uri, accessToken string) (string, error) {
+	token, err := jwt.ParseSigned(proof, asymmetricSignatureAlgorithms)
+	if err != nil || len(token.Headers) != 1 {
+		return "", invalidDPoPProofError("the DPoP proof is malformed")
+	}
+
+	header := token.Headers[0]
+	if header.ExtraHeaders[jose.HeaderType] != dpopProofType {
+		return "", invalidDPoPProofError("the DPoP proof type is invalid")
+	}
+
+	jwk := header.JSONWebKey
+	if jwk == nil || !jwk.Valid() || !jwk.IsPublic() {
+		return "", invalidDPoPProofError("the DPoP proof key is missing or invalid")
+	}
+
+	var claims dpopClaims
+	if err = token.Claims(jwk.Key, &claims); err != nil {
+		return "", invalidDPoPProofError("the DPoP proof signature is invalid")
+	}
+
+	if err = checkDPoPClaims(claims, method, uri, accessToken); err != nil {
+		return "", err
+	}
+
+	if !m.isValidNonce(claims.Nonce) {
+		return "", model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorUseDPoPNonce, "the DPoP proof must contain a valid nonce")
+	}
+
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", invalidDPoPProofError("the DPoP proof key is invalid")
+	}
+
+	jkt := base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	// The proof can't be accepted once it is too old, so its jti is only kept for that long
+	entry := &model.ReplayEntry{
+		ID:        "dpop:" + jkt + ":" + claims.ID,
+		ExpiresAt: claims.IssuedAt.Time().Add(2 * config.DPoPProofMaxAge()),
+	}
+
+	created, err := m.replayEntryDAO.Create(ctx, entry)
+	if err != nil {
+		return "", err
+	}
+
+	if !created {
+		return "", invalidDPoPProofError("the DPoP proof has already been used")
+	}
+
+	return jkt, nil
+}
+
+func (m *dpopManager) Nonce() string {
+	secret := config.DPoPNonceSecret()
+	if secret == "" {
+		return ""
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	return timestamp + "." + security.Sign(secret, timestamp)
+}
+
+// isValidNonce checks that the nonce was issued by Nonce, and is still fresh.
+func (m *dpopManager) isValidNonce(nonce string) bool {
+	secret := config.DPoPNonceSecret()
+	if secret == "" {
+		return true
+	}
+
+	timestamp, signature, found := strings.Cut(nonce, ".")
+	if !found || !security.CheckSignature(secret, timestamp, signature) {
+		return false
+	}
+
+	issuedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return time.Since(time.Unix(issuedAt, 0)) <= config.DPoPNonceLifetime()
+}
+
+func checkDPoPClaims(claims dpopClaims, method, uri, accessToken string) error {
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return invalidDPoPProofError("the DPoP proof must contain the jti and iat claims")
+	}
+
+	if claims.HTTPMethod != method {
+		return invalidDPoPProofError("the DPoP proof htm claim does not match the request method")
+	}
+
+	if !isSameHTTPURI(claims.HTTPURI, uri) {
+		return invalidDPoPProofError("the DPoP proof htu claim does not match the request URI")
+	}
+
+	maxAge := config.DPoPProofMaxAge()
+	now := time.Now()
+	issuedAt := claims.IssuedAt.Time()
+
+	if issuedAt.Before(now.Add(-maxAge)) || issuedAt.After(now.Add(maxAge)) {
+		return invalidDPoPProofError("the DPoP proof iat claim is out of the acceptable window")
+	}
+
+	if accessToken != "" && !security.EqualTokens(claims.AccessToken, security.HashToken(accessToken)) {
+		return invalidDPoPProofError("the DPoP proof ath claim does not match the access token")
+	}
+
+	return nil
+}
+
+// isSameHTTPURI compares two URIs without their query and fragment parts (RFC 9449, section 4.3).
+func isSameHTTPURI(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host) && ua.EscapedPath() == ub.EscapedPath()
+}
+
+// DPoPSignatureAlgorithms returns the names of the algorithms accepted for DPoP proofs. Symmetric
+// algorithms are excluded since the proof key has to be public.
+func DPoPSignatureAlgorithms() []string {
+	return signatureAlgorithmNames(asymmetricSignatureAlgorithms)
+}
+
+func invalidDPoPProofError(description string) model.OAuthError {
+	return model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidDPoPProof, description)
+}
+
+func NewDPoPManager(replayEntryDAO mongo.CrudDAO[model.ReplayEntry]) DPoPManager {
+	return &dpopManager{
+		replayEntryDAO: replayEntryDAO,
+	}
+}
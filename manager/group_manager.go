@@ -0,0 +1,253 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GroupManager interface {
+	// Groups returns the groups of the organization of the context, sorted by display name.
+	Groups(ctx context.Context) ([]model.Group, error)
+
+	// Get returns a group, or a 404 API error if it does not exist.
+	Get(ctx context.Context, groupID string) (*model.Group, error)
+
+	// Create creates a group. Its members must be users and groups of the organization of the context, and
+	// its roles can't grant more than the permissions of the caller.
+	Create(ctx context.Context, group model.Group, permissions []string) (*model.Group, error)
+
+	// Update replaces the display name, the members, the member groups and the roles of a group. A group
+	// can't contain itself, directly or through its member groups. The roles granted to the members, the
+	// roles of the group and of the groups containing it, can't grant more than the permissions of the caller.
+	Update(ctx context.Context, groupID string, group model.Group, permissions []string) (*model.Group, error)
+
+	// Delete deletes a group, and removes it from the groups it is a member of.
+	Delete(ctx context.Context, groupID string) error
+
+	// UserGroups returns the effective groups of a user in the organization of the context: the groups the
+	// user is a member of, and the groups containing them, sorted by display name.
+	UserGroups(ctx context.Context, userID string) ([]model.Group, error)
+}
+
+type groupManager struct {
+	groupDAO mongo.CrudDAO[model.Group]
+	userDAO  mongo.CrudDAO[model.User]
+	roleDAO  mongo.CrudDAO[model.Role]
+}
+
+func (m *groupManager) Groups(ctx context.Context) ([]model.Group, error) {
+	return m.groupDAO.FindMany(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "displayName", Value: 1}}))
+}
+
+func (m *groupManager) Get(ctx context.Context, groupID string) (*model.Group, error) {
+	group, err := m.groupDAO.FindOne(ctx, bson.M{"_id": groupID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if group == nil {
+		return nil, groupNotFoundError()
+	}
+
+	return group, nil
+}
+
+func (m *groupManager) Create(ctx context.Context, group model.Group, permissions []string) (*model.Group, error) {
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	group.ID = id
+
+	if err = m.validate(ctx, &group, permissions); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	group = model.Group{
+		ID:           group.ID,
+		DisplayName:  group.DisplayName,
+		Members:      group.Members,
+		MemberGroups: group.MemberGroups,
+		Roles:        group.Roles,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if _, err = m.groupDAO.Create(ctx, &group); err != nil {
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+func (m *groupManager) Update(ctx context.Context, groupID string, group model.Group, permissions []string) (*model.Group, error) {
+	group.ID = groupID
+
+	if err := m.validate(ctx, &group, permissions); err != nil {
+		return nil, err
+	}
+
+	// The groups containing the group, directly or not, can't become its members
+	ancestors, err := groupAncestors(ctx, m.groupDAO, bson.M{"_id": groupID})
+	if err != nil {
+		return nil, err
+	}
+
+	// The members of the group are granted the roles of the groups containing it too
+	ancestorRoles := make([]string, 0)
+
+	for _, ancestor := range ancestors {
+		if slices.Contains(group.MemberGroups, ancestor.ID) {
+			return nil, model.NewAPIResponseError(http.StatusBadRequest, "the "+ancestor.DisplayName+" group already contains the group")
+		}
+
+		if ancestor.ID != groupID {
+			ancestorRoles = append(ancestorRoles, ancestor.Roles...)
+		}
+	}
+
+	if err = checkGrantedPermissions(ctx, m.roleDAO, ancestorRoles, permissions); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"displayName":  group.DisplayName,
+		"members":      group.Members,
+		"memberGroups": group.MemberGroups,
+		"roles":        group.Roles,
+		"updatedAt":    time.Now(),
+	}}
+
+	res, err := m.groupDAO.Update(ctx, bson.M{"_id": groupID}, update, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.NotFound {
+		return nil, groupNotFoundError()
+	}
+
+	return m.Get(ctx, groupID)
+}
+
+func (m *groupManager) Delete(ctx context.Context, groupID string) error {
+	deleted, err := m.groupDAO.Delete(ctx, bson.M{"_id": groupID})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return groupNotFoundError()
+	}
+
+	return removeMemberGroup(ctx, m.groupDAO, groupID)
+}
+
+func (m *groupManager) UserGroups(ctx context.Context, userID string) ([]model.Group, error) {
+	return groupAncestors(ctx, m.groupDAO, bson.M{"members": userID})
+}
+
+// validate checks the display name of a group, that its members, member groups and roles exist, and that
+// its roles don't grant more than the permissions of the caller.
+func (m *groupManager) validate(ctx context.Context, group *model.Group, permissions []string) error {
+	group.DisplayName = strings.TrimSpace(group.DisplayName)
+	if group.DisplayName == "" {
+		return model.NewAPIResponseError(http.StatusBadRequest, "the display name of the group is required")
+	}
+
+	slices.Sort(group.Members)
+	group.Members = slices.Compact(group.Members)
+
+	if group.Members == nil {
+		group.Members = make([]string, 0)
+	}
+
+	if len(group.Members) > 0 && m.userDAO.Count(ctx, bson.M{"_id": bson.M{"$in": group.Members}}) != int64(len(group.Members)) {
+		return model.NewAPIResponseError(http.StatusBadRequest, "the members must be users of the organization")
+	}
+
+	slices.Sort(group.MemberGroups)
+	group.MemberGroups = slices.Compact(group.MemberGroups)
+
+	if slices.Contains(group.MemberGroups, group.ID) {
+		return model.NewAPIResponseError(http.StatusBadRequest, "the group can't be a member of itself")
+	}
+
+	// The member groups are checked to be in the organization, which keeps the graph lookups in it
+	if len(group.MemberGroups) > 0 && m.groupDAO.Count(ctx, bson.M{"_id": bson.M{"$in": group.MemberGroups}}) != int64(len(group.MemberGroups)) {
+		return model.NewAPIResponseError(http.StatusBadRequest, "the member groups must be groups of the organization")
+	}
+
+	roles, err := checkGrantableRoles(ctx, m.roleDAO, group.Roles, permissions)
+	group.Roles = roles
+
+	return err
+}
+
+// groupAncestors returns the groups matching a filter, along with the groups containing them directly or
+// through other groups, sorted by display name.
+func groupAncestors(ctx context.Context, groupDAO mongo.CrudDAO[model.Group], filter bson.M) ([]model.Group, error) {
+	return groupDAO.Aggregate(ctx, bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$graphLookup": bson.M{
+			"from":             model.Group{}.CollectionName(),
+			"startWith":        "$_id",
+			"connectFromField": "_id",
+			"connectToField":   "memberGroups",
+			"as":               "ancestors",
+		}},
+		bson.M{"$project": bson.M{"groups": bson.M{"$concatArrays": bson.A{bson.A{"$$ROOT"}, "$ancestors"}}}},
+		bson.M{"$unwind": "$groups"},
+		bson.M{"$replaceRoot": bson.M{"newRoot": "$groups"}},
+		bson.M{"$project": bson.M{"ancestors": 0}},
+		// The groups reached through several paths are only returned once
+		bson.M{"$group": bson.M{"_id": "$_id", "group": bson.M{"$first": "$$ROOT"}}},
+		bson.M{"$replaceRoot": bson.M{"newRoot": "$group"}},
+		bson.M{"$sort": bson.M{"displayName": 1}},
+	})
+}
+
+// removeMemberGroup removes a deleted group from the groups it was a member of.
+func removeMemberGroup(ctx context.Context, groupDAO mongo.CrudDAO[model.Group], groupID string) error {
+	parents, err := groupDAO.FindMany(ctx, bson.M{"memberGroups": groupID}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, parent := range parents {
+		update := bson.M{"$pull": bson.M{"memberGroups": groupID}, "$set": bson.M{"updatedAt": time.Now()}}
+		if _, err = groupDAO.Update(ctx, bson.M{"_id": parent.ID}, update, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func groupNotFoundError() error {
+	return model.NewAPIResponseError(http.StatusNotFound, "the group does not exist")
+}
+
+func NewGroupManager(
+	groupDAO mongo.CrudDAO[model.Group],
+	userDAO mongo.CrudDAO[model.User],
+	roleDAO mongo.CrudDAO[model.Role],
+) GroupManager {
+	return &groupManager{
+		groupDAO: groupDAO,
+		userDAO:  userDAO,
+		roleDAO:  roleDAO,
+	}
+}
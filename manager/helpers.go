@@ -0,0 +1,33 @@
+package manager
+
+import (
+	"github.com/go-jose/go-jose/v4"
+)
+
+// asymmetricSignatureAlgorithms lists the asymmetric algorithms accepted for the JWTs signed by clients.
+var asymmetricSignatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.EdDSA,
+}
+
+// hmacSignatureAlgorithms lists the symmetric algorithms accepted for the JWTs signed by clients.
+var hmacSignatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.HS256, jose.HS384, jose.HS512,
+}
+
+// ClientAssertionSignatureAlgorithms returns the names of the algorithms accepted for client assertions.
+func ClientAssertionSignatureAlgorithms() []string {
+	return append(signatureAlgorithmNames(asymmetricSignatureAlgorithms), signatureAlgorithmNames(hmacSignatureAlgorithms)...)
+}
+
+func signatureAlgorithmNames(algs []jose.SignatureAlgorithm) []string {
+	names := make([]string, len(algs))
+
+	for i, alg := range algs {
+		names[i] = string(alg)
+	}
+
+	return names
+}
@@ -0,0 +1,193 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InvitationManager interface {
+	// Invitations returns the pending invitations of the organization of the context.
+	Invitations(ctx context.Context) ([]model.Invitation, error)
+
+	// Create invites an email to join the organization of the context with the given roles. The returned
+	// link, to send to the invitee, is only shown once. invitedBy is the subject who sent the invitation: the
+	// roles can't grant more than its permissions.
+	Create(ctx context.Context, email string, roles []string, invitedBy string, permissions []string) (*model.InvitationCreation, error)
+
+	// Revoke deletes a pending invitation.
+	Revoke(ctx context.Context, invitationID string) error
+
+	// Open returns the pending invitation of the token of an invitation link, or an invalid request error
+	// if it is unknown or expired.
+	Open(ctx context.Context, token string) (*model.Invitation, error)
+
+	// Accept accepts an invitation once the invitee logged in with an upstream profile, whose verified email
+	// must be the invited one. The account of the invitee is made a member of the organization, with the
+	// roles of the invitation added to its current ones, and is created if it does not exist. The invitation
+	// can't be used again.
+	Accept(ctx context.Context, invitationID string, profile model.ExternalProfile) (*model.User, error)
+}
+
+type invitationManager struct {
+	invitationDAO       mongo.CrudDAO[model.Invitation]
+	roleDAO             mongo.CrudDAO[model.Role]
+	userManager         UserManager
+	organizationManager OrganizationManager
+}
+
+func (m *invitationManager) Invitations(ctx context.Context) ([]model.Invitation, error) {
+	filter := bson.M{"expiresAt": bson.M{"$gt": time.Now()}}
+
+	return m.invitationDAO.FindMany(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+}
+
+func (m *invitationManager) Create(ctx context.Context, email string, roles []string, invitedBy string, permissions []string) (*model.InvitationCreation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if emailDomain(email) == "" {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the email is invalid")
+	}
+
+	roles, err := checkGrantableRoles(ctx, m.roleDAO, roles, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	invitation := model.Invitation{
+		ID:        id,
+		Email:     email,
+		Roles:     roles,
+		InvitedBy: invitedBy,
+		TokenHash: security.HashToken(value),
+		CreatedAt: now,
+		ExpiresAt: now.Add(config.InvitationLifetime()),
+	}
+
+	if _, err = m.invitationDAO.Create(ctx, &invitation); err != nil {
+		return nil, err
+	}
+
+	return &model.InvitationCreation{
+		Invitation: invitation,
+		Token:      value,
+		URL:        tenant.URL(ctx, config.InvitationURL(value)),
+	}, nil
+}
+
+func (m *invitationManager) Revoke(ctx context.Context, invitationID string) error {
+	deleted, err := m.invitationDAO.Delete(ctx, bson.M{"_id": invitationID})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return model.NewAPIResponseError(http.StatusNotFound, "the invitation does not exist")
+	}
+
+	return nil
+}
+
+func (m *invitationManager) Open(ctx context.Context, token string) (*model.Invitation, error) {
+	invitation, err := m.invitationDAO.FindOne(ctx, bson.M{"tokenHash": security.HashToken(token)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation == nil || invitation.IsExpired(time.Now()) {
+		return nil, invalidInvitationError()
+	}
+
+	return invitation, nil
+}
+
+func (m *invitationManager) Accept(ctx context.Context, invitationID string, profile model.ExternalProfile) (*model.User, error) {
+	invitation, err := m.invitationDAO.FindOne(ctx, bson.M{"_id": invitationID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation == nil || invitation.IsExpired(time.Now()) {
+		return nil, invalidInvitationError()
+	}
+
+	// The invitation is not consumed yet: the invitee can still log in with the invited account
+	if !profile.EmailVerified || !strings.EqualFold(profile.Email, invitation.Email) {
+		return nil, model.NewOAuthError(http.StatusForbidden, model.OAuthErrorAccessDenied, "the invitation was sent to another email address")
+	}
+
+	deleted, err := m.invitationDAO.Delete(ctx, bson.M{"_id": invitationID})
+	if err != nil {
+		return nil, err
+	}
+
+	if !deleted {
+		return nil, invalidInvitationError()
+	}
+
+	user, err := m.userManager.Find(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	// The account is created in the organization on its first login
+	if user == nil {
+		if user, err = m.userManager.Login(ctx, profile); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Disabled {
+		return nil, disabledUserError()
+	}
+
+	roles := invitation.Roles
+	if membership := user.Membership(invitation.OrganizationID); membership != nil {
+		roles = append(roles, membership.Roles...)
+	}
+
+	// The roles of the invitation were checked against the permissions of the member who sent it
+	if _, err = m.organizationManager.SetMember(ctx, invitation.OrganizationID, user.ID, roles, []string{model.PermissionAll}); err != nil {
+		return nil, err
+	}
+
+	return m.userManager.Login(ctx, profile)
+}
+
+func invalidInvitationError() error {
+	return model.NewOAuthInvalidRequestError("the invitation is invalid or expired")
+}
+
+func NewInvitationManager(
+	invitationDAO mongo.CrudDAO[model.Invitation],
+	roleDAO mongo.CrudDAO[model.Role],
+	userManager UserManager,
+	organizationManager OrganizationManager,
+) InvitationManager {
+	return &invitationManager{
+		invitationDAO:       invitationDAO,
+		roleDAO:             roleDAO,
+		userManager:         userManager,
+		organizationManager: organizationManager,
+	}
+}
@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/m3talux/goauth/config"
+	"github.com/rs/zerolog/log"
+)
+
+// Minimum delay between two fetches of the same key set, when looking for an unknown key.
+const jwksMinRefreshInterval = 10 * time.Second
+
+type JWKSManager interface {
+	// Get returns the JSON Web Key Set published at the given URI. Key sets are cached for the
+	// configured lifetime.
+	Get(ctx context.Context, uri string) (*jose.JSONWebKeySet, error)
+
+	// Key returns the key with the given identifier from the key set published at the given URI.
+	// When the key is not in the cached key set, the key set is fetched again since keys may have
+	// been rotated. It returns nil if the key does not exist.
+	Key(ctx context.Context, uri, kid string) (*jose.JSONWebKey, error)
+}
+
+type jwksManager struct {
+	httpClient *http.Client
+	mutex      sync.Mutex
+	cache      map[string]cachedKeySet
+}
+
+type cachedKeySet struct {
+	KeySet    *jose.JSONWebKeySet
+	FetchedAt time.Time
+}
+
+func (m *jwksManager) Get(ctx context.Context, uri string) (*jose.JSONWebKeySet, error) {
+	m.mutex.Lock()
+	cached, found := m.cache[uri]
+	m.mutex.Unlock()
+
+	if found && time.Since(cached.FetchedAt) < config.JWKSCacheLifetime() {
+		return cached.KeySet, nil
+	}
+
+	return m.fetch(ctx, uri)
+}
+
+func (m *jwksManager) Key(ctx context.Context, uri, kid string) (*jose.JSONWebKey, error) {
+	keySet, err := m.Get(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if keys := keySet.Key(kid); len(keys) > 0 {
+		return &keys[0], nil
+	}
+
+	m.mutex.Lock()
+	fetchedAt := m.cache[uri].FetchedAt
+	m.mutex.Unlock()
+
+	if time.Since(fetchedAt) < jwksMinRefreshInterval {
+		//nolint:nilnil // An unknown key is not an error
+		return nil, nil
+	}
+
+	keySet, err = m.fetch(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if keys := keySet.Key(kid); len(keys) > 0 {
+		return &keys[0], nil
+	}
+
+	//nolint:nilnil // An unknown key is not an error
+	return nil, nil
+}
+
+func (m *jwksManager) fetch(ctx context.Context, uri string) (*jose.JSONWebKeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	res, err := m.httpClient.Do(req)
+	if err != nil {
+		log.Err(err).Str("uri", uri).Msg("Could not fetch key set")
+
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the key set request returned the status %d", res.StatusCode)
+	}
+
+	keySet := new(jose.JSONWebKeySet)
+	if err = json.NewDecoder(res.Body).Decode(keySet); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	m.cache[uri] = cachedKeySet{KeySet: keySet, FetchedAt: time.Now()}
+	m.mutex.Unlock()
+
+	log.Debug().Str("uri", uri).Int("keys", len(keySet.Keys)).Msg("Successfully fetched key set")
+
+	return keySet, nil
+}
+
+func NewJWKSManager() JWKSManager {
+	return &jwksManager{
+		httpClient: &http.Client{Timeout: config.ConnectionTimeout()},
+		cache:      make(map[string]cachedKeySet),
+	}
+}
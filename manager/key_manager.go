@@ -0,0 +1,150 @@
+package manager
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/m3talux/goauth/config"
+	"github.com/rs/zerolog/log"
+)
+
+type KeyManager interface {
+	// Sign serializes the claims as a JWT of the given type (typ header), signed with the goauth key.
+	Sign(claims interface{}, typ string) (string, error)
+
+	// Verify checks the signature and the type (typ header) of a JWT signed by goauth, and decodes its
+	// claims. The validity of the claims (expiration, audience...) is left to the caller.
+	Verify(token string, typ string, claims ...interface{}) error
+
+	// KeySet returns the public keys that clients use to verify the JWTs signed by goauth.
+	KeySet() jose.JSONWebKeySet
+}
+
+type keyManager struct {
+	privateKey jose.JSONWebKey
+	publicKey  jose.JSONWebKey
+}
+
+func (m *keyManager) Sign(claims interface{}, typ string) (string, error) {
+	key := jose.SigningKey{Algorithm: jose.SignatureAlgorithm(m.privateKey.Algorithm), Key: m.privateKey}
+
+	signer, err := jose.NewSigner(key, (&jose.SignerOptions{}).WithType(jose.ContentType(typ)))
+	if err != nil {
+		return "", err
+	}
+
+	return jwt.Signed(signer).Claims(claims).Serialize()
+}
+
+func (m *keyManager) Verify(token string, typ string, claims ...interface{}) error {
+	parsed, err := jwt.ParseSigned(token, []jose.SignatureAlgorithm{jose.SignatureAlgorithm(m.publicKey.Algorithm)})
+	if err != nil {
+		return err
+	}
+
+	// Prevents a JWT from being used for another purpose than the one it was issued for
+	if header, _ := parsed.Headers[0].ExtraHeaders[jose.HeaderType].(string); !strings.EqualFold(header, typ) {
+		return errors.New("unexpected JWT type")
+	}
+
+	return parsed.Claims(m.publicKey, claims...)
+}
+
+func (m *keyManager) KeySet() jose.JSONWebKeySet {
+	return jose.JSONWebKeySet{Keys: []jose.JSONWebKey{m.publicKey}}
+}
+
+// loadSigningKey reads the configured signing key, or generates an ephemeral one.
+func loadSigningKey() (crypto.Signer, error) {
+	file := config.OAuthSigningKeyFile()
+	if file == "" {
+		log.Warn().Msg("No signing key is configured, an ephemeral key is generated: issued JWTs won't survive a restart")
+
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("the signing key file contains no PEM block")
+	}
+
+	if key, pkcs1Err := x509.ParsePKCS1PrivateKey(block.Bytes); pkcs1Err == nil {
+		return key, nil
+	}
+
+	if key, ecErr := x509.ParseECPrivateKey(block.Bytes); ecErr == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("the signing key type is not supported")
+	}
+
+	return signer, nil
+}
+
+func signingAlgorithm(key crypto.Signer) (jose.SignatureAlgorithm, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return jose.RS256, nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jose.ES256, nil
+		case elliptic.P384():
+			return jose.ES384, nil
+		case elliptic.P521():
+			return jose.ES512, nil
+		}
+	}
+
+	return "", errors.New("the signing key type is not supported")
+}
+
+func NewKeyManager() (KeyManager, error) {
+	signer, err := loadSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	alg, err := signingAlgorithm(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey := jose.JSONWebKey{Key: signer, Algorithm: string(alg), Use: "sig"}
+
+	thumbprint, err := privateKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	return &keyManager{
+		privateKey: privateKey,
+		publicKey:  privateKey.Public(),
+	}, nil
+}
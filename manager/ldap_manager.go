@@ -0,0 +1,384 @@
+package manager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// Attribute listing the groups of a user entry, on Active Directory and directories with the memberOf overlay.
+	ldapMemberOfAttribute = "memberOf"
+	// Special attribute list requesting no attribute, when only the DN of the entries is needed (RFC 4511).
+	ldapNoAttributes = "1.1"
+	// Maximum number of entries returned by the user search: a second entry means that the username is ambiguous.
+	ldapUserSearchSizeLimit = 2
+)
+
+type LDAPManager interface {
+	// Authenticate verifies the credentials of an end-user against the directory of an LDAP connection,
+	// and returns the profile read from the user entry, with the roles mapped from the groups of the user.
+	Authenticate(ctx context.Context, connectionID, username, password string) (model.ExternalProfile, error)
+}
+
+type ldapManager struct {
+	connectionDAO mongo.CrudDAO[model.Connection]
+	mutex         sync.Mutex
+	pools         map[string]*ldapPool
+}
+
+// ldapPool keeps idle connections to a directory, bound as its service account. The pool is replaced
+// when the connection settings are updated: the connections released to a closed pool are closed.
+type ldapPool struct {
+	settings    model.LDAPSettings
+	updatedAt   time.Time
+	connections chan *ldap.Conn
+	mutex       sync.Mutex
+	closed      bool
+}
+
+func (m *ldapManager) Authenticate(ctx context.Context, connectionID, username, password string) (model.ExternalProfile, error) {
+	invalidCredentialsError := model.NewOAuthError(http.StatusForbidden, model.OAuthErrorAccessDenied, "the username or password is invalid")
+
+	// Binding with an empty password is an unauthenticated bind, which always succeeds
+	if username == "" || password == "" {
+		return model.ExternalProfile{}, invalidCredentialsError
+	}
+
+	connection, err := m.connectionDAO.FindOne(ctx, bson.M{"_id": connectionID, "type": model.ConnectionTypeLDAP}, nil)
+	if err != nil {
+		return model.ExternalProfile{}, err
+	}
+
+	if connection == nil || !connection.Enabled || connection.LDAP == nil {
+		return model.ExternalProfile{}, model.NewOAuthInvalidRequestError("the connection is unknown")
+	}
+
+	pool := m.pool(connection)
+
+	conn, err := pool.acquire()
+	if err != nil {
+		log.Err(err).Str("connection", connection.ID).Msg("Could not connect to the LDAP directory")
+
+		return model.ExternalProfile{}, err
+	}
+
+	entry, groups, err := m.searchUser(connection, conn, username)
+	if err != nil {
+		pool.release(conn)
+
+		return model.ExternalProfile{}, err
+	}
+
+	if entry == nil {
+		pool.release(conn)
+
+		return model.ExternalProfile{}, invalidCredentialsError
+	}
+
+	bindErr := conn.Bind(entry.DN, password)
+
+	// The connection is bound as the service account again before being reused
+	pool.releaseAfterBind(conn)
+
+	if ldap.IsErrorWithCode(bindErr, ldap.LDAPResultInvalidCredentials) {
+		return model.ExternalProfile{}, invalidCredentialsError
+	}
+
+	if bindErr != nil {
+		return model.ExternalProfile{}, bindErr
+	}
+
+	profile := model.ExternalProfile{
+		ConnectionID: connection.ID,
+		Subject:      ldapAttribute(entry, connection.Claim(model.UserAttributeSubject)),
+		Email:        ldapAttribute(entry, connection.Claim(model.UserAttributeEmail)),
+		Name:         ldapAttribute(entry, connection.Claim(model.UserAttributeName)),
+		GivenName:    ldapAttribute(entry, connection.Claim(model.UserAttributeGivenName)),
+		FamilyName:   ldapAttribute(entry, connection.Claim(model.UserAttributeFamilyName)),
+		Picture:      ldapAttribute(entry, connection.Claim(model.UserAttributePicture)),
+		Roles:        groupRoles(connection.LDAP.GroupRoles, groups),
+	}
+
+	if emailVerifiedAttribute := connection.Claim(model.UserAttributeEmailVerified); emailVerifiedAttribute != "" {
+		profile.EmailVerified = strings.EqualFold(ldapAttribute(entry, emailVerifiedAttribute), "true")
+	}
+
+	if profile.Subject == "" {
+		return model.ExternalProfile{}, fmt.Errorf("the LDAP entry %s has no %s attribute", entry.DN, connection.Claim(model.UserAttributeSubject))
+	}
+
+	return profile, nil
+}
+
+// searchUser searches the entry of the end-user and its groups. It returns a nil entry if the username
+// matches no entry, or several ones.
+func (m *ldapManager) searchUser(connection *model.Connection, conn *ldap.Conn, username string) (*ldap.Entry, []string, error) {
+	settings := connection.LDAP
+
+	attributes := []string{ldapMemberOfAttribute}
+
+	for _, attribute := range []string{
+		model.UserAttributeSubject,
+		model.UserAttributeEmail,
+		model.UserAttributeEmailVerified,
+		model.UserAttributeName,
+		model.UserAttributeGivenName,
+		model.UserAttributeFamilyName,
+		model.UserAttributePicture,
+	} {
+		if name := connection.Claim(attribute); name != "" {
+			attributes = append(attributes, name)
+		}
+	}
+
+	filter := strings.ReplaceAll(settings.UserSearchFilter, "{username}", ldap.EscapeFilter(username))
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		settings.UserSearchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, ldapUserSearchSizeLimit, int(config.LDAPTimeout().Seconds()), false,
+		filter, attributes, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, nil, err
+	}
+
+	if res == nil || len(res.Entries) != 1 {
+		return nil, nil, nil
+	}
+
+	entry := res.Entries[0]
+
+	if settings.GroupSearchBase == "" {
+		return entry, entry.GetEqualFoldAttributeValues(ldapMemberOfAttribute), nil
+	}
+
+	filter = strings.ReplaceAll(settings.GroupSearchFilter, "{dn}", ldap.EscapeFilter(entry.DN))
+	filter = strings.ReplaceAll(filter, "{username}", ldap.EscapeFilter(username))
+
+	res, err = conn.Search(ldap.NewSearchRequest(
+		settings.GroupSearchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(config.LDAPTimeout().Seconds()), false,
+		filter, []string{ldapNoAttributes}, nil,
+	))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups := make([]string, 0, len(res.Entries))
+	for _, group := range res.Entries {
+		groups = append(groups, group.DN)
+	}
+
+	return entry, groups, nil
+}
+
+// pool returns the pool of connections to the directory of a connection.
+func (m *ldapManager) pool(connection *model.Connection) *ldapPool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	pool, found := m.pools[connection.ID]
+	if found && pool.updatedAt.Equal(connection.UpdatedAt) {
+		return pool
+	}
+
+	if found {
+		pool.close()
+	}
+
+	pool = &ldapPool{
+		settings:    *connection.LDAP,
+		updatedAt:   connection.UpdatedAt,
+		connections: make(chan *ldap.Conn, config.LDAPPoolSize()),
+	}
+
+	m.pools[connection.ID] = pool
+
+	return pool
+}
+
+// acquire returns an idle connection of the pool, or opens a new one.
+func (p *ldapPool) acquire() (*ldap.Conn, error) {
+	for {
+		select {
+		case conn := <-p.connections:
+			if !conn.IsClosing() {
+				return conn, nil
+			}
+		default:
+			return p.dial()
+		}
+	}
+}
+
+// release returns a connection to the pool, or closes it when the pool is full or closed.
+func (p *ldapPool) release(conn *ldap.Conn) {
+	if conn.IsClosing() {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		conn.Close()
+
+		return
+	}
+
+	select {
+	case p.connections <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// releaseAfterBind binds a connection as the service account again, then returns it to the pool.
+func (p *ldapPool) releaseAfterBind(conn *ldap.Conn) {
+	if err := p.bind(conn); err != nil {
+		conn.Close()
+
+		return
+	}
+
+	p.release(conn)
+}
+
+// close closes the idle connections of a pool that is no longer used. The connections in use are closed
+// when they are released.
+func (p *ldapPool) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closed = true
+
+	for {
+		select {
+		case conn := <-p.connections:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// dial opens a connection to the directory, over TLS for ldaps:// URLs or with StartTLS, and binds it
+// as the service account.
+func (p *ldapPool) dial() (*ldap.Conn, error) {
+	serverURL, err := url.Parse(p.settings.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: serverURL.Hostname(),
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if p.settings.CACertificate != "" {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM([]byte(p.settings.CACertificate)) {
+			return nil, errors.New("the LDAP CA certificate is invalid")
+		}
+	}
+
+	conn, err := ldap.DialURL(p.settings.URL, ldap.DialWithDialer(&net.Dialer{Timeout: config.LDAPTimeout()}), ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetTimeout(config.LDAPTimeout())
+
+	if p.settings.StartTLS && serverURL.Scheme != "ldaps" {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+
+			return nil, err
+		}
+	}
+
+	if err = p.bind(conn); err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// bind binds a connection as the service account, or anonymously when there is none.
+func (p *ldapPool) bind(conn *ldap.Conn) error {
+	if p.settings.BindDN == "" {
+		return conn.UnauthenticatedBind("")
+	}
+
+	password, err := security.Decrypt(p.settings.BindPasswordEncrypted)
+	if err != nil {
+		return err
+	}
+
+	return conn.Bind(p.settings.BindDN, password)
+}
+
+// ldapAttribute returns the first value of an attribute of an entry. Binary values, like the objectGUID
+// of Active Directory, are base64url encoded.
+func ldapAttribute(entry *ldap.Entry, name string) string {
+	if name == "" {
+		return ""
+	}
+
+	if strings.EqualFold(name, "dn") {
+		return entry.DN
+	}
+
+	values := entry.GetEqualFoldRawAttributeValues(name)
+	if len(values) == 0 {
+		return ""
+	}
+
+	if !utf8.Valid(values[0]) {
+		return base64.RawURLEncoding.EncodeToString(values[0])
+	}
+
+	return string(values[0])
+}
+
+// groupRoles returns the roles granted to the members of the given groups.
+func groupRoles(groupRoles []model.LDAPGroupRole, groups []string) []string {
+	roles := make([]string, 0)
+
+	for _, groupRole := range groupRoles {
+		for _, group := range groups {
+			if strings.EqualFold(groupRole.GroupDN, group) && !slices.Contains(roles, groupRole.Role) {
+				roles = append(roles, groupRole.Role)
+			}
+		}
+	}
+
+	return roles
+}
+
+func NewLDAPManager(connectionDAO mongo.CrudDAO[model.Connection]) LDAPManager {
+	return &ldapManager{
+		connectionDAO: connectionDAO,
+		pools:         make(map[string]*ldapPool),
+	}
+}
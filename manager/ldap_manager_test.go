@@ -0,0 +1,179 @@
+package manager_test
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/m3talux/goauth/internal/goauthtest"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/security"
+)
+
+// Entries of the in-process directory.
+const (
+	ldapServiceDN       = "cn=goauth,ou=services,dc=example,dc=com"
+	ldapServicePassword = "service-password"
+	ldapUserDN          = "uid=alice,ou=users,dc=example,dc=com"
+	ldapUserPassword    = "alice-password"
+	ldapAdminsDN        = "cn=admins,ou=groups,dc=example,dc=com"
+)
+
+func newLDAPDirectory(t *testing.T) *goauthtest.LDAPServer {
+	t.Helper()
+
+	return goauthtest.NewLDAPServer(t,
+		goauthtest.LDAPEntry{DN: ldapServiceDN, Password: ldapServicePassword},
+		goauthtest.LDAPEntry{DN: ldapUserDN, Password: ldapUserPassword, Attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {"alice"},
+			"entryUUID":   {"0b5c3a2e-6f1d-4f7e-9c51-2d6a8e3b1f40"},
+			"mail":        {"alice@example.com"},
+			"cn":          {"Alice Liddell"},
+			"memberOf":    {ldapAdminsDN},
+		}},
+		goauthtest.LDAPEntry{DN: "uid=bob,ou=users,dc=example,dc=com", Password: "bob-password", Attributes: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {"bob"},
+			"entryUUID":   {"7e2d9c14-3a85-4b6f-a0d2-5c8e1f4b9a73"},
+		}},
+		goauthtest.LDAPEntry{DN: ldapAdminsDN, Attributes: map[string][]string{
+			"objectClass": {"groupOfNames"},
+			"member":      {ldapUserDN},
+		}},
+	)
+}
+
+// newLDAPManager returns a manager knowing an LDAP connection to the directory, bound as its service
+// account. The groups are searched under groupSearchBase, or read from memberOf when it is empty.
+func newLDAPManager(t *testing.T, directory *goauthtest.LDAPServer, groupSearchBase string) manager.LDAPManager {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SECRETS_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(key))
+	goauthtest.Configure(t, "https://auth.example.com")
+
+	bindPassword, err := security.Encrypt(ldapServicePassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connectionDAO := goauthtest.NewMemoryDAO[model.Connection]()
+	if _, err = connectionDAO.Create(goauthtest.Context(), &model.Connection{
+		ID:   "ldap",
+		Name: "Directory",
+		Type: model.ConnectionTypeLDAP,
+		LDAP: &model.LDAPSettings{
+			URL:                   directory.URL,
+			BindDN:                ldapServiceDN,
+			BindPasswordEncrypted: bindPassword,
+			UserSearchBase:        "ou=users,dc=example,dc=com",
+			UserSearchFilter:      "(&(objectClass=person)(uid={username}))",
+			GroupSearchBase:       groupSearchBase,
+			GroupSearchFilter:     "(&(objectClass=groupOfNames)(member={dn}))",
+			GroupRoles:            []model.LDAPGroupRole{{GroupDN: ldapAdminsDN, Role: model.RoleIDAdmin}},
+		},
+		Enabled: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return manager.NewLDAPManager(connectionDAO)
+}
+
+// expectInvalidCredentials fails the test unless the error is the rejection of the credentials.
+func expectInvalidCredentials(t *testing.T, err error) {
+	t.Helper()
+
+	var oauthErr model.OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected the credentials to be rejected, got %v", err)
+	}
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	for name, groupSearchBase := range map[string]string{"memberOf": "", "group search": "ou=groups,dc=example,dc=com"} {
+		t.Run(name, func(t *testing.T) {
+			directory := newLDAPDirectory(t)
+			m := newLDAPManager(t, directory, groupSearchBase)
+
+			profile, err := m.Authenticate(goauthtest.Context(), "ldap", "alice", ldapUserPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if profile.ConnectionID != "ldap" || profile.Subject != "0b5c3a2e-6f1d-4f7e-9c51-2d6a8e3b1f40" || profile.Email != "alice@example.com" ||
+				profile.Name != "Alice Liddell" || !slices.Equal(profile.Roles, []string{model.RoleIDAdmin}) {
+				t.Fatalf("unexpected profile %+v", profile)
+			}
+
+			// The password is verified by binding as the entry, after the search of the service account
+			if binds := directory.Binds(); !slices.Equal(binds, []string{ldapServiceDN, ldapUserDN, ldapServiceDN}) {
+				t.Fatalf("unexpected binds %v", binds)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticateWrongPassword(t *testing.T) {
+	directory := newLDAPDirectory(t)
+	m := newLDAPManager(t, directory, "")
+
+	_, err := m.Authenticate(goauthtest.Context(), "ldap", "alice", "bob-password")
+	expectInvalidCredentials(t, err)
+
+	// The connection is bound as the service account again, and reused
+	if _, err = m.Authenticate(goauthtest.Context(), "ldap", "alice", ldapUserPassword); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.Authenticate(goauthtest.Context(), "ldap", "carol", ldapUserPassword)
+	expectInvalidCredentials(t, err)
+}
+
+func TestLDAPAuthenticateEmptyPassword(t *testing.T) {
+	directory := newLDAPDirectory(t)
+	m := newLDAPManager(t, directory, "")
+
+	// The directory accepts the unauthenticated bind of the entry, which must not log the user in
+	_, err := m.Authenticate(goauthtest.Context(), "ldap", "alice", "")
+	expectInvalidCredentials(t, err)
+
+	if binds := directory.Binds(); slices.Contains(binds, ldapUserDN) {
+		t.Fatalf("unexpected binds %v", binds)
+	}
+}
+
+func TestLDAPAuthenticateFilterInjection(t *testing.T) {
+	tests := []struct {
+		username string
+		filter   string
+	}{
+		// Unescaped, the filter would match the entry of alice, whose password is given
+		{username: "alice)(uid=*", filter: `(&(objectClass=person)(uid=alice\29\28uid=\2a))`},
+		{username: "*", filter: `(&(objectClass=person)(uid=\2a))`},
+		{username: `alice\`, filter: `(&(objectClass=person)(uid=alice\5c))`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			directory := newLDAPDirectory(t)
+			m := newLDAPManager(t, directory, "")
+
+			_, err := m.Authenticate(goauthtest.Context(), "ldap", tt.username, ldapUserPassword)
+			expectInvalidCredentials(t, err)
+
+			if filters := directory.Filters(); !slices.Equal(filters, []string{tt.filter}) {
+				t.Fatalf("unexpected search filters %v", filters)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package manager
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/tenant"
+)
+
+type LogoutManager interface {
+	// EndSession processes an RP-initiated logout request (OpenID Connect RP-Initiated Logout 1.0).
+	// The session to end is identified by the sid claim of the id_token_hint, or else by the session
+	// cookie of the user-agent.
+	EndSession(ctx context.Context, params url.Values, cookieSessionID string) (model.LogoutResponse, error)
+}
+
+type logoutManager struct {
+	clientManager  ClientManager
+	sessionManager SessionManager
+	keyManager     KeyManager
+}
+
+func (m *logoutManager) EndSession(ctx context.Context, params url.Values, cookieSessionID string) (model.LogoutResponse, error) {
+	clientID := params.Get("client_id")
+	sessionID := cookieSessionID
+
+	if hint := params.Get("id_token_hint"); hint != "" {
+		var claims model.IDTokenClaims
+
+		// The hint may have expired, only the signature and the issuer matter
+		if err := m.keyManager.Verify(hint, model.JWTTypeIDToken, &claims); err != nil || claims.Issuer != tenant.Issuer(ctx) || len(claims.Audience) == 0 {
+			return model.LogoutResponse{}, model.NewOAuthInvalidRequestError("the id_token_hint is invalid")
+		}
+
+		if clientID != "" && !claims.Audience.Contains(clientID) {
+			return model.LogoutResponse{}, model.NewOAuthInvalidRequestError("the client_id does not match the id_token_hint")
+		}
+
+		clientID = claims.Audience[0]
+
+		if claims.SessionID != "" {
+			sessionID = claims.SessionID
+		}
+	}
+
+	redirectURI, err := m.postLogoutRedirectURI(ctx, clientID, params)
+	if err != nil {
+		return model.LogoutResponse{}, err
+	}
+
+	frontchannelLogoutURIs := make([]string, 0)
+
+	if sessionID != "" {
+		frontchannelLogoutURIs, err = m.sessionManager.End(ctx, sessionID)
+		if err != nil {
+			return model.LogoutResponse{}, err
+		}
+	}
+
+	return model.LogoutResponse{
+		FrontchannelLogoutURIs: frontchannelLogoutURIs,
+		RedirectURI:            redirectURI,
+	}, nil
+}
+
+// postLogoutRedirectURI validates the requested post logout redirection against the registration of
+// the client, and adds the state to it.
+func (m *logoutManager) postLogoutRedirectURI(ctx context.Context, clientID string, params url.Values) (string, error) {
+	redirectURI := params.Get("post_logout_redirect_uri")
+	if redirectURI == "" {
+		return "", nil
+	}
+
+	if clientID == "" {
+		return "", model.NewOAuthInvalidRequestError("the client must be identified to redirect after logout")
+	}
+
+	client, err := m.clientManager.Get(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+
+	if client == nil || !client.HasPostLogoutRedirectURI(redirectURI) {
+		return "", model.NewOAuthInvalidRequestError("the post_logout_redirect_uri is not registered")
+	}
+
+	state := params.Get("state")
+	if state == "" {
+		return redirectURI, nil
+	}
+
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set("state", state)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func NewLogoutManager(clientManager ClientManager, sessionManager SessionManager, keyManager KeyManager) LogoutManager {
+	return &logoutManager{
+		clientManager:  clientManager,
+		sessionManager: sessionManager,
+		keyManager:     keyManager,
+	}
+}
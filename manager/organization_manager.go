@@ -0,0 +1,454 @@
+package manager
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// organizationIDPattern restricts organization identifiers to the characters that can be used in a path segment.
+var organizationIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+type OrganizationManager interface {
+	// Organizations returns every organization, sorted by identifier.
+	Organizations(ctx context.Context) ([]model.Organization, error)
+
+	// Get returns an organization, or a 404 API error if it does not exist.
+	Get(ctx context.Context, organizationID string) (*model.Organization, error)
+
+	// Resolve returns the organization targeted by a request: the organization of its path if any, then
+	// the organization owning the requested host, and finally the default organization. The returned
+	// boolean is false when the request targets the default organization implicitly. The organization is
+	// nil if it does not exist.
+	Resolve(ctx context.Context, organizationID, host string) (*model.Organization, bool, error)
+
+	// Create creates an organization. Its identifier can't be changed afterwards.
+	Create(ctx context.Context, organization model.Organization) (*model.Organization, error)
+
+	// Update replaces the name, domains, branding and settings of an organization.
+	Update(ctx context.Context, organizationID string, organization model.Organization) (*model.Organization, error)
+
+	// Delete deletes an organization without members. The default organization can't be deleted.
+	Delete(ctx context.Context, organizationID string) error
+
+	// Members returns the members of an organization.
+	Members(ctx context.Context, organizationID string) ([]model.Member, error)
+
+	// SetMember makes a user a member of an organization with the given roles, or replaces the roles of
+	// an existing member. The roles can't grant more than the permissions of the caller.
+	SetMember(ctx context.Context, organizationID, userID string, roles, permissions []string) (*model.Member, error)
+
+	// UpdateMember replaces the roles of a member of the organization of the context, or returns a 404 API
+	// error if the user is not a member: users join an organization through invitations. The roles can't
+	// grant more than the permissions of the caller.
+	UpdateMember(ctx context.Context, userID string, roles, permissions []string) (*model.Member, error)
+
+	// RemoveMember removes a user from an organization: the sessions of the user in the organization are
+	// ended, and the user is removed from its groups.
+	RemoveMember(ctx context.Context, organizationID, userID string) error
+
+	// EnsureDefaultOrganization creates the default organization if it does not exist.
+	EnsureDefaultOrganization(ctx context.Context) error
+}
+
+type organizationManager struct {
+	organizationDAO mongo.CrudDAO[model.Organization]
+	userDAO         mongo.CrudDAO[model.User]
+	roleDAO         mongo.CrudDAO[model.Role]
+	groupDAO        mongo.CrudDAO[model.Group]
+	sessionManager  SessionManager
+}
+
+func (m *organizationManager) Organizations(ctx context.Context) ([]model.Organization, error) {
+	return m.organizationDAO.FindMany(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+}
+
+func (m *organizationManager) Get(ctx context.Context, organizationID string) (*model.Organization, error) {
+	organization, err := m.organizationDAO.FindOne(ctx, bson.M{"_id": organizationID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if organization == nil {
+		return nil, organizationNotFoundError()
+	}
+
+	return organization, nil
+}
+
+func (m *organizationManager) Resolve(ctx context.Context, organizationID, host string) (*model.Organization, bool, error) {
+	if organizationID != "" {
+		organization, err := m.organizationDAO.FindOne(ctx, bson.M{"_id": organizationID}, nil)
+
+		return organization, true, err
+	}
+
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	organization, err := m.organizationDAO.FindOne(ctx, bson.M{"domains": strings.ToLower(host)}, nil)
+	if err != nil || organization != nil {
+		return organization, true, err
+	}
+
+	organization, err = m.organizationDAO.FindOne(ctx, bson.M{"_id": config.DefaultOrganizationID()}, nil)
+
+	return organization, false, err
+}
+
+func (m *organizationManager) Create(ctx context.Context, organization model.Organization) (*model.Organization, error) {
+	if !organizationIDPattern.MatchString(organization.ID) {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the organization identifier is invalid")
+	}
+
+	if organization.Name == "" {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the organization name is required")
+	}
+
+	now := time.Now()
+
+	organization.Domains = normalizeDomains(organization.Domains)
+	organization.CreatedAt = now
+	organization.UpdatedAt = now
+
+	created, err := m.organizationDAO.Create(ctx, &organization)
+	if err != nil {
+		return nil, err
+	}
+
+	// The domains are unique as well
+	if !created {
+		return nil, model.NewAPIResponseError(http.StatusConflict, "the organization or one of its domains already exists")
+	}
+
+	return &organization, nil
+}
+
+func (m *organizationManager) Update(ctx context.Context, organizationID string, organization model.Organization) (*model.Organization, error) {
+	if organization.Name == "" {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the organization name is required")
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":      organization.Name,
+		"branding":  organization.Branding,
+		"settings":  organization.Settings,
+		"updatedAt": time.Now(),
+	}}
+
+	// An empty list of domains would be indexed, and conflict with the other organizations without domains
+	if domains := normalizeDomains(organization.Domains); len(domains) > 0 {
+		update["$set"].(bson.M)["domains"] = domains
+	} else {
+		update["$unset"] = bson.M{"domains": ""}
+	}
+
+	res, err := m.organizationDAO.Update(ctx, bson.M{"_id": organizationID}, update, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.NotFound {
+		return nil, organizationNotFoundError()
+	}
+
+	if res.UniqueError {
+		return nil, model.NewAPIResponseError(http.StatusConflict, "one of the domains belongs to another organization")
+	}
+
+	return m.Get(ctx, organizationID)
+}
+
+func (m *organizationManager) Delete(ctx context.Context, organizationID string) error {
+	if organizationID == config.DefaultOrganizationID() {
+		return model.NewAPIResponseError(http.StatusForbidden, "the default organization can't be deleted")
+	}
+
+	hasMembers, err := m.userDAO.Exists(tenant.WithAllOrganizations(ctx), bson.M{"memberships.organizationId": organizationID}, nil)
+	if err != nil {
+		return err
+	}
+
+	if hasMembers {
+		return model.NewAPIResponseError(http.StatusConflict, "the organization still has members")
+	}
+
+	deleted, err := m.organizationDAO.Delete(ctx, bson.M{"_id": organizationID})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return organizationNotFoundError()
+	}
+
+	return nil
+}
+
+func (m *organizationManager) Members(ctx context.Context, organizationID string) ([]model.Member, error) {
+	users, err := m.userDAO.FindMany(tenant.WithOrganization(ctx, organizationID), bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]model.Member, 0, len(users))
+
+	for _, user := range users {
+		members = append(members, organizationMember(user, organizationID))
+	}
+
+	return members, nil
+}
+
+func (m *organizationManager) SetMember(ctx context.Context, organizationID, userID string, roles, permissions []string) (*model.Member, error) {
+	if _, err := m.Get(ctx, organizationID); err != nil {
+		return nil, err
+	}
+
+	roles, err := checkGrantableRoles(ctx, m.roleDAO, roles, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users of any organization can be added, the filters target the organization explicitly
+	member, err := m.setMembership(tenant.WithAllOrganizations(ctx), organizationID, userID, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	if member == nil {
+		return nil, model.NewAPIResponseError(http.StatusNotFound, "the user does not exist")
+	}
+
+	return member, nil
+}
+
+func (m *organizationManager) UpdateMember(ctx context.Context, userID string, roles, permissions []string) (*model.Member, error) {
+	roles, err := checkGrantableRoles(ctx, m.roleDAO, roles, permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user is looked up in the organization of the context, so that users of other organizations can't
+	// be added
+	member, err := m.setMembership(ctx, tenant.OrganizationID(ctx), userID, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	if member == nil {
+		return nil, model.NewAPIResponseError(http.StatusNotFound, "the user is not a member of the organization")
+	}
+
+	return member, nil
+}
+
+func (m *organizationManager) RemoveMember(ctx context.Context, organizationID, userID string) error {
+	res, err := m.userDAO.Update(tenant.WithAllOrganizations(ctx), bson.M{"_id": userID, "memberships.organizationId": organizationID}, bson.M{
+		"$pull": bson.M{"memberships": bson.M{"organizationId": organizationID}},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	}, false)
+	if err != nil {
+		return err
+	}
+
+	if res.NotFound {
+		return model.NewAPIResponseError(http.StatusNotFound, "the user is not a member of the organization")
+	}
+
+	organizationCtx := tenant.WithOrganization(ctx, organizationID)
+
+	if err = m.sessionManager.EndAll(organizationCtx, userID); err != nil {
+		return err
+	}
+
+	groups, err := m.groupDAO.FindMany(organizationCtx, bson.M{"members": userID}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		update := bson.M{"$pull": bson.M{"members": userID}, "$set": bson.M{"updatedAt": time.Now()}}
+		if _, err = m.groupDAO.Update(organizationCtx, bson.M{"_id": group.ID}, update, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *organizationManager) EnsureDefaultOrganization(ctx context.Context) error {
+	exists, err := m.organizationDAO.Exists(ctx, bson.M{"_id": config.DefaultOrganizationID()}, nil)
+	if err != nil || exists {
+		return err
+	}
+
+	now := time.Now()
+
+	_, err = m.organizationDAO.Create(ctx, &model.Organization{
+		ID:        config.DefaultOrganizationID(),
+		Name:      config.AppName(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+
+	return err
+}
+
+// setMembership sets the roles of a user in an organization, adding the membership when the user has
+// none. The user is looked up in the scope of the context. It returns nil if the user is not found.
+func (m *organizationManager) setMembership(ctx context.Context, organizationID, userID string, roles []string) (*model.Member, error) {
+	now := time.Now()
+
+	res, err := m.userDAO.Update(ctx, bson.M{"_id": userID, "memberships.organizationId": organizationID}, bson.M{
+		"$set": bson.M{"memberships.$.roles": roles, "updatedAt": now},
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.NotFound {
+		res, err = m.userDAO.Update(ctx, bson.M{"_id": userID}, bson.M{
+			"$push": bson.M{"memberships": model.Membership{OrganizationID: organizationID, Roles: roles, CreatedAt: now}},
+			"$set":  bson.M{"updatedAt": now},
+		}, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.NotFound {
+			//nolint:nilnil // An unknown user is not an error
+			return nil, nil
+		}
+	}
+
+	user, err := m.userDAO.FindOne(ctx, bson.M{"_id": userID}, nil)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	member := organizationMember(*user, organizationID)
+
+	return &member, nil
+}
+
+// organizationMember returns a user as a member of an organization. The users of the default organization
+// created before organizations were introduced have no membership.
+func organizationMember(user model.User, organizationID string) model.Member {
+	member := model.Member{User: user, Roles: []string{}, JoinedAt: user.CreatedAt}
+
+	if membership := user.Membership(organizationID); membership != nil {
+		member.JoinedAt = membership.CreatedAt
+
+		if membership.Roles != nil {
+			member.Roles = membership.Roles
+		}
+	}
+
+	return member
+}
+
+// checkRoles sorts and deduplicates a list of roles, and checks that they exist.
+func checkRoles(ctx context.Context, roleDAO mongo.CrudDAO[model.Role], roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return nil, nil
+	}
+
+	slices.Sort(roles)
+	roles = slices.Compact(roles)
+
+	if count := roleDAO.Count(ctx, bson.M{"_id": bson.M{"$in": roles}}); count != int64(len(roles)) {
+		if count < 0 {
+			return nil, model.NewAPIResponseError(http.StatusInternalServerError, "the roles could not be verified")
+		}
+
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "one of the roles does not exist")
+	}
+
+	return roles, nil
+}
+
+// checkGrantableRoles checks a list of roles like checkRoles, and that none of them grants a permission
+// the caller does not have, so that callers can't give more permissions than their own.
+func checkGrantableRoles(ctx context.Context, roleDAO mongo.CrudDAO[model.Role], roles, permissions []string) ([]string, error) {
+	roles, err := checkRoles(ctx, roleDAO, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkGrantedPermissions(ctx, roleDAO, roles, permissions); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// checkGrantedPermissions checks that none of the existing roles of a list grants a permission the caller
+// does not have.
+func checkGrantedPermissions(ctx context.Context, roleDAO mongo.CrudDAO[model.Role], roles, permissions []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	granted, err := roleDAO.FindMany(ctx, bson.M{"_id": bson.M{"$in": roles}}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range granted {
+		for _, permission := range role.Permissions {
+			if !model.GrantsPermission(permissions, permission) {
+				return model.NewAPIResponseError(http.StatusForbidden, "the "+role.ID+" role exceeds the permissions of the access token")
+			}
+		}
+	}
+
+	return nil
+}
+
+// normalizeDomains lowercases the domains of an organization, and removes their duplicates.
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+
+	for _, domain := range domains {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized)
+}
+
+func organizationNotFoundError() error {
+	return model.NewAPIResponseError(http.StatusNotFound, "the organization does not exist")
+}
+
+func NewOrganizationManager(
+	organizationDAO mongo.CrudDAO[model.Organization],
+	userDAO mongo.CrudDAO[model.User],
+	roleDAO mongo.CrudDAO[model.Role],
+	groupDAO mongo.CrudDAO[model.Group],
+	sessionManager SessionManager,
+) OrganizationManager {
+	return &organizationManager{
+		organizationDAO: organizationDAO,
+		userDAO:         userDAO,
+		roleDAO:         roleDAO,
+		groupDAO:        groupDAO,
+		sessionManager:  sessionManager,
+	}
+}
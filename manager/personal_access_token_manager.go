@@ -0,0 +1,221 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PersonalAccessTokenManager interface {
+	// Authenticate returns the access token granted by a personal access token, or nil if it is unknown or
+	// expired, or if its end-user is disabled or left the organization. Its permissions are the scopes of
+	// the token still granted by the roles of the end-user. Its last use is recorded, with the IP of the client.
+	Authenticate(ctx context.Context, value, ip string) (*model.Token, error)
+
+	// Create mints a personal access token for an end-user, in the organization of the context. Its scopes
+	// must be granted by the roles of the end-user, and it expires at the latest after the maximum lifetime
+	// of the configuration. The token value is only returned once.
+	Create(ctx context.Context, subject string, token model.PersonalAccessToken) (*model.PersonalAccessTokenCreation, error)
+
+	// Tokens returns the personal access tokens of an end-user in the organization of the context.
+	Tokens(ctx context.Context, subject string) ([]model.PersonalAccessToken, error)
+
+	// Revoke deletes a personal access token of an end-user.
+	Revoke(ctx context.Context, subject, tokenID string) error
+}
+
+type personalAccessTokenManager struct {
+	personalAccessTokenDAO mongo.CrudDAO[model.PersonalAccessToken]
+	userDAO                mongo.CrudDAO[model.User]
+	roleManager            RoleManager
+}
+
+func (m *personalAccessTokenManager) Authenticate(ctx context.Context, value, ip string) (*model.Token, error) {
+	prefix, secret, found := strings.Cut(value, ".")
+	if !found {
+		//nolint:nilnil // An invalid token is not an error
+		return nil, nil
+	}
+
+	pat, err := m.personalAccessTokenDAO.FindOne(tenant.WithAllOrganizations(ctx), bson.M{"prefix": prefix}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if pat == nil || !security.EqualTokens(pat.SecretHash, security.HashToken(secret)) || pat.IsExpired(now) {
+		//nolint:nilnil // An unknown or expired token is not an error
+		return nil, nil
+	}
+
+	ctx = tenant.WithOrganization(ctx, pat.OrganizationID)
+
+	// The tokens are revoked when the end-user is disabled or leaves, but may have been minted concurrently
+	user, err := m.userDAO.FindOne(ctx, bson.M{"_id": pat.Subject}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil || user.Disabled {
+		//nolint:nilnil // The token of a disabled end-user is not an error
+		return nil, nil
+	}
+
+	permissions, err := m.permissions(ctx, pat.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := make([]string, 0, len(pat.Scopes))
+
+	for _, scope := range pat.Scopes {
+		if model.GrantsPermission(permissions, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) >= credentialUsageResolution || pat.LastUsedIP != ip {
+		update := bson.M{"$set": bson.M{"lastUsedAt": now, "lastUsedIp": ip}}
+		if _, err = m.personalAccessTokenDAO.Update(ctx, bson.M{"_id": pat.ID}, update, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return &model.Token{
+		Kind:           model.TokenKindAccessToken,
+		OrganizationID: pat.OrganizationID,
+		Subject:        pat.Subject,
+		Scopes:         pat.Scopes,
+		Permissions:    granted,
+		IssuedAt:       pat.CreatedAt,
+		ExpiresAt:      pat.ExpiresAt,
+	}, nil
+}
+
+func (m *personalAccessTokenManager) Create(
+	ctx context.Context,
+	subject string,
+	token model.PersonalAccessToken,
+) (*model.PersonalAccessTokenCreation, error) {
+	if strings.TrimSpace(token.Name) == "" {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the name of the token is required")
+	}
+
+	if len(token.Scopes) == 0 {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the scopes of the token are required")
+	}
+
+	permissions, err := m.permissions(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scope := range token.Scopes {
+		if !model.GrantsPermission(permissions, scope) {
+			return nil, model.NewAPIResponseError(http.StatusForbidden, "the "+scope+" scope exceeds the permissions of the end-user")
+		}
+	}
+
+	now := time.Now()
+	maxExpiresAt := now.Add(config.PersonalAccessTokenMaxLifetime())
+
+	switch {
+	case token.ExpiresAt.IsZero():
+		token.ExpiresAt = maxExpiresAt
+	case !token.ExpiresAt.After(now):
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the expiration date of the token is past")
+	case token.ExpiresAt.After(maxExpiresAt):
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the expiration date of the token exceeds the maximum lifetime")
+	}
+
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := security.RandomToken(credentialPrefixSize)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	token = model.PersonalAccessToken{
+		ID:         id,
+		Subject:    subject,
+		Name:       token.Name,
+		Prefix:     config.PersonalAccessTokenPrefix() + "_" + prefix,
+		SecretHash: security.HashToken(secret),
+		Scopes:     token.Scopes,
+		ExpiresAt:  token.ExpiresAt,
+		CreatedAt:  now,
+	}
+
+	created, err := m.personalAccessTokenDAO.Create(ctx, &token)
+	if err != nil {
+		return nil, err
+	}
+
+	// The random prefix collided with another token
+	if !created {
+		return nil, model.NewAPIResponseError(http.StatusConflict, "the token could not be created, please retry")
+	}
+
+	return &model.PersonalAccessTokenCreation{PersonalAccessToken: token, Token: token.Prefix + "." + secret}, nil
+}
+
+func (m *personalAccessTokenManager) Tokens(ctx context.Context, subject string) ([]model.PersonalAccessToken, error) {
+	filter := bson.M{"subject": subject, "expiresAt": bson.M{"$gt": time.Now()}}
+
+	return m.personalAccessTokenDAO.FindMany(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+}
+
+func (m *personalAccessTokenManager) Revoke(ctx context.Context, subject, tokenID string) error {
+	deleted, err := m.personalAccessTokenDAO.Delete(ctx, bson.M{"_id": tokenID, "subject": subject})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return model.NewAPIResponseError(http.StatusNotFound, "the token does not exist")
+	}
+
+	return nil
+}
+
+// permissions returns the permissions granted to an end-user in the organization of the context.
+func (m *personalAccessTokenManager) permissions(ctx context.Context, subject string) ([]string, error) {
+	roles, err := m.roleManager.Resolve(ctx, subject, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, permissions := model.RoleGrants(roles)
+
+	return permissions, nil
+}
+
+func NewPersonalAccessTokenManager(
+	personalAccessTokenDAO mongo.CrudDAO[model.PersonalAccessToken],
+	userDAO mongo.CrudDAO[model.User],
+	roleManager RoleManager,
+) PersonalAccessTokenManager {
+	return &personalAccessTokenManager{
+		personalAccessTokenDAO: personalAccessTokenDAO,
+		userDAO:                userDAO,
+		roleManager:            roleManager,
+	}
+}
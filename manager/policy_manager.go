@@ -0,0 +1,388 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PolicyManager interface {
+	// Policies returns the policies of the organization of the context, sorted by name.
+	Policies(ctx context.Context) ([]model.Policy, error)
+
+	// Get returns a policy, or a 404 API error if it does not exist.
+	Get(ctx context.Context, policyID string) (*model.Policy, error)
+
+	// Create creates a policy. Its condition must be a valid CEL expression evaluating to a boolean.
+	Create(ctx context.Context, policy model.Policy) (*model.Policy, error)
+
+	// Update replaces every field of a policy but its identifier.
+	Update(ctx context.Context, policyID string, policy model.Policy) (*model.Policy, error)
+
+	// Delete deletes a policy.
+	Delete(ctx context.Context, policyID string) error
+
+	// Decide returns the authorization decisions of requests, in order, with the policies of the
+	// organization of the context. A request is allowed when an allow policy matches it and no deny policy
+	// does, and a deny policy whose condition can't be evaluated denies it. The subject of a request is
+	// resolved as a user of the organization, whose roles and permissions are given to the conditions. In
+	// explain mode, the decisions tell which policy decided, and how every policy was evaluated.
+	Decide(ctx context.Context, requests []model.DecisionRequest, explain bool) ([]model.Decision, error)
+}
+
+type policyManager struct {
+	policyDAO   mongo.CrudDAO[model.Policy]
+	roleManager RoleManager
+	env         *cel.Env
+	mutex       sync.Mutex
+	programs    map[string]compiledPolicy
+}
+
+// compiledPolicy is the program of a policy condition, cached until the condition changes.
+type compiledPolicy struct {
+	Condition string
+	Program   cel.Program
+}
+
+func (m *policyManager) Policies(ctx context.Context) ([]model.Policy, error) {
+	return m.policyDAO.FindMany(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+}
+
+func (m *policyManager) Get(ctx context.Context, policyID string) (*model.Policy, error) {
+	policy, err := m.policyDAO.FindOne(ctx, bson.M{"_id": policyID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy == nil {
+		return nil, model.NewAPIResponseError(http.StatusNotFound, "the policy does not exist")
+	}
+
+	return policy, nil
+}
+
+func (m *policyManager) Create(ctx context.Context, policy model.Policy) (*model.Policy, error) {
+	if err := m.validate(&policy); err != nil {
+		return nil, err
+	}
+
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	policy.ID = id
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+
+	created, err := m.policyDAO.Create(ctx, &policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		return nil, policyConflictError()
+	}
+
+	return &policy, nil
+}
+
+func (m *policyManager) Update(ctx context.Context, policyID string, policy model.Policy) (*model.Policy, error) {
+	if err := m.validate(&policy); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":          policy.Name,
+		"description":   policy.Description,
+		"effect":        policy.Effect,
+		"actions":       policy.Actions,
+		"resourceTypes": policy.ResourceTypes,
+		"condition":     policy.Condition,
+		"disabled":      policy.Disabled,
+		"updatedAt":     time.Now(),
+	}}
+
+	res, err := m.policyDAO.Update(ctx, bson.M{"_id": policyID}, update, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.NotFound {
+		return nil, model.NewAPIResponseError(http.StatusNotFound, "the policy does not exist")
+	}
+
+	if res.UniqueError {
+		return nil, policyConflictError()
+	}
+
+	return m.Get(ctx, policyID)
+}
+
+func (m *policyManager) Delete(ctx context.Context, policyID string) error {
+	deleted, err := m.policyDAO.Delete(ctx, bson.M{"_id": policyID})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return model.NewAPIResponseError(http.StatusNotFound, "the policy does not exist")
+	}
+
+	m.mutex.Lock()
+	delete(m.programs, policyID)
+	m.mutex.Unlock()
+
+	return nil
+}
+
+func (m *policyManager) Decide(ctx context.Context, requests []model.DecisionRequest, explain bool) ([]model.Decision, error) {
+	if len(requests) == 0 || len(requests) > config.PolicyBatchLimit() {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the number of decision requests is invalid")
+	}
+
+	for _, request := range requests {
+		if request.Action == "" {
+			return nil, model.NewAPIResponseError(http.StatusBadRequest, "the action of the decision requests is required")
+		}
+	}
+
+	policies, err := m.policyDAO.FindMany(ctx, bson.M{"disabled": false}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	// The subjects are resolved once, since the requests of a batch usually share them
+	subjects := make(map[string]map[string]any)
+	decisions := make([]model.Decision, len(requests))
+
+	for i, request := range requests {
+		subject, found := subjects[request.Subject.ID]
+		if !found {
+			if subject, err = m.subject(ctx, request.Subject.ID); err != nil {
+				return nil, err
+			}
+
+			subjects[request.Subject.ID] = subject
+		}
+
+		decisions[i] = m.decide(policies, request, subject, explain)
+	}
+
+	return decisions, nil
+}
+
+// decide returns the decision of a request. Without explain mode, the evaluation stops on the first
+// matching deny policy.
+func (m *policyManager) decide(policies []model.Policy, request model.DecisionRequest, subject map[string]any, explain bool) model.Decision {
+	subject = map[string]any{
+		"id":          request.Subject.ID,
+		"roles":       subject["roles"],
+		"permissions": subject["permissions"],
+		"attributes":  attributes(request.Subject.Attributes),
+	}
+
+	variables := map[string]any{
+		"subject": subject,
+		"resource": map[string]any{
+			"type":       request.Resource.Type,
+			"id":         request.Resource.ID,
+			"attributes": attributes(request.Resource.Attributes),
+		},
+		"action":  request.Action,
+		"context": attributes(request.Context),
+	}
+
+	var decision model.Decision
+
+	var allowedBy, deniedBy string
+
+	for _, policy := range policies {
+		if !policy.AppliesTo(request) {
+			continue
+		}
+
+		matched, err := m.match(policy, variables)
+
+		switch {
+		case policy.Effect == model.PolicyEffectDeny && (matched || err != nil) && deniedBy == "":
+			deniedBy = policy.ID
+		case policy.Effect == model.PolicyEffectAllow && matched && allowedBy == "":
+			allowedBy = policy.ID
+		}
+
+		if explain {
+			evaluation := model.PolicyEvaluation{PolicyID: policy.ID, Name: policy.Name, Effect: policy.Effect, Matched: matched}
+			if err != nil {
+				evaluation.Error = err.Error()
+			}
+
+			decision.Evaluations = append(decision.Evaluations, evaluation)
+		} else if deniedBy != "" {
+			break
+		}
+	}
+
+	decision.Allowed = deniedBy == "" && allowedBy != ""
+
+	if explain {
+		decision.PolicyID = allowedBy
+		if deniedBy != "" {
+			decision.PolicyID = deniedBy
+		}
+	}
+
+	return decision
+}
+
+// match evaluates the condition of a policy.
+func (m *policyManager) match(policy model.Policy, variables map[string]any) (bool, error) {
+	if policy.Condition == "" {
+		return true, nil
+	}
+
+	program, err := m.program(policy)
+	if err != nil {
+		return false, err
+	}
+
+	out, _, err := program.Eval(variables)
+	if err != nil {
+		return false, err
+	}
+
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, errors.New("the condition did not evaluate to a boolean")
+	}
+
+	return matched, nil
+}
+
+// program returns the program of the condition of a policy, compiling it if it is not cached or changed.
+func (m *policyManager) program(policy model.Policy) (cel.Program, error) {
+	m.mutex.Lock()
+	compiled, found := m.programs[policy.ID]
+	m.mutex.Unlock()
+
+	if found && compiled.Condition == policy.Condition {
+		return compiled.Program, nil
+	}
+
+	program, err := m.compile(policy.Condition)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	m.programs[policy.ID] = compiledPolicy{Condition: policy.Condition, Program: program}
+	m.mutex.Unlock()
+
+	return program, nil
+}
+
+func (m *policyManager) compile(condition string) (cel.Program, error) {
+	ast, issues := m.env.Compile(condition)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	if !ast.OutputType().IsExactType(cel.BoolType) && !ast.OutputType().IsExactType(cel.DynType) {
+		return nil, errors.New("the condition does not evaluate to a boolean")
+	}
+
+	return m.env.Program(ast, cel.CostLimit(config.PolicyCostLimit()))
+}
+
+// subject returns the roles and the permissions of a user of the organization of the context, empty if the
+// user is not a member.
+func (m *policyManager) subject(ctx context.Context, userID string) (map[string]any, error) {
+	roles := make([]model.Role, 0)
+
+	if userID != "" {
+		var err error
+
+		if roles, err = m.roleManager.Resolve(ctx, userID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	roleIDs, permissions := model.RoleGrants(roles)
+
+	return map[string]any{"roles": roleIDs, "permissions": permissions}, nil
+}
+
+// validate normalizes a policy, and checks its effect and its condition.
+func (m *policyManager) validate(policy *model.Policy) error {
+	policy.Name = strings.TrimSpace(policy.Name)
+	if policy.Name == "" {
+		return model.NewAPIResponseError(http.StatusBadRequest, "the name of the policy is required")
+	}
+
+	if policy.Effect != model.PolicyEffectAllow && policy.Effect != model.PolicyEffectDeny {
+		return model.NewAPIResponseError(http.StatusBadRequest, "the effect of the policy must be allow or deny")
+	}
+
+	policy.Condition = strings.TrimSpace(policy.Condition)
+	if policy.Condition != "" {
+		if _, err := m.compile(policy.Condition); err != nil {
+			return model.NewAPIResponseError(http.StatusBadRequest, "the condition of the policy is invalid: "+err.Error())
+		}
+	}
+
+	slices.Sort(policy.Actions)
+	policy.Actions = slices.Compact(policy.Actions)
+
+	slices.Sort(policy.ResourceTypes)
+	policy.ResourceTypes = slices.Compact(policy.ResourceTypes)
+
+	return nil
+}
+
+// attributes returns attributes, or an empty map so that the conditions can test them.
+func attributes(values map[string]any) map[string]any {
+	if values == nil {
+		return map[string]any{}
+	}
+
+	return values
+}
+
+func policyConflictError() error {
+	return model.NewAPIResponseError(http.StatusConflict, "a policy with the same name already exists")
+}
+
+func NewPolicyManager(policyDAO mongo.CrudDAO[model.Policy], roleManager RoleManager) (PolicyManager, error) {
+	attributes := cel.MapType(cel.StringType, cel.DynType)
+
+	env, err := cel.NewEnv(
+		cel.Variable("subject", attributes),
+		cel.Variable("resource", attributes),
+		cel.Variable("action", cel.StringType),
+		cel.Variable("context", attributes),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policyManager{
+		policyDAO:   policyDAO,
+		roleManager: roleManager,
+		env:         env,
+		programs:    make(map[string]compiledPolicy),
+	}, nil
+}
@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RateLimitManager interface {
+	// Take takes a token from the bucket of a key, refilled according to the policy. The request is
+	// allowed if the bucket was not empty.
+	Take(ctx context.Context, policy model.RateLimitPolicy, key string) (model.RateLimitResult, error)
+}
+
+// memoryRateLimitManager keeps the buckets in memory, for the deployments running a single instance.
+type memoryRateLimitManager struct {
+	mutex     sync.Mutex
+	buckets   map[string]*memoryBucket
+	sweptAt   time.Time
+	sweepFreq time.Duration
+}
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	expiresAt time.Time
+}
+
+func (m *memoryRateLimitManager) Take(_ context.Context, policy model.RateLimitPolicy, key string) (model.RateLimitResult, error) {
+	now := time.Now()
+	id := rateLimitBucketID(policy, key)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sweep(now)
+
+	bucket, found := m.buckets[id]
+	if !found {
+		bucket = &memoryBucket{tokens: float64(policy.Capacity), updatedAt: now}
+		m.buckets[id] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(policy.Capacity), bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*policy.Rate())
+	bucket.updatedAt = now
+	bucket.expiresAt = now.Add(policy.Period)
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+
+	return policy.Result(allowed, bucket.tokens), nil
+}
+
+// sweep removes the buckets that are full again, which behave like missing ones.
+func (m *memoryRateLimitManager) sweep(now time.Time) {
+	if now.Sub(m.sweptAt) < m.sweepFreq {
+		return
+	}
+
+	for id, bucket := range m.buckets {
+		if now.After(bucket.expiresAt) {
+			delete(m.buckets, id)
+		}
+	}
+
+	m.sweptAt = now
+}
+
+// mongoRateLimitManager keeps the buckets in MongoDB, so that the instances of goauth share them.
+type mongoRateLimitManager struct {
+	bucketDAO mongo.CrudDAO[model.RateLimitBucket]
+}
+
+func (m *mongoRateLimitManager) Take(ctx context.Context, policy model.RateLimitPolicy, key string) (model.RateLimitResult, error) {
+	now := time.Now()
+	capacity := float64(policy.Capacity)
+
+	// The bucket is refilled and a token is taken in a single atomic update, so that concurrent requests
+	// handled by several instances can't take the same token
+	pipeline := bson.A{
+		bson.M{"$set": bson.M{
+			"tokens": bson.M{"$min": bson.A{capacity, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", capacity}},
+				bson.M{"$multiply": bson.A{
+					bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updatedAt", now}}}}, 1000}},
+					policy.Rate(),
+				}},
+			}}}},
+		}},
+		bson.M{"$set": bson.M{
+			"allowed":   bson.M{"$gte": bson.A{"$tokens", 1}},
+			"tokens":    bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$tokens", 1}}, bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"updatedAt": now,
+			"expiresAt": now.Add(policy.Period),
+		}},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var bucket model.RateLimitBucket
+
+	err := m.bucketDAO.GetCollection().FindOneAndUpdate(ctx, bson.M{"_id": rateLimitBucketID(policy, key)}, pipeline, opts).Decode(&bucket)
+
+	// Concurrent upserts of a new bucket may conflict, the update then applies to the created bucket
+	if mongodriver.IsDuplicateKeyError(err) {
+		err = m.bucketDAO.GetCollection().FindOneAndUpdate(ctx, bson.M{"_id": rateLimitBucketID(policy, key)}, pipeline, opts).Decode(&bucket)
+	}
+
+	if err != nil {
+		return model.RateLimitResult{}, err
+	}
+
+	return policy.Result(bucket.Allowed, bucket.Tokens), nil
+}
+
+// rateLimitBucketID returns the identifier of the bucket of a key in a policy.
+func rateLimitBucketID(policy model.RateLimitPolicy, key string) string {
+	return policy.Name + ":" + policy.Key + ":" + key
+}
+
+// NewMemoryRateLimitManager returns a rate limiter keeping its buckets in memory.
+func NewMemoryRateLimitManager() RateLimitManager {
+	return &memoryRateLimitManager{
+		buckets:   make(map[string]*memoryBucket),
+		sweepFreq: time.Minute,
+	}
+}
+
+// NewMongoRateLimitManager returns a rate limiter keeping its buckets in MongoDB.
+func NewMongoRateLimitManager(bucketDAO mongo.CrudDAO[model.RateLimitBucket]) RateLimitManager {
+	return &mongoRateLimitManager{
+		bucketDAO: bucketDAO,
+	}
+}
@@ -0,0 +1,704 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// errRelationTooDeep is returned when the evaluation of a relation follows too many usersets.
+var errRelationTooDeep = errors.New("the relation is nested too deeply")
+
+type RelationManager interface {
+	// Namespaces returns the namespaces of the organization of the context, sorted by name.
+	Namespaces(ctx context.Context) ([]model.RelationNamespace, error)
+
+	// SaveNamespace creates a namespace, or replaces its relations. The computed usersets and the tuplesets
+	// of the rewrites of its relations must be relations of the namespace.
+	SaveNamespace(ctx context.Context, namespace model.RelationNamespace) (*model.RelationNamespace, error)
+
+	// DeleteNamespace deletes a namespace, once its objects have no relation tuples anymore.
+	DeleteNamespace(ctx context.Context, name string) error
+
+	// Write creates and deletes relation tuples, given in their text form. The relations of the tuples, and
+	// of their subject usersets, must be defined by the namespaces. Creating an existing tuple, or deleting
+	// an unknown one, is not an error. The writes are not atomic: a failed write can be retried.
+	Write(ctx context.Context, writes, deletes []string) (*model.RelationWrite, error)
+
+	// Check returns whether the subject of a tuple has its relation with its object, directly or through the
+	// rewrites of the relation. The result of a check may be reused for a short time, unless the given
+	// consistency token is newer: a check with the token of a write sees the write.
+	Check(ctx context.Context, tuple, consistencyToken string) (*model.RelationCheck, error)
+
+	// Expand returns the tree of the subjects of a userset, namespace:object#relation.
+	Expand(ctx context.Context, userset, consistencyToken string) (*model.RelationExpansion, error)
+
+	// ListObjects returns the objects of a namespace, sorted, a subject has a relation with.
+	ListObjects(ctx context.Context, namespace, relation, subject, consistencyToken string) (*model.RelationObjects, error)
+}
+
+type relationManager struct {
+	namespaceDAO mongo.CrudDAO[model.RelationNamespace]
+	tupleDAO     mongo.CrudDAO[model.RelationTuple]
+	revisionDAO  mongo.CrudDAO[model.RelationRevision]
+	mutex        sync.Mutex
+	checks       map[string]cachedRelationCheck
+}
+
+// cachedRelationCheck is the result of a check, along with the revision it was evaluated at.
+type cachedRelationCheck struct {
+	Allowed   bool
+	Revision  int64
+	CheckedAt time.Time
+}
+
+func (m *relationManager) Namespaces(ctx context.Context) ([]model.RelationNamespace, error) {
+	return m.namespaceDAO.FindMany(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+}
+
+func (m *relationManager) SaveNamespace(ctx context.Context, namespace model.RelationNamespace) (*model.RelationNamespace, error) {
+	if err := validateRelationNamespace(namespace); err != nil {
+		return nil, err
+	}
+
+	existing, err := m.namespaceDAO.FindOne(ctx, bson.M{"name": namespace.Name}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if existing == nil {
+		id, err := security.RandomToken(userIDSize)
+		if err != nil {
+			return nil, err
+		}
+
+		namespace = model.RelationNamespace{ID: id, Name: namespace.Name, Relations: namespace.Relations, CreatedAt: now, UpdatedAt: now}
+
+		created, err := m.namespaceDAO.Create(ctx, &namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		if !created {
+			return nil, model.NewAPIResponseError(http.StatusConflict, "the namespace was created concurrently")
+		}
+
+		return &namespace, nil
+	}
+
+	update := bson.M{"$set": bson.M{"relations": namespace.Relations, "updatedAt": now}}
+	if _, err = m.namespaceDAO.Update(ctx, bson.M{"_id": existing.ID}, update, false); err != nil {
+		return nil, err
+	}
+
+	// The checks cached by the other instances expire on their own
+	m.clearChecks()
+
+	existing.Relations = namespace.Relations
+	existing.UpdatedAt = now
+
+	return existing, nil
+}
+
+func (m *relationManager) DeleteNamespace(ctx context.Context, name string) error {
+	exists, err := m.tupleDAO.Exists(ctx, bson.M{"namespace": name}, nil)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return model.NewAPIResponseError(http.StatusConflict, "the objects of the namespace still have relation tuples")
+	}
+
+	deleted, err := m.namespaceDAO.Delete(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return model.NewAPIResponseError(http.StatusNotFound, "the namespace does not exist")
+	}
+
+	m.clearChecks()
+
+	return nil
+}
+
+func (m *relationManager) Write(ctx context.Context, writes, deletes []string) (*model.RelationWrite, error) {
+	if len(writes) == 0 && len(deletes) == 0 {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the write has no relation tuples")
+	}
+
+	namespaces, err := m.namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := parseRelationTuples(namespaces, writes)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := parseRelationTuples(namespaces, deletes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	for _, tuple := range created {
+		if tuple.ID, err = security.RandomToken(userIDSize); err != nil {
+			return nil, err
+		}
+
+		tuple.CreatedAt = now
+
+		// An existing tuple violates the unique index, and is left as is
+		if _, err = m.tupleDAO.Create(ctx, &tuple); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tuple := range deleted {
+		if _, err = m.tupleDAO.DeleteMany(ctx, tuple.Filter()); err != nil {
+			return nil, err
+		}
+	}
+
+	// The revision is incremented once the tuples are written, so that the checks evaluated at this
+	// revision see them
+	organizationID := tenant.OrganizationID(ctx)
+	if _, err = m.revisionDAO.Update(ctx, bson.M{"_id": organizationID}, bson.M{"$inc": bson.M{"revision": 1}}, true); err != nil {
+		return nil, err
+	}
+
+	revision, err := m.revision(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.RelationWrite{ConsistencyToken: consistencyToken(revision)}, nil
+}
+
+func (m *relationManager) Check(ctx context.Context, value, token string) (*model.RelationCheck, error) {
+	tuple, err := model.ParseRelationTuple(value)
+	if err != nil {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, err.Error())
+	}
+
+	minRevision, err := parseConsistencyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	key := tenant.OrganizationID(ctx) + " " + tuple.String()
+
+	m.mutex.Lock()
+	cached, found := m.checks[key]
+	m.mutex.Unlock()
+
+	if found && cached.Revision >= minRevision && time.Since(cached.CheckedAt) < config.RelationCheckCacheLifetime() {
+		return &model.RelationCheck{Allowed: cached.Allowed, ConsistencyToken: consistencyToken(cached.Revision)}, nil
+	}
+
+	revision, err := m.snapshot(ctx, minRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation, err := m.evaluation(ctx, tuple.Namespace, tuple.Relation)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := evaluation.check(tuple.Namespace, tuple.ObjectID, tuple.Relation, tuple.Subject(), 0)
+	if err != nil {
+		return nil, relationEvaluationError(err)
+	}
+
+	m.mutex.Lock()
+	// The cache is cleared once full, rather than tracking the oldest checks
+	if len(m.checks) >= config.RelationCheckCacheSize() {
+		m.checks = make(map[string]cachedRelationCheck)
+	}
+
+	m.checks[key] = cachedRelationCheck{Allowed: allowed, Revision: revision, CheckedAt: time.Now()}
+	m.mutex.Unlock()
+
+	return &model.RelationCheck{Allowed: allowed, ConsistencyToken: consistencyToken(revision)}, nil
+}
+
+func (m *relationManager) Expand(ctx context.Context, userset, token string) (*model.RelationExpansion, error) {
+	namespace, objectID, relation, err := model.ParseRelationUserset(userset)
+	if err != nil || relation == "" {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the userset must be formatted as namespace:object#relation")
+	}
+
+	minRevision, err := parseConsistencyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := m.snapshot(ctx, minRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation, err := m.evaluation(ctx, namespace, relation)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := evaluation.expand(namespace, objectID, relation, 0)
+	if err != nil {
+		return nil, relationEvaluationError(err)
+	}
+
+	return &model.RelationExpansion{Tree: tree, ConsistencyToken: consistencyToken(revision)}, nil
+}
+
+func (m *relationManager) ListObjects(ctx context.Context, namespace, relation, subject, token string) (*model.RelationObjects, error) {
+	if _, _, _, err := model.ParseRelationUserset(subject); err != nil {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the subject must be formatted as namespace:object or namespace:object#relation")
+	}
+
+	minRevision, err := parseConsistencyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := m.snapshot(ctx, minRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation, err := m.evaluation(ctx, namespace, relation)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every rewrite starts from the tuples of the object, so the objects without tuples have no relation
+	tuples, err := m.tupleDAO.FindMany(ctx, bson.M{"namespace": namespace}, options.Find().SetProjection(bson.M{"objectId": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]string, 0, len(tuples))
+	for _, tuple := range tuples {
+		candidates = append(candidates, tuple.ObjectID)
+	}
+
+	slices.Sort(candidates)
+
+	objects := make([]string, 0)
+
+	for _, objectID := range slices.Compact(candidates) {
+		allowed, err := evaluation.check(namespace, objectID, relation, subject, 0)
+		if err != nil {
+			return nil, relationEvaluationError(err)
+		}
+
+		if allowed {
+			objects = append(objects, objectID)
+		}
+	}
+
+	return &model.RelationObjects{Objects: objects, ConsistencyToken: consistencyToken(revision)}, nil
+}
+
+// namespaces returns the namespaces of the organization of the context, by name.
+func (m *relationManager) namespaces(ctx context.Context) (map[string]model.RelationNamespace, error) {
+	namespaces, err := m.namespaceDAO.FindMany(ctx, bson.M{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]model.RelationNamespace, len(namespaces))
+	for _, namespace := range namespaces {
+		byName[namespace.Name] = namespace
+	}
+
+	return byName, nil
+}
+
+// evaluation returns the evaluation of a relation of a namespace, or a 400 API error if it is not defined.
+func (m *relationManager) evaluation(ctx context.Context, namespace, relation string) (*relationEvaluation, error) {
+	namespaces, err := m.namespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if definition, found := namespaces[namespace]; !found || definition.Relation(relation) == nil {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the "+namespace+"#"+relation+" relation is not defined")
+	}
+
+	return &relationEvaluation{
+		ctx:        ctx,
+		tupleDAO:   m.tupleDAO,
+		namespaces: namespaces,
+		tuples:     make(map[string][]model.RelationTuple),
+		allowed:    make(map[string]bool),
+		visiting:   make(map[string]bool),
+	}, nil
+}
+
+// revision returns the current revision of the relation tuples of the organization of the context.
+func (m *relationManager) revision(ctx context.Context) (int64, error) {
+	revision, err := m.revisionDAO.FindOne(ctx, bson.M{"_id": tenant.OrganizationID(ctx)}, nil)
+	if err != nil || revision == nil {
+		return 0, err
+	}
+
+	return revision.Revision, nil
+}
+
+// snapshot returns the revision the relations are evaluated at, which can't be older than the revision of
+// the consistency token. The tuples are read from the primary, which holds every write up to the current
+// revision.
+func (m *relationManager) snapshot(ctx context.Context, minRevision int64) (int64, error) {
+	revision, err := m.revision(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if revision < minRevision {
+		return 0, invalidConsistencyTokenError()
+	}
+
+	return revision, nil
+}
+
+func (m *relationManager) clearChecks() {
+	m.mutex.Lock()
+	m.checks = make(map[string]cachedRelationCheck)
+	m.mutex.Unlock()
+}
+
+// relationEvaluation evaluates the relations of an organization for a request. It caches the tuples it
+// reads and the granted relations it finds, since the usersets of a relation often overlap.
+type relationEvaluation struct {
+	ctx        context.Context
+	tupleDAO   mongo.CrudDAO[model.RelationTuple]
+	namespaces map[string]model.RelationNamespace
+	tuples     map[string][]model.RelationTuple
+	allowed    map[string]bool
+	// visiting holds the usersets being evaluated, to stop on cycles.
+	visiting map[string]bool
+}
+
+// check returns true if a subject, namespace:object or namespace:object#relation, has a relation with an
+// object.
+func (e *relationEvaluation) check(namespace, objectID, relation, subject string, depth int) (bool, error) {
+	if depth > config.RelationMaxDepth() {
+		return false, errRelationTooDeep
+	}
+
+	userset := namespace + ":" + objectID + "#" + relation
+	if userset == subject {
+		return true, nil
+	}
+
+	// Only the granted relations are remembered: a denial found while a userset of a cycle was being
+	// evaluated may not hold once it is
+	key := userset + "@" + subject
+	if e.allowed[key] || e.visiting[userset] {
+		return e.allowed[key], nil
+	}
+
+	e.visiting[userset] = true
+	defer delete(e.visiting, userset)
+
+	for _, rewrite := range e.usersets(namespace, relation) {
+		allowed, err := e.checkUserset(rewrite, namespace, objectID, relation, subject, depth)
+		if err != nil {
+			return false, err
+		}
+
+		if allowed {
+			e.allowed[key] = true
+
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkUserset returns true if a subject is in a userset of the relation of an object.
+func (e *relationEvaluation) checkUserset(rewrite model.Userset, namespace, objectID, relation, subject string, depth int) (bool, error) {
+	switch {
+	case rewrite.This:
+		tuples, err := e.relationTuples(namespace, objectID, relation)
+		if err != nil {
+			return false, err
+		}
+
+		for _, tuple := range tuples {
+			if tuple.Subject() == subject {
+				return true, nil
+			}
+		}
+
+		for _, tuple := range tuples {
+			if tuple.SubjectRelation == "" {
+				continue
+			}
+
+			allowed, err := e.check(tuple.SubjectNamespace, tuple.SubjectID, tuple.SubjectRelation, subject, depth+1)
+			if err != nil || allowed {
+				return allowed, err
+			}
+		}
+	case rewrite.ComputedUserset != "":
+		return e.check(namespace, objectID, rewrite.ComputedUserset, subject, depth+1)
+	case rewrite.TupleToUserset != nil:
+		tuples, err := e.relationTuples(namespace, objectID, rewrite.TupleToUserset.Tupleset)
+		if err != nil {
+			return false, err
+		}
+
+		for _, tuple := range tuples {
+			allowed, err := e.check(tuple.SubjectNamespace, tuple.SubjectID, rewrite.TupleToUserset.ComputedUserset, subject, depth+1)
+			if err != nil || allowed {
+				return allowed, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// expand returns the tree of the subjects of the relation of an object.
+func (e *relationEvaluation) expand(namespace, objectID, relation string, depth int) (model.RelationTree, error) {
+	tree := model.RelationTree{Userset: namespace + ":" + objectID + "#" + relation}
+
+	if depth > config.RelationMaxDepth() {
+		return tree, errRelationTooDeep
+	}
+
+	// A cycle is not expanded again
+	if e.visiting[tree.Userset] {
+		return tree, nil
+	}
+
+	e.visiting[tree.Userset] = true
+	defer delete(e.visiting, tree.Userset)
+
+	for _, rewrite := range e.usersets(namespace, relation) {
+		switch {
+		case rewrite.This:
+			tuples, err := e.relationTuples(namespace, objectID, relation)
+			if err != nil {
+				return tree, err
+			}
+
+			for _, tuple := range tuples {
+				if tuple.SubjectRelation == "" {
+					tree.Subjects = append(tree.Subjects, tuple.Subject())
+
+					continue
+				}
+
+				child, err := e.expand(tuple.SubjectNamespace, tuple.SubjectID, tuple.SubjectRelation, depth+1)
+				if err != nil {
+					return tree, err
+				}
+
+				tree.Children = append(tree.Children, child)
+			}
+		case rewrite.ComputedUserset != "":
+			child, err := e.expand(namespace, objectID, rewrite.ComputedUserset, depth+1)
+			if err != nil {
+				return tree, err
+			}
+
+			tree.Children = append(tree.Children, child)
+		case rewrite.TupleToUserset != nil:
+			tuples, err := e.relationTuples(namespace, objectID, rewrite.TupleToUserset.Tupleset)
+			if err != nil {
+				return tree, err
+			}
+
+			for _, tuple := range tuples {
+				child, err := e.expand(tuple.SubjectNamespace, tuple.SubjectID, rewrite.TupleToUserset.ComputedUserset, depth+1)
+				if err != nil {
+					return tree, err
+				}
+
+				tree.Children = append(tree.Children, child)
+			}
+		}
+	}
+
+	return tree, nil
+}
+
+// usersets returns the rewrites of a relation, none if it is not defined.
+func (e *relationEvaluation) usersets(namespace, relation string) []model.Userset {
+	definition := e.namespaces[namespace].Relation(relation)
+
+	switch {
+	case definition == nil:
+		return nil
+	case len(definition.Usersets) == 0:
+		return []model.Userset{{This: true}}
+	default:
+		return definition.Usersets
+	}
+}
+
+// relationTuples returns the tuples of the relation of an object.
+func (e *relationEvaluation) relationTuples(namespace, objectID, relation string) ([]model.RelationTuple, error) {
+	key := namespace + ":" + objectID + "#" + relation
+	if tuples, found := e.tuples[key]; found {
+		return tuples, nil
+	}
+
+	filter := bson.M{"namespace": namespace, "objectId": objectID, "relation": relation}
+
+	tuples, err := e.tupleDAO.FindMany(e.ctx, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	e.tuples[key] = tuples
+
+	return tuples, nil
+}
+
+// validateRelationNamespace checks the names of a namespace and of its relations, and the references of
+// the rewrites of its relations.
+func validateRelationNamespace(namespace model.RelationNamespace) error {
+	if !model.ValidRelationName(namespace.Name) {
+		return model.NewAPIResponseError(http.StatusBadRequest, "the name of the namespace is invalid")
+	}
+
+	defined := make(map[string]bool, len(namespace.Relations))
+
+	for _, relation := range namespace.Relations {
+		if !model.ValidRelationName(relation.Name) {
+			return model.NewAPIResponseError(http.StatusBadRequest, "the name of the "+relation.Name+" relation is invalid")
+		}
+
+		if defined[relation.Name] {
+			return model.NewAPIResponseError(http.StatusBadRequest, "the "+relation.Name+" relation is defined twice")
+		}
+
+		defined[relation.Name] = true
+
+		for _, rewrite := range relation.Usersets {
+			if err := validateUserset(namespace, relation.Name, rewrite); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateUserset(namespace model.RelationNamespace, relation string, rewrite model.Userset) error {
+	invalid := model.NewAPIResponseError(http.StatusBadRequest, "a userset of the "+relation+" relation is invalid")
+
+	switch {
+	case rewrite.This:
+		if rewrite.ComputedUserset != "" || rewrite.TupleToUserset != nil {
+			return invalid
+		}
+	case rewrite.ComputedUserset != "":
+		if rewrite.TupleToUserset != nil || namespace.Relation(rewrite.ComputedUserset) == nil {
+			return invalid
+		}
+	case rewrite.TupleToUserset != nil:
+		// The computed userset is a relation of the related objects, which may be of any namespace
+		if namespace.Relation(rewrite.TupleToUserset.Tupleset) == nil || !model.ValidRelationName(rewrite.TupleToUserset.ComputedUserset) {
+			return invalid
+		}
+	default:
+		return invalid
+	}
+
+	return nil
+}
+
+// parseRelationTuples parses the text form of tuples, whose relations must be defined by the namespaces.
+func parseRelationTuples(namespaces map[string]model.RelationNamespace, values []string) ([]model.RelationTuple, error) {
+	tuples := make([]model.RelationTuple, 0, len(values))
+
+	for _, value := range values {
+		tuple, err := model.ParseRelationTuple(value)
+		if err != nil {
+			return nil, model.NewAPIResponseError(http.StatusBadRequest, err.Error())
+		}
+
+		if namespaces[tuple.Namespace].Relation(tuple.Relation) == nil {
+			return nil, model.NewAPIResponseError(http.StatusBadRequest, "the "+tuple.Namespace+"#"+tuple.Relation+" relation is not defined")
+		}
+
+		if tuple.SubjectRelation != "" && namespaces[tuple.SubjectNamespace].Relation(tuple.SubjectRelation) == nil {
+			return nil, model.NewAPIResponseError(http.StatusBadRequest, "the "+tuple.SubjectNamespace+"#"+tuple.SubjectRelation+" relation is not defined")
+		}
+
+		tuples = append(tuples, *tuple)
+	}
+
+	return tuples, nil
+}
+
+// parseConsistencyToken returns the revision of a consistency token, 0 without token.
+func parseConsistencyToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	revision, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || revision < 0 {
+		return 0, invalidConsistencyTokenError()
+	}
+
+	return revision, nil
+}
+
+func consistencyToken(revision int64) string {
+	return strconv.FormatInt(revision, 10)
+}
+
+func invalidConsistencyTokenError() error {
+	return model.NewAPIResponseError(http.StatusBadRequest, "the consistency token is invalid")
+}
+
+// relationEvaluationError returns a 400 API error when a relation is nested too deeply, since it comes
+// from the schema and the tuples rather than from the server.
+func relationEvaluationError(err error) error {
+	if errors.Is(err, errRelationTooDeep) {
+		return model.NewAPIResponseError(http.StatusBadRequest, err.Error())
+	}
+
+	return err
+}
+
+func NewRelationManager(
+	namespaceDAO mongo.CrudDAO[model.RelationNamespace],
+	tupleDAO mongo.CrudDAO[model.RelationTuple],
+	revisionDAO mongo.CrudDAO[model.RelationRevision],
+) RelationManager {
+	return &relationManager{
+		namespaceDAO: namespaceDAO,
+		tupleDAO:     tupleDAO,
+		revisionDAO:  revisionDAO,
+		checks:       make(map[string]cachedRelationCheck),
+	}
+}
@@ -0,0 +1,401 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// roleIDPattern restricts role identifiers to the characters that can be used in URLs and token claims.
+var roleIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]{0,63}$`)
+
+type RoleManager interface {
+	// Roles returns every role, sorted by identifier.
+	Roles(ctx context.Context) ([]model.Role, error)
+
+	// Get returns a role, or a 404 API error if it does not exist.
+	Get(ctx context.Context, roleID string) (*model.Role, error)
+
+	// Create creates a role. Its identifier can't be changed afterwards, and its permissions must be
+	// granted by the permissions of the caller.
+	Create(ctx context.Context, role model.Role, permissions []string) (*model.Role, error)
+
+	// Update replaces the description and the permissions of a role. The new permissions must be granted
+	// by the permissions of the caller.
+	Update(ctx context.Context, roleID string, role model.Role, permissions []string) (*model.Role, error)
+
+	// Delete deletes a role and unassigns it from the users, members, groups and clients holding it, in
+	// every organization. The admin role can't be deleted.
+	Delete(ctx context.Context, roleID string) error
+
+	// UserRoles returns the roles assigned to a user on the whole platform, whatever the organizations of
+	// the user.
+	UserRoles(ctx context.Context, userID string) ([]model.Role, error)
+
+	// AssignUserRole assigns a role to a user, if its permissions are granted by the permissions of the
+	// caller.
+	AssignUserRole(ctx context.Context, userID, roleID string, permissions []string) error
+
+	// UnassignUserRole removes a role from a user.
+	UnassignUserRole(ctx context.Context, userID, roleID string) error
+
+	// ClientRoles returns the roles assigned to a client.
+	ClientRoles(ctx context.Context, clientID string) ([]model.Role, error)
+
+	// AssignClientRole assigns a role to a client, if its permissions are granted by the permissions of the
+	// caller.
+	AssignClientRole(ctx context.Context, clientID, roleID string, permissions []string) error
+
+	// UnassignClientRole removes a role from a client.
+	UnassignClientRole(ctx context.Context, clientID, roleID string) error
+
+	// Resolve returns the roles granted to the tokens of a subject: the roles of the user, along with the
+	// roles of the user as a member of the organization of the context and the roles of their effective
+	// groups in it, or the roles of the client when the tokens are issued to the client itself.
+	Resolve(ctx context.Context, subject string, client *model.Client) ([]model.Role, error)
+
+	// EnsureAdminRole creates the admin role if it does not exist, and assigns it to the administrators
+	// of the configuration.
+	EnsureAdminRole(ctx context.Context) error
+}
+
+type roleManager struct {
+	roleDAO   mongo.CrudDAO[model.Role]
+	userDAO   mongo.CrudDAO[model.User]
+	clientDAO mongo.CrudDAO[model.Client]
+	groupDAO  mongo.CrudDAO[model.Group]
+}
+
+func (m *roleManager) Roles(ctx context.Context) ([]model.Role, error) {
+	return m.roleDAO.FindMany(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+}
+
+func (m *roleManager) Get(ctx context.Context, roleID string) (*model.Role, error) {
+	role, err := m.roleDAO.FindOne(ctx, bson.M{"_id": roleID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if role == nil {
+		return nil, roleNotFoundError()
+	}
+
+	return role, nil
+}
+
+func (m *roleManager) Create(ctx context.Context, role model.Role, granted []string) (*model.Role, error) {
+	if !roleIDPattern.MatchString(role.ID) {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the role identifier is invalid")
+	}
+
+	permissions, err := normalizePermissions(role.Permissions, granted)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	role.Permissions = permissions
+	role.CreatedAt = now
+	role.UpdatedAt = now
+
+	created, err := m.roleDAO.Create(ctx, &role)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		return nil, model.NewAPIResponseError(http.StatusConflict, "the role already exists")
+	}
+
+	return &role, nil
+}
+
+func (m *roleManager) Update(ctx context.Context, roleID string, role model.Role, granted []string) (*model.Role, error) {
+	if roleID == model.RoleIDAdmin {
+		return nil, model.NewAPIResponseError(http.StatusForbidden, "the admin role can't be modified")
+	}
+
+	permissions, err := normalizePermissions(role.Permissions, granted)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := m.roleDAO.Update(ctx, bson.M{"_id": roleID}, bson.M{"$set": bson.M{
+		"description": role.Description,
+		"permissions": permissions,
+		"updatedAt":   time.Now(),
+	}}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.NotFound {
+		return nil, roleNotFoundError()
+	}
+
+	return m.Get(ctx, roleID)
+}
+
+func (m *roleManager) Delete(ctx context.Context, roleID string) error {
+	if roleID == model.RoleIDAdmin {
+		return model.NewAPIResponseError(http.StatusForbidden, "the admin role can't be deleted")
+	}
+
+	deleted, err := m.roleDAO.Delete(ctx, bson.M{"_id": roleID})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return roleNotFoundError()
+	}
+
+	// The role is unassigned in every organization
+	allCtx := tenant.WithAllOrganizations(ctx)
+
+	users, err := m.userDAO.FindMany(allCtx, bson.M{"roles": roleID}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err = m.UnassignUserRole(ctx, user.ID, roleID); err != nil {
+			return err
+		}
+	}
+
+	members, err := m.userDAO.FindMany(allCtx, bson.M{"memberships.roles": roleID}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if err = updateRoles(allCtx, m.userDAO, member.ID, bson.M{"$pull": bson.M{"memberships.$[].roles": roleID}}, "the user does not exist"); err != nil {
+			return err
+		}
+	}
+
+	clients, err := m.clientDAO.FindMany(allCtx, bson.M{"roles": roleID}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, client := range clients {
+		if err = updateRoles(allCtx, m.clientDAO, client.ID, bson.M{"$pull": bson.M{"roles": roleID}}, "the client does not exist"); err != nil {
+			return err
+		}
+	}
+
+	groups, err := m.groupDAO.FindMany(allCtx, bson.M{"roles": roleID}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		if err = updateRoles(allCtx, m.groupDAO, group.ID, bson.M{"$pull": bson.M{"roles": roleID}}, "the group does not exist"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *roleManager) UserRoles(ctx context.Context, userID string) ([]model.Role, error) {
+	user, err := m.userDAO.FindOne(tenant.WithAllOrganizations(ctx), bson.M{"_id": userID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, model.NewAPIResponseError(http.StatusNotFound, "the user does not exist")
+	}
+
+	return m.find(ctx, user.Roles)
+}
+
+func (m *roleManager) AssignUserRole(ctx context.Context, userID, roleID string, permissions []string) error {
+	if _, err := checkGrantableRoles(ctx, m.roleDAO, []string{roleID}, permissions); err != nil {
+		return err
+	}
+
+	return updateRoles(tenant.WithAllOrganizations(ctx), m.userDAO, userID, bson.M{"$addToSet": bson.M{"roles": roleID}}, "the user does not exist")
+}
+
+func (m *roleManager) UnassignUserRole(ctx context.Context, userID, roleID string) error {
+	return updateRoles(tenant.WithAllOrganizations(ctx), m.userDAO, userID, bson.M{"$pull": bson.M{"roles": roleID}}, "the user does not exist")
+}
+
+func (m *roleManager) ClientRoles(ctx context.Context, clientID string) ([]model.Role, error) {
+	client, err := m.clientDAO.FindOne(ctx, bson.M{"_id": clientID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		return nil, model.NewAPIResponseError(http.StatusNotFound, "the client does not exist")
+	}
+
+	return m.find(ctx, client.Roles)
+}
+
+func (m *roleManager) AssignClientRole(ctx context.Context, clientID, roleID string, permissions []string) error {
+	if _, err := checkGrantableRoles(ctx, m.roleDAO, []string{roleID}, permissions); err != nil {
+		return err
+	}
+
+	return updateRoles(ctx, m.clientDAO, clientID, bson.M{"$addToSet": bson.M{"roles": roleID}}, "the client does not exist")
+}
+
+func (m *roleManager) UnassignClientRole(ctx context.Context, clientID, roleID string) error {
+	return updateRoles(ctx, m.clientDAO, clientID, bson.M{"$pull": bson.M{"roles": roleID}}, "the client does not exist")
+}
+
+func (m *roleManager) Resolve(ctx context.Context, subject string, client *model.Client) ([]model.Role, error) {
+	if subject == "" {
+		return m.find(ctx, client.Roles)
+	}
+
+	user, err := m.userDAO.FindOne(ctx, bson.M{"_id": subject}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return []model.Role{}, nil
+	}
+
+	roleIDs := slices.Clone(user.Roles)
+
+	if membership := user.Membership(tenant.OrganizationID(ctx)); membership != nil {
+		roleIDs = append(roleIDs, membership.Roles...)
+	}
+
+	// The roles of the groups containing the user, directly or through other groups, are granted too
+	groups, err := groupAncestors(ctx, m.groupDAO, bson.M{"members": subject})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		roleIDs = append(roleIDs, group.Roles...)
+	}
+
+	return m.find(ctx, roleIDs)
+}
+
+func (m *roleManager) EnsureAdminRole(ctx context.Context) error {
+	// The administrators of the configuration are looked up in every organization
+	ctx = tenant.WithAllOrganizations(ctx)
+
+	role, err := m.roleDAO.FindOne(ctx, bson.M{"_id": model.RoleIDAdmin}, nil)
+	if err != nil {
+		return err
+	}
+
+	if role == nil {
+		now := time.Now()
+
+		if _, err = m.roleDAO.Create(ctx, &model.Role{
+			ID:          model.RoleIDAdmin,
+			Description: "Grants every permission",
+			Permissions: []string{model.PermissionAll},
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// The administrators of the configuration are not limited by the permissions of a caller
+	admin := []string{model.PermissionAll}
+
+	for _, userID := range config.RBACAdminUsers() {
+		if err = m.AssignUserRole(ctx, userID, model.RoleIDAdmin, admin); err != nil {
+			return err
+		}
+	}
+
+	for _, clientID := range config.RBACAdminClients() {
+		if err = m.AssignClientRole(ctx, clientID, model.RoleIDAdmin, admin); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// find returns the roles with the given identifiers, ignoring the roles that no longer exist.
+func (m *roleManager) find(ctx context.Context, roleIDs []string) ([]model.Role, error) {
+	if len(roleIDs) == 0 {
+		return []model.Role{}, nil
+	}
+
+	return m.roleDAO.FindMany(ctx, bson.M{"_id": bson.M{"$in": roleIDs}}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+}
+
+// updateRoles applies an update to the roles of a user or a client.
+func updateRoles[T mongo.Document](ctx context.Context, dao mongo.CrudDAO[T], id string, update bson.M, notFound string) error {
+	update["$set"] = bson.M{"updatedAt": time.Now()}
+
+	res, err := dao.Update(ctx, bson.M{"_id": id}, update, false)
+	if err != nil {
+		return err
+	}
+
+	if res.NotFound {
+		return model.NewAPIResponseError(http.StatusNotFound, notFound)
+	}
+
+	return nil
+}
+
+// normalizePermissions validates the permissions of a role, and removes their duplicates. The permissions
+// must be granted by the permissions of the caller, so that callers can't create roles exceeding their own.
+func normalizePermissions(permissions, granted []string) ([]string, error) {
+	normalized := make([]string, 0, len(permissions))
+
+	for _, permission := range permissions {
+		if permission == "" || strings.ContainsAny(permission, " \t\n") {
+			return nil, model.NewAPIResponseError(http.StatusBadRequest, "the permission "+permission+" is invalid")
+		}
+
+		if !model.GrantsPermission(granted, permission) {
+			return nil, model.NewAPIResponseError(http.StatusForbidden, "the permission "+permission+" exceeds the permissions of the access token")
+		}
+
+		normalized = append(normalized, permission)
+	}
+
+	slices.Sort(normalized)
+
+	return slices.Compact(normalized), nil
+}
+
+func roleNotFoundError() error {
+	return model.NewAPIResponseError(http.StatusNotFound, "the role does not exist")
+}
+
+func NewRoleManager(
+	roleDAO mongo.CrudDAO[model.Role],
+	userDAO mongo.CrudDAO[model.User],
+	clientDAO mongo.CrudDAO[model.Client],
+	groupDAO mongo.CrudDAO[model.Group],
+) RoleManager {
+	return &roleManager{
+		roleDAO:   roleDAO,
+		userDAO:   userDAO,
+		clientDAO: clientDAO,
+		groupDAO:  groupDAO,
+	}
+}
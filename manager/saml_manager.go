@@ -0,0 +1,364 @@
+package manager
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"github.com/rs/zerolog/log"
+	dsig "github.com/russellhaering/goxmldsig"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SAML attribute name format of the attributes mapped for service providers.
+const samlBasicAttributeNameFormat = "urn:oasis:names:tc:SAML:2.0:attrname-format:basic"
+
+type SAMLManager interface {
+	// Metadata returns the SAML metadata of the identity provider of the organization of the context, to be
+	// imported by service providers.
+	Metadata(ctx context.Context) ([]byte, error)
+
+	// Receive validates an authentication request sent by a registered service provider, through the
+	// HTTP-Redirect or HTTP-POST binding, and stores it until the end-user is logged in. A request can
+	// only be received once.
+	Receive(ctx context.Context, r *http.Request) (*model.SAMLAuthnRequest, error)
+
+	// Respond completes a pending authentication request once the end-user is logged in: it returns the
+	// signed response to post to the service provider. The request can't be used again.
+	Respond(ctx context.Context, r *http.Request, requestID string, session *model.Session) (model.SAMLResponseForm, error)
+
+	// RespondUnsolicited returns a signed response logging the end-user in to a service provider that did not
+	// request it (IdP-initiated single sign-on), with the given relay state.
+	RespondUnsolicited(ctx context.Context, r *http.Request, serviceProviderID, relayState string, session *model.Session) (model.SAMLResponseForm, error)
+}
+
+type samlManager struct {
+	userManager         UserManager
+	serviceProviderDAO  mongo.CrudDAO[model.SAMLServiceProvider]
+	samlAuthnRequestDAO mongo.CrudDAO[model.SAMLAuthnRequest]
+	replayEntryDAO      mongo.CrudDAO[model.ReplayEntry]
+	identityProvider    *saml.IdentityProvider
+	serviceProviders    samlServiceProviderProvider
+}
+
+// samlServiceProviderProvider looks up the metadata of the registered service providers for the SAML library.
+type samlServiceProviderProvider struct {
+	serviceProviderDAO mongo.CrudDAO[model.SAMLServiceProvider]
+}
+
+func (p samlServiceProviderProvider) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	sp, err := p.serviceProviderDAO.FindOne(r.Context(), bson.M{"_id": serviceProviderID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if sp == nil || !sp.Enabled {
+		return nil, os.ErrNotExist
+	}
+
+	return parseSAMLMetadata([]byte(sp.Metadata))
+}
+
+func (m *samlManager) Metadata(ctx context.Context) ([]byte, error) {
+	metadata := m.organizationIdentityProvider(ctx).Metadata()
+	metadata.IDPSSODescriptors[0].NameIDFormats = []saml.NameIDFormat{saml.PersistentNameIDFormat, saml.EmailAddressNameIDFormat}
+
+	return xml.MarshalIndent(metadata, "", "  ")
+}
+
+func (m *samlManager) Receive(ctx context.Context, r *http.Request) (*model.SAMLAuthnRequest, error) {
+	req, err := saml.NewIdpAuthnRequest(m.organizationIdentityProvider(ctx), r)
+	if err != nil {
+		return nil, model.NewOAuthInvalidRequestError("the SAML request could not be decoded")
+	}
+
+	if err = req.Validate(); err != nil {
+		log.Warn().Err(err).Msg("Invalid SAML authentication request")
+
+		return nil, model.NewOAuthInvalidRequestError("the SAML request is invalid")
+	}
+
+	now := time.Now()
+
+	// The identifier of the request must be unique, it is the one the response is issued for
+	replayEntry := &model.ReplayEntry{
+		ID:        "saml:" + req.ServiceProviderMetadata.EntityID + ":" + req.Request.ID,
+		ExpiresAt: now.Add(config.SAMLRequestLifetime()),
+	}
+
+	created, err := m.replayEntryDAO.Create(ctx, replayEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		return nil, model.NewOAuthInvalidRequestError("the SAML request was already received")
+	}
+
+	id, err := security.RandomToken(requestIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	authnRequest := &model.SAMLAuthnRequest{
+		ID:                id,
+		ServiceProviderID: req.ServiceProviderMetadata.EntityID,
+		Request:           string(req.RequestBuffer),
+		RelayState:        req.RelayState,
+		ReceivedAt:        now,
+		ExpiresAt:         now.Add(config.SAMLRequestLifetime()),
+	}
+
+	if _, err = m.samlAuthnRequestDAO.Create(ctx, authnRequest); err != nil {
+		return nil, err
+	}
+
+	return authnRequest, nil
+}
+
+func (m *samlManager) Respond(ctx context.Context, r *http.Request, requestID string, session *model.Session) (model.SAMLResponseForm, error) {
+	authnRequest, err := m.consumeRequest(ctx, requestID)
+	if err != nil {
+		return model.SAMLResponseForm{}, err
+	}
+
+	// The request is validated again as of the time it was received, to resolve its service provider
+	// and its assertion consumer service
+	req := &saml.IdpAuthnRequest{
+		IDP:           m.organizationIdentityProvider(ctx),
+		HTTPRequest:   r,
+		RelayState:    authnRequest.RelayState,
+		RequestBuffer: []byte(authnRequest.Request),
+		Now:           authnRequest.ReceivedAt,
+	}
+
+	if err = req.Validate(); err != nil {
+		log.Warn().Err(err).Msg("Invalid pending SAML authentication request")
+
+		return model.SAMLResponseForm{}, model.NewOAuthInvalidRequestError("the SAML request is invalid")
+	}
+
+	req.Now = time.Now()
+
+	return m.respond(ctx, req, session)
+}
+
+func (m *samlManager) RespondUnsolicited(
+	ctx context.Context,
+	r *http.Request,
+	serviceProviderID, relayState string,
+	session *model.Session,
+) (model.SAMLResponseForm, error) {
+	metadata, err := m.serviceProviders.GetServiceProvider(r, serviceProviderID)
+	if errors.Is(err, os.ErrNotExist) {
+		return model.SAMLResponseForm{}, model.NewOAuthInvalidRequestError("the SAML service provider is unknown")
+	}
+
+	if err != nil {
+		return model.SAMLResponseForm{}, err
+	}
+
+	req := &saml.IdpAuthnRequest{
+		IDP:                     m.organizationIdentityProvider(ctx),
+		HTTPRequest:             r,
+		RelayState:              relayState,
+		ServiceProviderMetadata: metadata,
+		Now:                     time.Now(),
+	}
+
+	// Unsolicited responses are posted to the first assertion consumer service supporting the HTTP-POST binding
+	for i := range metadata.SPSSODescriptors {
+		for j := range metadata.SPSSODescriptors[i].AssertionConsumerServices {
+			if req.ACSEndpoint == nil && metadata.SPSSODescriptors[i].AssertionConsumerServices[j].Binding == saml.HTTPPostBinding {
+				req.SPSSODescriptor = &metadata.SPSSODescriptors[i]
+				req.ACSEndpoint = &metadata.SPSSODescriptors[i].AssertionConsumerServices[j]
+			}
+		}
+	}
+
+	if req.ACSEndpoint == nil {
+		return model.SAMLResponseForm{}, model.NewOAuthInvalidRequestError("the SAML service provider has no assertion consumer service with the HTTP-POST binding")
+	}
+
+	return m.respond(ctx, req, session)
+}
+
+// respond issues the signed assertion of the logged-in end-user for a validated request, and returns the
+// response to post to the assertion consumer service of the service provider.
+func (m *samlManager) respond(ctx context.Context, req *saml.IdpAuthnRequest, session *model.Session) (model.SAMLResponseForm, error) {
+	sp, err := m.serviceProviderDAO.FindOne(ctx, bson.M{"_id": req.ServiceProviderMetadata.EntityID}, nil)
+	if err != nil {
+		return model.SAMLResponseForm{}, err
+	}
+
+	if sp == nil || !sp.Enabled {
+		return model.SAMLResponseForm{}, model.NewOAuthInvalidRequestError("the SAML service provider is unknown")
+	}
+
+	user, err := m.userManager.Get(ctx, session.Subject)
+	if err != nil {
+		return model.SAMLResponseForm{}, err
+	}
+
+	if user == nil {
+		return model.SAMLResponseForm{}, errors.New("the user of the session does not exist")
+	}
+
+	samlSession, err := samlSession(sp, user, session)
+	if err != nil {
+		return model.SAMLResponseForm{}, err
+	}
+
+	if err = (saml.DefaultAssertionMaker{}).MakeAssertion(req, samlSession); err != nil {
+		return model.SAMLResponseForm{}, err
+	}
+
+	form, err := req.PostBinding()
+	if err != nil {
+		return model.SAMLResponseForm{}, err
+	}
+
+	return model.SAMLResponseForm{URL: form.URL, SAMLResponse: form.SAMLResponse, RelayState: form.RelayState}, nil
+}
+
+// consumeRequest returns a pending authentication request and deletes it, so that it is answered only once.
+func (m *samlManager) consumeRequest(ctx context.Context, requestID string) (*model.SAMLAuthnRequest, error) {
+	invalidRequestError := model.NewOAuthInvalidRequestError("the SAML request is unknown or expired")
+
+	authnRequest, err := m.samlAuthnRequestDAO.FindOne(ctx, bson.M{"_id": requestID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if authnRequest == nil {
+		return nil, invalidRequestError
+	}
+
+	deleted, err := m.samlAuthnRequestDAO.Delete(ctx, bson.M{"_id": requestID})
+	if err != nil {
+		return nil, err
+	}
+
+	if !deleted || authnRequest.IsExpired(time.Now()) {
+		return nil, invalidRequestError
+	}
+
+	return authnRequest, nil
+}
+
+// samlSession describes the logged-in end-user to the SAML library. The name identifier is the user
+// identifier, unless the service provider expects email addresses, and the session index is derived
+// from the session identifier, which must not be disclosed.
+func samlSession(sp *model.SAMLServiceProvider, user *model.User, session *model.Session) (*saml.Session, error) {
+	samlSession := &saml.Session{
+		ID:             security.HashToken(session.ID),
+		CreateTime:     session.AuthTime,
+		ExpireTime:     session.ExpiresAt,
+		Index:          security.HashToken(session.ID),
+		NameID:         user.ID,
+		NameIDFormat:   string(saml.PersistentNameIDFormat),
+		UserName:       user.ID,
+		UserEmail:      user.Email,
+		UserCommonName: user.Name,
+		UserGivenName:  user.GivenName,
+		UserSurname:    user.FamilyName,
+	}
+
+	if sp.NameIDFormat == model.SAMLNameIDFormatEmail {
+		if user.Email == "" {
+			return nil, model.NewOAuthInvalidRequestError("the SAML service provider requires an email address")
+		}
+
+		samlSession.NameID = user.Email
+		samlSession.NameIDFormat = string(saml.EmailAddressNameIDFormat)
+	}
+
+	values := map[string]string{
+		model.UserAttributeSubject:       user.ID,
+		model.UserAttributeEmail:         user.Email,
+		model.UserAttributeEmailVerified: strconv.FormatBool(user.EmailVerified),
+		model.UserAttributeName:          user.Name,
+		model.UserAttributeGivenName:     user.GivenName,
+		model.UserAttributeFamilyName:    user.FamilyName,
+		model.UserAttributePicture:       user.Picture,
+	}
+
+	for attribute, name := range sp.AttributeMapping {
+		if value := values[attribute]; value != "" {
+			samlSession.CustomAttributes = append(samlSession.CustomAttributes, saml.Attribute{
+				Name:       name,
+				NameFormat: samlBasicAttributeNameFormat,
+				Values:     []saml.AttributeValue{{Type: "xs:string", Value: value}},
+			})
+		}
+	}
+
+	return samlSession, nil
+}
+
+// organizationIdentityProvider returns the identity provider of the organization of the context, whose
+// endpoints are served under the path of the organization.
+func (m *samlManager) organizationIdentityProvider(ctx context.Context) *saml.IdentityProvider {
+	idp := *m.identityProvider
+
+	if metadataURL, err := url.Parse(tenant.URL(ctx, idp.MetadataURL.String())); err == nil {
+		idp.MetadataURL = *metadataURL
+	}
+
+	if ssoURL, err := url.Parse(tenant.URL(ctx, idp.SSOURL.String())); err == nil {
+		idp.SSOURL = *ssoURL
+	}
+
+	return &idp
+}
+
+func NewSAMLManager(
+	userManager UserManager,
+	serviceProviderDAO mongo.CrudDAO[model.SAMLServiceProvider],
+	samlAuthnRequestDAO mongo.CrudDAO[model.SAMLAuthnRequest],
+	replayEntryDAO mongo.CrudDAO[model.ReplayEntry],
+) (SAMLManager, error) {
+	key, cert, err := security.SAMLKeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	metadataURL, err := url.Parse(config.SAMLEndpoint("/metadata"))
+	if err != nil {
+		return nil, err
+	}
+
+	ssoURL, err := url.Parse(config.SAMLEndpoint("/sso"))
+	if err != nil {
+		return nil, err
+	}
+
+	serviceProviders := samlServiceProviderProvider{serviceProviderDAO: serviceProviderDAO}
+
+	return &samlManager{
+		userManager:         userManager,
+		serviceProviderDAO:  serviceProviderDAO,
+		samlAuthnRequestDAO: samlAuthnRequestDAO,
+		replayEntryDAO:      replayEntryDAO,
+		identityProvider: &saml.IdentityProvider{
+			Key:                     key,
+			Certificate:             cert,
+			MetadataURL:             *metadataURL,
+			SSOURL:                  *ssoURL,
+			ServiceProviderProvider: serviceProviders,
+			SignatureMethod:         dsig.RSASHA256SignatureMethod,
+		},
+		serviceProviders: serviceProviders,
+	}, nil
+}
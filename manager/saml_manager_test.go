@@ -0,0 +1,237 @@
+package manager_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m3talux/goauth/internal/goauthtest"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+)
+
+var (
+	// samlSignature matches the XML signatures of the SAML responses.
+	samlSignature = regexp.MustCompile(`(?s)<ds:Signature[ >].*?</ds:Signature>`)
+	// samlEncryptionKey matches the encryption key of the SAML metadata of a service provider.
+	samlEncryptionKey = regexp.MustCompile(`(?s)<KeyDescriptor use="encryption">.*?</KeyDescriptor>`)
+)
+
+// users is a user manager knowing a single user.
+type users struct {
+	manager.UserManager
+	user *model.User
+}
+
+func (u users) Get(_ context.Context, userID string) (*model.User, error) {
+	if userID != u.user.ID {
+		//nolint:nilnil // An unknown user is not an error
+		return nil, nil
+	}
+
+	return u.user, nil
+}
+
+// samlFederation is the identity provider of goauth, federated with the SAML connection of goauth as an
+// upstream identity provider: the assertions signed by one are verified by the other, offline.
+type samlFederation struct {
+	identityProvider manager.SAMLManager
+	socialLogin      manager.SocialLoginManager
+}
+
+// newSAMLFederation federates the identity provider with the SAML connection. Without encryption, the
+// service provider is registered without its encryption key, for the assertions to be sent in clear.
+func newSAMLFederation(t *testing.T, encryption bool) *samlFederation {
+	t.Helper()
+
+	goauthtest.Configure(t, "https://auth.example.com")
+	ctx := goauthtest.Context()
+
+	serviceProviderDAO := goauthtest.NewMemoryDAO[model.SAMLServiceProvider]()
+
+	identityProvider, err := manager.NewSAMLManager(users{user: &model.User{ID: "user-1", Email: "user@example.com"}}, serviceProviderDAO,
+		goauthtest.NewMemoryDAO[model.SAMLAuthnRequest](), goauthtest.NewMemoryDAO[model.ReplayEntry]())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idpMetadata, err := identityProvider.Metadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connectionDAO := goauthtest.NewMemoryDAO[model.Connection]()
+	if _, err = connectionDAO.Create(ctx, &model.Connection{
+		ID:              "saml",
+		Name:            "SAML provider",
+		Type:            model.ConnectionTypeSAML,
+		SAMLIdPMetadata: string(idpMetadata),
+		Enabled:         true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	socialLogin := manager.NewSocialLoginManager(connectionDAO, goauthtest.NewMemoryDAO[model.SocialLoginState](), manager.NewJWKSManager())
+
+	spMetadata, err := socialLogin.ServiceProviderMetadata(ctx, "saml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !encryption {
+		spMetadata = samlEncryptionKey.ReplaceAll(spMetadata, nil)
+	}
+
+	if _, err = serviceProviderDAO.Create(ctx, &model.SAMLServiceProvider{
+		ID:       "https://auth.example.com/login/saml/metadata",
+		Name:     "goauth",
+		Metadata: string(spMetadata),
+		Enabled:  true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return &samlFederation{identityProvider: identityProvider, socialLogin: socialLogin}
+}
+
+// login starts a login on the SAML connection, and returns the authentication request sent to the
+// identity provider, and the state of the login.
+func (f *samlFederation) login(t *testing.T) (*http.Request, string) {
+	t.Helper()
+
+	redirectURL, state, err := f.socialLogin.Start(goauthtest.Context(), "saml", "login-challenge", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The service providers are looked up in the organization of the request, set by the tenant middleware
+	return httptest.NewRequest(http.MethodGet, redirectURL, http.NoBody).WithContext(goauthtest.Context()), state
+}
+
+// respond answers an authentication request with the signed response of the identity provider, and
+// returns the XML document of the response.
+func (f *samlFederation) respond(t *testing.T, r *http.Request) string {
+	t.Helper()
+
+	ctx := goauthtest.Context()
+
+	authnRequest, err := f.identityProvider.Receive(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form, err := f.identityProvider.Respond(ctx, r, authnRequest.ID, &model.Session{
+		ID:        "session",
+		Subject:   "user-1",
+		AuthTime:  time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := base64.StdEncoding.DecodeString(form.SAMLResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(response)
+}
+
+// callback posts a response to the assertion consumer service of the connection.
+func (f *samlFederation) callback(response, state string) (model.ExternalProfile, error) {
+	params := url.Values{"SAMLResponse": {base64.StdEncoding.EncodeToString([]byte(response))}, "RelayState": {state}}
+
+	profile, _, err := f.socialLogin.Callback(goauthtest.Context(), "saml", params, state)
+
+	return profile, err
+}
+
+func TestSAMLAssertion(t *testing.T) {
+	for _, encryption := range []bool{true, false} {
+		f := newSAMLFederation(t, encryption)
+
+		r, state := f.login(t)
+		response := f.respond(t, r)
+
+		if !samlSignature.MatchString(response) || strings.Contains(response, "EncryptedAssertion") != encryption {
+			t.Fatalf("unexpected response %s", response)
+		}
+
+		profile, err := f.callback(response, state)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if profile.Subject != "user-1" || profile.ConnectionID != "saml" {
+			t.Fatalf("unexpected profile %+v", profile)
+		}
+	}
+}
+
+func TestSAMLAssertionRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(response string) string
+	}{
+		{name: "tampered", modify: func(response string) string { return strings.ReplaceAll(response, ">user-1<", ">admin<") }},
+		{name: "tampered assertion", modify: func(response string) string {
+			// The signature of the response is removed, for the one of the assertion to be checked
+			response = strings.Replace(response, samlSignature.FindString(response), "", 1)
+
+			return strings.ReplaceAll(response, ">user-1<", ">admin<")
+		}},
+		{name: "unsigned", modify: func(response string) string { return samlSignature.ReplaceAllString(response, "") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSAMLFederation(t, false)
+
+			r, state := f.login(t)
+			response := f.respond(t, r)
+
+			modified := tt.modify(response)
+			if modified == response {
+				t.Fatal("the response was not modified")
+			}
+
+			if _, err := f.callback(modified, state); err == nil {
+				t.Fatal("expected the response to be rejected")
+			}
+		})
+	}
+}
+
+func TestSAMLAssertionReplay(t *testing.T) {
+	f := newSAMLFederation(t, true)
+
+	r, state := f.login(t)
+	response := f.respond(t, r)
+
+	// The response is issued in response to the request of the first login, not to the one of another
+	_, otherState := f.login(t)
+
+	if _, err := f.callback(response, otherState); err == nil {
+		t.Fatal("expected a response to another request to be rejected")
+	}
+
+	if _, err := f.callback(response, state); err != nil {
+		t.Fatal(err)
+	}
+
+	// The response can't be posted again, nor the request be answered twice
+	if _, err := f.callback(response, state); err == nil {
+		t.Fatal("expected a replayed response to be rejected")
+	}
+
+	if _, err := f.identityProvider.Receive(goauthtest.Context(), r); err == nil {
+		t.Fatal("expected a replayed request to be rejected")
+	}
+}
@@ -0,0 +1,452 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/model"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Logical operators of the SCIM filters, and the operator of value paths like emails[type eq "work"].
+const (
+	scimFilterAnd       = "and"
+	scimFilterOr        = "or"
+	scimFilterNot       = "not"
+	scimFilterValuePath = "[]"
+	scimFilterPresent   = "pr"
+)
+
+// Kinds of the SCIM attributes that can be filtered.
+const (
+	scimKindString = iota
+	scimKindBoolean
+	scimKindDateTime
+)
+
+// scimFilter is a node of a parsed SCIM filter (RFC 7644, section 3.4.2.2). Logical nodes hold their
+// operands, comparison nodes the attribute path and the compared value.
+type scimFilter struct {
+	op       string
+	path     string
+	value    any
+	children []*scimFilter
+}
+
+// scimAttribute maps a SCIM attribute to the field of the stored documents. Inverted booleans are stored
+// negated, like the active attribute of users stored as disabled.
+type scimAttribute struct {
+	field     string
+	kind      int
+	caseExact bool
+	inverted  bool
+}
+
+var scimComparisonOperators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "gt": true, "ge": true, "lt": true, "le": true,
+}
+
+// parseSCIMFilter parses a SCIM filter expression.
+func parseSCIMFilter(expression string) (*scimFilter, error) {
+	tokens, err := scimTokens(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &scimFilterParser{tokens: tokens}
+
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, invalidSCIMFilterError("unexpected %q", p.tokens[p.pos])
+	}
+
+	return filter, nil
+}
+
+// scimTokens splits a SCIM filter into parentheses, brackets, JSON strings and words.
+func scimTokens(expression string) ([]string, error) {
+	var tokens []string
+
+	for i := 0; i < len(expression); {
+		switch c := expression[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.IndexByte("()[]", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expression) && expression[end] != '"' {
+				if expression[end] == '\\' {
+					end++
+				}
+
+				end++
+			}
+
+			if end >= len(expression) {
+				return nil, invalidSCIMFilterError("unterminated string")
+			}
+
+			tokens = append(tokens, expression[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(expression) && strings.IndexByte(" \t()[]\"", expression[end]) < 0 {
+				end++
+			}
+
+			tokens = append(tokens, expression[i:end])
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+type scimFilterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *scimFilterParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+
+	return token
+}
+
+func (p *scimFilterParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+
+	return p.tokens[p.pos]
+}
+
+func (p *scimFilterParser) expect(token string) error {
+	if next := p.next(); next != token {
+		return invalidSCIMFilterError("expected %q instead of %q", token, next)
+	}
+
+	return nil
+}
+
+func (p *scimFilterParser) parseOr() (*scimFilter, error) {
+	return p.parseLogical(scimFilterOr, p.parseAnd)
+}
+
+func (p *scimFilterParser) parseAnd() (*scimFilter, error) {
+	return p.parseLogical(scimFilterAnd, p.parseNot)
+}
+
+// parseLogical parses operands joined by a logical operator.
+func (p *scimFilterParser) parseLogical(op string, parseOperand func() (*scimFilter, error)) (*scimFilter, error) {
+	filter, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for strings.EqualFold(p.peek(), op) {
+		p.pos++
+
+		operand, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		if filter.op == op {
+			filter.children = append(filter.children, operand)
+		} else {
+			filter = &scimFilter{op: op, children: []*scimFilter{filter, operand}}
+		}
+	}
+
+	return filter, nil
+}
+
+func (p *scimFilterParser) parseNot() (*scimFilter, error) {
+	if !strings.EqualFold(p.peek(), scimFilterNot) {
+		return p.parsePrimary()
+	}
+
+	p.pos++
+
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	return &scimFilter{op: scimFilterNot, children: []*scimFilter{filter}}, nil
+}
+
+func (p *scimFilterParser) parsePrimary() (*scimFilter, error) {
+	token := p.next()
+
+	switch token {
+	case "(":
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return filter, nil
+	case "", ")", "[", "]":
+		return nil, invalidSCIMFilterError("expected an attribute instead of %q", token)
+	}
+
+	if p.peek() == "[" {
+		p.pos++
+
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err = p.expect("]"); err != nil {
+			return nil, err
+		}
+
+		return &scimFilter{op: scimFilterValuePath, path: token, children: []*scimFilter{filter}}, nil
+	}
+
+	op := strings.ToLower(p.next())
+
+	if op == scimFilterPresent {
+		return &scimFilter{op: op, path: token}, nil
+	}
+
+	if !scimComparisonOperators[op] {
+		return nil, invalidSCIMFilterError("unsupported operator %q", op)
+	}
+
+	value, err := scimFilterValue(p.next())
+	if err != nil {
+		return nil, err
+	}
+
+	return &scimFilter{op: op, path: token, value: value}, nil
+}
+
+// scimFilterValue decodes the compared value of a filter: a JSON string, boolean, number or null.
+func scimFilterValue(token string) (any, error) {
+	var value any
+	if err := json.Unmarshal([]byte(token), &value); err != nil {
+		return nil, invalidSCIMFilterError("invalid value %q", token)
+	}
+
+	return value, nil
+}
+
+// bson translates the filter to a MongoDB filter. The attributes are mapped by their lowercased path,
+// without schema URN.
+func (f *scimFilter) bson(attributes map[string]scimAttribute) (bson.M, error) {
+	return f.bsonWithPrefix(attributes, "")
+}
+
+func (f *scimFilter) bsonWithPrefix(attributes map[string]scimAttribute, prefix string) (bson.M, error) {
+	switch f.op {
+	case scimFilterAnd, scimFilterOr, scimFilterNot:
+		operands := make(bson.A, 0, len(f.children))
+
+		for _, child := range f.children {
+			operand, err := child.bsonWithPrefix(attributes, prefix)
+			if err != nil {
+				return nil, err
+			}
+
+			operands = append(operands, operand)
+		}
+
+		operator := map[string]string{scimFilterAnd: "$and", scimFilterOr: "$or", scimFilterNot: "$nor"}[f.op]
+
+		return bson.M{operator: operands}, nil
+	case scimFilterValuePath:
+		return f.children[0].bsonWithPrefix(attributes, prefix+f.path+".")
+	}
+
+	path := scimAttributePath(prefix + f.path)
+
+	attribute, found := attributes[path]
+	if !found {
+		return nil, invalidSCIMFilterError("the attribute %s can't be filtered", f.path)
+	}
+
+	condition, err := attribute.condition(f.op, f.value)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.M{attribute.field: condition}, nil
+}
+
+// condition returns the MongoDB condition comparing the attribute with a value.
+func (a scimAttribute) condition(op string, value any) (any, error) {
+	if op == scimFilterPresent {
+		return bson.M{"$exists": true, "$nin": bson.A{nil, "", bson.A{}}}, nil
+	}
+
+	if value == nil {
+		switch op {
+		case "eq":
+			return bson.M{"$eq": nil}, nil
+		case "ne":
+			return bson.M{"$ne": nil}, nil
+		}
+
+		return nil, invalidSCIMFilterError("null can only be compared for equality")
+	}
+
+	switch a.kind {
+	case scimKindBoolean:
+		b, ok := value.(bool)
+		if !ok || (op != "eq" && op != "ne") {
+			return nil, invalidSCIMFilterError("booleans can only be compared for equality")
+		}
+
+		// Negated booleans may be missing from the documents
+		if b == a.inverted == (op == "eq") {
+			return bson.M{"$ne": true}, nil
+		}
+
+		return true, nil
+	case scimKindDateTime:
+		s, _ := value.(string)
+
+		date, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, invalidSCIMFilterError("invalid date %q", s)
+		}
+
+		return scimOrderCondition(op, date)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, invalidSCIMFilterError("the value %v must be a string", value)
+	}
+
+	options := "i"
+	if a.caseExact {
+		options = ""
+	}
+
+	switch op {
+	case "eq":
+		if a.caseExact {
+			return s, nil
+		}
+
+		return bson.M{"$regex": "^" + regexp.QuoteMeta(s) + "$", "$options": options}, nil
+	case "ne":
+		if a.caseExact {
+			return bson.M{"$ne": s}, nil
+		}
+
+		return bson.M{"$not": bson.M{"$regex": "^" + regexp.QuoteMeta(s) + "$", "$options": options}}, nil
+	case "co":
+		return bson.M{"$regex": regexp.QuoteMeta(s), "$options": options}, nil
+	case "sw":
+		return bson.M{"$regex": "^" + regexp.QuoteMeta(s), "$options": options}, nil
+	case "ew":
+		return bson.M{"$regex": regexp.QuoteMeta(s) + "$", "$options": options}, nil
+	}
+
+	return scimOrderCondition(op, s)
+}
+
+// scimOrderCondition returns the MongoDB condition of the eq, ne, gt, ge, lt and le operators.
+func scimOrderCondition(op string, value any) (any, error) {
+	operator, found := map[string]string{"eq": "$eq", "ne": "$ne", "gt": "$gt", "ge": "$gte", "lt": "$lt", "le": "$lte"}[op]
+	if !found {
+		return nil, invalidSCIMFilterError("the operator %s is not supported for this attribute", op)
+	}
+
+	return bson.M{operator: value}, nil
+}
+
+// matches evaluates the filter against the sub-attributes of a value of a multi-valued attribute, like
+// the members of a group. Values are compared case-insensitively.
+func (f *scimFilter) matches(values map[string]string) (bool, error) {
+	switch f.op {
+	case scimFilterAnd, scimFilterOr:
+		for _, child := range f.children {
+			matches, err := child.matches(values)
+			if err != nil {
+				return false, err
+			}
+
+			if matches == (f.op == scimFilterOr) {
+				return matches, nil
+			}
+		}
+
+		return f.op == scimFilterAnd, nil
+	case scimFilterNot:
+		matches, err := f.children[0].matches(values)
+
+		return !matches, err
+	case scimFilterValuePath:
+		return false, invalidSCIMFilterError("nested value filters are not supported")
+	}
+
+	actual, found := values[scimAttributePath(f.path)]
+	if f.op == scimFilterPresent {
+		return found && actual != "", nil
+	}
+
+	expected, ok := f.value.(string)
+	if !ok {
+		return false, invalidSCIMFilterError("the value %v must be a string", f.value)
+	}
+
+	actual, expected = strings.ToLower(actual), strings.ToLower(expected)
+
+	switch f.op {
+	case "eq":
+		return found && actual == expected, nil
+	case "ne":
+		return !found || actual != expected, nil
+	case "co":
+		return strings.Contains(actual, expected), nil
+	case "sw":
+		return strings.HasPrefix(actual, expected), nil
+	case "ew":
+		return strings.HasSuffix(actual, expected), nil
+	}
+
+	return false, invalidSCIMFilterError("the operator %s is not supported in value filters", f.op)
+}
+
+// scimAttributePath returns the lowercased path of an attribute, without the URN of its schema.
+func scimAttributePath(path string) string {
+	return strings.ToLower(stripSCIMSchema(path))
+}
+
+func invalidSCIMFilterError(format string, args ...any) error {
+	return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidFilter, "the filter is invalid: "+fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,749 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Type of the emails of the SCIM users: goauth keeps a single email per user.
+const scimEmailType = "work"
+
+// SCIM attributes that can be filtered, by lowercased path.
+var (
+	scimUserAttributes = map[string]scimAttribute{
+		"id":                {field: "_id", caseExact: true},
+		"externalid":        {field: "externalId", caseExact: true},
+		"username":          {field: "userName"},
+		"displayname":       {field: "name"},
+		"name.formatted":    {field: "name"},
+		"name.givenname":    {field: "givenName"},
+		"name.familyname":   {field: "familyName"},
+		"emails":            {field: "email"},
+		"emails.value":      {field: "email"},
+		"active":            {field: "disabled", kind: scimKindBoolean, inverted: true},
+		"meta.created":      {field: "createdAt", kind: scimKindDateTime},
+		"meta.lastmodified": {field: "updatedAt", kind: scimKindDateTime},
+	}
+	scimGroupAttributes = map[string]scimAttribute{
+		"id":                {field: "_id", caseExact: true},
+		"externalid":        {field: "externalId", caseExact: true},
+		"displayname":       {field: "displayName"},
+		"members":           {field: "members", caseExact: true},
+		"members.value":     {field: "members", caseExact: true},
+		"meta.created":      {field: "createdAt", kind: scimKindDateTime},
+		"meta.lastmodified": {field: "updatedAt", kind: scimKindDateTime},
+	}
+)
+
+type SCIMManager interface {
+	// Authenticate returns the SCIM token with the given value, or nil if there is none. The token is
+	// looked up in every organization, the requests being then served by the organization of the token.
+	Authenticate(ctx context.Context, token string) (*model.SCIMToken, error)
+
+	// CreateToken creates a SCIM token for a provisioning client. The token value is only returned here.
+	CreateToken(ctx context.Context, name string) (*model.SCIMTokenCreation, error)
+
+	// Tokens returns the SCIM tokens, without their value.
+	Tokens(ctx context.Context) ([]model.SCIMToken, error)
+
+	// RevokeToken deletes a SCIM token.
+	RevokeToken(ctx context.Context, tokenID string) error
+
+	// Users returns a page of the users matching a SCIM query.
+	Users(ctx context.Context, query model.SCIMQuery) (model.SCIMListResponse, error)
+
+	// User returns a user as a SCIM resource.
+	User(ctx context.Context, userID string) (*model.SCIMUser, error)
+
+	// CreateUser provisions a user. Provisioned users log in through the connections of the organization:
+	// their account is linked on the first login, by email.
+	CreateUser(ctx context.Context, resource model.SCIMUser) (*model.SCIMUser, error)
+
+	// ReplaceUser replaces the attributes of a user. Deactivating a user ends their sessions.
+	ReplaceUser(ctx context.Context, userID string, resource model.SCIMUser) (*model.SCIMUser, error)
+
+	// PatchUser modifies the attributes of a user. Deactivating a user ends their sessions.
+	PatchUser(ctx context.Context, userID string, request model.SCIMPatchRequest) (*model.SCIMUser, error)
+
+	// DeleteUser deletes a user, their upstream accounts and their group memberships, and ends their sessions.
+	// The users who are members of other organizations are only removed from the organization of the context.
+	DeleteUser(ctx context.Context, userID string) error
+
+	// Groups returns a page of the groups matching a SCIM query.
+	Groups(ctx context.Context, query model.SCIMQuery) (model.SCIMListResponse, error)
+
+	// Group returns a group as a SCIM resource.
+	Group(ctx context.Context, groupID string) (*model.SCIMGroup, error)
+
+	// CreateGroup provisions a group.
+	CreateGroup(ctx context.Context, resource model.SCIMGroup) (*model.SCIMGroup, error)
+
+	// ReplaceGroup replaces the attributes and the members of a group.
+	ReplaceGroup(ctx context.Context, groupID string, resource model.SCIMGroup) (*model.SCIMGroup, error)
+
+	// PatchGroup modifies the attributes or the members of a group.
+	PatchGroup(ctx context.Context, groupID string, request model.SCIMPatchRequest) (*model.SCIMGroup, error)
+
+	// DeleteGroup deletes a group.
+	DeleteGroup(ctx context.Context, groupID string) error
+}
+
+type scimManager struct {
+	scimTokenDAO        mongo.CrudDAO[model.SCIMToken]
+	userDAO             mongo.CrudDAO[model.User]
+	identityDAO         mongo.CrudDAO[model.Identity]
+	groupDAO            mongo.CrudDAO[model.Group]
+	sessionManager      SessionManager
+	organizationManager OrganizationManager
+	auditManager        AuditManager
+}
+
+func (m *scimManager) Authenticate(ctx context.Context, token string) (*model.SCIMToken, error) {
+	ctx = tenant.WithAllOrganizations(ctx)
+
+	scimToken, err := m.scimTokenDAO.FindOne(ctx, bson.M{"tokenHash": security.HashToken(token)}, nil)
+	if err != nil || scimToken == nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	if _, err = m.scimTokenDAO.Update(ctx, bson.M{"_id": scimToken.ID}, bson.M{"$set": bson.M{"lastUsedAt": now}}, false); err != nil {
+		return nil, err
+	}
+
+	scimToken.LastUsedAt = &now
+
+	return scimToken, nil
+}
+
+func (m *scimManager) CreateToken(ctx context.Context, name string) (*model.SCIMTokenCreation, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, model.NewAPIResponseError(http.StatusBadRequest, "the name of the token is required")
+	}
+
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	token := model.SCIMToken{
+		ID:        id,
+		Name:      name,
+		TokenHash: security.HashToken(value),
+		CreatedAt: time.Now(),
+	}
+
+	if _, err = m.scimTokenDAO.Create(ctx, &token); err != nil {
+		return nil, err
+	}
+
+	return &model.SCIMTokenCreation{SCIMToken: token, Token: value}, nil
+}
+
+func (m *scimManager) Tokens(ctx context.Context) ([]model.SCIMToken, error) {
+	return m.scimTokenDAO.FindMany(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+}
+
+func (m *scimManager) RevokeToken(ctx context.Context, tokenID string) error {
+	deleted, err := m.scimTokenDAO.Delete(ctx, bson.M{"_id": tokenID})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return model.NewAPIResponseError(http.StatusNotFound, "the token does not exist")
+	}
+
+	return nil
+}
+
+func (m *scimManager) Users(ctx context.Context, query model.SCIMQuery) (model.SCIMListResponse, error) {
+	filter, err := scimQueryFilter(query, scimUserAttributes)
+	if err != nil {
+		return model.SCIMListResponse{}, err
+	}
+
+	total := m.userDAO.Count(ctx, filter)
+	if total < 0 {
+		return model.SCIMListResponse{}, errors.New("could not count the users")
+	}
+
+	users := make([]model.User, 0)
+
+	if query.Count > 0 {
+		if users, err = m.userDAO.FindMany(ctx, filter, scimPage(query)); err != nil {
+			return model.SCIMListResponse{}, err
+		}
+	}
+
+	userIDs := make([]string, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+	}
+
+	groups, err := m.groupDAO.FindMany(ctx, bson.M{"members": bson.M{"$in": userIDs}}, nil)
+	if err != nil {
+		return model.SCIMListResponse{}, err
+	}
+
+	resources := make([]any, 0, len(users))
+	for i := range users {
+		resources = append(resources, scimUser(&users[i], groups))
+	}
+
+	return scimListResponse(query, total, resources), nil
+}
+
+func (m *scimManager) User(ctx context.Context, userID string) (*model.SCIMUser, error) {
+	user, err := m.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.scimUserWithGroups(ctx, user)
+}
+
+func (m *scimManager) CreateUser(ctx context.Context, resource model.SCIMUser) (*model.SCIMUser, error) {
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	user := &model.User{
+		ID:        id,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err = applySCIMUser(user, resource); err != nil {
+		return nil, err
+	}
+
+	created, err := m.userDAO.Create(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		return nil, model.NewSCIMError(http.StatusConflict, model.SCIMErrorUniqueness, "the userName is already used")
+	}
+
+	m.auditUser(ctx, model.AuditEventUserCreate, user.ID)
+
+	resourceUser := scimUser(user, nil)
+
+	return &resourceUser, nil
+}
+
+func (m *scimManager) ReplaceUser(ctx context.Context, userID string, resource model.SCIMUser) (*model.SCIMUser, error) {
+	user, err := m.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.updateUser(ctx, user, resource)
+}
+
+func (m *scimManager) PatchUser(ctx context.Context, userID string, request model.SCIMPatchRequest) (*model.SCIMUser, error) {
+	user, err := m.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var resource model.SCIMUser
+	if err = patchSCIMResource(scimUser(user, nil), request, &resource); err != nil {
+		return nil, err
+	}
+
+	return m.updateUser(ctx, user, resource)
+}
+
+func (m *scimManager) DeleteUser(ctx context.Context, userID string) error {
+	user, err := m.userDAO.FindOne(ctx, bson.M{"_id": userID}, nil)
+	if err != nil {
+		return err
+	}
+
+	if user != nil && slices.ContainsFunc(user.Memberships, func(membership model.Membership) bool {
+		return membership.OrganizationID != tenant.OrganizationID(ctx)
+	}) {
+		return m.organizationManager.RemoveMember(ctx, tenant.OrganizationID(ctx), userID)
+	}
+
+	deleted, err := m.userDAO.Delete(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return scimNotFoundError(model.SCIMResourceTypeUser)
+	}
+
+	m.auditUser(ctx, model.AuditEventUserDelete, userID)
+
+	if err = m.sessionManager.EndAll(ctx, userID); err != nil {
+		return err
+	}
+
+	if _, err = m.identityDAO.DeleteMany(ctx, bson.M{"userId": userID}); err != nil {
+		return err
+	}
+
+	groups, err := m.groupDAO.FindMany(ctx, bson.M{"members": userID}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		update := bson.M{"$pull": bson.M{"members": userID}, "$set": bson.M{"updatedAt": time.Now()}}
+		if _, err = m.groupDAO.Update(ctx, bson.M{"_id": group.ID}, update, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *scimManager) Groups(ctx context.Context, query model.SCIMQuery) (model.SCIMListResponse, error) {
+	filter, err := scimQueryFilter(query, scimGroupAttributes)
+	if err != nil {
+		return model.SCIMListResponse{}, err
+	}
+
+	total := m.groupDAO.Count(ctx, filter)
+	if total < 0 {
+		return model.SCIMListResponse{}, errors.New("could not count the groups")
+	}
+
+	groups := make([]model.Group, 0)
+
+	if query.Count > 0 {
+		if groups, err = m.groupDAO.FindMany(ctx, filter, scimPage(query)); err != nil {
+			return model.SCIMListResponse{}, err
+		}
+	}
+
+	resources := make([]any, 0, len(groups))
+	for i := range groups {
+		resources = append(resources, scimGroup(&groups[i]))
+	}
+
+	return scimListResponse(query, total, resources), nil
+}
+
+func (m *scimManager) Group(ctx context.Context, groupID string) (*model.SCIMGroup, error) {
+	group, err := m.group(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := scimGroup(group)
+
+	return &resource, nil
+}
+
+func (m *scimManager) CreateGroup(ctx context.Context, resource model.SCIMGroup) (*model.SCIMGroup, error) {
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	group := &model.Group{
+		ID:        id,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err = m.applySCIMGroup(ctx, group, resource); err != nil {
+		return nil, err
+	}
+
+	if _, err = m.groupDAO.Create(ctx, group); err != nil {
+		return nil, err
+	}
+
+	resourceGroup := scimGroup(group)
+
+	return &resourceGroup, nil
+}
+
+func (m *scimManager) ReplaceGroup(ctx context.Context, groupID string, resource model.SCIMGroup) (*model.SCIMGroup, error) {
+	group, err := m.group(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.updateGroup(ctx, group, resource)
+}
+
+func (m *scimManager) PatchGroup(ctx context.Context, groupID string, request model.SCIMPatchRequest) (*model.SCIMGroup, error) {
+	group, err := m.group(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var resource model.SCIMGroup
+	if err = patchSCIMResource(scimGroup(group), request, &resource); err != nil {
+		return nil, err
+	}
+
+	return m.updateGroup(ctx, group, resource)
+}
+
+func (m *scimManager) DeleteGroup(ctx context.Context, groupID string) error {
+	deleted, err := m.groupDAO.Delete(ctx, bson.M{"_id": groupID})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return scimNotFoundError(model.SCIMResourceTypeGroup)
+	}
+
+	return removeMemberGroup(ctx, m.groupDAO, groupID)
+}
+
+// user returns the user with the given identifier, or a SCIM not found error.
+func (m *scimManager) user(ctx context.Context, userID string) (*model.User, error) {
+	user, err := m.userDAO.FindOne(ctx, bson.M{"_id": userID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, scimNotFoundError(model.SCIMResourceTypeUser)
+	}
+
+	return user, nil
+}
+
+// group returns the group with the given identifier, or a SCIM not found error.
+func (m *scimManager) group(ctx context.Context, groupID string) (*model.Group, error) {
+	group, err := m.groupDAO.FindOne(ctx, bson.M{"_id": groupID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if group == nil {
+		return nil, scimNotFoundError(model.SCIMResourceTypeGroup)
+	}
+
+	return group, nil
+}
+
+// updateUser saves the attributes of a SCIM resource to a user. The sessions of deactivated users are ended.
+func (m *scimManager) updateUser(ctx context.Context, user *model.User, resource model.SCIMUser) (*model.SCIMUser, error) {
+	wasDisabled := user.Disabled
+
+	if err := applySCIMUser(user, resource); err != nil {
+		return nil, err
+	}
+
+	user.UpdatedAt = time.Now()
+
+	res, err := m.userDAO.Update(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{
+		"userName":      user.UserName,
+		"externalId":    user.ExternalID,
+		"email":         user.Email,
+		"emailVerified": user.EmailVerified,
+		"name":          user.Name,
+		"givenName":     user.GivenName,
+		"familyName":    user.FamilyName,
+		"picture":       user.Picture,
+		"disabled":      user.Disabled,
+		"updatedAt":     user.UpdatedAt,
+	}}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.UniqueError {
+		return nil, model.NewSCIMError(http.StatusConflict, model.SCIMErrorUniqueness, "the userName is already used")
+	}
+
+	if res.NotFound {
+		return nil, scimNotFoundError(model.SCIMResourceTypeUser)
+	}
+
+	m.auditUser(ctx, model.AuditEventUserUpdate, user.ID)
+
+	if user.Disabled && !wasDisabled {
+		if err = m.sessionManager.EndAll(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.scimUserWithGroups(ctx, user)
+}
+
+// updateGroup saves the attributes and the members of a SCIM resource to a group.
+func (m *scimManager) updateGroup(ctx context.Context, group *model.Group, resource model.SCIMGroup) (*model.SCIMGroup, error) {
+	if err := m.applySCIMGroup(ctx, group, resource); err != nil {
+		return nil, err
+	}
+
+	group.UpdatedAt = time.Now()
+
+	res, err := m.groupDAO.Update(ctx, bson.M{"_id": group.ID}, bson.M{"$set": bson.M{
+		"displayName": group.DisplayName,
+		"externalId":  group.ExternalID,
+		"members":     group.Members,
+		"updatedAt":   group.UpdatedAt,
+	}}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.NotFound {
+		return nil, scimNotFoundError(model.SCIMResourceTypeGroup)
+	}
+
+	resourceGroup := scimGroup(group)
+
+	return &resourceGroup, nil
+}
+
+// applySCIMGroup sets the attributes of a SCIM resource to a group. Members must be existing users.
+func (m *scimManager) applySCIMGroup(ctx context.Context, group *model.Group, resource model.SCIMGroup) error {
+	if strings.TrimSpace(resource.DisplayName) == "" {
+		return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidValue, "the displayName is required")
+	}
+
+	members := make([]string, 0, len(resource.Members))
+
+	for _, member := range resource.Members {
+		if member.Value != "" && !slices.Contains(members, member.Value) {
+			members = append(members, member.Value)
+		}
+	}
+
+	if len(members) > 0 && m.userDAO.Count(ctx, bson.M{"_id": bson.M{"$in": members}}) != int64(len(members)) {
+		return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidValue, "the members must be existing users")
+	}
+
+	group.DisplayName = resource.DisplayName
+	group.ExternalID = resource.ExternalID
+	group.Members = members
+
+	return nil
+}
+
+// scimUserWithGroups returns a user as a SCIM resource, with the groups it belongs to.
+func (m *scimManager) scimUserWithGroups(ctx context.Context, user *model.User) (*model.SCIMUser, error) {
+	groups, err := m.groupDAO.FindMany(ctx, bson.M{"members": user.ID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := scimUser(user, groups)
+
+	return &resource, nil
+}
+
+// applySCIMUser sets the attributes of a SCIM resource to a user. The name of the user is the display
+// name, or the formatted name. The primary email is kept, and trusted as verified since it is managed by
+// the organization.
+func applySCIMUser(user *model.User, resource model.SCIMUser) error {
+	if strings.TrimSpace(resource.UserName) == "" {
+		return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidValue, "the userName is required")
+	}
+
+	user.UserName = resource.UserName
+	user.ExternalID = resource.ExternalID
+	user.Name = resource.DisplayName
+	user.GivenName = ""
+	user.FamilyName = ""
+
+	if resource.Name != nil {
+		user.GivenName = resource.Name.GivenName
+		user.FamilyName = resource.Name.FamilyName
+
+		if user.Name == "" {
+			user.Name = resource.Name.Formatted
+		}
+
+		if user.Name == "" {
+			user.Name = strings.TrimSpace(resource.Name.GivenName + " " + resource.Name.FamilyName)
+		}
+	}
+
+	user.Email = strings.ToLower(primarySCIMValue(resource.Emails))
+	user.EmailVerified = user.Email != ""
+	user.Picture = primarySCIMValue(resource.Photos)
+	user.Disabled = resource.Active != nil && !*resource.Active
+
+	return nil
+}
+
+// primarySCIMValue returns the primary value of a multi-valued attribute, or its first value.
+func primarySCIMValue(values []model.SCIMMultiValue) string {
+	for _, value := range values {
+		if value.Primary {
+			return value.Value
+		}
+	}
+
+	if len(values) > 0 {
+		return values[0].Value
+	}
+
+	return ""
+}
+
+// scimUser returns a user as a SCIM resource. Its groups are the given groups it is a member of.
+func scimUser(user *model.User, groups []model.Group) model.SCIMUser {
+	active := !user.Disabled
+
+	resource := model.SCIMUser{
+		Schemas:     []string{model.SCIMSchemaUser},
+		ID:          user.ID,
+		ExternalID:  user.ExternalID,
+		UserName:    user.UserName,
+		DisplayName: user.Name,
+		Active:      &active,
+		Meta:        scimMeta(model.SCIMResourceTypeUser, "/Users/"+user.ID, user.CreatedAt, user.UpdatedAt),
+	}
+
+	if resource.UserName == "" {
+		resource.UserName = user.Email
+	}
+
+	if user.GivenName != "" || user.FamilyName != "" {
+		resource.Name = &model.SCIMName{Formatted: user.Name, GivenName: user.GivenName, FamilyName: user.FamilyName}
+	}
+
+	if user.Email != "" {
+		resource.Emails = []model.SCIMMultiValue{{Value: user.Email, Type: scimEmailType, Primary: true}}
+	}
+
+	if user.Picture != "" {
+		resource.Photos = []model.SCIMMultiValue{{Value: user.Picture, Type: "photo", Primary: true}}
+	}
+
+	for _, group := range groups {
+		if slices.Contains(group.Members, user.ID) {
+			resource.Groups = append(resource.Groups, model.SCIMMember{
+				Value:   group.ID,
+				Ref:     config.SCIMEndpoint("/Groups/" + group.ID),
+				Display: group.DisplayName,
+			})
+		}
+	}
+
+	return resource
+}
+
+// scimGroup returns a group as a SCIM resource.
+func scimGroup(group *model.Group) model.SCIMGroup {
+	resource := model.SCIMGroup{
+		Schemas:     []string{model.SCIMSchemaGroup},
+		ID:          group.ID,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Meta:        scimMeta(model.SCIMResourceTypeGroup, "/Groups/"+group.ID, group.CreatedAt, group.UpdatedAt),
+	}
+
+	for _, member := range group.Members {
+		resource.Members = append(resource.Members, model.SCIMMember{Value: member, Ref: config.SCIMEndpoint("/Users/" + member)})
+	}
+
+	return resource
+}
+
+func scimMeta(resourceType, path string, created, lastModified time.Time) *model.SCIMMeta {
+	return &model.SCIMMeta{
+		ResourceType: resourceType,
+		Created:      &created,
+		LastModified: &lastModified,
+		Location:     config.SCIMEndpoint(path),
+	}
+}
+
+// scimQueryFilter returns the MongoDB filter of a SCIM query.
+func scimQueryFilter(query model.SCIMQuery, attributes map[string]scimAttribute) (bson.M, error) {
+	if query.Filter == "" {
+		return bson.M{}, nil
+	}
+
+	filter, err := parseSCIMFilter(query.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return filter.bson(attributes)
+}
+
+// scimPage returns the options selecting the page of a SCIM query. Resources are sorted by creation,
+// so that pages are stable.
+func scimPage(query model.SCIMQuery) *options.FindOptions {
+	return options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64(query.StartIndex - 1)).
+		SetLimit(int64(query.Count))
+}
+
+// auditUser records a change of a user by a provisioning client in the audit log.
+func (m *scimManager) auditUser(ctx context.Context, eventType, userID string) {
+	m.auditManager.Record(ctx, model.AuditEvent{
+		Type:    eventType,
+		Outcome: model.AuditOutcomeSuccess,
+		Target:  &model.AuditTarget{Type: model.AuditTargetUser, ID: userID},
+		Details: map[string]string{"source": "scim"},
+	})
+}
+
+func scimListResponse(query model.SCIMQuery, total int64, resources []any) model.SCIMListResponse {
+	return model.SCIMListResponse{
+		Schemas:      []string{model.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   query.StartIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func scimNotFoundError(resourceType string) error {
+	return model.NewSCIMError(http.StatusNotFound, "", "the "+strings.ToLower(resourceType)+" does not exist")
+}
+
+func NewSCIMManager(
+	scimTokenDAO mongo.CrudDAO[model.SCIMToken],
+	userDAO mongo.CrudDAO[model.User],
+	identityDAO mongo.CrudDAO[model.Identity],
+	groupDAO mongo.CrudDAO[model.Group],
+	sessionManager SessionManager,
+	organizationManager OrganizationManager,
+	auditManager AuditManager,
+) SCIMManager {
+	return &scimManager{
+		scimTokenDAO:        scimTokenDAO,
+		userDAO:             userDAO,
+		identityDAO:         identityDAO,
+		groupDAO:            groupDAO,
+		sessionManager:      sessionManager,
+		organizationManager: organizationManager,
+		auditManager:        auditManager,
+	}
+}
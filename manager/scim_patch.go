@@ -0,0 +1,400 @@
+package manager
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/m3talux/goauth/model"
+)
+
+// Multi-valued attributes of the SCIM resources, and the attributes that can't be modified.
+var (
+	scimMultiValuedAttributes = map[string]bool{"emails": true, "photos": true, "members": true, "groups": true}
+	scimReadOnlyAttributes    = map[string]bool{"id": true, "schemas": true, "meta": true, "groups": true}
+)
+
+// scimPath is a parsed attribute path of a PATCH operation (RFC 7644, section 3.5.2), like name.givenName
+// or emails[type eq "work"].value.
+type scimPath struct {
+	attribute    string
+	filter       *scimFilter
+	subAttribute string
+}
+
+// patchSCIMResource applies the operations of a PATCH request to the JSON representation of a resource.
+func patchSCIMResource(resource any, request model.SCIMPatchRequest, patched any) error {
+	if len(request.Schemas) != 1 || request.Schemas[0] != model.SCIMSchemaPatchOp {
+		return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidSyntax, "the request must use the PatchOp schema")
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+
+	var document map[string]any
+	if err = json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+
+	for _, operation := range request.Operations {
+		if err = applySCIMOperation(document, operation); err != nil {
+			return err
+		}
+	}
+
+	if data, err = json.Marshal(document); err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(data, patched); err != nil {
+		return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidValue, "the patched resource is invalid: "+err.Error())
+	}
+
+	return nil
+}
+
+// applySCIMOperation applies an operation to a resource. Operations without path hold an object whose
+// members are applied as if they were given by path.
+func applySCIMOperation(document map[string]any, operation model.SCIMPatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	if op != model.SCIMPatchOpAdd && op != model.SCIMPatchOpRemove && op != model.SCIMPatchOpReplace {
+		return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidSyntax, "the operation "+operation.Op+" is not supported")
+	}
+
+	var value any
+	if len(operation.Value) > 0 {
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidValue, "the value of the operation is invalid")
+		}
+	}
+
+	if operation.Path != "" {
+		return applySCIMPath(document, op, operation.Path, value)
+	}
+
+	if op == model.SCIMPatchOpRemove {
+		return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorNoTarget, "a path is required to remove attributes")
+	}
+
+	values, ok := value.(map[string]any)
+	if !ok {
+		return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidValue, "the value of an operation without path must be an object")
+	}
+
+	for path, v := range values {
+		// Complex attributes without path are merged, not replaced
+		if subValues, isMap := v.(map[string]any); isMap && !scimMultiValuedAttributes[scimAttributePath(path)] {
+			for subAttribute, subValue := range subValues {
+				if err := applySCIMPath(document, op, path+"."+subAttribute, subValue); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		if err := applySCIMPath(document, op, path, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applySCIMPath(document map[string]any, op, rawPath string, value any) error {
+	path, err := parseSCIMPath(rawPath)
+	if err != nil {
+		return err
+	}
+
+	if scimReadOnlyAttributes[path.attribute] {
+		return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorMutability, "the attribute "+path.attribute+" can't be modified")
+	}
+
+	// Some clients, like Azure AD, send booleans as strings
+	if s, ok := value.(string); ok && path.attribute == "active" {
+		if b, parseErr := strconv.ParseBool(s); parseErr == nil {
+			value = b
+		}
+	}
+
+	key := scimKey(document, path.attribute)
+
+	if path.filter != nil {
+		return applySCIMValuePath(document, key, op, path, value)
+	}
+
+	if path.subAttribute != "" {
+		return applySCIMSubAttribute(document, key, op, path, value)
+	}
+
+	switch op {
+	case model.SCIMPatchOpRemove:
+		if values, ok := value.([]any); ok && scimMultiValuedAttributes[path.attribute] {
+			document[key] = removeSCIMValues(document[key], values)
+		} else {
+			delete(document, key)
+		}
+	case model.SCIMPatchOpAdd:
+		if scimMultiValuedAttributes[path.attribute] {
+			document[key] = addSCIMValues(document[key], value)
+		} else {
+			document[key] = value
+		}
+	default:
+		document[key] = value
+	}
+
+	return nil
+}
+
+// applySCIMSubAttribute modifies a sub-attribute of a complex attribute, or of every value of a
+// multi-valued attribute.
+func applySCIMSubAttribute(document map[string]any, key, op string, path scimPath, value any) error {
+	if values, ok := document[key].([]any); ok {
+		for _, v := range values {
+			if element, isMap := v.(map[string]any); isMap {
+				setSCIMValue(element, op, path.subAttribute, value)
+			}
+		}
+
+		if len(values) == 0 && op != model.SCIMPatchOpRemove {
+			document[key] = []any{map[string]any{path.subAttribute: value}}
+		}
+
+		return nil
+	}
+
+	element, ok := document[key].(map[string]any)
+	if !ok {
+		if op == model.SCIMPatchOpRemove {
+			return nil
+		}
+
+		element = make(map[string]any)
+
+		if scimMultiValuedAttributes[path.attribute] {
+			document[key] = []any{element}
+		} else {
+			document[key] = element
+		}
+	}
+
+	setSCIMValue(element, op, path.subAttribute, value)
+
+	return nil
+}
+
+// applySCIMValuePath modifies the values of a multi-valued attribute matching the filter of the path.
+// Replacing a missing value of an equality filter, like emails[type eq "work"].value, adds it.
+func applySCIMValuePath(document map[string]any, key, op string, path scimPath, value any) error {
+	values, _ := document[key].([]any)
+
+	remaining := make([]any, 0, len(values))
+	matched := false
+
+	for _, v := range values {
+		element, ok := v.(map[string]any)
+		if !ok {
+			remaining = append(remaining, v)
+
+			continue
+		}
+
+		matches, err := path.filter.matches(scimStringValues(element))
+		if err != nil {
+			return err
+		}
+
+		if !matches {
+			remaining = append(remaining, v)
+
+			continue
+		}
+
+		matched = true
+
+		switch {
+		case op == model.SCIMPatchOpRemove && path.subAttribute == "":
+			continue
+		case path.subAttribute != "":
+			setSCIMValue(element, op, path.subAttribute, value)
+		default:
+			if replacement, isMap := value.(map[string]any); isMap {
+				for name, v := range replacement {
+					element[scimKey(element, strings.ToLower(name))] = v
+				}
+			}
+		}
+
+		remaining = append(remaining, element)
+	}
+
+	if !matched && op != model.SCIMPatchOpRemove {
+		if path.filter.op != "eq" || path.subAttribute == "" {
+			return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorNoTarget, "no value matches the path")
+		}
+
+		filterValue, ok := path.filter.value.(string)
+		if !ok {
+			return model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorNoTarget, "no value matches the path")
+		}
+
+		remaining = append(remaining, map[string]any{scimAttributePath(path.filter.path): filterValue, path.subAttribute: value})
+	}
+
+	document[key] = remaining
+
+	return nil
+}
+
+// parseSCIMPath parses the attribute path of a PATCH operation.
+func parseSCIMPath(rawPath string) (scimPath, error) {
+	invalidPathError := model.NewSCIMError(http.StatusBadRequest, model.SCIMErrorInvalidPath, "the path "+rawPath+" is invalid")
+
+	tokens, err := scimTokens(stripSCIMSchema(rawPath))
+	if err != nil || len(tokens) == 0 {
+		return scimPath{}, invalidPathError
+	}
+
+	if len(tokens) == 1 {
+		attribute, subAttribute, _ := strings.Cut(strings.ToLower(tokens[0]), ".")
+
+		return scimPath{attribute: attribute, subAttribute: subAttribute}, nil
+	}
+
+	if tokens[1] != "[" {
+		return scimPath{}, invalidPathError
+	}
+
+	end := len(tokens) - 1
+
+	subAttribute := ""
+	if strings.HasPrefix(tokens[end], ".") {
+		subAttribute = strings.ToLower(strings.TrimPrefix(tokens[end], "."))
+		end--
+	}
+
+	if tokens[end] != "]" {
+		return scimPath{}, invalidPathError
+	}
+
+	p := &scimFilterParser{tokens: tokens[2:end]}
+
+	filter, err := p.parseOr()
+	if err != nil {
+		return scimPath{}, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return scimPath{}, invalidPathError
+	}
+
+	return scimPath{attribute: strings.ToLower(tokens[0]), filter: filter, subAttribute: subAttribute}, nil
+}
+
+// setSCIMValue sets or removes an attribute of a complex value.
+func setSCIMValue(element map[string]any, op, name string, value any) {
+	key := scimKey(element, name)
+
+	if op == model.SCIMPatchOpRemove {
+		delete(element, key)
+	} else {
+		element[key] = value
+	}
+}
+
+// addSCIMValues adds values to a multi-valued attribute, ignoring the values it already holds.
+func addSCIMValues(current, added any) any {
+	values, _ := current.([]any)
+
+	newValues, ok := added.([]any)
+	if !ok {
+		newValues = []any{added}
+	}
+
+	for _, newValue := range newValues {
+		if !containsSCIMValue(values, newValue) {
+			values = append(values, newValue)
+		}
+	}
+
+	return values
+}
+
+// removeSCIMValues removes the given values from a multi-valued attribute.
+func removeSCIMValues(current any, removed []any) any {
+	values, _ := current.([]any)
+
+	remaining := make([]any, 0, len(values))
+
+	for _, value := range values {
+		if !containsSCIMValue(removed, value) {
+			remaining = append(remaining, value)
+		}
+	}
+
+	return remaining
+}
+
+// containsSCIMValue returns true if one of the values is the given value, comparing the value sub-attribute
+// of complex values.
+func containsSCIMValue(values []any, value any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(scimComparableValue(v), scimComparableValue(value)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func scimComparableValue(value any) any {
+	if element, ok := value.(map[string]any); ok {
+		return element[scimKey(element, "value")]
+	}
+
+	return value
+}
+
+// scimStringValues returns the string attributes of a complex value, by lowercased name.
+func scimStringValues(element map[string]any) map[string]string {
+	values := make(map[string]string, len(element))
+
+	for name, value := range element {
+		switch v := value.(type) {
+		case string:
+			values[strings.ToLower(name)] = v
+		case bool:
+			values[strings.ToLower(name)] = strconv.FormatBool(v)
+		}
+	}
+
+	return values
+}
+
+// scimKey returns the key of an attribute in a JSON object, attribute names being case-insensitive.
+func scimKey(document map[string]any, name string) string {
+	for key := range document {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+
+	return name
+}
+
+// stripSCIMSchema removes the URN of the core schemas from an attribute path.
+func stripSCIMSchema(path string) string {
+	for _, schema := range []string{model.SCIMSchemaUser, model.SCIMSchemaGroup} {
+		if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+			return path[len(schema)+1:]
+		}
+	}
+
+	return path
+}
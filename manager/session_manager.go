@@ -0,0 +1,287 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Delay before the first retry of a failed back-channel logout, doubled after each attempt.
+const backchannelLogoutRetryDelay = time.Second
+
+type SessionManager interface {
+	// Create opens a session for an end-user who has just been authenticated.
+	Create(ctx context.Context, subject string) (*model.Session, error)
+
+	// Get returns the active session with the given identifier, or nil if there is none.
+	Get(ctx context.Context, sessionID string) (*model.Session, error)
+
+	// AddClient records that the client received tokens within the session.
+	AddClient(ctx context.Context, sessionID, clientID string) error
+
+	// End terminates a session: the tokens issued within the session are revoked, and its clients are
+	// notified through back-channel logout, in the background. It returns the front-channel logout URIs
+	// of its clients, to be rendered by the user-agent.
+	End(ctx context.Context, sessionID string) ([]string, error)
+
+	// EndAll terminates every session of an end-user in the organization of the context, like End, and
+	// revokes the tokens issued to them outside of a session, and their personal access tokens. It is used
+	// when the account is disabled or deleted, or when the end-user leaves the organization.
+	EndAll(ctx context.Context, subject string) error
+}
+
+type sessionManager struct {
+	sessionDAO             mongo.CrudDAO[model.Session]
+	tokenDAO               mongo.CrudDAO[model.Token]
+	personalAccessTokenDAO mongo.CrudDAO[model.PersonalAccessToken]
+	clientManager          ClientManager
+	keyManager             KeyManager
+	auditManager           AuditManager
+	httpClient             *http.Client
+}
+
+func (m *sessionManager) Create(ctx context.Context, subject string) (*model.Session, error) {
+	id, err := security.RandomToken(requestIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	session := &model.Session{
+		ID:        id,
+		Subject:   subject,
+		ClientIDs: []string{},
+		AuthTime:  now,
+		CreatedAt: now,
+		ExpiresAt: now.Add(config.SessionLifetime()),
+	}
+
+	if _, err = m.sessionDAO.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (m *sessionManager) Get(ctx context.Context, sessionID string) (*model.Session, error) {
+	session, err := m.sessionDAO.FindOne(ctx, bson.M{"_id": sessionID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil || !session.IsActive(time.Now()) {
+		//nolint:nilnil // An unknown or expired session is not an error
+		return nil, nil
+	}
+
+	return session, nil
+}
+
+func (m *sessionManager) AddClient(ctx context.Context, sessionID, clientID string) error {
+	_, err := m.sessionDAO.Update(ctx, bson.M{"_id": sessionID}, bson.M{"$addToSet": bson.M{"clientIds": clientID}}, false)
+
+	return err
+}
+
+func (m *sessionManager) End(ctx context.Context, sessionID string) ([]string, error) {
+	session, err := m.Get(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil {
+		return nil, nil
+	}
+
+	if _, err = m.sessionDAO.Delete(ctx, bson.M{"_id": session.ID}); err != nil {
+		return nil, err
+	}
+
+	if _, err = m.tokenDAO.DeleteMany(ctx, bson.M{"sessionId": session.ID}); err != nil {
+		return nil, err
+	}
+
+	frontchannelLogoutURIs := make([]string, 0)
+
+	for _, clientID := range session.ClientIDs {
+		client, getErr := m.clientManager.Get(ctx, clientID)
+		if getErr != nil || client == nil {
+			continue
+		}
+
+		if client.BackchannelLogoutURI != "" {
+			// The back-channel logout must not be canceled along with the end-user request
+			go m.sendLogoutToken(context.WithoutCancel(ctx), client, session)
+		}
+
+		if client.FrontchannelLogoutURI != "" {
+			frontchannelLogoutURIs = append(frontchannelLogoutURIs, frontchannelLogoutURI(client, session))
+		}
+	}
+
+	log.Info().Str("subject", session.Subject).Int("clients", len(session.ClientIDs)).Msg("Session ended")
+
+	m.auditManager.Record(ctx, model.AuditEvent{
+		Type:    model.AuditEventLogout,
+		Outcome: model.AuditOutcomeSuccess,
+		Actor:   model.AuditActor{Subject: session.Subject},
+		Details: map[string]string{"clients": strconv.Itoa(len(session.ClientIDs))},
+	})
+
+	return frontchannelLogoutURIs, nil
+}
+
+func (m *sessionManager) EndAll(ctx context.Context, subject string) error {
+	sessions, err := m.sessionDAO.FindMany(ctx, bson.M{"subject": subject}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if _, err = m.End(ctx, session.ID); err != nil {
+			return err
+		}
+	}
+
+	if _, err = m.tokenDAO.DeleteMany(ctx, bson.M{"subject": subject}); err != nil {
+		return err
+	}
+
+	_, err = m.personalAccessTokenDAO.DeleteMany(ctx, bson.M{"subject": subject})
+
+	return err
+}
+
+// sendLogoutToken posts a logout token to the back-channel logout URI of the client, and retries
+// with an exponential backoff until the client acknowledges it.
+func (m *sessionManager) sendLogoutToken(ctx context.Context, client *model.Client, session *model.Session) {
+	logoutToken, err := m.logoutToken(client, session)
+	if err != nil {
+		log.Err(err).Str("clientId", client.ID).Msg("Could not create logout token")
+
+		return
+	}
+
+	delay := backchannelLogoutRetryDelay
+
+	for attempt := 1; attempt <= config.BackchannelLogoutMaxAttempts(); attempt++ {
+		if err = m.postLogoutToken(ctx, client.BackchannelLogoutURI, logoutToken); err == nil {
+			log.Debug().Str("clientId", client.ID).Int("attempt", attempt).Msg("Successfully sent logout token")
+
+			return
+		}
+
+		log.Warn().Err(err).Str("clientId", client.ID).Int("attempt", attempt).Msg("Could not send logout token")
+
+		// No delay after the last attempt, the failure is reported right away
+		if attempt == config.BackchannelLogoutMaxAttempts() {
+			break
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	log.Error().Str("clientId", client.ID).Msg("Back-channel logout failed after all attempts")
+}
+
+func (m *sessionManager) postLogoutToken(ctx context.Context, uri, logoutToken string) error {
+	body := strings.NewReader(url.Values{"logout_token": {logoutToken}}.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("the client responded with the status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// logoutToken creates the logout token of a client (OpenID Connect Back-Channel Logout 1.0, section 2.4).
+func (m *sessionManager) logoutToken(client *model.Client, session *model.Session) (string, error) {
+	jti, err := security.RandomToken(requestIDSize)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	claims := model.LogoutTokenClaims{
+		Claims: jwt.Claims{
+			Issuer:   config.OrganizationIssuer(session.OrganizationID),
+			Subject:  session.Subject,
+			Audience: jwt.Audience{client.ID},
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(config.LogoutTokenLifetime())),
+			ID:       jti,
+		},
+		SessionID: session.ID,
+		Events:    map[string]map[string]any{model.BackchannelLogoutEvent: {}},
+	}
+
+	return m.keyManager.Sign(claims, model.JWTTypeLogoutToken)
+}
+
+// frontchannelLogoutURI returns the front-channel logout URI of a client, with the issuer and session
+// identifier (OpenID Connect Front-Channel Logout 1.0, section 2).
+func frontchannelLogoutURI(client *model.Client, session *model.Session) string {
+	if !client.FrontchannelLogoutSessionRequired {
+		return client.FrontchannelLogoutURI
+	}
+
+	u, err := url.Parse(client.FrontchannelLogoutURI)
+	if err != nil {
+		return client.FrontchannelLogoutURI
+	}
+
+	query := u.Query()
+	query.Set("iss", config.OrganizationIssuer(session.OrganizationID))
+	query.Set("sid", session.ID)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+func NewSessionManager(
+	sessionDAO mongo.CrudDAO[model.Session],
+	tokenDAO mongo.CrudDAO[model.Token],
+	personalAccessTokenDAO mongo.CrudDAO[model.PersonalAccessToken],
+	clientManager ClientManager,
+	keyManager KeyManager,
+	auditManager AuditManager,
+) SessionManager {
+	return &sessionManager{
+		sessionDAO:             sessionDAO,
+		tokenDAO:               tokenDAO,
+		personalAccessTokenDAO: personalAccessTokenDAO,
+		clientManager:          clientManager,
+		keyManager:             keyManager,
+		auditManager:           auditManager,
+		httpClient:             &http.Client{Timeout: config.ConnectionTimeout()},
+	}
+}
@@ -0,0 +1,580 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Maximum size of the responses read from upstream providers.
+const upstreamResponseMaxSize = 1 << 20
+
+type SocialLoginManager interface {
+	// Connections returns the enabled connections, to be listed on the login page.
+	Connections(ctx context.Context) ([]model.LoginConnection, error)
+
+	// Start begins a login on the upstream provider of a connection, for the authorization request of the
+	// login challenge, or to link the upstream account to the user linkUserID. It returns the authorization
+	// URL of the provider, and the state that the callback must come back with, to be bound to the user-agent.
+	Start(ctx context.Context, connectionID, loginChallenge, linkUserID string) (string, string, error)
+
+	// Callback completes a login on the upstream provider of a connection: the state is checked against the
+	// one bound to the user-agent, the authorization code is exchanged, and the ID token is verified. For
+	// SAML connections, the params are the ones posted to the assertion consumer service instead. It
+	// returns the profile of the end-user, and the login state telling what the login was started for.
+	// The login state is returned with the errors occurring after it was checked.
+	Callback(ctx context.Context, connectionID string, params url.Values, state string) (model.ExternalProfile, *model.SocialLoginState, error)
+
+	// ServiceProviderMetadata returns the SAML metadata of the service provider federated with the identity
+	// provider of a SAML connection, to be imported by the identity provider.
+	ServiceProviderMetadata(ctx context.Context, connectionID string) ([]byte, error)
+}
+
+type socialLoginManager struct {
+	connectionDAO       mongo.CrudDAO[model.Connection]
+	socialLoginStateDAO mongo.CrudDAO[model.SocialLoginState]
+	jwksManager         JWKSManager
+	httpClient          *http.Client
+	mutex               sync.Mutex
+	metadataCache       map[string]cachedProviderMetadata
+}
+
+// providerMetadata holds the endpoints of an upstream provider (OpenID Connect Discovery 1.0, section 3).
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type cachedProviderMetadata struct {
+	Metadata  providerMetadata
+	FetchedAt time.Time
+}
+
+// upstreamTokenResponse is the response of the token endpoint of an upstream provider.
+type upstreamTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (m *socialLoginManager) Connections(ctx context.Context) ([]model.LoginConnection, error) {
+	connections, err := m.connectionDAO.FindMany(ctx, bson.M{"enabled": true}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]model.LoginConnection, 0, len(connections))
+	for _, connection := range connections {
+		res = append(res, model.LoginConnection{ID: connection.ID, Name: connection.Name})
+	}
+
+	return res, nil
+}
+
+func (m *socialLoginManager) Start(ctx context.Context, connectionID, loginChallenge, linkUserID string) (string, string, error) {
+	connection, err := m.connection(ctx, connectionID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if connection.Type == model.ConnectionTypeSAML {
+		return m.startSAML(ctx, connection, loginChallenge, linkUserID)
+	}
+
+	metadata, err := m.metadata(ctx, connection)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return "", "", err
+	}
+
+	codeVerifier, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := m.createState(ctx, connection, loginChallenge, linkUserID, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	scopes := connection.Scopes
+	if connection.Type == model.ConnectionTypeOIDC && !slices.Contains(scopes, ScopeOpenID) {
+		scopes = append([]string{ScopeOpenID}, scopes...)
+	}
+
+	params := url.Values{
+		"response_type":         {model.ResponseTypeCode},
+		"client_id":             {connection.ClientID},
+		"redirect_uri":          {tenant.URL(ctx, config.SocialLoginCallbackURL(connection.ID))},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {security.HashToken(codeVerifier)},
+		"code_challenge_method": {model.CodeChallengeMethodS256},
+	}
+
+	if connection.Type == model.ConnectionTypeOIDC {
+		params.Set("nonce", nonce)
+	}
+
+	authorizationURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", err
+	}
+
+	query := authorizationURL.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+
+	authorizationURL.RawQuery = query.Encode()
+
+	return authorizationURL.String(), state, nil
+}
+
+func (m *socialLoginManager) Callback(
+	ctx context.Context,
+	connectionID string,
+	params url.Values,
+	state string,
+) (model.ExternalProfile, *model.SocialLoginState, error) {
+	invalidStateError := model.NewOAuthInvalidRequestError("the login state is invalid or expired")
+
+	connection, err := m.connection(ctx, connectionID)
+	if err != nil {
+		return model.ExternalProfile{}, nil, err
+	}
+
+	// SAML identity providers send the state back as the relay state
+	stateParam := params.Get("state")
+	if connection.Type == model.ConnectionTypeSAML {
+		stateParam = params.Get("RelayState")
+	}
+
+	// The state must come back to the user-agent that started the login, to prevent login CSRF
+	if state == "" || !security.EqualTokens(state, stateParam) {
+		return model.ExternalProfile{}, nil, invalidStateError
+	}
+
+	loginState, err := m.consumeState(ctx, state)
+	if err != nil {
+		return model.ExternalProfile{}, nil, err
+	}
+
+	if loginState == nil || loginState.ConnectionID != connectionID {
+		return model.ExternalProfile{}, nil, invalidStateError
+	}
+
+	if upstreamError := params.Get("error"); upstreamError != "" {
+		description := "the login was canceled on the upstream provider"
+		if upstreamError != model.OAuthErrorAccessDenied {
+			description = "the upstream provider returned the error " + upstreamError
+		}
+
+		return model.ExternalProfile{}, loginState, model.NewOAuthError(http.StatusForbidden, model.OAuthErrorAccessDenied, description)
+	}
+
+	var claims map[string]any
+
+	if connection.Type == model.ConnectionTypeSAML {
+		claims, err = m.samlProfile(ctx, connection, params, loginState.Nonce)
+	} else {
+		claims, err = m.oauthProfile(ctx, connection, params, loginState)
+	}
+
+	if err != nil {
+		return model.ExternalProfile{}, loginState, err
+	}
+
+	profile := model.ExternalProfile{
+		ConnectionID:  connection.ID,
+		Subject:       claimString(claims[connection.Claim(model.UserAttributeSubject)]),
+		Email:         claimString(claims[connection.Claim(model.UserAttributeEmail)]),
+		EmailVerified: claimString(claims[connection.Claim(model.UserAttributeEmailVerified)]) == "true",
+		Name:          claimString(claims[connection.Claim(model.UserAttributeName)]),
+		GivenName:     claimString(claims[connection.Claim(model.UserAttributeGivenName)]),
+		FamilyName:    claimString(claims[connection.Claim(model.UserAttributeFamilyName)]),
+		Picture:       claimString(claims[connection.Claim(model.UserAttributePicture)]),
+	}
+
+	if profile.Subject == "" {
+		return model.ExternalProfile{}, loginState, errors.New("the upstream provider did not return the subject of the end-user")
+	}
+
+	return profile, loginState, nil
+}
+
+// oauthProfile exchanges the authorization code returned by an OAuth 2.0 or OpenID provider, and returns
+// the claims of the end-user, from the ID token and the user info endpoint.
+func (m *socialLoginManager) oauthProfile(
+	ctx context.Context,
+	connection *model.Connection,
+	params url.Values,
+	loginState *model.SocialLoginState,
+) (map[string]any, error) {
+	metadata, err := m.metadata(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := m.exchange(ctx, connection, metadata, params.Get("code"), loginState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]any)
+
+	if connection.Type == model.ConnectionTypeOIDC {
+		claims, err = m.verifyIDToken(ctx, connection, metadata, tokens.IDToken, loginState.Nonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if metadata.UserInfoEndpoint != "" {
+		if err = m.userInfo(ctx, connection, metadata, tokens.AccessToken, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
+// createState stores the state of a login on an upstream provider, and returns the state parameter.
+func (m *socialLoginManager) createState(
+	ctx context.Context,
+	connection *model.Connection,
+	loginChallenge, linkUserID, nonce, codeVerifier string,
+) (string, error) {
+	state, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	loginState := &model.SocialLoginState{
+		ID:             security.HashToken(state),
+		ConnectionID:   connection.ID,
+		LoginChallenge: loginChallenge,
+		UserID:         linkUserID,
+		Nonce:          nonce,
+		CodeVerifier:   codeVerifier,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(config.SocialLoginStateLifetime()),
+	}
+
+	if _, err = m.socialLoginStateDAO.Create(ctx, loginState); err != nil {
+		return "", err
+	}
+
+	return state, nil
+}
+
+// connection returns an enabled connection, or an error if there is none with the given identifier.
+func (m *socialLoginManager) connection(ctx context.Context, connectionID string) (*model.Connection, error) {
+	connection, err := m.connectionDAO.FindOne(ctx, bson.M{"_id": connectionID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if connection == nil || !connection.Enabled {
+		return nil, model.NewOAuthInvalidRequestError("the connection is unknown")
+	}
+
+	return connection, nil
+}
+
+// consumeState returns the login state of the given state parameter and deletes it, so that a callback is
+// processed only once. It returns nil if the state is unknown, expired or already used.
+func (m *socialLoginManager) consumeState(ctx context.Context, state string) (*model.SocialLoginState, error) {
+	filter := bson.M{"_id": security.HashToken(state)}
+
+	loginState, err := m.socialLoginStateDAO.FindOne(ctx, filter, nil)
+	if err != nil || loginState == nil {
+		return nil, err
+	}
+
+	deleted, err := m.socialLoginStateDAO.Delete(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if !deleted || loginState.IsExpired(time.Now()) {
+		//nolint:nilnil // An expired or already used state is not an error
+		return nil, nil
+	}
+
+	return loginState, nil
+}
+
+// metadata returns the endpoints of the upstream provider of a connection. The metadata of OpenID
+// providers are discovered from their issuer and cached, the endpoints registered on the connection
+// take precedence.
+func (m *socialLoginManager) metadata(ctx context.Context, connection *model.Connection) (providerMetadata, error) {
+	metadata := providerMetadata{Issuer: connection.Issuer}
+
+	if connection.Type == model.ConnectionTypeOIDC && connection.Issuer != "" {
+		discovered, err := m.discover(ctx, connection.Issuer)
+		if err != nil {
+			return providerMetadata{}, err
+		}
+
+		metadata = discovered
+	}
+
+	for _, override := range []struct {
+		value  string
+		target *string
+	}{
+		{connection.AuthorizationEndpoint, &metadata.AuthorizationEndpoint},
+		{connection.TokenEndpoint, &metadata.TokenEndpoint},
+		{connection.UserInfoEndpoint, &metadata.UserInfoEndpoint},
+		{connection.JWKSURI, &metadata.JWKSURI},
+	} {
+		if override.value != "" {
+			*override.target = override.value
+		}
+	}
+
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" {
+		return providerMetadata{}, fmt.Errorf("the endpoints of the connection %s are not configured", connection.ID)
+	}
+
+	return metadata, nil
+}
+
+// discover fetches the metadata of an OpenID provider, unless they are cached.
+func (m *socialLoginManager) discover(ctx context.Context, issuer string) (providerMetadata, error) {
+	m.mutex.Lock()
+	cached, found := m.metadataCache[issuer]
+	m.mutex.Unlock()
+
+	if found && time.Since(cached.FetchedAt) < config.SocialDiscoveryCacheLifetime() {
+		return cached.Metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", http.NoBody)
+	if err != nil {
+		return providerMetadata{}, err
+	}
+
+	var metadata providerMetadata
+	if err = m.do(req, &metadata); err != nil {
+		log.Err(err).Str("issuer", issuer).Msg("Could not discover the upstream provider metadata")
+
+		return providerMetadata{}, err
+	}
+
+	// The issuer of the metadata must be the one they were discovered from (OpenID Connect Discovery 1.0, section 4.3)
+	if metadata.Issuer != issuer {
+		return providerMetadata{}, fmt.Errorf("the discovered issuer %s does not match %s", metadata.Issuer, issuer)
+	}
+
+	m.mutex.Lock()
+	m.metadataCache[issuer] = cachedProviderMetadata{Metadata: metadata, FetchedAt: time.Now()}
+	m.mutex.Unlock()
+
+	return metadata, nil
+}
+
+// exchange exchanges an upstream authorization code for tokens, with the PKCE code verifier.
+func (m *socialLoginManager) exchange(
+	ctx context.Context,
+	connection *model.Connection,
+	metadata providerMetadata,
+	code, codeVerifier string,
+) (upstreamTokenResponse, error) {
+	clientSecret, err := security.Decrypt(connection.ClientSecretEncrypted)
+	if err != nil {
+		return upstreamTokenResponse{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {model.GrantTypeAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {tenant.URL(ctx, config.SocialLoginCallbackURL(connection.ID))},
+		"code_verifier": {codeVerifier},
+		"client_id":     {connection.ClientID},
+		"client_secret": {clientSecret},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return upstreamTokenResponse{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var res upstreamTokenResponse
+	if err = m.do(req, &res); err != nil && res.Error == "" {
+		return upstreamTokenResponse{}, err
+	}
+
+	if res.Error != "" || res.AccessToken == "" {
+		return upstreamTokenResponse{}, fmt.Errorf("the upstream token request failed: %s %s", res.Error, res.ErrorDescription)
+	}
+
+	return res, nil
+}
+
+// verifyIDToken checks the signature and the claims of an upstream ID token (OpenID Connect Core 1.0,
+// section 3.1.3.7), and returns its claims.
+func (m *socialLoginManager) verifyIDToken(
+	ctx context.Context,
+	connection *model.Connection,
+	metadata providerMetadata,
+	idToken, nonce string,
+) (map[string]any, error) {
+	if idToken == "" {
+		return nil, errors.New("the upstream provider did not return an ID token")
+	}
+
+	parsed, err := jwt.ParseSigned(idToken, asymmetricSignatureAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := m.jwksManager.Key(ctx, metadata.JWKSURI, parsed.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return nil, errors.New("the upstream ID token key is unknown")
+	}
+
+	var registeredClaims jwt.Claims
+
+	claims := make(map[string]any)
+	if err = parsed.Claims(key.Key, &registeredClaims, &claims); err != nil {
+		return nil, err
+	}
+
+	expected := jwt.Expected{Issuer: metadata.Issuer, AnyAudience: jwt.Audience{connection.ClientID}, Time: time.Now()}
+	if err = registeredClaims.Validate(expected); err != nil {
+		return nil, err
+	}
+
+	if registeredClaims.Expiry == nil || !security.EqualTokens(claimString(claims["nonce"]), nonce) {
+		return nil, errors.New("the upstream ID token is not valid for this login")
+	}
+
+	return claims, nil
+}
+
+// userInfo fetches the claims of the end-user from the user info endpoint, and adds them to the given
+// ones. For OpenID providers, the subject must match the one of the ID token.
+func (m *socialLoginManager) userInfo(
+	ctx context.Context,
+	connection *model.Connection,
+	metadata providerMetadata,
+	accessToken string,
+	claims map[string]any,
+) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadata.UserInfoEndpoint, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	userInfo := make(map[string]any)
+	if err = m.do(req, &userInfo); err != nil {
+		return err
+	}
+
+	subjectClaim := connection.Claim(model.UserAttributeSubject)
+
+	if subject, found := claims[subjectClaim]; found && claimString(subject) != claimString(userInfo[subjectClaim]) {
+		return errors.New("the subject of the user info does not match the ID token")
+	}
+
+	for name, value := range userInfo {
+		claims[name] = value
+	}
+
+	return nil
+}
+
+// do sends a request to an upstream provider, and decodes its JSON response. The response is decoded
+// even if its status is not successful, since it may describe the error.
+func (m *socialLoginManager) do(req *http.Request, response any) error {
+	req.Header.Set("Accept", "application/json")
+
+	res, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	decoder := json.NewDecoder(io.LimitReader(res.Body, upstreamResponseMaxSize))
+	decoder.UseNumber()
+
+	if err = decoder.Decode(response); err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("the upstream request to %s returned the status %d", req.URL.Redacted(), res.StatusCode)
+	}
+
+	return nil
+}
+
+// claimString returns the string representation of a claim value. Some providers return numeric
+// subjects, or boolean claims as strings.
+func claimString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
+
+func NewSocialLoginManager(
+	connectionDAO mongo.CrudDAO[model.Connection],
+	socialLoginStateDAO mongo.CrudDAO[model.SocialLoginState],
+	jwksManager JWKSManager,
+) SocialLoginManager {
+	return &socialLoginManager{
+		connectionDAO:       connectionDAO,
+		socialLoginStateDAO: socialLoginStateDAO,
+		jwksManager:         jwksManager,
+		httpClient:          &http.Client{Timeout: config.ConnectionTimeout()},
+		metadataCache:       make(map[string]cachedProviderMetadata),
+	}
+}
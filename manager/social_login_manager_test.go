@@ -0,0 +1,292 @@
+package manager_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/m3talux/goauth/internal/goauthtest"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/security"
+)
+
+// Client of goauth registered on the mock OpenID provider.
+const (
+	providerClientID     = "goauth"
+	providerClientSecret = "provider-secret"
+)
+
+// oidcProvider is an in-process OpenID provider, issuing the ID tokens of a single end-user.
+type oidcProvider struct {
+	*httptest.Server
+	key *ecdsa.PrivateKey
+
+	mutex sync.Mutex
+	// authorizations are the authorization requests of the codes issued by the provider.
+	authorizations map[string]url.Values
+	// idTokenClaims alter the claims of the ID tokens issued by the provider.
+	idTokenClaims func(claims map[string]any)
+}
+
+func newOIDCProvider(t *testing.T) *oidcProvider {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &oidcProvider{key: key, authorizations: make(map[string]url.Values)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "provider-key", Use: "sig"}}})
+	})
+	mux.HandleFunc("/token", p.token)
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+// authorize approves an authorization request of goauth, and returns the authorization code of the
+// callback.
+func (p *oidcProvider) authorize(t *testing.T, authorizationURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := security.RandomToken(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.mutex.Lock()
+	p.authorizations[code] = u.Query()
+	p.mutex.Unlock()
+
+	return code
+}
+
+// token exchanges an authorization code, checking the PKCE code verifier against the code challenge of
+// its authorization request.
+func (p *oidcProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("client_id") != providerClientID || r.PostForm.Get("client_secret") != providerClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+
+		return
+	}
+
+	p.mutex.Lock()
+	authorization, found := p.authorizations[r.PostForm.Get("code")]
+	delete(p.authorizations, r.PostForm.Get("code"))
+	p.mutex.Unlock()
+
+	if !found || authorization.Get("redirect_uri") != r.PostForm.Get("redirect_uri") ||
+		authorization.Get("code_challenge_method") != model.CodeChallengeMethodS256 ||
+		security.HashToken(r.PostForm.Get("code_verifier")) != authorization.Get("code_challenge") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":   p.URL,
+		"sub":   "upstream-user",
+		"aud":   providerClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Minute).Unix(),
+		"nonce": authorization.Get("nonce"),
+		"email": "user@example.com",
+	}
+
+	if p.idTokenClaims != nil {
+		p.idTokenClaims(claims)
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: jose.JSONWebKey{Key: p.key, KeyID: "provider-key"}}, nil)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+
+		return
+	}
+
+	idToken, err := jwt.Signed(signer).Claims(claims).Serialize()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"access_token": "upstream-access-token", "token_type": "Bearer", "id_token": idToken})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func newSocialLoginManager(t *testing.T, provider *oidcProvider) manager.SocialLoginManager {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SECRETS_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(key))
+	goauthtest.Configure(t, "https://auth.example.com")
+
+	clientSecret, err := security.Encrypt(providerClientSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connectionDAO := goauthtest.NewMemoryDAO[model.Connection]()
+	if _, err = connectionDAO.Create(goauthtest.Context(), &model.Connection{
+		ID:                    "oidc",
+		Name:                  "OpenID provider",
+		Type:                  model.ConnectionTypeOIDC,
+		Issuer:                provider.URL,
+		ClientID:              providerClientID,
+		ClientSecretEncrypted: clientSecret,
+		Scopes:                []string{"email"},
+		Enabled:               true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return manager.NewSocialLoginManager(connectionDAO, goauthtest.NewMemoryDAO[model.SocialLoginState](), manager.NewJWKSManager())
+}
+
+// startLogin starts a login on the provider, and returns its authorization URL and its state.
+func startLogin(t *testing.T, m manager.SocialLoginManager) (string, string) {
+	t.Helper()
+
+	authorizationURL, state, err := m.Start(goauthtest.Context(), "oidc", "login-challenge", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := u.Query()
+	if query.Get("state") != state || query.Get("nonce") == "" || query.Get("code_challenge") == "" || query.Get("code_challenge_method") != model.CodeChallengeMethodS256 {
+		t.Fatalf("unexpected authorization request %s", query.Encode())
+	}
+
+	return authorizationURL, state
+}
+
+func TestSocialLoginCallback(t *testing.T) {
+	provider := newOIDCProvider(t)
+	m := newSocialLoginManager(t, provider)
+
+	authorizationURL, state := startLogin(t, m)
+	params := url.Values{"code": {provider.authorize(t, authorizationURL)}, "state": {state}}
+
+	profile, loginState, err := m.Callback(goauthtest.Context(), "oidc", params, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if profile.Subject != "upstream-user" || profile.Email != "user@example.com" || loginState.LoginChallenge != "login-challenge" {
+		t.Fatalf("unexpected profile %+v and login state %+v", profile, loginState)
+	}
+
+	// The state is consumed by the callback
+	if _, _, err = m.Callback(goauthtest.Context(), "oidc", params, state); err == nil {
+		t.Fatal("expected the callback to be processed only once")
+	}
+}
+
+func TestSocialLoginCallbackState(t *testing.T) {
+	provider := newOIDCProvider(t)
+	m := newSocialLoginManager(t, provider)
+
+	authorizationURL, state := startLogin(t, m)
+	code := provider.authorize(t, authorizationURL)
+
+	// The state of the callback must be the one bound to the user-agent that started the login
+	_, otherState := startLogin(t, m)
+
+	for _, bound := range []string{"", otherState} {
+		if _, _, err := m.Callback(goauthtest.Context(), "oidc", url.Values{"code": {code}, "state": {state}}, bound); err == nil {
+			t.Fatalf("expected the state %q to be rejected", bound)
+		}
+	}
+
+	if _, _, err := m.Callback(goauthtest.Context(), "oidc", url.Values{"code": {code}, "state": {"unknown"}}, "unknown"); err == nil {
+		t.Fatal("expected an unknown state to be rejected")
+	}
+}
+
+func TestSocialLoginCallbackPKCE(t *testing.T) {
+	provider := newOIDCProvider(t)
+	m := newSocialLoginManager(t, provider)
+
+	// A code issued for another login is exchanged with the code verifier of this login
+	otherAuthorizationURL, _ := startLogin(t, m)
+	_, state := startLogin(t, m)
+
+	params := url.Values{"code": {provider.authorize(t, otherAuthorizationURL)}, "state": {state}}
+	if _, _, err := m.Callback(goauthtest.Context(), "oidc", params, state); err == nil {
+		t.Fatal("expected the code of another login to be rejected")
+	}
+}
+
+func TestSocialLoginCallbackIDToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(claims map[string]any)
+	}{
+		{name: "wrong audience", claims: func(claims map[string]any) { claims["aud"] = "other-client" }},
+		{name: "wrong nonce", claims: func(claims map[string]any) { claims["nonce"] = "other-nonce" }},
+		{name: "missing nonce", claims: func(claims map[string]any) { delete(claims, "nonce") }},
+		{name: "wrong issuer", claims: func(claims map[string]any) { claims["iss"] = "https://other.example.com" }},
+		{name: "expired", claims: func(claims map[string]any) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newOIDCProvider(t)
+			provider.idTokenClaims = tt.claims
+			m := newSocialLoginManager(t, provider)
+
+			authorizationURL, state := startLogin(t, m)
+
+			params := url.Values{"code": {provider.authorize(t, authorizationURL)}, "state": {state}}
+			if _, _, err := m.Callback(goauthtest.Context(), "oidc", params, state); err == nil {
+				t.Fatal("expected the ID token to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package manager
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"net/url"
+
+	"github.com/crewjam/saml"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"github.com/rs/zerolog/log"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// Claim holding the name identifier of SAML assertions.
+const samlNameIDClaim = "sub"
+
+// startSAML begins a login on the SAML identity provider of a connection: a signed authentication request
+// is sent through the user-agent (HTTP-Redirect binding), with the state as relay state. The identifier of
+// the request is stored as the nonce of the login, since the assertion must be issued in response to it.
+func (m *socialLoginManager) startSAML(ctx context.Context, connection *model.Connection, loginChallenge, linkUserID string) (string, string, error) {
+	sp, err := serviceProvider(ctx, connection)
+	if err != nil {
+		return "", "", err
+	}
+
+	ssoURL := sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if ssoURL == "" {
+		return "", "", errors.New("the SAML identity provider has no single sign-on service with the HTTP-Redirect binding")
+	}
+
+	req, err := sp.MakeAuthenticationRequest(ssoURL, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := m.createState(ctx, connection, loginChallenge, linkUserID, req.ID, "")
+	if err != nil {
+		return "", "", err
+	}
+
+	redirectURL, err := req.Redirect(state, sp)
+	if err != nil {
+		return "", "", err
+	}
+
+	return redirectURL.String(), state, nil
+}
+
+// samlProfile validates the response posted by the SAML identity provider of a connection to the assertion
+// consumer service: the response or the assertion must be signed by the identity provider, and the assertion
+// must be issued for this login. The claims of the profile are the attributes of the assertion.
+func (m *socialLoginManager) samlProfile(ctx context.Context, connection *model.Connection, params url.Values, requestID string) (map[string]any, error) {
+	sp, err := serviceProvider(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := base64.StdEncoding.DecodeString(params.Get("SAMLResponse"))
+	if err != nil {
+		return nil, model.NewOAuthInvalidRequestError("the SAML response could not be decoded")
+	}
+
+	assertion, err := sp.ParseXMLResponse(response, []string{requestID})
+	if err != nil {
+		var invalidResponseErr *saml.InvalidResponseError
+		if errors.As(err, &invalidResponseErr) {
+			log.Warn().Err(invalidResponseErr.PrivateErr).Str("connection", connection.ID).Msg("Invalid SAML response")
+		}
+
+		return nil, model.NewOAuthInvalidRequestError("the SAML response is invalid")
+	}
+
+	claims := make(map[string]any)
+
+	if assertion.Subject != nil && assertion.Subject.NameID != nil {
+		claims[samlNameIDClaim] = assertion.Subject.NameID.Value
+	}
+
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			if len(attribute.Values) == 0 {
+				continue
+			}
+
+			claims[attribute.Name] = attribute.Values[0].Value
+
+			if attribute.FriendlyName != "" {
+				claims[attribute.FriendlyName] = attribute.Values[0].Value
+			}
+		}
+	}
+
+	return claims, nil
+}
+
+func (m *socialLoginManager) ServiceProviderMetadata(ctx context.Context, connectionID string) ([]byte, error) {
+	connection, err := m.connection(ctx, connectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if connection.Type != model.ConnectionTypeSAML {
+		return nil, model.NewOAuthInvalidRequestError("the connection is not a SAML connection")
+	}
+
+	sp, err := serviceProvider(ctx, connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return xml.MarshalIndent(sp.Metadata(), "", "  ")
+}
+
+// serviceProvider returns the SAML service provider federated with the identity provider of a connection.
+// Its entity ID is the URL of its metadata, and its assertion consumer service the callback of the connection.
+func serviceProvider(ctx context.Context, connection *model.Connection) (*saml.ServiceProvider, error) {
+	idpMetadata, err := parseSAMLMetadata([]byte(connection.SAMLIdPMetadata))
+	if err != nil {
+		return nil, err
+	}
+
+	key, cert, err := security.SAMLKeyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	metadataURL, err := url.Parse(tenant.URL(ctx, config.SAMLServiceProviderMetadataURL(connection.ID)))
+	if err != nil {
+		return nil, err
+	}
+
+	acsURL, err := url.Parse(tenant.URL(ctx, config.SocialLoginCallbackURL(connection.ID)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		Key:               key,
+		Certificate:       cert,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.PersistentNameIDFormat,
+		SignatureMethod:   dsig.RSASHA256SignatureMethod,
+	}, nil
+}
+
+// parseSAMLMetadata parses the metadata of a SAML entity. Metadata documents holding several entities
+// are accepted, as long as they describe a single one.
+func parseSAMLMetadata(data []byte) (*saml.EntityDescriptor, error) {
+	if len(data) == 0 {
+		return nil, errors.New("the SAML metadata are missing")
+	}
+
+	entity := new(saml.EntityDescriptor)
+	if err := xml.Unmarshal(data, entity); err == nil {
+		return entity, nil
+	}
+
+	entities := new(saml.EntitiesDescriptor)
+	if err := xml.Unmarshal(data, entities); err != nil {
+		return nil, err
+	}
+
+	if len(entities.EntityDescriptors) != 1 {
+		return nil, errors.New("the SAML metadata must describe a single entity")
+	}
+
+	return &entities.EntityDescriptors[0], nil
+}
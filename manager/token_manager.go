@@ -0,0 +1,464 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Size, in bytes, of the random part of issued tokens and codes.
+const tokenSize = 32
+
+// ScopeOpenID is the scope of OpenID Connect requests, for which an ID token is issued.
+const ScopeOpenID = "openid"
+
+type TokenManager interface {
+	// Exchange processes a token request sent by an authenticated client. The confirmation holds the
+	// proof-of-possession keys of the request: the thumbprint of its DPoP proof key (RFC 9449), and the
+	// thumbprint of its client certificate (RFC 8705). The issued tokens are bound to them.
+	Exchange(ctx context.Context, client *model.Client, params url.Values, cnf model.Confirmation) (model.TokenResponse, error)
+
+	// Introspect returns the active token matching the given value, or nil if there is none. The token may
+	// belong to another organization than the one of the context.
+	Introspect(ctx context.Context, value string) (*model.Token, error)
+}
+
+type tokenManager struct {
+	tokenDAO             mongo.CrudDAO[model.Token]
+	authorizationCodeDAO mongo.CrudDAO[model.AuthorizationCode]
+	userDAO              mongo.CrudDAO[model.User]
+	sessionManager       SessionManager
+	keyManager           KeyManager
+	roleManager          RoleManager
+	groupManager         GroupManager
+	auditManager         AuditManager
+	// opaqueStrategy issues refresh tokens and opaque access tokens, jwtStrategy JWT access tokens.
+	opaqueStrategy accessTokenStrategy
+	jwtStrategy    accessTokenStrategy
+}
+
+// tokenGrant describes the tokens to issue at the end of a successful token request.
+type tokenGrant struct {
+	GrantType string
+	Client    *model.Client
+	Subject   string
+	Scopes    []string
+	// Confirmation binds the access token to the keys of the request.
+	Confirmation model.Confirmation
+	// Refresh issues a refresh token along with the access token.
+	Refresh bool
+	// SessionID is the end-user session in which the tokens are issued, if any.
+	SessionID string
+	// IDToken issues an ID token along with the access token, with the nonce and the
+	// authentication time of the authorization request.
+	IDToken  bool
+	Nonce    string
+	AuthTime time.Time
+	// Roles and Permissions are granted to the subject when the tokens are issued, and added to the
+	// access token.
+	Roles       []string
+	Permissions []string
+	// Groups are the effective groups of the subject, replaced by GroupsOverage when there are more than
+	// the claim limit.
+	Groups        []string
+	GroupsOverage bool
+}
+
+func (m *tokenManager) Exchange(
+	ctx context.Context,
+	client *model.Client,
+	params url.Values,
+	cnf model.Confirmation,
+) (model.TokenResponse, error) {
+	res, err := m.exchange(ctx, client, params, cnf)
+	if err != nil {
+		m.auditManager.Record(ctx, model.AuditEvent{
+			Type:    model.AuditEventTokenIssue,
+			Outcome: model.AuditOutcomeFailure,
+			Actor:   model.AuditActor{ClientID: client.ID},
+			Reason:  err.Error(),
+			Details: map[string]string{"grant_type": params.Get("grant_type")},
+		})
+	}
+
+	return res, err
+}
+
+// exchange processes a token request, see Exchange.
+func (m *tokenManager) exchange(
+	ctx context.Context,
+	client *model.Client,
+	params url.Values,
+	cnf model.Confirmation,
+) (model.TokenResponse, error) {
+	grantType := params.Get("grant_type")
+
+	switch grantType {
+	case model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken, model.GrantTypeClientCredentials:
+	default:
+		return model.TokenResponse{}, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorUnsupportedGrantType, "")
+	}
+
+	if !client.HasGrantType(grantType) {
+		return model.TokenResponse{}, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorUnauthorizedClient, "the client can't use this grant type")
+	}
+
+	// Certificate-bound tokens are only issued to clients that registered for it (RFC 8705, section 3.4)
+	if !client.TLSClientCertificateBoundAccessTokens {
+		cnf.X5TS256 = ""
+	}
+
+	switch grantType {
+	case model.GrantTypeAuthorizationCode:
+		return m.exchangeAuthorizationCode(ctx, client, params, cnf)
+	case model.GrantTypeRefreshToken:
+		return m.refresh(ctx, client, params, cnf)
+	default:
+		return m.exchangeClientCredentials(ctx, client, params, cnf)
+	}
+}
+
+func (m *tokenManager) Introspect(ctx context.Context, value string) (*model.Token, error) {
+	if isJWT(value) {
+		return m.jwtStrategy.Validate(ctx, value)
+	}
+
+	return m.opaqueStrategy.Validate(ctx, value)
+}
+
+func (m *tokenManager) exchangeAuthorizationCode(
+	ctx context.Context,
+	client *model.Client,
+	params url.Values,
+	cnf model.Confirmation,
+) (model.TokenResponse, error) {
+	invalidGrantError := model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidGrant, "the authorization code is invalid or expired")
+
+	filter := bson.M{"_id": security.HashToken(params.Get("code"))}
+
+	code, err := m.authorizationCodeDAO.FindOne(ctx, filter, nil)
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+
+	if code == nil {
+		return model.TokenResponse{}, invalidGrantError
+	}
+
+	// Codes are single-use: whoever deletes it first is the only one allowed to use it
+	deleted, err := m.authorizationCodeDAO.Delete(ctx, filter)
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+
+	if !deleted || code.IsExpired(time.Now()) || code.ClientID != client.ID {
+		return model.TokenResponse{}, invalidGrantError
+	}
+
+	// The redirect URI is only required when it was sent to the authorization endpoint (RFC 6749 4.1.3)
+	redirectURI := params.Get("redirect_uri")
+	if (code.RedirectURIRequired || redirectURI != "") && code.RedirectURI != redirectURI {
+		return model.TokenResponse{}, invalidGrantError
+	}
+
+	if code.CodeChallenge != "" && !security.EqualTokens(code.CodeChallenge, security.HashToken(params.Get("code_verifier"))) {
+		return model.TokenResponse{}, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidGrant, "the code verifier is invalid")
+	}
+
+	if code.DPoPJKT != "" && code.DPoPJKT != cnf.JKT {
+		return model.TokenResponse{}, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidDPoPProof, "the DPoP proof key does not match")
+	}
+
+	res, err := m.issue(ctx, tokenGrant{
+		GrantType:    model.GrantTypeAuthorizationCode,
+		Client:       client,
+		Subject:      code.Subject,
+		Scopes:       code.Scopes,
+		Confirmation: cnf,
+		Refresh:      client.HasGrantType(model.GrantTypeRefreshToken),
+		SessionID:    code.SessionID,
+		IDToken:      slices.Contains(code.Scopes, ScopeOpenID),
+		Nonce:        code.Nonce,
+		AuthTime:     code.AuthTime,
+	})
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+
+	// The client will have to be notified when the end-user logs out
+	if code.SessionID != "" {
+		if err = m.sessionManager.AddClient(ctx, code.SessionID, client.ID); err != nil {
+			return model.TokenResponse{}, err
+		}
+	}
+
+	return res, nil
+}
+
+func (m *tokenManager) refresh(ctx context.Context, client *model.Client, params url.Values, cnf model.Confirmation) (model.TokenResponse, error) {
+	invalidGrantError := model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidGrant, "the refresh token is invalid or expired")
+
+	token, err := m.Introspect(ctx, params.Get("refresh_token"))
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+
+	if token == nil || token.Kind != model.TokenKindRefreshToken || token.ClientID != client.ID || !tenant.Matches(ctx, token.OrganizationID) {
+		return model.TokenResponse{}, invalidGrantError
+	}
+
+	// Refresh tokens of public clients are bound to the keys used when they were issued
+	if token.Confirmation != nil && token.Confirmation.JKT != "" && token.Confirmation.JKT != cnf.JKT {
+		return model.TokenResponse{}, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidDPoPProof, "the DPoP proof key does not match")
+	}
+
+	if token.Confirmation != nil && token.Confirmation.X5TS256 != "" && token.Confirmation.X5TS256 != cnf.X5TS256 {
+		return model.TokenResponse{}, invalidGrantError
+	}
+
+	scopes := token.Scopes
+
+	if scope := params.Get("scope"); scope != "" {
+		scopes = strings.Fields(scope)
+
+		for _, s := range scopes {
+			if !slices.Contains(token.Scopes, s) {
+				return model.TokenResponse{}, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidScope, "the scope exceeds the granted one")
+			}
+		}
+	}
+
+	// Refresh tokens are rotated, a refresh token used twice is rejected
+	deleted, err := m.tokenDAO.Delete(ctx, bson.M{"_id": token.ID})
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+
+	if !deleted {
+		return model.TokenResponse{}, invalidGrantError
+	}
+
+	// The tokens are revoked when the end-user is disabled or leaves, but may have been minted concurrently
+	if token.Subject != "" {
+		user, err := m.userDAO.FindOne(ctx, bson.M{"_id": token.Subject}, nil)
+		if err != nil {
+			return model.TokenResponse{}, err
+		}
+
+		if user == nil || user.Disabled {
+			return model.TokenResponse{}, invalidGrantError
+		}
+	}
+
+	return m.issue(ctx, tokenGrant{
+		GrantType:    model.GrantTypeRefreshToken,
+		Client:       client,
+		Subject:      token.Subject,
+		Scopes:       scopes,
+		Confirmation: cnf,
+		Refresh:      true,
+		SessionID:    token.SessionID,
+	})
+}
+
+func (m *tokenManager) exchangeClientCredentials(
+	ctx context.Context,
+	client *model.Client,
+	params url.Values,
+	cnf model.Confirmation,
+) (model.TokenResponse, error) {
+	if client.IsPublic() {
+		return model.TokenResponse{}, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorUnauthorizedClient, "public clients can't use this grant type")
+	}
+
+	scopes := strings.Fields(params.Get("scope"))
+	if !client.AllowsScopes(scopes) {
+		return model.TokenResponse{}, model.NewOAuthError(http.StatusBadRequest, model.OAuthErrorInvalidScope, "the requested scope is not allowed")
+	}
+
+	return m.issue(ctx, tokenGrant{
+		GrantType:    model.GrantTypeClientCredentials,
+		Client:       client,
+		Scopes:       scopes,
+		Confirmation: cnf,
+	})
+}
+
+// issue creates the tokens of a grant, and returns them as a token response.
+func (m *tokenManager) issue(ctx context.Context, grant tokenGrant) (model.TokenResponse, error) {
+	roles, err := m.roleManager.Resolve(ctx, grant.Subject, grant.Client)
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+
+	grant.Roles, grant.Permissions = model.RoleGrants(roles)
+
+	if grant.Subject != "" {
+		if grant.Groups, grant.GroupsOverage, err = m.groups(ctx, grant.Subject); err != nil {
+			return model.TokenResponse{}, err
+		}
+	}
+
+	accessToken, token, err := m.create(ctx, model.TokenKindAccessToken, grant, grant.Confirmation, config.AccessTokenLifetime())
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+
+	res := model.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   token.TokenType(),
+		ExpiresIn:   int(config.AccessTokenLifetime().Seconds()),
+		Scope:       strings.Join(grant.Scopes, " "),
+	}
+
+	if grant.IDToken {
+		res.IDToken, err = m.idToken(ctx, grant)
+		if err != nil {
+			return model.TokenResponse{}, err
+		}
+	}
+
+	if grant.Refresh {
+		// Refresh tokens of confidential clients are already bound to the client authentication (RFC 9449, section 5)
+		refreshConfirmation := model.Confirmation{}
+		if grant.Client.IsPublic() {
+			refreshConfirmation = grant.Confirmation
+		}
+
+		res.RefreshToken, _, err = m.create(ctx, model.TokenKindRefreshToken, grant, refreshConfirmation, config.RefreshTokenLifetime())
+		if err != nil {
+			return model.TokenResponse{}, err
+		}
+	}
+
+	m.auditManager.Record(ctx, model.AuditEvent{
+		Type:    model.AuditEventTokenIssue,
+		Outcome: model.AuditOutcomeSuccess,
+		Actor:   model.AuditActor{Subject: grant.Subject, ClientID: grant.Client.ID},
+		Details: map[string]string{"grant_type": grant.GrantType, "scope": res.Scope},
+	})
+
+	return res, nil
+}
+
+func (m *tokenManager) create(
+	ctx context.Context,
+	kind string,
+	grant tokenGrant,
+	cnf model.Confirmation,
+	lifetime time.Duration,
+) (string, *model.Token, error) {
+	now := time.Now()
+
+	token := &model.Token{
+		Kind:           kind,
+		OrganizationID: tenant.OrganizationID(ctx),
+		ClientID:       grant.Client.ID,
+		Subject:        grant.Subject,
+		Scopes:         grant.Scopes,
+		SessionID:      grant.SessionID,
+		IssuedAt:       now,
+		ExpiresAt:      now.Add(lifetime),
+	}
+
+	if !cnf.IsEmpty() {
+		token.Confirmation = &cnf
+	}
+
+	// Refresh tokens don't hold the roles, which are resolved again when they are used
+	if kind == model.TokenKindAccessToken {
+		token.Roles = grant.Roles
+		token.Permissions = grant.Permissions
+		token.Groups = grant.Groups
+		token.GroupsOverage = grant.GroupsOverage
+	}
+
+	strategy := m.opaqueStrategy
+	if kind == model.TokenKindAccessToken && grant.Client.AccessTokenFormat == model.AccessTokenFormatJWT {
+		strategy = m.jwtStrategy
+	}
+
+	value, err := strategy.Issue(ctx, token, grant)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return value, token, nil
+}
+
+// groups returns the identifiers of the effective groups of a user, or an overage indicator when the
+// user is in more groups than the claim limit. The groups are then fetched from the API.
+func (m *tokenManager) groups(ctx context.Context, userID string) ([]string, bool, error) {
+	groups, err := m.groupManager.UserGroups(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(groups) > config.GroupClaimLimit() {
+		return nil, true, nil
+	}
+
+	ids := make([]string, 0, len(groups))
+	for _, group := range groups {
+		ids = append(ids, group.ID)
+	}
+
+	slices.Sort(ids)
+
+	return ids, false, nil
+}
+
+// idToken creates the ID token of a grant (OpenID Connect Core 1.0, section 2).
+func (m *tokenManager) idToken(ctx context.Context, grant tokenGrant) (string, error) {
+	now := time.Now()
+
+	claims := model.IDTokenClaims{
+		Claims: jwt.Claims{
+			Issuer:   tenant.Issuer(ctx),
+			Subject:  grant.Subject,
+			Audience: jwt.Audience{grant.Client.ID},
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(config.IDTokenLifetime())),
+		},
+		Nonce:     grant.Nonce,
+		AuthTime:  grant.AuthTime.Unix(),
+		SessionID: grant.SessionID,
+	}
+
+	return m.keyManager.Sign(claims, model.JWTTypeIDToken)
+}
+
+func NewTokenManager(
+	tokenDAO mongo.CrudDAO[model.Token],
+	authorizationCodeDAO mongo.CrudDAO[model.AuthorizationCode],
+	userDAO mongo.CrudDAO[model.User],
+	sessionManager SessionManager,
+	keyManager KeyManager,
+	roleManager RoleManager,
+	groupManager GroupManager,
+	auditManager AuditManager,
+) TokenManager {
+	return &tokenManager{
+		tokenDAO:             tokenDAO,
+		authorizationCodeDAO: authorizationCodeDAO,
+		userDAO:              userDAO,
+		sessionManager:       sessionManager,
+		keyManager:           keyManager,
+		roleManager:          roleManager,
+		groupManager:         groupManager,
+		auditManager:         auditManager,
+		opaqueStrategy:       &opaqueTokenStrategy{tokenDAO: tokenDAO},
+		jwtStrategy:          &jwtAccessTokenStrategy{keyManager: keyManager},
+	}
+}
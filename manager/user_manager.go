@@ -0,0 +1,391 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Size, in bytes, of the random identifiers of users and identities.
+const userIDSize = 16
+
+type UserManager interface {
+	// Get returns the user with the given identifier, or nil if there is none.
+	Get(ctx context.Context, userID string) (*model.User, error)
+
+	// Login returns the user linked to the profile of an upstream provider. On the first login with
+	// this upstream account, it is linked to the user with the same email if both emails are verified,
+	// otherwise the user is created just-in-time from the profile. The roles granted by the upstream
+	// provider are synchronized on each login. The users join the organizations that verified the domain
+	// of their email and enabled automatic joins. Disabled users, and the users who are not members of the
+	// organization of the context, can't log in.
+	Login(ctx context.Context, profile model.ExternalProfile) (*model.User, error)
+
+	// Find returns the user of an upstream profile whatever its organizations: the user linked to the
+	// upstream account, or else the oldest user with the same verified email. It returns nil if there is none.
+	Find(ctx context.Context, profile model.ExternalProfile) (*model.User, error)
+
+	// Identities returns the upstream accounts linked to a user.
+	Identities(ctx context.Context, userID string) ([]model.Identity, error)
+
+	// Link links the upstream account of the profile to a user. It fails if the upstream account is
+	// already linked to another user.
+	Link(ctx context.Context, userID string, profile model.ExternalProfile) (*model.Identity, error)
+
+	// Unlink removes an upstream account from a user. The last login method of a user can't be removed.
+	Unlink(ctx context.Context, userID, identityID string) error
+}
+
+type userManager struct {
+	userDAO        mongo.CrudDAO[model.User]
+	identityDAO    mongo.CrudDAO[model.Identity]
+	domainClaimDAO mongo.CrudDAO[model.DomainClaim]
+	auditManager   AuditManager
+}
+
+func (m *userManager) Get(ctx context.Context, userID string) (*model.User, error) {
+	return m.userDAO.FindOne(ctx, bson.M{"_id": userID}, nil)
+}
+
+func (m *userManager) Login(ctx context.Context, profile model.ExternalProfile) (*model.User, error) {
+	profile.Email = strings.ToLower(profile.Email)
+
+	filter := bson.M{"connectionId": profile.ConnectionID, "subject": profile.Subject}
+
+	identity, err := m.identityDAO.FindOne(ctx, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity == nil {
+		return m.autoLink(ctx, profile)
+	}
+
+	update := bson.M{"$set": bson.M{"email": profile.Email, "emailVerified": profile.EmailVerified, "roles": profile.Roles, "lastLoginAt": time.Now()}}
+	if _, err = m.identityDAO.Update(ctx, filter, update, false); err != nil {
+		return nil, err
+	}
+
+	user, err := m.Get(ctx, identity.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		user, err = m.join(ctx, identity.UserID, profile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Disabled {
+		return nil, disabledUserError()
+	}
+
+	return user, nil
+}
+
+func (m *userManager) Find(ctx context.Context, profile model.ExternalProfile) (*model.User, error) {
+	allCtx := tenant.WithAllOrganizations(ctx)
+
+	identity, err := m.identityDAO.FindOne(ctx, bson.M{"connectionId": profile.ConnectionID, "subject": profile.Subject}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity != nil {
+		return m.userDAO.FindOne(allCtx, bson.M{"_id": identity.UserID}, nil)
+	}
+
+	if !profile.EmailVerified || profile.Email == "" {
+		//nolint:nilnil // An unknown end-user is not an error
+		return nil, nil
+	}
+
+	filter := bson.M{"email": strings.ToLower(profile.Email), "emailVerified": true}
+
+	return m.userDAO.FindOne(allCtx, filter, options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+}
+
+func (m *userManager) Identities(ctx context.Context, userID string) ([]model.Identity, error) {
+	return m.identityDAO.FindMany(ctx, bson.M{"userId": userID}, nil)
+}
+
+func (m *userManager) Link(ctx context.Context, userID string, profile model.ExternalProfile) (*model.Identity, error) {
+	alreadyLinkedError := model.NewAPIResponseError(http.StatusConflict, "this account is already linked to another user")
+
+	profile.Email = strings.ToLower(profile.Email)
+
+	identity, err := m.identityDAO.FindOne(ctx, bson.M{"connectionId": profile.ConnectionID, "subject": profile.Subject}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity != nil {
+		if identity.UserID != userID {
+			return nil, alreadyLinkedError
+		}
+
+		return identity, nil
+	}
+
+	identity, err = m.createIdentity(ctx, userID, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity == nil {
+		return nil, alreadyLinkedError
+	}
+
+	return identity, nil
+}
+
+func (m *userManager) Unlink(ctx context.Context, userID, identityID string) error {
+	filter := bson.M{"_id": identityID, "userId": userID}
+
+	identity, err := m.identityDAO.FindOne(ctx, filter, nil)
+	if err != nil {
+		return err
+	}
+
+	if identity == nil {
+		return model.NewAPIResponseError(http.StatusNotFound, "the identity does not exist")
+	}
+
+	lastLoginMethodError := model.NewAPIResponseError(http.StatusConflict, "the last login method of an account can't be unlinked")
+
+	if m.identityDAO.Count(ctx, bson.M{"userId": userID}) <= 1 {
+		return lastLoginMethodError
+	}
+
+	if _, err = m.identityDAO.Delete(ctx, filter); err != nil {
+		return err
+	}
+
+	// Concurrent unlinks may have removed every other login method in the meantime
+	if m.identityDAO.Count(ctx, bson.M{"userId": userID}) == 0 {
+		if _, err = m.identityDAO.Create(ctx, identity); err != nil {
+			return err
+		}
+
+		return lastLoginMethodError
+	}
+
+	return nil
+}
+
+// autoLink links a new upstream account to the user with the same email, when the upstream provider
+// and goauth both verified it. Otherwise, a new user is created. Unverified emails are never trusted,
+// since anyone could take over an account by registering its email on a provider.
+func (m *userManager) autoLink(ctx context.Context, profile model.ExternalProfile) (*model.User, error) {
+	if !profile.EmailVerified || profile.Email == "" {
+		return m.create(ctx, profile)
+	}
+
+	user, err := m.userDAO.FindOne(ctx, bson.M{"email": profile.Email, "emailVerified": true}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return m.create(ctx, profile)
+	}
+
+	if user.Disabled {
+		return nil, disabledUserError()
+	}
+
+	identity, err := m.createIdentity(ctx, user.ID, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	// The upstream account was linked concurrently
+	if identity == nil {
+		return m.Login(ctx, profile)
+	}
+
+	return user, nil
+}
+
+// createIdentity links an upstream account to a user. It returns nil if the account is already linked.
+func (m *userManager) createIdentity(ctx context.Context, userID string, profile model.ExternalProfile) (*model.Identity, error) {
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	identity := &model.Identity{
+		ID:            id,
+		UserID:        userID,
+		ConnectionID:  profile.ConnectionID,
+		Subject:       profile.Subject,
+		Email:         profile.Email,
+		EmailVerified: profile.EmailVerified,
+		Roles:         profile.Roles,
+		CreatedAt:     now,
+		LastLoginAt:   now,
+	}
+
+	created, err := m.identityDAO.Create(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		//nolint:nilnil // An already linked account is not an error
+		return nil, nil
+	}
+
+	return identity, nil
+}
+
+// create creates a user from an upstream profile, and links the upstream account to it.
+func (m *userManager) create(ctx context.Context, profile model.ExternalProfile) (*model.User, error) {
+	userID, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	user := &model.User{
+		ID:            userID,
+		Email:         profile.Email,
+		EmailVerified: profile.EmailVerified,
+		Name:          profile.Name,
+		GivenName:     profile.GivenName,
+		FamilyName:    profile.FamilyName,
+		Picture:       profile.Picture,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	// The identity is created first: on concurrent first logins, only one of them creates the user
+	identity, err := m.createIdentity(ctx, userID, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity == nil {
+		return m.Login(ctx, profile)
+	}
+
+	if _, err = m.userDAO.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	m.auditManager.Record(ctx, model.AuditEvent{
+		Type:    model.AuditEventUserCreate,
+		Outcome: model.AuditOutcomeSuccess,
+		Actor:   model.AuditActor{Subject: user.ID},
+		Target:  &model.AuditTarget{Type: model.AuditTargetUser, ID: user.ID},
+		Details: map[string]string{"connection": profile.ConnectionID},
+	})
+
+	if err = m.joinByDomain(ctx, user, profile); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// join returns the user of an identity who is not a member of the organization of the context, once the
+// user joined it by the domain of its email. It fails if the organization does not accept the domain.
+func (m *userManager) join(ctx context.Context, userID string, profile model.ExternalProfile) (*model.User, error) {
+	user, err := m.userDAO.FindOne(tenant.WithAllOrganizations(ctx), bson.M{"_id": userID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, errors.New("the identity is linked to an unknown user")
+	}
+
+	if err = m.joinByDomain(ctx, user, profile); err != nil {
+		return nil, err
+	}
+
+	if user.Membership(tenant.OrganizationID(ctx)) == nil {
+		return nil, model.NewOAuthError(http.StatusForbidden, model.OAuthErrorAccessDenied, "the account is not a member of the organization")
+	}
+
+	return user, nil
+}
+
+// joinByDomain makes a user a member of the organizations that verified the domain of the email of an
+// upstream profile, and enabled automatic joins, with the roles of their claim. The email must have been
+// verified by the upstream provider.
+func (m *userManager) joinByDomain(ctx context.Context, user *model.User, profile model.ExternalProfile) error {
+	domain := emailDomain(profile.Email)
+	if !profile.EmailVerified || domain == "" {
+		return nil
+	}
+
+	allCtx := tenant.WithAllOrganizations(ctx)
+
+	claims, err := m.domainClaimDAO.FindMany(allCtx, bson.M{"domain": domain, "verified": true, "autoJoin": true}, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, claim := range claims {
+		if user.Membership(claim.OrganizationID) != nil {
+			continue
+		}
+
+		now := time.Now()
+		membership := model.Membership{OrganizationID: claim.OrganizationID, Roles: claim.Roles, CreatedAt: now}
+
+		filter := bson.M{"_id": user.ID, "memberships.organizationId": bson.M{"$ne": claim.OrganizationID}}
+		update := bson.M{"$push": bson.M{"memberships": membership}, "$set": bson.M{"updatedAt": now}}
+
+		if _, err = m.userDAO.Update(allCtx, filter, update, false); err != nil {
+			return err
+		}
+
+		user.Memberships = append(user.Memberships, membership)
+	}
+
+	return nil
+}
+
+// emailDomain returns the lowercased domain of an email, or an empty string if the email is invalid.
+func emailDomain(email string) string {
+	_, domain, found := strings.Cut(email, "@")
+	if !found || domain == "" || strings.Contains(domain, "@") {
+		return ""
+	}
+
+	return strings.ToLower(domain)
+}
+
+func disabledUserError() error {
+	return model.NewOAuthError(http.StatusForbidden, model.OAuthErrorAccessDenied, "the account is disabled")
+}
+
+func NewUserManager(
+	userDAO mongo.CrudDAO[model.User],
+	identityDAO mongo.CrudDAO[model.Identity],
+	domainClaimDAO mongo.CrudDAO[model.DomainClaim],
+	auditManager AuditManager,
+) UserManager {
+	return &userManager{
+		userDAO:        userDAO,
+		identityDAO:    identityDAO,
+		domainClaimDAO: domainClaimDAO,
+		auditManager:   auditManager,
+	}
+}
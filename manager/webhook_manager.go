@@ -0,0 +1,512 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// errWebhookAddressForbidden is the error of the deliveries to addresses that are not public.
+var errWebhookAddressForbidden = errors.New("the webhook resolves to an address that is not public")
+
+// webhookForbiddenPrefixes are the ranges of addresses, besides the loopback, private, link-local and
+// multicast ones, that webhooks can't reach: shared, reserved and translated addresses.
+var webhookForbiddenPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+const (
+	// webhookDeliveryLease is how long a delivery is reserved by the instance sending it. The deliveries
+	// of an instance stopped while sending them are sent again at the end of the lease.
+	webhookDeliveryLease = time.Minute
+
+	// webhookDeliveriesLimit is the number of deliveries returned by the log of a webhook.
+	webhookDeliveriesLimit = 100
+)
+
+type WebhookManager interface {
+	// Webhooks returns the webhooks of the organization of the context.
+	Webhooks(ctx context.Context) ([]model.Webhook, error)
+
+	// Get returns a webhook, or a 404 API error if it does not exist.
+	Get(ctx context.Context, webhookID string) (*model.Webhook, error)
+
+	// Create creates a webhook, with a random secret signing its deliveries. The secret is only returned once.
+	Create(ctx context.Context, webhook model.Webhook) (*model.WebhookCreation, error)
+
+	// Update replaces the URL, the description, the events and the state of a webhook.
+	Update(ctx context.Context, webhookID string, webhook model.Webhook) (*model.Webhook, error)
+
+	// Delete deletes a webhook and its deliveries.
+	Delete(ctx context.Context, webhookID string) error
+
+	// Deliveries returns the most recent deliveries of a webhook, with the log of their attempts. They are
+	// filtered by status, unless it is empty.
+	Deliveries(ctx context.Context, webhookID, status string) ([]model.WebhookDelivery, error)
+
+	// Replay queues the event of a delivery again, as a new delivery. It returns a 404 API error if the
+	// delivery does not exist.
+	Replay(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDelivery, error)
+
+	// Publish queues an audit event for the webhooks of its organization subscribed to its webhook event,
+	// if it has one. Failures are logged, and don't fail the published operation.
+	Publish(ctx context.Context, event model.AuditEvent)
+
+	// Run sends the queued deliveries until the context is canceled. Failed deliveries are retried with an
+	// exponential backoff. Several instances can run at once: each delivery is reserved by one of them.
+	Run(ctx context.Context)
+}
+
+type webhookManager struct {
+	webhookDAO         mongo.CrudDAO[model.Webhook]
+	webhookDeliveryDAO mongo.CrudDAO[model.WebhookDelivery]
+	httpClient         *http.Client
+}
+
+func (m *webhookManager) Webhooks(ctx context.Context) ([]model.Webhook, error) {
+	return m.webhookDAO.FindMany(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+}
+
+func (m *webhookManager) Get(ctx context.Context, webhookID string) (*model.Webhook, error) {
+	webhook, err := m.webhookDAO.FindOne(ctx, bson.M{"_id": webhookID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook == nil {
+		return nil, webhookNotFoundError()
+	}
+
+	return webhook, nil
+}
+
+func (m *webhookManager) Create(ctx context.Context, webhook model.Webhook) (*model.WebhookCreation, error) {
+	if err := validateWebhook(&webhook); err != nil {
+		return nil, err
+	}
+
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := security.RandomToken(tokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	secretEncrypted, err := security.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	webhook = model.Webhook{
+		ID:              id,
+		URL:             webhook.URL,
+		Description:     webhook.Description,
+		Events:          webhook.Events,
+		Disabled:        webhook.Disabled,
+		SecretEncrypted: secretEncrypted,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if _, err = m.webhookDAO.Create(ctx, &webhook); err != nil {
+		return nil, err
+	}
+
+	return &model.WebhookCreation{Webhook: webhook, Secret: secret}, nil
+}
+
+func (m *webhookManager) Update(ctx context.Context, webhookID string, webhook model.Webhook) (*model.Webhook, error) {
+	if err := validateWebhook(&webhook); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"url":         webhook.URL,
+		"description": webhook.Description,
+		"events":      webhook.Events,
+		"disabled":    webhook.Disabled,
+		"updatedAt":   time.Now(),
+	}}
+
+	res, err := m.webhookDAO.Update(ctx, bson.M{"_id": webhookID}, update, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.NotFound {
+		return nil, webhookNotFoundError()
+	}
+
+	return m.Get(ctx, webhookID)
+}
+
+func (m *webhookManager) Delete(ctx context.Context, webhookID string) error {
+	deleted, err := m.webhookDAO.Delete(ctx, bson.M{"_id": webhookID})
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return webhookNotFoundError()
+	}
+
+	_, err = m.webhookDeliveryDAO.DeleteMany(ctx, bson.M{"webhookId": webhookID})
+
+	return err
+}
+
+func (m *webhookManager) Deliveries(ctx context.Context, webhookID, status string) ([]model.WebhookDelivery, error) {
+	if _, err := m.Get(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"webhookId": webhookID}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(webhookDeliveriesLimit)
+
+	return m.webhookDeliveryDAO.FindMany(ctx, filter, opts)
+}
+
+func (m *webhookManager) Replay(ctx context.Context, webhookID, deliveryID string) (*model.WebhookDelivery, error) {
+	delivery, err := m.webhookDeliveryDAO.FindOne(ctx, bson.M{"_id": deliveryID, "webhookId": webhookID}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if delivery == nil {
+		return nil, model.NewAPIResponseError(http.StatusNotFound, "the delivery does not exist")
+	}
+
+	return m.queue(ctx, webhookID, delivery.EventID, delivery.Event, delivery.Payload)
+}
+
+func (m *webhookManager) Publish(ctx context.Context, event model.AuditEvent) {
+	eventType := event.WebhookEvent()
+	if eventType == "" {
+		return
+	}
+
+	webhooks, err := m.webhookDAO.FindMany(ctx, bson.M{
+		"disabled": false,
+		"events":   bson.M{"$in": bson.A{eventType, model.WebhookEventAll}},
+	}, nil)
+	if err != nil {
+		log.Err(err).Str("event", eventType).Msg("Could not find the webhooks of an event")
+
+		return
+	}
+
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(model.WebhookPayload{
+		ID:             event.ID,
+		Type:           eventType,
+		OrganizationID: event.OrganizationID,
+		CreatedAt:      event.CreatedAt,
+		Data:           event,
+	})
+	if err != nil {
+		log.Err(err).Str("event", eventType).Msg("Could not encode a webhook event")
+
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if _, err = m.queue(ctx, webhook.ID, event.ID, eventType, string(payload)); err != nil {
+			log.Err(err).Str("webhookId", webhook.ID).Str("event", eventType).Msg("Could not queue a webhook delivery")
+		}
+	}
+}
+
+func (m *webhookManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(config.WebhookPollInterval())
+	defer ticker.Stop()
+
+	for {
+		// The deliveries are sent one at a time, until none is due
+		if m.deliverNext(ctx) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// queue creates a delivery of an event to a webhook, sent as soon as possible.
+func (m *webhookManager) queue(ctx context.Context, webhookID, eventID, event, payload string) (*model.WebhookDelivery, error) {
+	id, err := security.RandomToken(userIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	delivery := &model.WebhookDelivery{
+		ID:            id,
+		WebhookID:     webhookID,
+		EventID:       eventID,
+		Event:         event,
+		Payload:       payload,
+		Status:        model.WebhookDeliveryPending,
+		Attempts:      make([]model.WebhookAttempt, 0),
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(config.WebhookDeliveryRetention()),
+	}
+
+	if _, err = m.webhookDeliveryDAO.Create(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// deliverNext reserves the next due delivery, of any organization, and sends it. It returns false when no
+// delivery is due.
+func (m *webhookManager) deliverNext(ctx context.Context) bool {
+	now := time.Now()
+
+	var delivery model.WebhookDelivery
+
+	err := m.webhookDeliveryDAO.GetCollection().FindOneAndUpdate(ctx,
+		bson.M{"nextAttemptAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"nextAttemptAt": now.Add(webhookDeliveryLease)}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).SetReturnDocument(options.After),
+	).Decode(&delivery)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return false
+	}
+
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Err(err).Msg("Could not reserve a webhook delivery")
+		}
+
+		return false
+	}
+
+	m.deliver(tenant.WithAllOrganizations(ctx), &delivery)
+
+	return true
+}
+
+// deliver sends a delivery, records the attempt, and schedules the next attempt if it failed. The delivery
+// is dead after the last attempt, or when its webhook is deleted or disabled.
+func (m *webhookManager) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	start := time.Now()
+	attempt := model.WebhookAttempt{At: start}
+	retry := true
+
+	webhook, err := m.webhookDAO.FindOne(ctx, bson.M{"_id": delivery.WebhookID}, nil)
+
+	switch {
+	case err != nil:
+	case webhook == nil || webhook.Disabled:
+		err = errors.New("the webhook was deleted or disabled")
+		retry = false
+	default:
+		attempt.StatusCode, err = m.post(ctx, webhook, delivery)
+
+		// The resolved address is not disclosed, and won't become public by retrying
+		if errors.Is(err, errWebhookAddressForbidden) {
+			err = errWebhookAddressForbidden
+			retry = false
+		}
+	}
+
+	attempt.Duration = time.Since(start).Milliseconds()
+
+	status := model.WebhookDeliverySucceeded
+	set := bson.M{}
+
+	if err != nil {
+		attempt.Error = err.Error()
+		status = model.WebhookDeliveryDead
+
+		attempts := len(delivery.Attempts) + 1
+		if retry && attempts < config.WebhookMaxAttempts() {
+			status = model.WebhookDeliveryPending
+			set["nextAttemptAt"] = time.Now().Add(config.WebhookRetryDelay() << (attempts - 1))
+		}
+
+		log.Warn().Err(err).Str("webhookId", delivery.WebhookID).Str("deliveryId", delivery.ID).Int("attempt", attempts).
+			Str("status", status).Msg("Could not send a webhook delivery")
+	}
+
+	set["status"] = status
+	update := bson.M{"$push": bson.M{"attempts": attempt}, "$set": set}
+
+	if status != model.WebhookDeliveryPending {
+		update["$unset"] = bson.M{"nextAttemptAt": ""}
+	}
+
+	if _, err = m.webhookDeliveryDAO.Update(ctx, bson.M{"_id": delivery.ID}, update, false); err != nil {
+		log.Err(err).Str("deliveryId", delivery.ID).Msg("Could not record a webhook delivery attempt")
+	}
+}
+
+// post sends the payload of a delivery to a webhook, signed with its secret. It returns the status code of
+// the response, and an error unless it is a 2xx.
+func (m *webhookManager) post(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) (int, error) {
+	secret, err := security.Decrypt(webhook.SecretEncrypted)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(model.WebhookHeaderEvent, delivery.Event)
+	req.Header.Set(model.WebhookHeaderDelivery, delivery.ID)
+	req.Header.Set(model.WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(model.WebhookHeaderSignature, "v1="+security.Sign(secret, timestamp+"."+delivery.Payload))
+
+	res, err := m.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return res.StatusCode, fmt.Errorf("the webhook responded with the status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// validateWebhook checks the URL and the events of a webhook.
+func validateWebhook(webhook *model.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && (u.Scheme != "http" || !config.WebhookAllowInsecureURLs())) {
+		return model.NewAPIResponseError(http.StatusBadRequest, "the URL of the webhook must be an absolute HTTPS URL")
+	}
+
+	// The host names are checked once resolved, when the deliveries are sent (see checkWebhookAddress)
+	if ip, ipErr := netip.ParseAddr(u.Hostname()); (ipErr == nil && !isPublicAddress(ip)) || strings.EqualFold(u.Hostname(), "localhost") {
+		if !config.WebhookAllowPrivateIPs() {
+			return model.NewAPIResponseError(http.StatusBadRequest, "the URL of the webhook must be a public address")
+		}
+	}
+
+	if len(webhook.Events) == 0 {
+		return model.NewAPIResponseError(http.StatusBadRequest, "the webhook must subscribe to at least one event")
+	}
+
+	for _, event := range webhook.Events {
+		if event != model.WebhookEventAll && !slices.Contains(model.WebhookEvents, event) {
+			return model.NewAPIResponseError(http.StatusBadRequest, "the "+event+" event does not exist")
+		}
+	}
+
+	slices.Sort(webhook.Events)
+	webhook.Events = slices.Compact(webhook.Events)
+
+	return nil
+}
+
+// checkWebhookAddress is the control of the connections of the deliveries, once their host is resolved:
+// the deliveries can't reach the addresses that are not public, whatever the host name resolves to.
+func checkWebhookAddress(_, address string, _ syscall.RawConn) error {
+	if config.WebhookAllowPrivateIPs() {
+		return nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil || !isPublicAddress(addrPort.Addr()) {
+		return errWebhookAddressForbidden
+	}
+
+	return nil
+}
+
+// isPublicAddress returns true if an address is a public unicast address.
+func isPublicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+
+	for _, prefix := range webhookForbiddenPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func webhookNotFoundError() error {
+	return model.NewAPIResponseError(http.StatusNotFound, "the webhook does not exist")
+}
+
+func NewWebhookManager(
+	webhookDAO mongo.CrudDAO[model.Webhook],
+	webhookDeliveryDAO mongo.CrudDAO[model.WebhookDelivery],
+) WebhookManager {
+	return &webhookManager{
+		webhookDAO:         webhookDAO,
+		webhookDeliveryDAO: webhookDeliveryDAO,
+		httpClient: &http.Client{
+			Timeout: config.ConnectionTimeout(),
+			// The deliveries are sent without proxy, so that the addresses they connect to are checked
+			Transport: &http.Transport{
+				DialContext:         (&net.Dialer{Timeout: config.ConnectionTimeout(), Control: checkWebhookAddress}).DialContext,
+				TLSHandshakeTimeout: config.ConnectionTimeout(),
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+				ForceAttemptHTTP2:   true,
+			},
+			// The redirects of the webhooks are not followed, so that they can't reach other hosts
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
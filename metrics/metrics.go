@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the names of the metrics.
+const namespace = "goauth"
+
+// HTTP metrics, by route. The requests matching no route share the UnmatchedRoute label, so that
+// unknown paths don't create new series.
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the HTTP requests, by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// UnmatchedRoute is the route label of the requests matching no route.
+const UnmatchedRoute = "unmatched"
+
+// MongoDB metrics, by collection and operation, and of the connection pools, by server.
+var (
+	MongoOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "operations_total",
+		Help:      "MongoDB operations, by collection and operation.",
+	}, []string{"collection", "operation"})
+
+	MongoOperationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "operation_errors_total",
+		Help:      "Failed MongoDB operations, by collection and operation.",
+	}, []string{"collection", "operation"})
+
+	MongoOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of the MongoDB operations, by collection and operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"collection", "operation"})
+
+	MongoPoolConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "pool_connections",
+		Help:      "Open connections of the MongoDB connection pools, by server.",
+	}, []string{"address"})
+
+	MongoPoolConnectionsInUse = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "pool_connections_in_use",
+		Help:      "Connections checked out of the MongoDB connection pools, by server.",
+	}, []string{"address"})
+
+	MongoPoolCheckoutFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "pool_checkout_failures_total",
+		Help:      "Failed checkouts of the MongoDB connection pools, by server and reason.",
+	}, []string{"address", "reason"})
+)
+
+// Authentication and rate limiting metrics.
+var (
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "logins_total",
+		Help:      "Logins of the end-users, by outcome.",
+	}, []string{"outcome"})
+
+	TokensIssued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "tokens_issued_total",
+		Help:      "Tokens issued by the token endpoint, by grant type.",
+	}, []string{"grant_type"})
+
+	TokenRequestFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "token_request_failures_total",
+		Help:      "Rejected requests of the token endpoint.",
+	})
+
+	Lockouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "lockouts_total",
+		Help:      "Login and authentication attempts locked out by their rate limit policy, by policy.",
+	}, []string{"policy"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by a rate limit policy, by policy.",
+	}, []string{"policy"})
+)
+
+// Handler returns the handler exposing the metrics, along with the metrics of the Go runtime and of the
+// process, in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
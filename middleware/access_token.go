@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/security"
+	"github.com/m3talux/goauth/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// Key of the authenticated access token in the gin context.
+const accessTokenKey = "accessToken"
+
+// AccessToken returns a middleware authenticating requests with an access token issued by goauth.
+// DPoP-bound tokens must be sent with the DPoP scheme and a valid proof (RFC 9449, section 7),
+// other tokens with the Bearer scheme (RFC 6750). Certificate-bound tokens must be sent with the
+// same client certificate (RFC 8705), and JWT access tokens must be issued for goauth. The request is
+// moved to the organization of the token, unless it targets another organization explicitly. The
+// requests already authenticated by the APIKey or the PersonalAccessToken middleware are let through.
+func AccessToken(tokenManager manager.TokenManager, dpopManager manager.DPoPManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetAccessToken(c) != nil {
+			c.Next()
+
+			return
+		}
+
+		scheme, value, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if value == "" {
+			abortUnauthorized(c, model.NewOAuthError(http.StatusUnauthorized, "", "the access token is missing"))
+
+			return
+		}
+
+		token, err := tokenManager.Introspect(c.Request.Context(), value)
+		if err != nil {
+			log.Err(err).Msg("Could not introspect an access token")
+
+			response := model.NewAPIResponseError(http.StatusInternalServerError, "the access token could not be verified")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		if token == nil || token.Kind != model.TokenKindAccessToken {
+			abortUnauthorized(c, model.NewOAuthError(http.StatusUnauthorized, model.OAuthErrorInvalidToken, "the access token is invalid or expired"))
+
+			return
+		}
+
+		// The tokens issued for other resource servers are not accepted by goauth
+		if !token.IsIntendedFor(config.OrganizationIssuer(token.OrganizationID)) {
+			abortUnauthorized(c, model.NewOAuthError(http.StatusUnauthorized, model.OAuthErrorInvalidToken, "the access token was issued for another audience"))
+
+			return
+		}
+
+		// The URL of the DPoP proofs is the URL of the organization the request targets
+		htu := requestURL(c)
+
+		if !ResumeOrganization(c, token.OrganizationID) {
+			abortUnauthorized(c, model.NewOAuthError(http.StatusUnauthorized, model.OAuthErrorInvalidToken, "the access token was issued for another organization"))
+
+			return
+		}
+
+		if err = verifyCertificateBinding(c, token); err != nil {
+			abortUnauthorized(c, err)
+
+			return
+		}
+
+		switch {
+		case strings.EqualFold(scheme, model.TokenTypeDPoP) && token.TokenType() == model.TokenTypeDPoP:
+			if err = verifyDPoPBinding(c, dpopManager, token, value, htu); err != nil {
+				abortUnauthorized(c, err)
+
+				return
+			}
+		case strings.EqualFold(scheme, model.TokenTypeBearer) && token.TokenType() == model.TokenTypeBearer:
+		default:
+			abortUnauthorized(c, model.NewOAuthError(http.StatusUnauthorized, model.OAuthErrorInvalidToken, "the authorization scheme does not match the token type"))
+
+			return
+		}
+
+		c.Set(accessTokenKey, token)
+		c.Next()
+	}
+}
+
+// GetAccessToken returns the access token authenticated by the AccessToken middleware.
+func GetAccessToken(c *gin.Context) *model.Token {
+	token, ok := c.Get(accessTokenKey)
+	if !ok {
+		return nil
+	}
+
+	t, ok := token.(*model.Token)
+	if !ok {
+		return nil
+	}
+
+	return t
+}
+
+// verifyDPoPBinding checks the DPoP proof of the request for its URL, and that it was signed with the token key.
+func verifyDPoPBinding(c *gin.Context, dpopManager manager.DPoPManager, token *model.Token, value, htu string) error {
+	proofs := c.Request.Header.Values("DPoP")
+	if len(proofs) != 1 {
+		return model.NewOAuthError(http.StatusUnauthorized, model.OAuthErrorInvalidDPoPProof, "a single DPoP proof is required")
+	}
+
+	jkt, err := dpopManager.VerifyProof(c.Request.Context(), proofs[0], c.Request.Method, htu, value)
+	if err != nil {
+		if nonce := dpopManager.Nonce(); nonce != "" {
+			c.Header("DPoP-Nonce", nonce)
+		}
+
+		return err
+	}
+
+	if jkt != token.Confirmation.JKT {
+		return model.NewOAuthError(http.StatusUnauthorized, model.OAuthErrorInvalidDPoPProof, "the DPoP proof key does not match the access token")
+	}
+
+	return nil
+}
+
+// requestURL returns the URL of a request on the issuer of its organization, like the URLs of the OAuth
+// endpoints (see tenant.URL), whether the organization was resolved from the path or from the host.
+func requestURL(c *gin.Context) string {
+	ctx := c.Request.Context()
+
+	return tenant.URL(ctx, config.OAuthIssuer()+strings.TrimPrefix(c.Request.URL.Path, tenant.Path(ctx, "")))
+}
+
+// verifyCertificateBinding checks that certificate-bound tokens are presented over a TLS connection
+// using the same client certificate (RFC 8705, section 3).
+func verifyCertificateBinding(c *gin.Context, token *model.Token) error {
+	if token.Confirmation == nil || token.Confirmation.X5TS256 == "" {
+		return nil
+	}
+
+	cert, err := security.PeerCertificate(c.Request)
+	if err != nil || cert == nil || security.CertificateThumbprint(cert) != token.Confirmation.X5TS256 {
+		return model.NewOAuthError(http.StatusUnauthorized, model.OAuthErrorInvalidToken, "the client certificate does not match the access token")
+	}
+
+	return nil
+}
+
+// abortUnauthorized sends a 401 response with the WWW-Authenticate challenges of both schemes.
+func abortUnauthorized(c *gin.Context, err error) {
+	var oauthErr model.OAuthError
+	if !errors.As(err, &oauthErr) {
+		log.Err(err).Msg("Could not verify the access token of a request")
+
+		response := model.NewAPIResponseError(http.StatusInternalServerError, "the access token could not be verified")
+		c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+		return
+	}
+
+	params := ""
+	if oauthErr.Code != "" {
+		params = fmt.Sprintf(`, error="%s", error_description="%s"`, oauthErr.Code, oauthErr.Description)
+	}
+
+	c.Writer.Header().Add("WWW-Authenticate", fmt.Sprintf(`%s realm="%s"%s`, model.TokenTypeBearer, config.AppName(), params))
+	c.Writer.Header().Add("WWW-Authenticate", fmt.Sprintf(`%s algs="%s"%s`, model.TokenTypeDPoP, strings.Join(manager.DPoPSignatureAlgorithms(), " "), params))
+
+	response := model.NewAPIResponseError(http.StatusUnauthorized, oauthErr.Description)
+	c.AbortWithStatusJSON(response.HTTPStatus(), response)
+}
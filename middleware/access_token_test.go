@@ -0,0 +1,77 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/m3talux/goauth/internal/goauthtest"
+	"github.com/m3talux/goauth/model"
+)
+
+func TestCertificateBoundAccessToken(t *testing.T) {
+	bound := goauthtest.NewClientCertificate(t, "bound-client", nil)
+
+	srv := goauthtest.NewTLSServer(t)
+	srv.AddClient(t, model.Client{
+		ID:                                    "bound-client",
+		GrantTypes:                            []string{model.GrantTypeClientCredentials},
+		Roles:                                 []string{model.RoleIDAdmin},
+		TokenEndpointAuthMethod:               model.ClientAuthMethodSelfSignedTLS,
+		JWKS:                                  bound.JWKS(t),
+		TLSClientCertificateBoundAccessTokens: true,
+	}, "")
+
+	form := url.Values{"grant_type": {model.GrantTypeClientCredentials}, "client_id": {"bound-client"}}
+
+	res, err := srv.TLSClient(bound.TLSCertificate()).Post(srv.Issuer+"/oauth2/token", "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	var token model.TokenResponse
+	if err = json.NewDecoder(res.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		t.Fatalf("no access token was issued, status %d: %v", res.StatusCode, err)
+	}
+
+	tests := []struct {
+		name         string
+		certificates []tls.Certificate
+		status       int
+	}{
+		{name: "bound certificate", certificates: []tls.Certificate{bound.TLSCertificate()}, status: http.StatusOK},
+		{
+			name:         "other certificate",
+			certificates: []tls.Certificate{goauthtest.NewClientCertificate(t, "bound-client", nil).TLSCertificate()},
+			status:       http.StatusUnauthorized,
+		},
+		{name: "without certificate", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.Issuer+"/api/v1/roles", http.NoBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", model.TokenTypeBearer+" "+token.AccessToken)
+
+			res, err := srv.TLSClient(tt.certificates...).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.status {
+				t.Fatalf("expected the status %d, got %d", tt.status, res.StatusCode)
+			}
+		})
+	}
+}
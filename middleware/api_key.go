@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/rs/zerolog/log"
+)
+
+// Key of the authenticated API key in the gin context.
+const apiKeyKey = "apiKey"
+
+// APIKey returns a middleware authenticating the requests sent with an API key, with the ApiKey scheme.
+// The request is then authenticated as with an access token granting the scopes of the key, and the
+// AccessToken middleware lets it through. The requests sent with another scheme are left to the AccessToken
+// middleware. The request is moved to the organization of the key, unless it targets another organization
+// explicitly.
+func APIKey(apiKeyManager manager.APIKeyManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, value, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, model.APIKeyScheme) {
+			c.Next()
+
+			return
+		}
+
+		apiKey, err := apiKeyManager.Authenticate(c.Request.Context(), value, c.ClientIP())
+		if err != nil {
+			log.Err(err).Msg("Could not authenticate an API key")
+
+			response := model.NewAPIResponseError(http.StatusInternalServerError, "the API key could not be verified")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		if apiKey == nil {
+			abortAPIKeyUnauthorized(c, "the API key is invalid or expired")
+
+			return
+		}
+
+		if !ResumeOrganization(c, apiKey.OrganizationID) {
+			abortAPIKeyUnauthorized(c, "the API key was issued for another organization")
+
+			return
+		}
+
+		c.Set(apiKeyKey, apiKey)
+		c.Set(accessTokenKey, &model.Token{
+			Kind:           model.TokenKindAccessToken,
+			OrganizationID: apiKey.OrganizationID,
+			ClientID:       apiKey.ClientID,
+			Subject:        apiKey.Subject,
+			Scopes:         apiKey.Scopes,
+			Permissions:    apiKey.Scopes,
+			IssuedAt:       apiKey.CreatedAt,
+		})
+		c.Next()
+	}
+}
+
+// GetAPIKey returns the API key authenticated by the APIKey middleware.
+func GetAPIKey(c *gin.Context) *model.APIKey {
+	apiKey, ok := c.Get(apiKeyKey)
+	if !ok {
+		return nil
+	}
+
+	k, ok := apiKey.(*model.APIKey)
+	if !ok {
+		return nil
+	}
+
+	return k
+}
+
+// abortAPIKeyUnauthorized sends a 401 response with the challenge of the ApiKey scheme.
+func abortAPIKeyUnauthorized(c *gin.Context, description string) {
+	c.Header("WWW-Authenticate", fmt.Sprintf(`%s realm="%s"`, model.APIKeyScheme, config.AppName()))
+
+	response := model.NewAPIResponseError(http.StatusUnauthorized, description)
+	c.AbortWithStatusJSON(response.HTTPStatus(), response)
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+)
+
+// AuditSource returns a middleware recording the client IP and the user agent of a request in the audit
+// events of the request.
+func AuditSource() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(manager.WithAuditSource(c.Request.Context(), c.ClientIP(), c.Request.UserAgent()))
+		c.Next()
+	}
+}
+
+// Audit returns a middleware recording the changes made through the administration API in the audit log:
+// the requests of the routes requiring a write permission (see Permission), whether they are allowed or not.
+// The target of the event is the resource of the last parameter of the route.
+func Audit(auditManager manager.AuditManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		permission := c.GetString(permissionKey)
+		if !strings.HasSuffix(permission, ":write") {
+			return
+		}
+
+		event := model.AuditEvent{
+			Type:    model.AuditEventAdminChange,
+			Outcome: model.AuditOutcomeSuccess,
+			Details: map[string]string{
+				"method":     c.Request.Method,
+				"route":      c.FullPath(),
+				"permission": permission,
+				"status":     strconv.Itoa(c.Writer.Status()),
+			},
+		}
+
+		if token := GetAccessToken(c); token != nil {
+			event.Actor = model.AuditActor{Subject: token.Subject, ClientID: token.ClientID}
+		}
+
+		if c.Writer.Status() >= http.StatusBadRequest {
+			event.Outcome = model.AuditOutcomeFailure
+			event.Reason = http.StatusText(c.Writer.Status())
+		}
+
+		// The organization segment of the path is not a target: the event is recorded in its organization
+		for _, param := range c.Params {
+			if param.Key != organizationParam {
+				event.Details[param.Key] = param.Value
+				event.Target = &model.AuditTarget{Type: param.Key, ID: param.Value}
+			}
+		}
+
+		auditManager.Record(c.Request.Context(), event)
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/tenant"
+)
+
+// Key of the permission required by the route in the gin context.
+const permissionKey = "permission"
+
+// Permission returns a middleware restricting a route to the access tokens granting a permission
+// through the roles of their subject. It must run after the AccessToken middleware.
+func Permission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(permissionKey, permission)
+
+		token := GetAccessToken(c)
+		if token == nil {
+			response := model.NewAPIResponseError(http.StatusUnauthorized, "the access token is missing")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		if !model.GrantsPermission(token.Permissions, permission) {
+			response := model.NewAPIResponseError(http.StatusForbidden, "the "+permission+" permission is required")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Platform returns a middleware restricting a route to the access tokens of the default organization,
+// which administers the platform. It must run after the AccessToken middleware.
+func Platform() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !tenant.Matches(c.Request.Context(), config.DefaultOrganizationID()) {
+			response := model.NewAPIResponseError(http.StatusForbidden, "the endpoint is restricted to the default organization")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/rs/zerolog/log"
+)
+
+// PersonalAccessToken returns a middleware authenticating the requests sent with a personal access token,
+// recognized by its prefix, with the Bearer scheme. The request is then authenticated as with an access
+// token issued to the end-user, and the AccessToken middleware lets it through. The other requests are left
+// to the AccessToken middleware. The request is moved to the organization of the token, unless it targets
+// another organization explicitly.
+func PersonalAccessToken(personalAccessTokenManager manager.PersonalAccessTokenManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, value, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, model.TokenTypeBearer) || !strings.HasPrefix(value, config.PersonalAccessTokenPrefix()+"_") {
+			c.Next()
+
+			return
+		}
+
+		token, err := personalAccessTokenManager.Authenticate(c.Request.Context(), value, c.ClientIP())
+		if err != nil {
+			log.Err(err).Msg("Could not authenticate a personal access token")
+
+			response := model.NewAPIResponseError(http.StatusInternalServerError, "the personal access token could not be verified")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		if token == nil {
+			abortUnauthorized(c, model.NewOAuthError(http.StatusUnauthorized, model.OAuthErrorInvalidToken, "the personal access token is invalid or expired"))
+
+			return
+		}
+
+		if !ResumeOrganization(c, token.OrganizationID) {
+			description := "the personal access token was issued for another organization"
+			abortUnauthorized(c, model.NewOAuthError(http.StatusUnauthorized, model.OAuthErrorInvalidToken, description))
+
+			return
+		}
+
+		c.Set(accessTokenKey, token)
+		c.Next()
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/metrics"
+	"github.com/m3talux/goauth/model"
+	"github.com/rs/zerolog/log"
+)
+
+// RateLimit returns a function creating the middleware that limits the requests of a route group with
+// one of the policies. The responses hold the RateLimit-* headers of the state of the bucket of the
+// request, and the rejected requests a Retry-After header. Requests are let through when the rate
+// limiter fails, and the route groups without policy are not limited.
+func RateLimit(rateLimitManager manager.RateLimitManager, policies map[string]model.RateLimitPolicy) func(name string) gin.HandlerFunc {
+	return func(name string) gin.HandlerFunc {
+		policy, found := policies[name]
+		if !found {
+			return func(c *gin.Context) {
+				c.Next()
+			}
+		}
+
+		return func(c *gin.Context) {
+			result, err := rateLimitManager.Take(c.Request.Context(), policy, rateLimitKey(c, policy.Key))
+			if err != nil {
+				log.Err(err).Str("policy", policy.Name).Msg("Could not apply a rate limit policy")
+				c.Next()
+
+				return
+			}
+
+			c.Header("RateLimit-Policy", strconv.Itoa(policy.Capacity)+";w="+strconv.Itoa(int(policy.Period.Seconds())))
+			c.Header("RateLimit-Limit", strconv.Itoa(policy.Capacity))
+			c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+			if !result.Allowed {
+				metrics.RateLimited.WithLabelValues(policy.Name).Inc()
+
+				if policy.GuardsCredentials() {
+					metrics.Lockouts.WithLabelValues(policy.Name).Inc()
+				}
+
+				c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+
+				response := model.NewAPIResponseError(http.StatusTooManyRequests, "too many requests, retry later")
+				c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+				return
+			}
+
+			c.Next()
+		}
+	}
+}
+
+// rateLimitKey returns the key of the bucket of a request. The requests are only keyed by the credentials
+// they were authenticated with, so that unauthenticated requests can't drain the bucket of others: the
+// requests without the key of the policy are keyed by IP.
+func rateLimitKey(c *gin.Context, key string) string {
+	switch key {
+	case model.RateLimitKeyUser:
+		if token := GetAccessToken(c); token != nil && token.Subject != "" {
+			return token.Subject
+		}
+
+		if session := GetSession(c); session != nil {
+			return session.Subject
+		}
+	case model.RateLimitKeyClient:
+		if token := GetAccessToken(c); token != nil && token.ClientID != "" {
+			return token.ClientID
+		}
+	case model.RateLimitKeyAPIKey:
+		if apiKey := GetAPIKey(c); apiKey != nil {
+			return apiKey.ID
+		}
+	}
+
+	return c.ClientIP()
+}
+
+// ceilSeconds rounds a delay up to the second, as in the rate limit headers.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
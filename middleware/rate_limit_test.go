@@ -0,0 +1,69 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/metrics"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRateLimitLockouts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policies := make(map[string]model.RateLimitPolicy)
+	for _, name := range []string{model.RateLimitPolicyLogin, model.RateLimitPolicyAuthentication, model.RateLimitPolicyAPI} {
+		policies[name] = model.RateLimitPolicy{Name: name, Key: model.RateLimitKeyIP, Capacity: 1, Period: time.Minute}
+	}
+
+	rateLimit := middleware.RateLimit(manager.NewMemoryRateLimitManager(), policies)
+
+	engine := gin.New()
+	for name := range policies {
+		engine.GET("/"+name, rateLimit(name), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+
+	tests := []struct {
+		policy  string
+		lockout bool
+	}{
+		{policy: model.RateLimitPolicyLogin, lockout: true},
+		{policy: model.RateLimitPolicyAuthentication, lockout: true},
+		{policy: model.RateLimitPolicyAPI, lockout: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			lockouts := testutil.ToFloat64(metrics.Lockouts.WithLabelValues(tt.policy))
+			rateLimited := testutil.ToFloat64(metrics.RateLimited.WithLabelValues(tt.policy))
+
+			for _, status := range []int{http.StatusNoContent, http.StatusTooManyRequests} {
+				w := httptest.NewRecorder()
+				engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tt.policy, http.NoBody))
+
+				if w.Code != status {
+					t.Fatalf("expected the status %d, got %d", status, w.Code)
+				}
+			}
+
+			if got := testutil.ToFloat64(metrics.RateLimited.WithLabelValues(tt.policy)) - rateLimited; got != 1 {
+				t.Fatalf("expected 1 rate limited request, got %v", got)
+			}
+
+			want := 0.0
+			if tt.lockout {
+				want = 1
+			}
+
+			if got := testutil.ToFloat64(metrics.Lockouts.WithLabelValues(tt.policy)) - lockouts; got != want {
+				t.Fatalf("expected %v lockouts, got %v", want, got)
+			}
+		})
+	}
+}
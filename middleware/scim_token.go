@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/rs/zerolog/log"
+)
+
+// Key of the authenticated SCIM token in the gin context.
+const scimTokenKey = "scimToken"
+
+// SCIMToken returns a middleware authenticating the provisioning clients calling the SCIM endpoints
+// with the bearer tokens created for them (RFC 7644, section 2). The request is moved to the organization
+// of the token, unless it targets another organization explicitly.
+func SCIMToken(scimManager manager.SCIMManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, value, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, model.TokenTypeBearer) || value == "" {
+			abortSCIMUnauthorized(c, "the bearer token is missing")
+
+			return
+		}
+
+		token, err := scimManager.Authenticate(c.Request.Context(), value)
+		if err != nil {
+			log.Err(err).Msg("Could not authenticate a SCIM token")
+
+			c.Header("Content-Type", model.SCIMContentType)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, model.NewSCIMError(http.StatusInternalServerError, "", "the token could not be verified"))
+
+			return
+		}
+
+		if token == nil {
+			abortSCIMUnauthorized(c, "the bearer token is invalid")
+
+			return
+		}
+
+		if !ResumeOrganization(c, token.OrganizationID) {
+			abortSCIMUnauthorized(c, "the bearer token was issued for another organization")
+
+			return
+		}
+
+		c.Set(scimTokenKey, token)
+		c.Next()
+	}
+}
+
+// GetSCIMToken returns the SCIM token authenticated by the SCIMToken middleware.
+func GetSCIMToken(c *gin.Context) *model.SCIMToken {
+	token, ok := c.Get(scimTokenKey)
+	if !ok {
+		return nil
+	}
+
+	t, ok := token.(*model.SCIMToken)
+	if !ok {
+		return nil
+	}
+
+	return t
+}
+
+// abortSCIMUnauthorized sends a 401 SCIM error with the challenge of the bearer scheme.
+func abortSCIMUnauthorized(c *gin.Context, detail string) {
+	c.Header("WWW-Authenticate", fmt.Sprintf(`%s realm="%s"`, model.TokenTypeBearer, config.AppName()))
+	c.Header("Content-Type", model.SCIMContentType)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, model.NewSCIMError(http.StatusUnauthorized, "", detail))
+}
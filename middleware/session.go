@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/rs/zerolog/log"
+)
+
+// Key of the authenticated login session in the gin context.
+const sessionKey = "session"
+
+// Session returns a middleware authenticating requests of logged-in end-users with their session cookie.
+func Session(sessionManager manager.SessionManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, _ := c.Cookie(config.SessionCookieName())
+		if sessionID == "" {
+			response := model.NewAPIResponseError(http.StatusUnauthorized, "the end-user is not logged in")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		session, err := sessionManager.Get(c.Request.Context(), sessionID)
+		if err != nil {
+			log.Err(err).Msg("Could not get the session of a request")
+
+			response := model.NewAPIResponseError(http.StatusInternalServerError, "the session could not be verified")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		if session == nil {
+			response := model.NewAPIResponseError(http.StatusUnauthorized, "the session is invalid or expired")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		c.Set(sessionKey, session)
+		c.Next()
+	}
+}
+
+// GetSession returns the login session authenticated by the Session middleware.
+func GetSession(c *gin.Context) *model.Session {
+	session, ok := c.Get(sessionKey)
+	if !ok {
+		return nil
+	}
+
+	s, ok := session.(*model.Session)
+	if !ok {
+		return nil
+	}
+
+	return s
+}
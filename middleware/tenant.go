@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// Keys of the organization of the request, and whether it was targeted explicitly, in the gin context.
+const (
+	organizationKey         = "organization"
+	explicitOrganizationKey = "explicitOrganization"
+)
+
+// organizationParam is the parameter of the routes holding the organization segment of the path.
+const organizationParam = "organization"
+
+// Tenant returns a middleware resolving the organization of a request, from the organization segment of
+// its path, or from its host. The requests targeting neither are served by the default organization, and
+// can be moved to another organization by their credentials (see ResumeOrganization). The context of the
+// request is scoped to the organization.
+func Tenant(organizationManager manager.OrganizationManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organization, explicit, err := organizationManager.Resolve(c.Request.Context(), c.Param(organizationParam), c.Request.Host)
+		if err != nil {
+			log.Err(err).Msg("Could not resolve the organization of a request")
+
+			response := model.NewAPIResponseError(http.StatusInternalServerError, "the organization could not be resolved")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		if organization == nil {
+			response := model.NewAPIResponseError(http.StatusNotFound, "the organization does not exist")
+			c.AbortWithStatusJSON(response.HTTPStatus(), response)
+
+			return
+		}
+
+		c.Set(organizationKey, organization)
+		c.Set(explicitOrganizationKey, explicit)
+		c.Request = c.Request.WithContext(tenant.WithOrganization(c.Request.Context(), organization.ID))
+		c.Next()
+	}
+}
+
+// GetOrganization returns the organization resolved by the Tenant middleware, or nil if the request was
+// moved to another organization since.
+func GetOrganization(c *gin.Context) *model.Organization {
+	organization, ok := c.Get(organizationKey)
+	if !ok {
+		return nil
+	}
+
+	o, ok := organization.(*model.Organization)
+	if !ok || o.ID != tenant.OrganizationID(c.Request.Context()) {
+		return nil
+	}
+
+	return o
+}
+
+// ResumeOrganization moves a request served by the default organization implicitly to the organization of
+// its credentials, like an access token or a login challenge. It returns false if the request explicitly
+// targets another organization.
+func ResumeOrganization(c *gin.Context, organizationID string) bool {
+	if organizationID == "" {
+		organizationID = config.DefaultOrganizationID()
+	}
+
+	if organizationID == tenant.OrganizationID(c.Request.Context()) {
+		return true
+	}
+
+	if c.GetBool(explicitOrganizationKey) {
+		return false
+	}
+
+	c.Set(explicitOrganizationKey, true)
+	c.Request = c.Request.WithContext(tenant.WithOrganization(c.Request.Context(), organizationID))
+
+	return true
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyScheme is the authorization scheme of the requests authenticated with an API key.
+const APIKeyScheme = "ApiKey"
+
+// APIKey is a long-lived credential of the scripts calling the administration API. A key is made of its
+// visible prefix, which identifies it, and of a secret only shown on creation: the secret is stored hashed.
+// Its scopes are the permissions granted to the requests authenticated with the key, on behalf of the
+// subject, or the client, who created it. Keys without expiration date never expire.
+type APIKey struct {
+	ID             string     `bson:"_id"                      json:"id"`
+	OrganizationID string     `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	Name           string     `bson:"name"                     json:"name"`
+	Prefix         string     `bson:"prefix"                   json:"prefix"`
+	SecretHash     string     `bson:"secretHash"               json:"-"`
+	Subject        string     `bson:"subject,omitempty"        json:"subject,omitempty"`
+	ClientID       string     `bson:"clientId,omitempty"       json:"client_id,omitempty"`
+	Scopes         []string   `bson:"scopes"                   json:"scopes"`
+	ExpiresAt      *time.Time `bson:"expiresAt,omitempty"      json:"expires_at,omitempty"`
+	LastUsedAt     *time.Time `bson:"lastUsedAt,omitempty"     json:"last_used_at,omitempty"`
+	LastUsedIP     string     `bson:"lastUsedIp,omitempty"     json:"last_used_ip,omitempty"`
+	CreatedAt      time.Time  `bson:"createdAt"                json:"created_at"`
+}
+
+// APIKeyCreation is the response to the creation of an API key, the only one holding its value.
+type APIKeyCreation struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+func (k APIKey) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "prefix", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+}
+
+func (k APIKey) NameSingular() string {
+	return "API key"
+}
+
+func (k APIKey) NamePlural() string {
+	return "API keys"
+}
+
+func (k APIKey) CollectionName() string {
+	return "apiKeys"
+}
+
+func (k APIKey) TenantField() string {
+	return "organizationId"
+}
+
+func (k *APIKey) SetTenant(organizationID string) {
+	k.OrganizationID = organizationID
+}
+
+// IsExpired returns true if the key has an expiration date, and it is past.
+func (k APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
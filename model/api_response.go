@@ -18,6 +18,11 @@ func (a APIResponse) HTTPStatus() int {
 	return a.StatusCode
 }
 
+// Error lets managers return API error responses as errors.
+func (a APIResponse) Error() string {
+	return a.Message
+}
+
 func NewAPIResponseError(statusCode int, message string) APIResponse {
 	return APIResponse{
 		StatusCode: statusCode,
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Types of the audit events.
+const (
+	AuditEventLogin       = "login"
+	AuditEventLogout      = "logout"
+	AuditEventTokenIssue  = "token.issue"
+	AuditEventTokenRevoke = "token.revoke"
+	AuditEventAdminChange = "admin.change"
+	AuditEventUserCreate  = "user.create"
+	AuditEventUserUpdate  = "user.update"
+	AuditEventUserDelete  = "user.delete"
+)
+
+// Outcomes of the audit events.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// Types of the targets of the audit events.
+const (
+	AuditTargetUser                = "user"
+	AuditTargetSession             = "session"
+	AuditTargetConnection          = "connection"
+	AuditTargetAPIKey              = "api_key"
+	AuditTargetPersonalAccessToken = "personal_access_token"
+	AuditTargetSCIMToken           = "scim_token"
+)
+
+// AuditEvent records an authentication or administration event: who did what, on what, from where, and
+// whether it succeeded. Audit events are append-only: they are never updated, and expire at the end of the
+// retention of the configuration, if any.
+type AuditEvent struct {
+	ID             string            `bson:"_id"                      json:"id"`
+	OrganizationID string            `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	Type           string            `bson:"type"                     json:"type"`
+	Outcome        string            `bson:"outcome"                  json:"outcome"`
+	Actor          AuditActor        `bson:"actor"                    json:"actor"`
+	Target         *AuditTarget      `bson:"target,omitempty"         json:"target,omitempty"`
+	Reason         string            `bson:"reason,omitempty"         json:"reason,omitempty"`
+	Details        map[string]string `bson:"details,omitempty"        json:"details,omitempty"`
+	IP             string            `bson:"ip,omitempty"             json:"ip,omitempty"`
+	UserAgent      string            `bson:"userAgent,omitempty"      json:"user_agent,omitempty"`
+	CreatedAt      time.Time         `bson:"createdAt"                json:"created_at"`
+	ExpiresAt      *time.Time        `bson:"expiresAt,omitempty"      json:"-"`
+}
+
+// AuditActor is the end-user, or the client, who triggered an audit event.
+type AuditActor struct {
+	Subject  string `bson:"subject,omitempty"  json:"subject,omitempty"`
+	ClientID string `bson:"clientId,omitempty" json:"client_id,omitempty"`
+}
+
+// AuditTarget is the resource an audit event acted on.
+type AuditTarget struct {
+	Type string `bson:"type" json:"type"`
+	ID   string `bson:"id"   json:"id"`
+}
+
+// AuditQuery filters the audit events. Events are returned from the most recent, a page at a time: the
+// cursor of the next page is returned along with each page.
+type AuditQuery struct {
+	Type    string
+	Outcome string
+	Subject string
+	Client  string
+	Target  string
+	IP      string
+	Since   *time.Time
+	Until   *time.Time
+	Cursor  string
+	Limit   int
+}
+
+// AuditPage is a page of the audit events matching a query. NextCursor is empty on the last page.
+type AuditPage struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// WebhookEvent returns the event sent to the webhooks for an audit event, or an empty string if the audit
+// event is not sent to the webhooks.
+func (e AuditEvent) WebhookEvent() string {
+	if e.Type == AuditEventLogin && e.Outcome == AuditOutcomeFailure {
+		return WebhookEventLoginFailed
+	}
+
+	if e.Outcome != AuditOutcomeSuccess {
+		return ""
+	}
+
+	switch e.Type {
+	case AuditEventLogin:
+		return WebhookEventLoginSucceeded
+	case AuditEventLogout:
+		return WebhookEventSessionEnded
+	case AuditEventTokenRevoke:
+		return WebhookEventTokenRevoked
+	case AuditEventUserCreate:
+		return WebhookEventUserCreated
+	case AuditEventUserUpdate:
+		return WebhookEventUserUpdated
+	case AuditEventUserDelete:
+		return WebhookEventUserDeleted
+	}
+
+	return ""
+}
+
+func (e AuditEvent) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "actor.subject", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "target.id", Value: 1}, {Key: "createdAt", Value: -1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (e AuditEvent) NameSingular() string {
+	return "audit event"
+}
+
+func (e AuditEvent) NamePlural() string {
+	return "audit events"
+}
+
+func (e AuditEvent) CollectionName() string {
+	return "auditEvents"
+}
+
+func (e AuditEvent) TenantField() string {
+	return "organizationId"
+}
+
+func (e *AuditEvent) SetTenant(organizationID string) {
+	e.OrganizationID = organizationID
+}
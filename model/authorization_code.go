@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuthorizationCode is an issued authorization code, stored hashed like tokens. The token request must
+// send the redirect URI of the code when RedirectURIRequired is set, see AuthorizationRequest.
+type AuthorizationCode struct {
+	ID                  string    `bson:"_id"`
+	OrganizationID      string    `bson:"organizationId,omitempty"`
+	ClientID            string    `bson:"clientId"`
+	Subject             string    `bson:"subject"`
+	RedirectURI         string    `bson:"redirectUri"`
+	RedirectURIRequired bool      `bson:"redirectUriRequired"`
+	Scopes              []string  `bson:"scopes"`
+	Nonce               string    `bson:"nonce,omitempty"`
+	CodeChallenge       string    `bson:"codeChallenge,omitempty"`
+	CodeChallengeMethod string    `bson:"codeChallengeMethod,omitempty"`
+	DPoPJKT             string    `bson:"dpopJkt,omitempty"`
+	SessionID           string    `bson:"sessionId,omitempty"`
+	AuthTime            time.Time `bson:"authTime"`
+	CreatedAt           time.Time `bson:"createdAt"`
+	ExpiresAt           time.Time `bson:"expiresAt"`
+}
+
+func (a AuthorizationCode) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (a AuthorizationCode) NameSingular() string {
+	return "authorization code"
+}
+
+func (a AuthorizationCode) NamePlural() string {
+	return "authorization codes"
+}
+
+func (a AuthorizationCode) CollectionName() string {
+	return "authorizationCodes"
+}
+
+func (a AuthorizationCode) TenantField() string {
+	return "organizationId"
+}
+
+func (a *AuthorizationCode) SetTenant(organizationID string) {
+	a.OrganizationID = organizationID
+}
+
+// IsExpired returns true if the code lifetime is over.
+func (a AuthorizationCode) IsExpired(now time.Time) bool {
+	return !now.Before(a.ExpiresAt)
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuthorizationRequest is a validated authorization request, waiting for the end-user to log in.
+// Its identifier is used as the login challenge given to the login page. When the login was requested by
+// another protocol (a SAML service provider for instance), ResumeURL is where the end-user is sent back to
+// once logged in, instead of the client redirect URI. It is not restricted to its organization, so that
+// login pages served from the root can resume it: the login then continues in the organization. When the
+// login accepts an invitation, InvitationID is the invitation accepted by the end-user once logged in.
+// RedirectURIRequired is set when the redirect URI was sent by the client, rather than defaulted to its
+// single registered one: the token request must then send it again.
+type AuthorizationRequest struct {
+	ID                  string    `bson:"_id"`
+	OrganizationID      string    `bson:"organizationId,omitempty"`
+	ClientID            string    `bson:"clientId"`
+	ResponseType        string    `bson:"responseType"`
+	RedirectURI         string    `bson:"redirectUri"`
+	RedirectURIRequired bool      `bson:"redirectUriRequired"`
+	Scopes              []string  `bson:"scopes"`
+	State               string    `bson:"state,omitempty"`
+	Nonce               string    `bson:"nonce,omitempty"`
+	CodeChallenge       string    `bson:"codeChallenge,omitempty"`
+	CodeChallengeMethod string    `bson:"codeChallengeMethod,omitempty"`
+	DPoPJKT             string    `bson:"dpopJkt,omitempty"`
+	ResumeURL           string    `bson:"resumeUrl,omitempty"`
+	InvitationID        string    `bson:"invitationId,omitempty"`
+	Pushed              bool      `bson:"pushed"`
+	CreatedAt           time.Time `bson:"createdAt"`
+	ExpiresAt           time.Time `bson:"expiresAt"`
+}
+
+func (a AuthorizationRequest) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (a AuthorizationRequest) NameSingular() string {
+	return "authorization request"
+}
+
+func (a AuthorizationRequest) NamePlural() string {
+	return "authorization requests"
+}
+
+func (a AuthorizationRequest) CollectionName() string {
+	return "authorizationRequests"
+}
+
+// IsExpired returns true if the end-user did not log in before the end of the request lifetime.
+func (a AuthorizationRequest) IsExpired(now time.Time) bool {
+	return !now.Before(a.ExpiresAt)
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	ClientAuthMethodNone          = "none"
+	ClientAuthMethodSecretBasic   = "client_secret_basic"
+	ClientAuthMethodSecretPost    = "client_secret_post"
+	ClientAuthMethodTLS           = "tls_client_auth"
+	ClientAuthMethodSelfSignedTLS = "self_signed_tls_client_auth"
+	ClientAuthMethodPrivateKeyJWT = "private_key_jwt"
+	ClientAuthMethodSecretJWT     = "client_secret_jwt"
+)
+
+// ClientAssertionTypeJWTBearer is the client_assertion_type of JWT client assertions (RFC 7523).
+const ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// Access token formats: opaque reference tokens validated through introspection, or self-contained
+// JWT access tokens (RFC 9068).
+const (
+	AccessTokenFormatOpaque = "opaque"
+	AccessTokenFormatJWT    = "jwt"
+)
+
+const (
+	ResponseTypeCode        = "code"
+	CodeChallengeMethodS256 = "S256"
+)
+
+// Client is an OAuth client registered on the authorization server.
+// SecretEncrypted holds the secret of client_secret_jwt clients, encrypted since it is needed to verify
+// their assertions. JWKS is the client JSON Web Key Set document (or JWKSURI its location), holding the
+// keys of private_key_jwt clients and the certificates of self_signed_tls_client_auth clients.
+// The TLSClientAuth fields identify the certificate of tls_client_auth clients, only one of them
+// should be set (RFC 8705, section 2.1.2).
+// AccessTokenFormat is the format of the access tokens issued to the client, opaque by default, and
+// AccessTokenAudiences the resource servers JWT access tokens are intended for. Roles are the
+// identifiers of the roles assigned to the client, granted to its client credentials tokens.
+type Client struct {
+	ID                                    string    `bson:"_id"                                   json:"client_id"`
+	OrganizationID                        string    `bson:"organizationId,omitempty"              json:"organization_id,omitempty"`
+	SecretHash                            string    `bson:"secretHash,omitempty"                  json:"-"`
+	SecretEncrypted                       string    `bson:"secretEncrypted,omitempty"             json:"-"`
+	Name                                  string    `bson:"name"                                  json:"client_name"`
+	RedirectURIs                          []string  `bson:"redirectUris"                          json:"redirect_uris"`
+	GrantTypes                            []string  `bson:"grantTypes"                            json:"grant_types"`
+	Scopes                                []string  `bson:"scopes"                                json:"scopes"`
+	TokenEndpointAuthMethod               string    `bson:"tokenEndpointAuthMethod"               json:"token_endpoint_auth_method"`
+	RequirePushedAuthorizationRequests    bool      `bson:"requirePushedAuthorizationRequests"    json:"require_pushed_authorization_requests"`
+	JWKS                                  string    `bson:"jwks,omitempty"                        json:"jwks,omitempty"`
+	JWKSURI                               string    `bson:"jwksUri,omitempty"                     json:"jwks_uri,omitempty"`
+	TLSClientAuthSubjectDN                string    `bson:"tlsClientAuthSubjectDn,omitempty"      json:"tls_client_auth_subject_dn,omitempty"`
+	TLSClientAuthSANDNS                   string    `bson:"tlsClientAuthSanDns,omitempty"         json:"tls_client_auth_san_dns,omitempty"`
+	TLSClientAuthSANURI                   string    `bson:"tlsClientAuthSanUri,omitempty"         json:"tls_client_auth_san_uri,omitempty"`
+	TLSClientAuthSANIP                    string    `bson:"tlsClientAuthSanIp,omitempty"          json:"tls_client_auth_san_ip,omitempty"`
+	TLSClientAuthSANEmail                 string    `bson:"tlsClientAuthSanEmail,omitempty"       json:"tls_client_auth_san_email,omitempty"`
+	TLSClientCertificateBoundAccessTokens bool      `bson:"tlsClientCertificateBoundAccessTokens" json:"tls_client_certificate_bound_access_tokens"`
+	PostLogoutRedirectURIs                []string  `bson:"postLogoutRedirectUris"                json:"post_logout_redirect_uris"`
+	BackchannelLogoutURI                  string    `bson:"backchannelLogoutUri,omitempty"        json:"backchannel_logout_uri,omitempty"`
+	BackchannelLogoutSessionRequired      bool      `bson:"backchannelLogoutSessionRequired"      json:"backchannel_logout_session_required"`
+	FrontchannelLogoutURI                 string    `bson:"frontchannelLogoutUri,omitempty"       json:"frontchannel_logout_uri,omitempty"`
+	FrontchannelLogoutSessionRequired     bool      `bson:"frontchannelLogoutSessionRequired"     json:"frontchannel_logout_session_required"`
+	AccessTokenFormat                     string    `bson:"accessTokenFormat,omitempty"           json:"access_token_format,omitempty"`
+	AccessTokenAudiences                  []string  `bson:"accessTokenAudiences,omitempty"        json:"access_token_audiences,omitempty"`
+	Roles                                 []string  `bson:"roles,omitempty"                       json:"roles,omitempty"`
+	CreatedAt                             time.Time `bson:"createdAt"                             json:"created_at"`
+	UpdatedAt                             time.Time `bson:"updatedAt"                             json:"updated_at"`
+}
+
+func (c Client) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}},
+		},
+	}
+}
+
+func (c Client) NameSingular() string {
+	return "client"
+}
+
+func (c Client) NamePlural() string {
+	return "clients"
+}
+
+func (c Client) CollectionName() string {
+	return "clients"
+}
+
+func (c Client) TenantField() string {
+	return "organizationId"
+}
+
+func (c *Client) SetTenant(organizationID string) {
+	c.OrganizationID = organizationID
+}
+
+// IsPublic returns true if the client cannot keep a secret (native and browser based applications).
+func (c Client) IsPublic() bool {
+	return c.TokenEndpointAuthMethod == ClientAuthMethodNone
+}
+
+// HasRedirectURI returns true if the given URI is one of the registered redirect URIs (exact match).
+func (c Client) HasRedirectURI(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+// HasGrantType returns true if the client is allowed to use the given grant type.
+// Clients without registered grant types may only use the authorization code grant.
+func (c Client) HasGrantType(grantType string) bool {
+	if len(c.GrantTypes) == 0 {
+		return grantType == GrantTypeAuthorizationCode
+	}
+
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+// HasPostLogoutRedirectURI returns true if the given URI is one of the registered post logout redirect URIs.
+func (c Client) HasPostLogoutRedirectURI(uri string) bool {
+	return slices.Contains(c.PostLogoutRedirectURIs, uri)
+}
+
+// KeySet parses the JSON Web Key Set registered for the client.
+func (c Client) KeySet() (*jose.JSONWebKeySet, error) {
+	keySet := new(jose.JSONWebKeySet)
+
+	if err := json.Unmarshal([]byte(c.JWKS), keySet); err != nil {
+		return nil, err
+	}
+
+	return keySet, nil
+}
+
+// AllowsScopes returns true if every given scope was registered for the client.
+func (c Client) AllowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,179 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Connection types: OpenID providers, whose endpoints can be discovered from the issuer, plain
+// OAuth 2.0 providers (like GitHub), whose profile is read from a user info endpoint, and SAML 2.0
+// identity providers, whose profile is read from the attributes of the assertion, and LDAP directories
+// (like Active Directory), verifying the credentials of the end-users.
+const (
+	ConnectionTypeOIDC   = "oidc"
+	ConnectionTypeOAuth2 = "oauth2"
+	ConnectionTypeSAML   = "saml"
+	ConnectionTypeLDAP   = "ldap"
+)
+
+// Attributes of the local users that can be mapped from the claims of an upstream provider.
+const (
+	UserAttributeSubject       = "subject"
+	UserAttributeEmail         = "email"
+	UserAttributeEmailVerified = "email_verified"
+	UserAttributeName          = "name"
+	UserAttributeGivenName     = "given_name"
+	UserAttributeFamilyName    = "family_name"
+	UserAttributePicture       = "picture"
+)
+
+// defaultClaimMapping maps the user attributes to the standard OpenID Connect claims.
+var defaultClaimMapping = map[string]string{
+	UserAttributeSubject:       "sub",
+	UserAttributeEmail:         "email",
+	UserAttributeEmailVerified: "email_verified",
+	UserAttributeName:          "name",
+	UserAttributeGivenName:     "given_name",
+	UserAttributeFamilyName:    "family_name",
+	UserAttributePicture:       "picture",
+}
+
+// defaultSAMLAttributeMapping maps the user attributes to the usual attributes of SAML assertions (LDAP
+// and eduPerson friendly names). The subject is the name identifier of the assertion.
+var defaultSAMLAttributeMapping = map[string]string{
+	UserAttributeSubject:    "sub",
+	UserAttributeEmail:      "mail",
+	UserAttributeName:       "cn",
+	UserAttributeGivenName:  "givenName",
+	UserAttributeFamilyName: "sn",
+}
+
+// defaultLDAPAttributeMapping maps the user attributes to the usual LDAP attributes. Active Directory
+// connections should map the subject to objectGUID.
+var defaultLDAPAttributeMapping = map[string]string{
+	UserAttributeSubject:    "entryUUID",
+	UserAttributeEmail:      "mail",
+	UserAttributeName:       "cn",
+	UserAttributeGivenName:  "givenName",
+	UserAttributeFamilyName: "sn",
+}
+
+// Connection is an upstream identity provider end-users can log in with (Google, GitHub, Microsoft or any
+// OpenID provider). The endpoints of OpenID providers are discovered from the issuer, unless they are
+// overridden. ClaimMapping maps user attributes to the claims of the provider, when they differ from the
+// standard OpenID Connect ones. For SAML identity providers, the claims are the attributes of the
+// assertion, by name or friendly name, and the subject is the name identifier by default.
+// SAMLIdPMetadata is the imported metadata of a SAML identity provider, and LDAP the settings of LDAP
+// directories, whose claims are the attributes of the user entry.
+type Connection struct {
+	ID                    string            `bson:"_id"                             json:"id"`
+	OrganizationID        string            `bson:"organizationId,omitempty"        json:"organization_id,omitempty"`
+	Name                  string            `bson:"name"                            json:"name"`
+	Type                  string            `bson:"type"                            json:"type"`
+	Issuer                string            `bson:"issuer,omitempty"                json:"issuer,omitempty"`
+	AuthorizationEndpoint string            `bson:"authorizationEndpoint,omitempty" json:"authorization_endpoint,omitempty"`
+	TokenEndpoint         string            `bson:"tokenEndpoint,omitempty"         json:"token_endpoint,omitempty"`
+	UserInfoEndpoint      string            `bson:"userInfoEndpoint,omitempty"      json:"userinfo_endpoint,omitempty"`
+	JWKSURI               string            `bson:"jwksUri,omitempty"               json:"jwks_uri,omitempty"`
+	ClientID              string            `bson:"clientId"                        json:"client_id"`
+	ClientSecretEncrypted string            `bson:"clientSecretEncrypted"           json:"-"`
+	Scopes                []string          `bson:"scopes"                          json:"scopes"`
+	ClaimMapping          map[string]string `bson:"claimMapping,omitempty"          json:"claim_mapping,omitempty"`
+	SAMLIdPMetadata       string            `bson:"samlIdpMetadata,omitempty"       json:"saml_idp_metadata,omitempty"`
+	LDAP                  *LDAPSettings     `bson:"ldap,omitempty"                  json:"ldap,omitempty"`
+	Enabled               bool              `bson:"enabled"                         json:"enabled"`
+	CreatedAt             time.Time         `bson:"createdAt"                       json:"created_at"`
+	UpdatedAt             time.Time         `bson:"updatedAt"                       json:"updated_at"`
+}
+
+func (c Connection) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}},
+		},
+	}
+}
+
+func (c Connection) NameSingular() string {
+	return "connection"
+}
+
+func (c Connection) NamePlural() string {
+	return "connections"
+}
+
+func (c Connection) CollectionName() string {
+	return "connections"
+}
+
+func (c Connection) TenantField() string {
+	return "organizationId"
+}
+
+func (c *Connection) SetTenant(organizationID string) {
+	c.OrganizationID = organizationID
+}
+
+// Claim returns the name of the upstream claim, or SAML or LDAP attribute, holding the given user attribute.
+func (c Connection) Claim(attribute string) string {
+	if claim, found := c.ClaimMapping[attribute]; found {
+		return claim
+	}
+
+	switch c.Type {
+	case ConnectionTypeSAML:
+		return defaultSAMLAttributeMapping[attribute]
+	case ConnectionTypeLDAP:
+		return defaultLDAPAttributeMapping[attribute]
+	}
+
+	return defaultClaimMapping[attribute]
+}
+
+// LDAPSettings are the settings of an LDAP directory. URL is the ldap:// or ldaps:// URL of the server,
+// and StartTLS upgrades ldap:// connections to TLS. CACertificate is the PEM certificate of the authority
+// of the server, when it is not publicly trusted. The service account of BindDN searches the entry of the
+// end-user with UserSearchFilter, where {username} is replaced by the escaped username, then the password
+// is verified by binding as the entry. The groups of the end-user are searched with GroupSearchFilter,
+// where {dn} is replaced by the escaped DN of the entry, or read from its memberOf attribute when there is
+// no GroupSearchBase. GroupRoles grant roles to the members of groups.
+type LDAPSettings struct {
+	URL                   string          `bson:"url"                             json:"url"`
+	StartTLS              bool            `bson:"startTls"                        json:"start_tls"`
+	CACertificate         string          `bson:"caCertificate,omitempty"         json:"ca_certificate,omitempty"`
+	BindDN                string          `bson:"bindDn,omitempty"                json:"bind_dn,omitempty"`
+	BindPasswordEncrypted string          `bson:"bindPasswordEncrypted,omitempty" json:"-"`
+	UserSearchBase        string          `bson:"userSearchBase"                  json:"user_search_base"`
+	UserSearchFilter      string          `bson:"userSearchFilter"                json:"user_search_filter"`
+	GroupSearchBase       string          `bson:"groupSearchBase,omitempty"       json:"group_search_base,omitempty"`
+	GroupSearchFilter     string          `bson:"groupSearchFilter,omitempty"     json:"group_search_filter,omitempty"`
+	GroupRoles            []LDAPGroupRole `bson:"groupRoles,omitempty"            json:"group_roles,omitempty"`
+}
+
+// LDAPGroupRole grants a role to the members of an LDAP group, identified by its DN.
+type LDAPGroupRole struct {
+	GroupDN string `bson:"groupDn" json:"group_dn"`
+	Role    string `bson:"role"    json:"role"`
+}
+
+// ExternalProfile is the profile of an end-user, as returned by an upstream provider. Roles are the roles
+// granted by the upstream provider, like the ones mapped from the groups of a directory.
+type ExternalProfile struct {
+	ConnectionID  string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+	Picture       string
+	Roles         []string
+}
+
+// LoginConnection is a connection as listed to the login page.
+type LoginConnection struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
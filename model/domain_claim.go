@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DomainClaim is an email domain claimed by an organization. The claim is verified once the verification
+// token is published in a DNS TXT record of the domain, and a domain can only be verified by one
+// organization. The end-users with a verified email of a verified domain are routed to the connection
+// of the claim, and automatically join the organization with the given roles when AutoJoin is set.
+type DomainClaim struct {
+	ID                string     `bson:"_id"                      json:"-"`
+	OrganizationID    string     `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	Domain            string     `bson:"domain"                   json:"domain"`
+	VerificationToken string     `bson:"verificationToken"        json:"verification_token"`
+	Verified          bool       `bson:"verified"                 json:"verified"`
+	VerifiedAt        *time.Time `bson:"verifiedAt,omitempty"     json:"verified_at,omitempty"`
+	ConnectionID      string     `bson:"connectionId,omitempty"   json:"connection_id,omitempty"`
+	AutoJoin          bool       `bson:"autoJoin"                 json:"auto_join"`
+	Roles             []string   `bson:"roles,omitempty"          json:"roles,omitempty"`
+	CreatedAt         time.Time  `bson:"createdAt"                json:"created_at"`
+	UpdatedAt         time.Time  `bson:"updatedAt"                json:"updated_at"`
+}
+
+// DomainDiscovery routes an end-user to the organization claiming the domain of the email of the end-user,
+// and to its connection, if any.
+type DomainDiscovery struct {
+	OrganizationID string `json:"organization_id"`
+	Issuer         string `json:"issuer"`
+	ConnectionID   string `json:"connection_id,omitempty"`
+}
+
+func (d DomainClaim) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "organizationId", Value: 1}, {Key: "domain", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "domain", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"verified": true}),
+		},
+	}
+}
+
+func (d DomainClaim) NameSingular() string {
+	return "domain claim"
+}
+
+func (d DomainClaim) NamePlural() string {
+	return "domain claims"
+}
+
+func (d DomainClaim) CollectionName() string {
+	return "domainClaims"
+}
+
+func (d DomainClaim) TenantField() string {
+	return "organizationId"
+}
+
+func (d *DomainClaim) SetTenant(organizationID string) {
+	d.OrganizationID = organizationID
+}
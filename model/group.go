@@ -0,0 +1,71 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Group is a named set of users, provisioned from the directory of an organization (SCIM) or managed
+// with the administration API.
+// Members are the identifiers of the users, and MemberGroups the identifiers of the groups whose members
+// are members of the group too. The roles of a group are granted to its members, direct or nested.
+type Group struct {
+	ID             string    `bson:"_id"                      json:"id"`
+	OrganizationID string    `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	DisplayName    string    `bson:"displayName"              json:"display_name"`
+	ExternalID     string    `bson:"externalId,omitempty"     json:"external_id,omitempty"`
+	Members        []string  `bson:"members"                  json:"members"`
+	MemberGroups   []string  `bson:"memberGroups,omitempty"   json:"member_groups,omitempty"`
+	Roles          []string  `bson:"roles,omitempty"          json:"roles,omitempty"`
+	CreatedAt      time.Time `bson:"createdAt"                json:"created_at"`
+	UpdatedAt      time.Time `bson:"updatedAt"                json:"updated_at"`
+}
+
+func (g Group) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "displayName", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "externalId", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "members", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "memberGroups", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "roles", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+}
+
+func (g Group) NameSingular() string {
+	return "group"
+}
+
+func (g Group) NamePlural() string {
+	return "groups"
+}
+
+func (g Group) CollectionName() string {
+	return "groups"
+}
+
+func (g Group) TenantField() string {
+	return "organizationId"
+}
+
+func (g *Group) SetTenant(organizationID string) {
+	g.OrganizationID = organizationID
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Identity links the account of an end-user on an upstream provider to a local user. Roles are the roles
+// granted by the upstream provider, synchronized on each login.
+type Identity struct {
+	ID            string    `bson:"_id"             json:"id"`
+	UserID        string    `bson:"userId"          json:"user_id"`
+	ConnectionID  string    `bson:"connectionId"    json:"connection_id"`
+	Subject       string    `bson:"subject"         json:"subject"`
+	Email         string    `bson:"email,omitempty" json:"email,omitempty"`
+	EmailVerified bool      `bson:"emailVerified"   json:"email_verified"`
+	Roles         []string  `bson:"roles,omitempty" json:"roles,omitempty"`
+	CreatedAt     time.Time `bson:"createdAt"       json:"created_at"`
+	LastLoginAt   time.Time `bson:"lastLoginAt"     json:"last_login_at"`
+}
+
+func (i Identity) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "connectionId", Value: 1}, {Key: "subject", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}},
+		},
+	}
+}
+
+func (i Identity) NameSingular() string {
+	return "identity"
+}
+
+func (i Identity) NamePlural() string {
+	return "identities"
+}
+
+func (i Identity) CollectionName() string {
+	return "identities"
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Invitation invites someone to join an organization with the given roles. Its link can be used once,
+// by the end-user logging in with the invited email: the account of the end-user is made a member of the
+// organization, and created if it does not exist. The token of the link is only shown on creation: it is
+// stored hashed.
+type Invitation struct {
+	ID             string    `bson:"_id"                      json:"id"`
+	OrganizationID string    `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	Email          string    `bson:"email"                    json:"email"`
+	Roles          []string  `bson:"roles,omitempty"          json:"roles,omitempty"`
+	InvitedBy      string    `bson:"invitedBy,omitempty"      json:"invited_by,omitempty"`
+	TokenHash      string    `bson:"tokenHash"                json:"-"`
+	CreatedAt      time.Time `bson:"createdAt"                json:"created_at"`
+	ExpiresAt      time.Time `bson:"expiresAt"                json:"expires_at"`
+}
+
+// InvitationCreation is the response to the creation of an invitation, the only one holding its link, to
+// send to the invitee.
+type InvitationCreation struct {
+	Invitation
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+func (i Invitation) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}, {Key: "email", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (i Invitation) NameSingular() string {
+	return "invitation"
+}
+
+func (i Invitation) NamePlural() string {
+	return "invitations"
+}
+
+func (i Invitation) CollectionName() string {
+	return "invitations"
+}
+
+func (i Invitation) TenantField() string {
+	return "organizationId"
+}
+
+func (i *Invitation) SetTenant(organizationID string) {
+	i.OrganizationID = organizationID
+}
+
+// IsExpired returns true if the invitation was not accepted before the end of its lifetime.
+func (i Invitation) IsExpired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
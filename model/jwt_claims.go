@@ -0,0 +1,45 @@
+package model
+
+import (
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// BackchannelLogoutEvent is the event identifier of logout tokens.
+const BackchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// Types (typ header) of the JWTs issued by goauth.
+const (
+	JWTTypeIDToken     = "JWT"
+	JWTTypeAccessToken = "at+jwt"
+	JWTTypeLogoutToken = "logout+jwt"
+)
+
+// IDTokenClaims are the claims of the ID tokens issued by goauth (OpenID Connect Core 1.0, section 2).
+type IDTokenClaims struct {
+	jwt.Claims
+	Nonce     string `json:"nonce,omitempty"`
+	AuthTime  int64  `json:"auth_time,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+}
+
+// LogoutTokenClaims are the claims of back-channel logout tokens (OpenID Connect Back-Channel Logout 1.0, section 2.4).
+type LogoutTokenClaims struct {
+	jwt.Claims
+	SessionID string                    `json:"sid,omitempty"`
+	Events    map[string]map[string]any `json:"events"`
+}
+
+// AccessTokenClaims are the claims of JWT access tokens (RFC 9068, section 2.2).
+type AccessTokenClaims struct {
+	jwt.Claims
+	ClientID       string        `json:"client_id"`
+	Scope          string        `json:"scope,omitempty"`
+	AuthTime       int64         `json:"auth_time,omitempty"`
+	SessionID      string        `json:"sid,omitempty"`
+	Confirmation   *Confirmation `json:"cnf,omitempty"`
+	OrganizationID string        `json:"org_id,omitempty"`
+	Roles          []string      `json:"roles,omitempty"`
+	Permissions    []string      `json:"permissions,omitempty"`
+	Groups         []string      `json:"groups,omitempty"`
+	GroupsOverage  bool          `json:"groups_overage,omitempty"`
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"net/http"
+)
+
+const (
+	OAuthErrorInvalidRequest          = "invalid_request"
+	OAuthErrorInvalidClient           = "invalid_client"
+	OAuthErrorInvalidGrant            = "invalid_grant"
+	OAuthErrorInvalidScope            = "invalid_scope"
+	OAuthErrorInvalidRequestURI       = "invalid_request_uri"
+	OAuthErrorUnauthorizedClient      = "unauthorized_client"
+	OAuthErrorAccessDenied            = "access_denied"
+	OAuthErrorUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthErrorUnsupportedResponseType = "unsupported_response_type"
+	OAuthErrorServerError             = "server_error"
+	OAuthErrorInvalidToken            = "invalid_token"
+	OAuthErrorInsufficientScope       = "insufficient_scope"
+	OAuthErrorInvalidDPoPProof        = "invalid_dpop_proof"
+	OAuthErrorUseDPoPNonce            = "use_dpop_nonce"
+)
+
+// OAuthError is an error response as defined by RFC 6749, section 5.2.
+// When RedirectURI is set, the error must be sent back to the client through a redirection.
+type OAuthError struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	RedirectURI string `json:"-"`
+	State       string `json:"-"`
+}
+
+func (e OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+
+	return e.Code + ": " + e.Description
+}
+
+func (e OAuthError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// WithRedirect returns a copy of the error that has to be sent to the client redirect URI.
+func (e OAuthError) WithRedirect(redirectURI, state string) OAuthError {
+	e.RedirectURI = redirectURI
+	e.State = state
+
+	return e
+}
+
+func NewOAuthError(statusCode int, code, description string) OAuthError {
+	return OAuthError{
+		StatusCode:  statusCode,
+		Code:        code,
+		Description: description,
+	}
+}
+
+func NewOAuthInvalidRequestError(description string) OAuthError {
+	return NewOAuthError(http.StatusBadRequest, OAuthErrorInvalidRequest, description)
+}
+
+func NewOAuthInvalidClientError(description string) OAuthError {
+	return NewOAuthError(http.StatusUnauthorized, OAuthErrorInvalidClient, description)
+}
+
+func NewOAuthServerError() OAuthError {
+	return NewOAuthError(http.StatusInternalServerError, OAuthErrorServerError, "")
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Organization is a tenant of goauth, isolating its users, clients, connections and settings. Its
+// endpoints are served under its path, or at the root of its domains.
+type Organization struct {
+	ID        string               `bson:"_id"               json:"id"`
+	Name      string               `bson:"name"              json:"name"`
+	Domains   []string             `bson:"domains,omitempty" json:"domains,omitempty"`
+	Branding  OrganizationBranding `bson:"branding"          json:"branding"`
+	Settings  OrganizationSettings `bson:"settings"          json:"settings"`
+	CreatedAt time.Time            `bson:"createdAt"         json:"created_at"`
+	UpdatedAt time.Time            `bson:"updatedAt"         json:"updated_at"`
+}
+
+// OrganizationBranding customizes the pages shown to the end-users of an organization.
+type OrganizationBranding struct {
+	DisplayName  string `bson:"displayName,omitempty"  json:"display_name,omitempty"`
+	LogoURI      string `bson:"logoUri,omitempty"      json:"logo_uri,omitempty"`
+	PrimaryColor string `bson:"primaryColor,omitempty" json:"primary_color,omitempty"`
+}
+
+// OrganizationSettings overrides the configuration of goauth for an organization. LoginURL is the login
+// page of the organization, instead of the global one.
+type OrganizationSettings struct {
+	LoginURL string `bson:"loginUrl,omitempty" json:"login_url,omitempty"`
+}
+
+// PublicOrganization is the public information of an organization, fetched by its login pages.
+type PublicOrganization struct {
+	ID       string               `json:"id"`
+	Name     string               `json:"name"`
+	Issuer   string               `json:"issuer"`
+	Branding OrganizationBranding `json:"branding"`
+}
+
+func (o Organization) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "domains", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+	}
+}
+
+func (o Organization) NameSingular() string {
+	return "organization"
+}
+
+func (o Organization) NamePlural() string {
+	return "organizations"
+}
+
+func (o Organization) CollectionName() string {
+	return "organizations"
+}
+
+// Membership makes a user a member of an organization, with the roles granted to the user in it.
+type Membership struct {
+	OrganizationID string    `bson:"organizationId"  json:"organization_id"`
+	Roles          []string  `bson:"roles,omitempty" json:"roles,omitempty"`
+	CreatedAt      time.Time `bson:"createdAt"       json:"created_at"`
+}
+
+// Member is a user of an organization, with the roles granted to the user in it.
+type Member struct {
+	User     User      `json:"user"`
+	Roles    []string  `json:"roles"`
+	JoinedAt time.Time `json:"joined_at"`
+}
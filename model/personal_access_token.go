@@ -0,0 +1,75 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PersonalAccessToken is a token minted by an end-user for their own scripts, sent as a bearer token to the
+// administration API. Like API keys, it is made of a visible prefix and of a secret only shown on creation.
+// Its scopes are the permissions it grants, as long as the end-user still holds them in the organization of
+// the token.
+type PersonalAccessToken struct {
+	ID             string     `bson:"_id"                      json:"id"`
+	OrganizationID string     `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	Subject        string     `bson:"subject"                  json:"subject"`
+	Name           string     `bson:"name"                     json:"name"`
+	Prefix         string     `bson:"prefix"                   json:"prefix"`
+	SecretHash     string     `bson:"secretHash"               json:"-"`
+	Scopes         []string   `bson:"scopes"                   json:"scopes"`
+	ExpiresAt      time.Time  `bson:"expiresAt"                json:"expires_at"`
+	LastUsedAt     *time.Time `bson:"lastUsedAt,omitempty"     json:"last_used_at,omitempty"`
+	LastUsedIP     string     `bson:"lastUsedIp,omitempty"     json:"last_used_ip,omitempty"`
+	CreatedAt      time.Time  `bson:"createdAt"                json:"created_at"`
+}
+
+// PersonalAccessTokenCreation is the response to the creation of a personal access token, the only one
+// holding its value.
+type PersonalAccessTokenCreation struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+func (t PersonalAccessToken) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}, {Key: "subject", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "prefix", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (t PersonalAccessToken) NameSingular() string {
+	return "personal access token"
+}
+
+func (t PersonalAccessToken) NamePlural() string {
+	return "personal access tokens"
+}
+
+func (t PersonalAccessToken) CollectionName() string {
+	return "personalAccessTokens"
+}
+
+func (t PersonalAccessToken) TenantField() string {
+	return "organizationId"
+}
+
+func (t *PersonalAccessToken) SetTenant(organizationID string) {
+	t.OrganizationID = organizationID
+}
+
+// IsExpired returns true if the token was used after the end of its lifetime.
+func (t PersonalAccessToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
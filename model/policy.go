@@ -0,0 +1,111 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Effects of the policies.
+const (
+	PolicyEffectAllow = "allow"
+	PolicyEffectDeny  = "deny"
+)
+
+// Policy is an attribute-based authorization rule of an organization, evaluated by the authorization
+// decision endpoint. It applies to the requests whose action and resource type it lists, or to every
+// request when the lists are empty, and matches them when its CEL condition evaluates to true. The
+// condition is evaluated over the subject, resource, action and context variables of the request, and a
+// policy without condition matches every request it applies to. Disabled policies are not evaluated.
+type Policy struct {
+	ID             string    `bson:"_id"                      json:"id"`
+	OrganizationID string    `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	Name           string    `bson:"name"                     json:"name"`
+	Description    string    `bson:"description,omitempty"    json:"description,omitempty"`
+	Effect         string    `bson:"effect"                   json:"effect"`
+	Actions        []string  `bson:"actions,omitempty"        json:"actions,omitempty"`
+	ResourceTypes  []string  `bson:"resourceTypes,omitempty"  json:"resource_types,omitempty"`
+	Condition      string    `bson:"condition,omitempty"      json:"condition,omitempty"`
+	Disabled       bool      `bson:"disabled"                 json:"disabled"`
+	CreatedAt      time.Time `bson:"createdAt"                json:"created_at"`
+	UpdatedAt      time.Time `bson:"updatedAt"                json:"updated_at"`
+}
+
+func (p Policy) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "organizationId", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+}
+
+func (p Policy) NameSingular() string {
+	return "policy"
+}
+
+func (p Policy) NamePlural() string {
+	return "policies"
+}
+
+func (p Policy) CollectionName() string {
+	return "policies"
+}
+
+func (p Policy) TenantField() string {
+	return "organizationId"
+}
+
+func (p *Policy) SetTenant(organizationID string) {
+	p.OrganizationID = organizationID
+}
+
+// AppliesTo returns true if the policy is enabled, and lists the action and the resource type of a request.
+func (p Policy) AppliesTo(request DecisionRequest) bool {
+	return !p.Disabled &&
+		(len(p.Actions) == 0 || slices.Contains(p.Actions, request.Action)) &&
+		(len(p.ResourceTypes) == 0 || slices.Contains(p.ResourceTypes, request.Resource.Type))
+}
+
+// DecisionRequest asks whether a subject may perform an action on a resource. The attributes of the
+// subject and of the resource, and the context, are provided by the calling service.
+type DecisionRequest struct {
+	Subject  DecisionSubject  `json:"subject"`
+	Resource DecisionResource `json:"resource"`
+	Action   string           `json:"action"`
+	Context  map[string]any   `json:"context,omitempty"`
+}
+
+// DecisionSubject is the subject of a decision request, usually a user of the organization.
+type DecisionSubject struct {
+	ID         string         `json:"id,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// DecisionResource is the resource of a decision request.
+type DecisionResource struct {
+	Type       string         `json:"type,omitempty"`
+	ID         string         `json:"id,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Decision is the authorization decision of a request. In explain mode, it also holds the policy which
+// decided, and the evaluation of every policy applying to the request.
+type Decision struct {
+	Allowed     bool               `json:"allowed"`
+	PolicyID    string             `json:"policy_id,omitempty"`
+	Evaluations []PolicyEvaluation `json:"evaluations,omitempty"`
+}
+
+// PolicyEvaluation is the result of the evaluation of a policy, with the error of its condition if it
+// could not be evaluated.
+type PolicyEvaluation struct {
+	PolicyID string `json:"policy_id"`
+	Name     string `json:"name"`
+	Effect   string `json:"effect"`
+	Matched  bool   `json:"matched"`
+	Error    string `json:"error,omitempty"`
+}
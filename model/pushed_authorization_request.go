@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PushedAuthorizationRequest holds the authorization parameters pushed by a client to the PAR endpoint (RFC 9126).
+// It is single-use: it is deleted as soon as the authorization endpoint consumes it.
+type PushedAuthorizationRequest struct {
+	ID             string              `bson:"_id"`
+	OrganizationID string              `bson:"organizationId,omitempty"`
+	ClientID       string              `bson:"clientId"`
+	Parameters     map[string][]string `bson:"parameters"`
+	CreatedAt      time.Time           `bson:"createdAt"`
+	ExpiresAt      time.Time           `bson:"expiresAt"`
+}
+
+func (p PushedAuthorizationRequest) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (p PushedAuthorizationRequest) NameSingular() string {
+	return "pushed authorization request"
+}
+
+func (p PushedAuthorizationRequest) NamePlural() string {
+	return "pushed authorization requests"
+}
+
+func (p PushedAuthorizationRequest) CollectionName() string {
+	return "pushedAuthorizationRequests"
+}
+
+func (p PushedAuthorizationRequest) TenantField() string {
+	return "organizationId"
+}
+
+func (p *PushedAuthorizationRequest) SetTenant(organizationID string) {
+	p.OrganizationID = organizationID
+}
+
+// IsExpired returns true if the request lifetime is over.
+// MongoDB TTL monitor only runs every minute, so expired documents may still be found.
+func (p PushedAuthorizationRequest) IsExpired(now time.Time) bool {
+	return !now.Before(p.ExpiresAt)
+}
+
+// PushedAuthorizationResponse is the response of the PAR endpoint.
+type PushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
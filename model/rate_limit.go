@@ -0,0 +1,139 @@
+package model
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Rate limit policies, attached to the route groups of the router. The authentication policy limits the
+// requests of the API before their credentials are checked, so that credentials can't be guessed.
+const (
+	RateLimitPolicyLogin          = "login"
+	RateLimitPolicyOAuth          = "oauth"
+	RateLimitPolicyAccount        = "account"
+	RateLimitPolicySCIM           = "scim"
+	RateLimitPolicyAPI            = "api"
+	RateLimitPolicyAuthentication = "authentication"
+)
+
+// Keys of the buckets of a rate limit policy. The requests without the key of their policy, like the
+// unauthenticated requests of a policy keyed by user, are keyed by IP.
+const (
+	RateLimitKeyIP     = "ip"
+	RateLimitKeyUser   = "user"
+	RateLimitKeyClient = "client"
+	RateLimitKeyAPIKey = "api_key"
+)
+
+// RateLimitPolicy is a token bucket policy: each key has a bucket of Capacity tokens, refilled over
+// Period. A request takes a token, and is rejected when the bucket is empty.
+type RateLimitPolicy struct {
+	Name     string
+	Key      string
+	Capacity int
+	Period   time.Duration
+}
+
+// RateLimitResult is the state of a bucket after a request took a token from it.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the delay before a token is available, for the rejected requests.
+	RetryAfter time.Duration
+	// ResetAfter is the delay before the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// RateLimitBucket is the bucket of a key, shared by the instances of goauth. Buckets expire once they
+// would be full again.
+type RateLimitBucket struct {
+	ID        string    `bson:"_id"`
+	Tokens    float64   `bson:"tokens"`
+	Allowed   bool      `bson:"allowed"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// ParseRateLimitPolicy parses a policy written as key:capacity/period, like ip:10/1m.
+func ParseRateLimitPolicy(name, value string) (RateLimitPolicy, error) {
+	key, limit, found := strings.Cut(value, ":")
+	capacity, period, found2 := strings.Cut(limit, "/")
+
+	if !found || !found2 {
+		return RateLimitPolicy{}, errors.New("the " + name + " rate limit policy must be written as key:capacity/period")
+	}
+
+	switch key {
+	case RateLimitKeyIP, RateLimitKeyUser, RateLimitKeyClient, RateLimitKeyAPIKey:
+	default:
+		return RateLimitPolicy{}, errors.New("the key of the " + name + " rate limit policy is invalid")
+	}
+
+	policy := RateLimitPolicy{Name: name, Key: key}
+
+	var err error
+
+	if policy.Capacity, err = strconv.Atoi(capacity); err != nil || policy.Capacity <= 0 {
+		return RateLimitPolicy{}, errors.New("the capacity of the " + name + " rate limit policy is invalid")
+	}
+
+	if policy.Period, err = time.ParseDuration(period); err != nil || policy.Period <= 0 {
+		return RateLimitPolicy{}, errors.New("the period of the " + name + " rate limit policy is invalid")
+	}
+
+	return policy, nil
+}
+
+// GuardsCredentials reports whether the policy limits the attempts to present credentials, so that the
+// requests it rejects are lockouts.
+func (p RateLimitPolicy) GuardsCredentials() bool {
+	return p.Name == RateLimitPolicyLogin || p.Name == RateLimitPolicyAuthentication
+}
+
+// Rate returns the number of tokens added to the buckets per second.
+func (p RateLimitPolicy) Rate() float64 {
+	return float64(p.Capacity) / p.Period.Seconds()
+}
+
+// Result returns the state of a bucket holding the given tokens, after a request took a token from it.
+func (p RateLimitPolicy) Result(allowed bool, tokens float64) RateLimitResult {
+	result := RateLimitResult{
+		Allowed:    allowed,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(p.Capacity) - tokens) / p.Rate() * float64(time.Second)),
+	}
+
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / p.Rate() * float64(time.Second))
+	}
+
+	return result
+}
+
+func (b RateLimitBucket) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (b RateLimitBucket) NameSingular() string {
+	return "rate limit bucket"
+}
+
+func (b RateLimitBucket) NamePlural() string {
+	return "rate limit buckets"
+}
+
+func (b RateLimitBucket) CollectionName() string {
+	return "rateLimitBuckets"
+}
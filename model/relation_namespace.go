@@ -0,0 +1,86 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RelationNamespace is the schema of a type of objects of the relationship-based authorization, like
+// documents or folders. It defines the relations the objects may have with subjects.
+type RelationNamespace struct {
+	ID             string               `bson:"_id"                      json:"-"`
+	OrganizationID string               `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	Name           string               `bson:"name"                     json:"name"`
+	Relations      []RelationDefinition `bson:"relations"                json:"relations"`
+	CreatedAt      time.Time            `bson:"createdAt"                json:"created_at"`
+	UpdatedAt      time.Time            `bson:"updatedAt"                json:"updated_at"`
+}
+
+// RelationDefinition is a relation of a namespace. The subjects of the relation are the union of its
+// usersets, or the subjects of the tuples of the relation when it has none.
+type RelationDefinition struct {
+	Name     string    `bson:"name"               json:"name"`
+	Usersets []Userset `bson:"usersets,omitempty" json:"usersets,omitempty"`
+}
+
+// Userset is a rewrite of a relation, setting exactly one of its fields:
+//   - This selects the subjects of the tuples of the relation itself,
+//   - ComputedUserset selects the subjects of another relation of the same object, like the editors of a
+//     document being viewers too,
+//   - TupleToUserset selects the subjects of a relation of the objects related to the object, like the
+//     viewers of the parent folder of a document.
+type Userset struct {
+	This            bool            `bson:"this,omitempty"            json:"this,omitempty"`
+	ComputedUserset string          `bson:"computedUserset,omitempty" json:"computed_userset,omitempty"`
+	TupleToUserset  *TupleToUserset `bson:"tupleToUserset,omitempty"  json:"tuple_to_userset,omitempty"`
+}
+
+// TupleToUserset selects the objects related to an object by the Tupleset relation, and then the subjects
+// of their ComputedUserset relation.
+type TupleToUserset struct {
+	Tupleset        string `bson:"tupleset"        json:"tupleset"`
+	ComputedUserset string `bson:"computedUserset" json:"computed_userset"`
+}
+
+func (n RelationNamespace) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "organizationId", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+}
+
+func (n RelationNamespace) NameSingular() string {
+	return "relation namespace"
+}
+
+func (n RelationNamespace) NamePlural() string {
+	return "relation namespaces"
+}
+
+func (n RelationNamespace) CollectionName() string {
+	return "relationNamespaces"
+}
+
+func (n RelationNamespace) TenantField() string {
+	return "organizationId"
+}
+
+func (n *RelationNamespace) SetTenant(organizationID string) {
+	n.OrganizationID = organizationID
+}
+
+// Relation returns the definition of a relation of the namespace, or nil if it is not defined.
+func (n RelationNamespace) Relation(name string) *RelationDefinition {
+	for i := range n.Relations {
+		if n.Relations[i].Name == name {
+			return &n.Relations[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package model
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// relationNamePattern matches the names of the namespaces and of the relations.
+	relationNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	// relationObjectIDPattern matches the identifiers of the objects, which can't hold the separators of the
+	// tuples.
+	relationObjectIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.|=+/-]{1,256}$`)
+)
+
+// ErrInvalidRelationTuple is returned when parsing a malformed relation tuple.
+var ErrInvalidRelationTuple = errors.New("the relation tuple must be formatted as namespace:object#relation@subject")
+
+// RelationTuple states that a subject has a relation with an object. Its text form is
+// namespace:object#relation@subject, where the subject is either an object, like user:alice, or the
+// subjects of the relation of an object (a userset), like group:eng#member.
+type RelationTuple struct {
+	ID               string    `bson:"_id"                       json:"-"`
+	OrganizationID   string    `bson:"organizationId,omitempty"  json:"-"`
+	Namespace        string    `bson:"namespace"                 json:"namespace"`
+	ObjectID         string    `bson:"objectId"                  json:"object_id"`
+	Relation         string    `bson:"relation"                  json:"relation"`
+	SubjectNamespace string    `bson:"subjectNamespace"          json:"subject_namespace"`
+	SubjectID        string    `bson:"subjectId"                 json:"subject_id"`
+	SubjectRelation  string    `bson:"subjectRelation,omitempty" json:"subject_relation,omitempty"`
+	CreatedAt        time.Time `bson:"createdAt"                 json:"created_at"`
+}
+
+func (t RelationTuple) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "organizationId", Value: 1},
+				{Key: "namespace", Value: 1},
+				{Key: "objectId", Value: 1},
+				{Key: "relation", Value: 1},
+				{Key: "subjectNamespace", Value: 1},
+				{Key: "subjectId", Value: 1},
+				{Key: "subjectRelation", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}, {Key: "subjectNamespace", Value: 1}, {Key: "subjectId", Value: 1}},
+		},
+	}
+}
+
+func (t RelationTuple) NameSingular() string {
+	return "relation tuple"
+}
+
+func (t RelationTuple) NamePlural() string {
+	return "relation tuples"
+}
+
+func (t RelationTuple) CollectionName() string {
+	return "relationTuples"
+}
+
+func (t RelationTuple) TenantField() string {
+	return "organizationId"
+}
+
+func (t *RelationTuple) SetTenant(organizationID string) {
+	t.OrganizationID = organizationID
+}
+
+// Object returns the object of the tuple, as namespace:object.
+func (t RelationTuple) Object() string {
+	return t.Namespace + ":" + t.ObjectID
+}
+
+// Subject returns the subject of the tuple, as namespace:object or namespace:object#relation.
+func (t RelationTuple) Subject() string {
+	if t.SubjectRelation == "" {
+		return t.SubjectNamespace + ":" + t.SubjectID
+	}
+
+	return t.SubjectNamespace + ":" + t.SubjectID + "#" + t.SubjectRelation
+}
+
+func (t RelationTuple) String() string {
+	return t.Object() + "#" + t.Relation + "@" + t.Subject()
+}
+
+// Filter returns the filter matching the tuple.
+func (t RelationTuple) Filter() bson.M {
+	var subjectRelation any
+	if t.SubjectRelation != "" {
+		subjectRelation = t.SubjectRelation
+	}
+
+	return bson.M{
+		"namespace":        t.Namespace,
+		"objectId":         t.ObjectID,
+		"relation":         t.Relation,
+		"subjectNamespace": t.SubjectNamespace,
+		"subjectId":        t.SubjectID,
+		"subjectRelation":  subjectRelation,
+	}
+}
+
+// ParseRelationTuple parses the text form of a relation tuple.
+func ParseRelationTuple(value string) (*RelationTuple, error) {
+	userset, subject, found := strings.Cut(value, "@")
+	if !found {
+		return nil, ErrInvalidRelationTuple
+	}
+
+	namespace, objectID, relation, err := ParseRelationUserset(userset)
+	if err != nil || relation == "" {
+		return nil, ErrInvalidRelationTuple
+	}
+
+	subjectNamespace, subjectID, subjectRelation, err := ParseRelationUserset(subject)
+	if err != nil {
+		return nil, ErrInvalidRelationTuple
+	}
+
+	return &RelationTuple{
+		Namespace:        namespace,
+		ObjectID:         objectID,
+		Relation:         relation,
+		SubjectNamespace: subjectNamespace,
+		SubjectID:        subjectID,
+		SubjectRelation:  subjectRelation,
+	}, nil
+}
+
+// ParseRelationUserset parses an object, namespace:object, or a userset, namespace:object#relation. The
+// relation is empty for an object.
+func ParseRelationUserset(value string) (string, string, string, error) {
+	object, relation, found := strings.Cut(value, "#")
+	namespace, objectID, _ := strings.Cut(object, ":")
+
+	if !relationNamePattern.MatchString(namespace) || !relationObjectIDPattern.MatchString(objectID) ||
+		(found && !relationNamePattern.MatchString(relation)) {
+		return "", "", "", ErrInvalidRelationTuple
+	}
+
+	return namespace, objectID, relation, nil
+}
+
+// ValidRelationName returns true if a name can be used for a namespace or a relation.
+func ValidRelationName(name string) bool {
+	return relationNamePattern.MatchString(name)
+}
+
+// RelationRevision counts the writes of relation tuples of an organization. The consistency tokens
+// returned by the relationship-based authorization hold a revision.
+type RelationRevision struct {
+	OrganizationID string `bson:"_id"      json:"organization_id"`
+	Revision       int64  `bson:"revision" json:"revision"`
+}
+
+func (r RelationRevision) Indexes() []mongo.IndexModel {
+	return nil
+}
+
+func (r RelationRevision) NameSingular() string {
+	return "relation revision"
+}
+
+func (r RelationRevision) NamePlural() string {
+	return "relation revisions"
+}
+
+func (r RelationRevision) CollectionName() string {
+	return "relationRevisions"
+}
+
+// RelationTree is the expansion of a userset: the subjects having the relation through tuples, and the
+// usersets granting it through other tuples or the rewrites of the relation.
+type RelationTree struct {
+	Userset  string         `json:"userset"`
+	Subjects []string       `json:"subjects,omitempty"`
+	Children []RelationTree `json:"children,omitempty"`
+}
+
+// RelationCheck is the result of a relationship check, evaluated at the revision of its consistency token.
+type RelationCheck struct {
+	Allowed          bool   `json:"allowed"`
+	ConsistencyToken string `json:"consistency_token"`
+}
+
+// RelationExpansion is the result of the expansion of a userset.
+type RelationExpansion struct {
+	Tree             RelationTree `json:"tree"`
+	ConsistencyToken string       `json:"consistency_token"`
+}
+
+// RelationObjects is the result of the listing of the objects a subject has a relation with.
+type RelationObjects struct {
+	Objects          []string `json:"objects"`
+	ConsistencyToken string   `json:"consistency_token"`
+}
+
+// RelationWrite is the result of a write of relation tuples. Its consistency token makes the following
+// checks see the write.
+type RelationWrite struct {
+	ConsistencyToken string `json:"consistency_token"`
+}
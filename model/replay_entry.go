@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReplayEntry records a one-time identifier (the jti of a DPoP proof for instance) until it expires.
+// The identifier is unique, so inserting an already used identifier fails.
+type ReplayEntry struct {
+	ID        string    `bson:"_id"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+func (r ReplayEntry) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (r ReplayEntry) NameSingular() string {
+	return "replay entry"
+}
+
+func (r ReplayEntry) NamePlural() string {
+	return "replay entries"
+}
+
+func (r ReplayEntry) CollectionName() string {
+	return "replayCache"
+}
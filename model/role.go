@@ -0,0 +1,91 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Permissions of the goauth administration API. The * permission grants every permission, and the
+// permissions ending with :* every permission of a resource, like roles:*.
+const (
+	PermissionAll                       = "*"
+	PermissionRolesRead                 = "roles:read"
+	PermissionRolesWrite                = "roles:write"
+	PermissionSCIMTokensRead            = "scim-tokens:read"
+	PermissionSCIMTokensWrite           = "scim-tokens:write"
+	PermissionOrganizationsRead         = "organizations:read"
+	PermissionOrganizationsWrite        = "organizations:write"
+	PermissionMembersRead               = "members:read"
+	PermissionMembersWrite              = "members:write"
+	PermissionDomainsRead               = "domains:read"
+	PermissionDomainsWrite              = "domains:write"
+	PermissionAPIKeysRead               = "api-keys:read"
+	PermissionAPIKeysWrite              = "api-keys:write"
+	PermissionPersonalAccessTokensRead  = "personal-access-tokens:read"
+	PermissionPersonalAccessTokensWrite = "personal-access-tokens:write"
+	PermissionPoliciesRead              = "policies:read"
+	PermissionPoliciesWrite             = "policies:write"
+	PermissionPoliciesEvaluate          = "policies:evaluate"
+	PermissionRelationsRead             = "relations:read"
+	PermissionRelationsWrite            = "relations:write"
+	PermissionGroupsRead                = "groups:read"
+	PermissionGroupsWrite               = "groups:write"
+	PermissionAuditRead                 = "audit:read"
+	PermissionWebhooksRead              = "webhooks:read"
+	PermissionWebhooksWrite             = "webhooks:write"
+)
+
+// RoleIDAdmin is the identifier of the built-in role granting every permission.
+const RoleIDAdmin = "admin"
+
+// Role is a named set of permissions, assigned to users and clients. The roles and permissions of the
+// subject are added to the access tokens issued to it.
+type Role struct {
+	ID          string    `bson:"_id"                   json:"id"`
+	Description string    `bson:"description,omitempty" json:"description,omitempty"`
+	Permissions []string  `bson:"permissions"           json:"permissions"`
+	CreatedAt   time.Time `bson:"createdAt"             json:"created_at"`
+	UpdatedAt   time.Time `bson:"updatedAt"             json:"updated_at"`
+}
+
+func (r Role) Indexes() []mongo.IndexModel {
+	return nil
+}
+
+func (r Role) NameSingular() string {
+	return "role"
+}
+
+func (r Role) NamePlural() string {
+	return "roles"
+}
+
+func (r Role) CollectionName() string {
+	return "roles"
+}
+
+// RoleGrants returns the identifiers of roles, and the union of their permissions.
+func RoleGrants(roles []Role) ([]string, []string) {
+	roleIDs := make([]string, 0, len(roles))
+	permissions := make([]string, 0)
+
+	for _, role := range roles {
+		roleIDs = append(roleIDs, role.ID)
+		permissions = append(permissions, role.Permissions...)
+	}
+
+	slices.Sort(permissions)
+
+	return roleIDs, slices.Compact(permissions)
+}
+
+// GrantsPermission returns true if one of the granted permissions is the given permission, or a
+// wildcard matching it.
+func GrantsPermission(granted []string, permission string) bool {
+	resource, _, _ := strings.Cut(permission, ":")
+
+	return slices.Contains(granted, permission) || slices.Contains(granted, PermissionAll) || slices.Contains(granted, resource+":*")
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SAMLAuthnRequest is an authentication request of a SAML service provider, waiting for the end-user
+// to log in. Request is the decoded XML of the request, kept to build the response once the end-user
+// is logged in, and ReceivedAt the time it was received, against which it was validated.
+type SAMLAuthnRequest struct {
+	ID                string    `bson:"_id"`
+	OrganizationID    string    `bson:"organizationId,omitempty"`
+	ServiceProviderID string    `bson:"serviceProviderId"`
+	Request           string    `bson:"request"`
+	RelayState        string    `bson:"relayState,omitempty"`
+	ReceivedAt        time.Time `bson:"receivedAt"`
+	ExpiresAt         time.Time `bson:"expiresAt"`
+}
+
+func (s SAMLAuthnRequest) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (s SAMLAuthnRequest) NameSingular() string {
+	return "SAML authentication request"
+}
+
+func (s SAMLAuthnRequest) NamePlural() string {
+	return "SAML authentication requests"
+}
+
+func (s SAMLAuthnRequest) CollectionName() string {
+	return "samlAuthnRequests"
+}
+
+func (s SAMLAuthnRequest) TenantField() string {
+	return "organizationId"
+}
+
+func (s *SAMLAuthnRequest) SetTenant(organizationID string) {
+	s.OrganizationID = organizationID
+}
+
+// IsExpired returns true if the end-user did not log in before the end of the request lifetime.
+func (s SAMLAuthnRequest) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// SAMLResponseForm holds the SAML response to post to the assertion consumer service of a service
+// provider, through the user-agent (HTTP-POST binding).
+type SAMLResponseForm struct {
+	URL          string
+	SAMLResponse string
+	RelayState   string
+}
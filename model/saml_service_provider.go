@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Name identifier formats of the SAML assertions: the user identifier, stable and opaque, or the
+// email address of the user.
+const (
+	SAMLNameIDFormatPersistent = "persistent"
+	SAMLNameIDFormatEmail      = "email"
+)
+
+// SAMLServiceProvider is a legacy application logging end-users in with goauth as a SAML 2.0 identity
+// provider. Its identifier is its entity ID, and Metadata its SAML metadata document, holding its
+// assertion consumer services and certificates. AttributeMapping maps user attributes to the names of
+// the assertion attributes the service provider expects, when they differ from the standard ones.
+type SAMLServiceProvider struct {
+	ID               string            `bson:"_id"                        json:"entity_id"`
+	OrganizationID   string            `bson:"organizationId,omitempty"   json:"organization_id,omitempty"`
+	Name             string            `bson:"name"                       json:"name"`
+	Metadata         string            `bson:"metadata"                   json:"metadata"`
+	NameIDFormat     string            `bson:"nameIdFormat,omitempty"     json:"name_id_format,omitempty"`
+	AttributeMapping map[string]string `bson:"attributeMapping,omitempty" json:"attribute_mapping,omitempty"`
+	Enabled          bool              `bson:"enabled"                    json:"enabled"`
+	CreatedAt        time.Time         `bson:"createdAt"                  json:"created_at"`
+	UpdatedAt        time.Time         `bson:"updatedAt"                  json:"updated_at"`
+}
+
+func (s SAMLServiceProvider) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}},
+		},
+	}
+}
+
+func (s SAMLServiceProvider) NameSingular() string {
+	return "SAML service provider"
+}
+
+func (s SAMLServiceProvider) NamePlural() string {
+	return "SAML service providers"
+}
+
+func (s SAMLServiceProvider) CollectionName() string {
+	return "samlServiceProviders"
+}
+
+func (s SAMLServiceProvider) TenantField() string {
+	return "organizationId"
+}
+
+func (s *SAMLServiceProvider) SetTenant(organizationID string) {
+	s.OrganizationID = organizationID
+}
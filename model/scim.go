@@ -0,0 +1,169 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// SCIM schemas and messages (RFC 7643, RFC 7644).
+const (
+	SCIMSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIMSchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SCIMSchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	SCIMSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// SCIMContentType is the media type of the SCIM messages (RFC 7644, section 8.1).
+const SCIMContentType = "application/scim+json"
+
+// SCIM resource types.
+const (
+	SCIMResourceTypeUser  = "User"
+	SCIMResourceTypeGroup = "Group"
+)
+
+// SCIM error types (RFC 7644, section 3.12).
+const (
+	SCIMErrorInvalidFilter = "invalidFilter"
+	SCIMErrorTooMany       = "tooMany"
+	SCIMErrorUniqueness    = "uniqueness"
+	SCIMErrorMutability    = "mutability"
+	SCIMErrorInvalidSyntax = "invalidSyntax"
+	SCIMErrorInvalidPath   = "invalidPath"
+	SCIMErrorNoTarget      = "noTarget"
+	SCIMErrorInvalidValue  = "invalidValue"
+)
+
+// SCIM patch operations (RFC 7644, section 3.5.2).
+const (
+	SCIMPatchOpAdd     = "add"
+	SCIMPatchOpRemove  = "remove"
+	SCIMPatchOpReplace = "replace"
+)
+
+// SCIMError is the error response of the SCIM endpoints (RFC 7644, section 3.12).
+type SCIMError struct {
+	StatusCode int
+	Type       string
+	Detail     string
+}
+
+func (e SCIMError) Error() string {
+	return e.Detail
+}
+
+func (e SCIMError) HTTPStatus() int {
+	return e.StatusCode
+}
+
+// MarshalJSON encodes the error with its schema, and the status as a string as required by the RFC.
+func (e SCIMError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Schemas  []string `json:"schemas"`
+		Status   string   `json:"status"`
+		SCIMType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail,omitempty"`
+	}{
+		Schemas:  []string{SCIMSchemaError},
+		Status:   strconv.Itoa(e.StatusCode),
+		SCIMType: e.Type,
+		Detail:   e.Detail,
+	})
+}
+
+func NewSCIMError(statusCode int, scimType, detail string) SCIMError {
+	return SCIMError{
+		StatusCode: statusCode,
+		Type:       scimType,
+		Detail:     detail,
+	}
+}
+
+// SCIMMeta holds the metadata of a SCIM resource.
+type SCIMMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+}
+
+// SCIMName is the name of a SCIM user.
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMMultiValue is a value of a multi-valued attribute, like the emails of a user.
+type SCIMMultiValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMMember references a member of a group, or a group of a user.
+type SCIMMember struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// SCIMUser is the representation of a user as a SCIM resource. Active is nil when a client does not
+// send it.
+type SCIMUser struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id,omitempty"`
+	ExternalID  string           `json:"externalId,omitempty"`
+	UserName    string           `json:"userName"`
+	Name        *SCIMName        `json:"name,omitempty"`
+	DisplayName string           `json:"displayName,omitempty"`
+	Emails      []SCIMMultiValue `json:"emails,omitempty"`
+	Photos      []SCIMMultiValue `json:"photos,omitempty"`
+	Active      *bool            `json:"active,omitempty"`
+	Groups      []SCIMMember     `json:"groups,omitempty"`
+	Meta        *SCIMMeta        `json:"meta,omitempty"`
+}
+
+// SCIMGroup is the representation of a group as a SCIM resource.
+type SCIMGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []SCIMMember `json:"members,omitempty"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+}
+
+// SCIMListResponse is a page of the resources matching a SCIM query.
+type SCIMListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+// SCIMQuery holds the query parameters of the SCIM list endpoints (RFC 7644, section 3.4.2).
+type SCIMQuery struct {
+	Filter     string
+	StartIndex int
+	Count      int
+}
+
+// SCIMPatchRequest is the body of a SCIM PATCH request.
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation is an operation of a SCIM PATCH request. The value is decoded according to the path.
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
@@ -0,0 +1,183 @@
+package model
+
+// SCIM attribute types, mutability and returned values (RFC 7643, section 7).
+const (
+	SCIMTypeString    = "string"
+	SCIMTypeBoolean   = "boolean"
+	SCIMTypeReference = "reference"
+	SCIMTypeComplex   = "complex"
+
+	SCIMMutabilityReadOnly  = "readOnly"
+	SCIMMutabilityReadWrite = "readWrite"
+	SCIMMutabilityImmutable = "immutable"
+
+	SCIMReturnedDefault = "default"
+
+	SCIMUniquenessNone   = "none"
+	SCIMUniquenessServer = "server"
+)
+
+// SCIMServiceProviderConfig describes the SCIM features supported by goauth (RFC 7643, section 5).
+type SCIMServiceProviderConfig struct {
+	Schemas               []string                   `json:"schemas"`
+	DocumentationURI      string                     `json:"documentationUri,omitempty"`
+	Patch                 SCIMSupported              `json:"patch"`
+	Bulk                  SCIMBulkSupported          `json:"bulk"`
+	Filter                SCIMFilterSupported        `json:"filter"`
+	ChangePassword        SCIMSupported              `json:"changePassword"`
+	Sort                  SCIMSupported              `json:"sort"`
+	ETag                  SCIMSupported              `json:"etag"`
+	AuthenticationSchemes []SCIMAuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  SCIMMeta                   `json:"meta"`
+}
+
+type SCIMSupported struct {
+	Supported bool `json:"supported"`
+}
+
+type SCIMBulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type SCIMFilterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type SCIMAuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+// SCIMResourceType describes an endpoint of a SCIM resource type (RFC 7643, section 6).
+type SCIMResourceType struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint"`
+	Description string   `json:"description"`
+	Schema      string   `json:"schema"`
+	Meta        SCIMMeta `json:"meta"`
+}
+
+// SCIMSchema describes the attributes of a SCIM resource type (RFC 7643, section 7).
+type SCIMSchema struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Attributes  []SCIMAttribute `json:"attributes"`
+	Meta        SCIMMeta        `json:"meta"`
+}
+
+type SCIMAttribute struct {
+	Name           string          `json:"name"`
+	Type           string          `json:"type"`
+	MultiValued    bool            `json:"multiValued"`
+	Required       bool            `json:"required"`
+	CaseExact      bool            `json:"caseExact"`
+	Mutability     string          `json:"mutability"`
+	Returned       string          `json:"returned"`
+	Uniqueness     string          `json:"uniqueness"`
+	ReferenceTypes []string        `json:"referenceTypes,omitempty"`
+	SubAttributes  []SCIMAttribute `json:"subAttributes,omitempty"`
+}
+
+// SCIMUserSchema returns the attributes of the core user schema supported by goauth.
+func SCIMUserSchema() SCIMSchema {
+	return SCIMSchema{
+		Schemas:     []string{SCIMSchemaSchema},
+		ID:          SCIMSchemaUser,
+		Name:        SCIMResourceTypeUser,
+		Description: "User Account",
+		Attributes: []SCIMAttribute{
+			scimStringAttribute("userName", true, SCIMUniquenessServer),
+			scimExternalIDAttribute(),
+			{
+				Name: "name", Type: SCIMTypeComplex, Mutability: SCIMMutabilityReadWrite, Returned: SCIMReturnedDefault, Uniqueness: SCIMUniquenessNone,
+				SubAttributes: []SCIMAttribute{
+					scimStringAttribute("formatted", false, SCIMUniquenessNone),
+					scimStringAttribute("givenName", false, SCIMUniquenessNone),
+					scimStringAttribute("familyName", false, SCIMUniquenessNone),
+				},
+			},
+			scimStringAttribute("displayName", false, SCIMUniquenessNone),
+			scimMultiValuedAttribute("emails"),
+			scimMultiValuedAttribute("photos"),
+			{Name: "active", Type: SCIMTypeBoolean, Mutability: SCIMMutabilityReadWrite, Returned: SCIMReturnedDefault, Uniqueness: SCIMUniquenessNone},
+			{
+				Name: "groups", Type: SCIMTypeComplex, MultiValued: true, Mutability: SCIMMutabilityReadOnly, Returned: SCIMReturnedDefault, Uniqueness: SCIMUniquenessNone,
+				SubAttributes: scimMemberAttributes(SCIMMutabilityReadOnly, SCIMResourceTypeGroup),
+			},
+		},
+	}
+}
+
+// SCIMGroupSchema returns the attributes of the core group schema supported by goauth.
+func SCIMGroupSchema() SCIMSchema {
+	return SCIMSchema{
+		Schemas:     []string{SCIMSchemaSchema},
+		ID:          SCIMSchemaGroup,
+		Name:        SCIMResourceTypeGroup,
+		Description: "Group",
+		Attributes: []SCIMAttribute{
+			scimStringAttribute("displayName", true, SCIMUniquenessNone),
+			scimExternalIDAttribute(),
+			{
+				Name: "members", Type: SCIMTypeComplex, MultiValued: true,
+				Mutability: SCIMMutabilityReadWrite, Returned: SCIMReturnedDefault, Uniqueness: SCIMUniquenessNone,
+				SubAttributes: scimMemberAttributes(SCIMMutabilityImmutable, SCIMResourceTypeUser),
+			},
+		},
+	}
+}
+
+func scimStringAttribute(name string, required bool, uniqueness string) SCIMAttribute {
+	return SCIMAttribute{
+		Name:       name,
+		Type:       SCIMTypeString,
+		Required:   required,
+		Mutability: SCIMMutabilityReadWrite,
+		Returned:   SCIMReturnedDefault,
+		Uniqueness: uniqueness,
+	}
+}
+
+// scimExternalIDAttribute returns the identifier of a resource in the provisioning client, which is case-sensitive.
+func scimExternalIDAttribute() SCIMAttribute {
+	attribute := scimStringAttribute("externalId", false, SCIMUniquenessNone)
+	attribute.CaseExact = true
+
+	return attribute
+}
+
+func scimMultiValuedAttribute(name string) SCIMAttribute {
+	return SCIMAttribute{
+		Name:        name,
+		Type:        SCIMTypeComplex,
+		MultiValued: true,
+		Mutability:  SCIMMutabilityReadWrite,
+		Returned:    SCIMReturnedDefault,
+		Uniqueness:  SCIMUniquenessNone,
+		SubAttributes: []SCIMAttribute{
+			scimStringAttribute("value", false, SCIMUniquenessNone),
+			scimStringAttribute("type", false, SCIMUniquenessNone),
+			{Name: "primary", Type: SCIMTypeBoolean, Mutability: SCIMMutabilityReadWrite, Returned: SCIMReturnedDefault, Uniqueness: SCIMUniquenessNone},
+		},
+	}
+}
+
+func scimMemberAttributes(mutability, referenceType string) []SCIMAttribute {
+	return []SCIMAttribute{
+		{Name: "value", Type: SCIMTypeString, Mutability: mutability, Returned: SCIMReturnedDefault, Uniqueness: SCIMUniquenessNone},
+		{
+			Name: "$ref", Type: SCIMTypeReference, ReferenceTypes: []string{referenceType},
+			Mutability: mutability, Returned: SCIMReturnedDefault, Uniqueness: SCIMUniquenessNone,
+		},
+		{Name: "display", Type: SCIMTypeString, Mutability: SCIMMutabilityReadOnly, Returned: SCIMReturnedDefault, Uniqueness: SCIMUniquenessNone},
+	}
+}
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SCIMToken is a bearer token of a provisioning client, like the HR system or the identity provider
+// of a customer, calling the SCIM endpoints. The token value is only shown on creation: it is stored
+// hashed.
+type SCIMToken struct {
+	ID             string     `bson:"_id"                      json:"id"`
+	OrganizationID string     `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	Name           string     `bson:"name"                     json:"name"`
+	TokenHash      string     `bson:"tokenHash"                json:"-"`
+	CreatedAt      time.Time  `bson:"createdAt"                json:"created_at"`
+	LastUsedAt     *time.Time `bson:"lastUsedAt,omitempty"     json:"last_used_at,omitempty"`
+}
+
+// SCIMTokenCreation is the response to the creation of a SCIM token, the only one holding its value.
+type SCIMTokenCreation struct {
+	SCIMToken
+	Token string `json:"token"`
+}
+
+func (t SCIMToken) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+}
+
+func (t SCIMToken) NameSingular() string {
+	return "SCIM token"
+}
+
+func (t SCIMToken) NamePlural() string {
+	return "SCIM tokens"
+}
+
+func (t SCIMToken) CollectionName() string {
+	return "scimTokens"
+}
+
+func (t SCIMToken) TenantField() string {
+	return "organizationId"
+}
+
+func (t *SCIMToken) SetTenant(organizationID string) {
+	t.OrganizationID = organizationID
+}
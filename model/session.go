@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Session is the login session of an end-user on goauth. It records the clients that received tokens
+// within the session, so they can be notified when the end-user logs out.
+type Session struct {
+	ID             string    `bson:"_id"`
+	OrganizationID string    `bson:"organizationId,omitempty"`
+	Subject        string    `bson:"subject"`
+	ClientIDs      []string  `bson:"clientIds"`
+	AuthTime       time.Time `bson:"authTime"`
+	CreatedAt      time.Time `bson:"createdAt"`
+	ExpiresAt      time.Time `bson:"expiresAt"`
+}
+
+func (s Session) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "subject", Value: 1}},
+		},
+	}
+}
+
+func (s Session) NameSingular() string {
+	return "session"
+}
+
+func (s Session) NamePlural() string {
+	return "sessions"
+}
+
+func (s Session) CollectionName() string {
+	return "sessions"
+}
+
+func (s Session) TenantField() string {
+	return "organizationId"
+}
+
+func (s *Session) SetTenant(organizationID string) {
+	s.OrganizationID = organizationID
+}
+
+// IsActive returns true if the session has not expired yet.
+func (s Session) IsActive(now time.Time) bool {
+	return now.Before(s.ExpiresAt)
+}
+
+// LogoutResponse describes how to complete an RP-initiated logout: the front-channel logout URIs to
+// render in iframes, then the post logout redirection, if any.
+type LogoutResponse struct {
+	FrontchannelLogoutURIs []string
+	RedirectURI            string
+}
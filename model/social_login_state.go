@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SocialLoginState is a login in progress on an upstream provider. Its identifier is the hash of the
+// state parameter sent to the provider, and it holds the nonce and the PKCE code verifier of the upstream
+// authorization request, as well as the login challenge of the authorization request to resume. When a
+// logged-in end-user links an identity to their account, UserID is the user to link it to instead.
+type SocialLoginState struct {
+	ID             string    `bson:"_id"`
+	ConnectionID   string    `bson:"connectionId"`
+	LoginChallenge string    `bson:"loginChallenge,omitempty"`
+	UserID         string    `bson:"userId,omitempty"`
+	Nonce          string    `bson:"nonce"`
+	CodeVerifier   string    `bson:"codeVerifier"`
+	CreatedAt      time.Time `bson:"createdAt"`
+	ExpiresAt      time.Time `bson:"expiresAt"`
+}
+
+func (s SocialLoginState) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (s SocialLoginState) NameSingular() string {
+	return "social login state"
+}
+
+func (s SocialLoginState) NamePlural() string {
+	return "social login states"
+}
+
+func (s SocialLoginState) CollectionName() string {
+	return "socialLoginStates"
+}
+
+// IsExpired returns true if the end-user did not come back from the upstream provider in time.
+func (s SocialLoginState) IsExpired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Token kinds, also used as token_type_hint values (RFC 7009).
+const (
+	TokenKindAccessToken  = "access_token"
+	TokenKindRefreshToken = "refresh_token"
+)
+
+const (
+	TokenTypeBearer = "Bearer"
+	TokenTypeDPoP   = "DPoP"
+)
+
+// Confirmation binds a token to proof-of-possession keys (RFC 7800): a DPoP key (RFC 9449)
+// or a client certificate (RFC 8705).
+type Confirmation struct {
+	JKT     string `bson:"jkt,omitempty"     json:"jkt,omitempty"`
+	X5TS256 string `bson:"x5tS256,omitempty" json:"x5t#S256,omitempty"`
+}
+
+// IsEmpty returns true if the confirmation binds to no key.
+func (c Confirmation) IsEmpty() bool {
+	return c.JKT == "" && c.X5TS256 == ""
+}
+
+// Token is an issued access or refresh token. Tokens are stored hashed: the identifier is the hash
+// of the token value, which is only known by the client. Access tokens hold the roles and permissions
+// granted to their subject when they were issued, in the organization of the token, and the groups of
+// the subject. GroupsOverage replaces the groups of the subjects in too many groups. Audience is the
+// audience of JWT access tokens, which may be issued for other resource servers than goauth.
+type Token struct {
+	ID             string        `bson:"_id"`
+	Kind           string        `bson:"kind"`
+	OrganizationID string        `bson:"organizationId,omitempty"`
+	ClientID       string        `bson:"clientId"`
+	Subject        string        `bson:"subject,omitempty"`
+	Scopes         []string      `bson:"scopes"`
+	Confirmation   *Confirmation `bson:"confirmation,omitempty"`
+	SessionID      string        `bson:"sessionId,omitempty"`
+	Roles          []string      `bson:"roles,omitempty"`
+	Permissions    []string      `bson:"permissions,omitempty"`
+	Groups         []string      `bson:"groups,omitempty"`
+	GroupsOverage  bool          `bson:"groupsOverage,omitempty"`
+	Audience       []string      `bson:"audience,omitempty"`
+	IssuedAt       time.Time     `bson:"issuedAt"`
+	ExpiresAt      time.Time     `bson:"expiresAt"`
+}
+
+func (t Token) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys: bson.D{{Key: "clientId", Value: 1}, {Key: "subject", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "subject", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "sessionId", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+}
+
+func (t Token) NameSingular() string {
+	return "token"
+}
+
+func (t Token) NamePlural() string {
+	return "tokens"
+}
+
+func (t Token) CollectionName() string {
+	return "tokens"
+}
+
+func (t Token) TenantField() string {
+	return "organizationId"
+}
+
+func (t *Token) SetTenant(organizationID string) {
+	t.OrganizationID = organizationID
+}
+
+// IsActive returns true if the token has not expired yet.
+func (t Token) IsActive(now time.Time) bool {
+	return now.Before(t.ExpiresAt)
+}
+
+// IsIntendedFor returns true if the token may be presented to a resource server: the tokens without
+// audience are intended for goauth only.
+func (t Token) IsIntendedFor(audience string) bool {
+	return len(t.Audience) == 0 || slices.Contains(t.Audience, audience)
+}
+
+// TokenType returns the type of the token, as returned to clients: DPoP if it is bound to a DPoP key.
+func (t Token) TokenType() string {
+	if t.Confirmation != nil && t.Confirmation.JKT != "" {
+		return TokenTypeDPoP
+	}
+
+	return TokenTypeBearer
+}
+
+// TokenResponse is a successful response of the token endpoint.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// IntrospectionResponse is a response of the introspection endpoint (RFC 7662).
+type IntrospectionResponse struct {
+	Active         bool          `json:"active"`
+	Scope          string        `json:"scope,omitempty"`
+	ClientID       string        `json:"client_id,omitempty"`
+	Subject        string        `json:"sub,omitempty"`
+	TokenType      string        `json:"token_type,omitempty"`
+	IssuedAt       int64         `json:"iat,omitempty"`
+	ExpiresAt      int64         `json:"exp,omitempty"`
+	Issuer         string        `json:"iss,omitempty"`
+	Audience       []string      `json:"aud,omitempty"`
+	Confirmation   *Confirmation `json:"cnf,omitempty"`
+	OrganizationID string        `json:"org_id,omitempty"`
+	Roles          []string      `json:"roles,omitempty"`
+	Permissions    []string      `json:"permissions,omitempty"`
+	Groups         []string      `json:"groups,omitempty"`
+	GroupsOverage  bool          `json:"groups_overage,omitempty"`
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// User is a local account of an end-user. Its identifier is the subject of the tokens issued to the
+// end-user. The user name and external ID are set by the provisioning clients (SCIM), and disabled
+// users can't log in. Roles are the identifiers of the roles assigned to the user on the whole platform,
+// and Memberships the organizations the user is a member of.
+type User struct {
+	ID            string       `bson:"_id"                   json:"id"`
+	UserName      string       `bson:"userName,omitempty"    json:"user_name,omitempty"`
+	ExternalID    string       `bson:"externalId,omitempty"  json:"external_id,omitempty"`
+	Email         string       `bson:"email,omitempty"       json:"email,omitempty"`
+	EmailVerified bool         `bson:"emailVerified"         json:"email_verified"`
+	Name          string       `bson:"name,omitempty"        json:"name,omitempty"`
+	GivenName     string       `bson:"givenName,omitempty"   json:"given_name,omitempty"`
+	FamilyName    string       `bson:"familyName,omitempty"  json:"family_name,omitempty"`
+	Picture       string       `bson:"picture,omitempty"     json:"picture,omitempty"`
+	Disabled      bool         `bson:"disabled"              json:"disabled"`
+	Roles         []string     `bson:"roles,omitempty"       json:"roles,omitempty"`
+	Memberships   []Membership `bson:"memberships,omitempty" json:"memberships,omitempty"`
+	CreatedAt     time.Time    `bson:"createdAt"             json:"created_at"`
+	UpdatedAt     time.Time    `bson:"updatedAt"             json:"updated_at"`
+}
+
+func (u User) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "memberships.organizationId", Value: 1}, {Key: "userName", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"userName": bson.M{"$exists": true}}).
+				SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+		},
+		{
+			Keys:    bson.D{{Key: "externalId", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "roles", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+}
+
+func (u User) NameSingular() string {
+	return "user"
+}
+
+func (u User) NamePlural() string {
+	return "users"
+}
+
+func (u User) CollectionName() string {
+	return "users"
+}
+
+func (u User) TenantField() string {
+	return "memberships.organizationId"
+}
+
+// SetTenant makes the user a member of an organization.
+func (u *User) SetTenant(organizationID string) {
+	if u.Membership(organizationID) == nil {
+		u.Memberships = append(u.Memberships, Membership{OrganizationID: organizationID, CreatedAt: time.Now()})
+	}
+}
+
+// Membership returns the membership of the user in an organization, or nil if the user is not a member.
+func (u User) Membership(organizationID string) *Membership {
+	for i := range u.Memberships {
+		if u.Memberships[i].OrganizationID == organizationID {
+			return &u.Memberships[i]
+		}
+	}
+
+	return nil
+}
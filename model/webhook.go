@@ -0,0 +1,174 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Events sent to the webhooks. The * event subscribes a webhook to every event.
+const (
+	WebhookEventAll            = "*"
+	WebhookEventUserCreated    = "user.created"
+	WebhookEventUserUpdated    = "user.updated"
+	WebhookEventUserDeleted    = "user.deleted"
+	WebhookEventLoginSucceeded = "login.succeeded"
+	WebhookEventLoginFailed    = "login.failed"
+	WebhookEventSessionEnded   = "session.ended"
+	WebhookEventTokenRevoked   = "token.revoked"
+)
+
+// WebhookEvents are the events a webhook can subscribe to.
+var WebhookEvents = []string{
+	WebhookEventUserCreated,
+	WebhookEventUserUpdated,
+	WebhookEventUserDeleted,
+	WebhookEventLoginSucceeded,
+	WebhookEventLoginFailed,
+	WebhookEventSessionEnded,
+	WebhookEventTokenRevoked,
+}
+
+// Headers of the webhook requests. The signature is the HMAC-SHA256 of the timestamp and the body, joined by
+// a dot, with the secret of the webhook: receivers should check it, and reject the old timestamps.
+const (
+	WebhookHeaderEvent     = "Goauth-Event"
+	WebhookHeaderDelivery  = "Goauth-Delivery"
+	WebhookHeaderTimestamp = "Goauth-Timestamp"
+	WebhookHeaderSignature = "Goauth-Signature"
+)
+
+// Statuses of the webhook deliveries. Deliveries are retried with an exponential backoff until they
+// succeed, or are dead after the last attempt.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead"
+)
+
+// Webhook subscribes an endpoint of another system to events of an organization. The events are posted
+// as JSON, signed with the secret of the webhook. The secret is only shown on creation: it is stored
+// encrypted, to sign the deliveries.
+type Webhook struct {
+	ID              string    `bson:"_id"                      json:"id"`
+	OrganizationID  string    `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	URL             string    `bson:"url"                      json:"url"`
+	Description     string    `bson:"description,omitempty"    json:"description,omitempty"`
+	Events          []string  `bson:"events"                   json:"events"`
+	Disabled        bool      `bson:"disabled"                 json:"disabled"`
+	SecretEncrypted string    `bson:"secretEncrypted"          json:"-"`
+	CreatedAt       time.Time `bson:"createdAt"                json:"created_at"`
+	UpdatedAt       time.Time `bson:"updatedAt"                json:"updated_at"`
+}
+
+// WebhookCreation is the response to the creation of a webhook, the only one holding its secret.
+type WebhookCreation struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookPayload is the body of the webhook requests. Its identifier is the identifier of the event, shared
+// by the replays of a delivery, so that receivers can ignore the events they already processed.
+type WebhookPayload struct {
+	ID             string     `json:"id"`
+	Type           string     `json:"type"`
+	OrganizationID string     `json:"organization_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Data           AuditEvent `json:"data"`
+}
+
+// WebhookDelivery is an event queued for a webhook, along with the log of its attempts. It expires at the
+// end of the retention of the configuration.
+type WebhookDelivery struct {
+	ID             string           `bson:"_id"                      json:"id"`
+	OrganizationID string           `bson:"organizationId,omitempty" json:"organization_id,omitempty"`
+	WebhookID      string           `bson:"webhookId"                json:"webhook_id"`
+	EventID        string           `bson:"eventId"                  json:"event_id"`
+	Event          string           `bson:"event"                    json:"event"`
+	Payload        string           `bson:"payload"                  json:"payload"`
+	Status         string           `bson:"status"                   json:"status"`
+	Attempts       []WebhookAttempt `bson:"attempts"                 json:"attempts"`
+	NextAttemptAt  *time.Time       `bson:"nextAttemptAt,omitempty"  json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time        `bson:"createdAt"                json:"created_at"`
+	ExpiresAt      time.Time        `bson:"expiresAt"                json:"-"`
+}
+
+// WebhookAttempt is an attempt to deliver an event. Error holds the reason of a failed attempt.
+type WebhookAttempt struct {
+	At         time.Time `bson:"at"                   json:"at"`
+	StatusCode int       `bson:"statusCode,omitempty" json:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty"      json:"error,omitempty"`
+	Duration   int64     `bson:"duration"             json:"duration_ms"`
+}
+
+// Subscribes returns true if the webhook receives an event.
+func (w Webhook) Subscribes(event string) bool {
+	return !w.Disabled && (slices.Contains(w.Events, event) || slices.Contains(w.Events, WebhookEventAll))
+}
+
+func (w Webhook) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "organizationId", Value: 1}},
+		},
+	}
+}
+
+func (w Webhook) NameSingular() string {
+	return "webhook"
+}
+
+func (w Webhook) NamePlural() string {
+	return "webhooks"
+}
+
+func (w Webhook) CollectionName() string {
+	return "webhooks"
+}
+
+func (w Webhook) TenantField() string {
+	return "organizationId"
+}
+
+func (w *Webhook) SetTenant(organizationID string) {
+	w.OrganizationID = organizationID
+}
+
+func (d WebhookDelivery) Indexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "nextAttemptAt", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+}
+
+func (d WebhookDelivery) NameSingular() string {
+	return "webhook delivery"
+}
+
+func (d WebhookDelivery) NamePlural() string {
+	return "webhook deliveries"
+}
+
+func (d WebhookDelivery) CollectionName() string {
+	return "webhookDeliveries"
+}
+
+func (d WebhookDelivery) TenantField() string {
+	return "organizationId"
+}
+
+func (d *WebhookDelivery) SetTenant(organizationID string) {
+	d.OrganizationID = organizationID
+}
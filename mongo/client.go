@@ -39,7 +39,7 @@ func initialize(ctx context.Context) error {
 			mongoURI = fmt.Sprintf("mongodb://%s:%d/%s", host, port, name)
 		}
 
-		clientOptions := options.Client().ApplyURI(mongoURI)
+		clientOptions := options.Client().ApplyURI(mongoURI).SetPoolMonitor(poolMonitor())
 
 		// Network compression allows to improve performance when requesting large volume of data.
 		if config.MongoDBUseCompression() {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,10 +12,13 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// CrudDAO executes the operations on a collection. The queries on documents of organizations
+// (TenantDocument) are restricted to the organization of their context, and fail without one.
 type CrudDAO[T Document] interface {
 	// GetCollection returns the collection associated to the document.
 	// This method is useful to execute custom mongodb operations that are
-	// not covered by the interface.
+	// not covered by the interface. These operations are not restricted
+	// to the organization of their context.
 	GetCollection() *mongo.Collection
 
 	// CreateIndexes launches the creation of defined indexes, for the
@@ -24,7 +28,8 @@ type CrudDAO[T Document] interface {
 	// Create launches the basic mongodb creation processes, but with a few
 	// personalised touches:
 	// - If the document contains a "CreatedAt" field, it will be set,
-	// - If the creation throws a unique constraint error, the method returns false and no error.
+	// - If the creation throws a unique constraint error, the method returns false and no error,
+	// - Documents of organizations (TenantDocument) are assigned to the organization of the context.
 	Create(ctx context.Context, t *T) (bool, error)
 
 	// Update launches a basic flexible mongodb update, but with a few
@@ -70,6 +75,8 @@ type CrudDAO[T Document] interface {
 type crudDAO[T Document] struct {
 	collection *mongo.Collection
 	modelRef   T
+	// tenantScoped is set for the documents of organizations.
+	tenantScoped bool
 }
 
 func (dao *crudDAO[T]) GetCollection() *mongo.Collection {
@@ -88,7 +95,13 @@ func (dao *crudDAO[T]) CreateIndexes(ctx context.Context, indexes []mongo.IndexM
 }
 
 func (dao *crudDAO[T]) Create(ctx context.Context, t *T) (bool, error) {
+	if err := dao.assign(ctx, t); err != nil {
+		return false, err
+	}
+
+	start := time.Now()
 	_, err := dao.collection.InsertOne(ctx, t)
+	dao.observe("insert_one", start, err)
 
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
@@ -111,9 +124,17 @@ func (dao *crudDAO[T]) Create(ctx context.Context, t *T) (bool, error) {
 }
 
 func (dao *crudDAO[T]) Update(ctx context.Context, filter bson.M, update bson.M, withUpsert bool) (UpdateResult, error) {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+
 	opts := options.Update().SetUpsert(withUpsert)
 
+	start := time.Now()
 	ur, err := dao.collection.UpdateOne(ctx, filter, update, opts)
+	dao.observe("update_one", start, err)
+
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			log.Err(err).Msgf("Could not update %s due to a unique constraint error", dao.modelRef.NameSingular())
@@ -147,7 +168,15 @@ func (dao *crudDAO[T]) Update(ctx context.Context, filter bson.M, update bson.M,
 }
 
 func (dao *crudDAO[T]) Exists(ctx context.Context, filter bson.M, opts *options.CountOptions) (bool, error) {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+
+	start := time.Now()
 	count, err := dao.collection.CountDocuments(ctx, filter, opts)
+	dao.observe("count", start, err)
+
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			log.Warn().Interface("filter", filter).Msgf("No %s with given filter exists", dao.modelRef.NameSingular())
@@ -167,7 +196,17 @@ func (dao *crudDAO[T]) Exists(ctx context.Context, filter bson.M, opts *options.
 }
 
 func (dao *crudDAO[T]) Count(ctx context.Context, filter bson.M) int64 {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		log.Err(err).Msgf("Could not count %s", dao.modelRef.NamePlural())
+
+		return -1
+	}
+
+	start := time.Now()
 	count, err := dao.collection.CountDocuments(ctx, filter)
+	dao.observe("count", start, err)
+
 	if err != nil {
 		log.Error().Fields(map[string]interface{}{
 			"filter": filter,
@@ -181,8 +220,16 @@ func (dao *crudDAO[T]) Count(ctx context.Context, filter bson.M) int64 {
 }
 
 func (dao *crudDAO[T]) FindOne(ctx context.Context, filter bson.M, opts *options.FindOneOptions) (*T, error) {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	sr := dao.collection.FindOne(ctx, filter, opts)
-	if err := sr.Err(); err != nil {
+	dao.observe("find_one", start, sr.Err())
+
+	if err = sr.Err(); err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			log.Warn().Interface("filter", filter).Msgf("No %s was found", dao.modelRef.NameSingular())
 
@@ -200,7 +247,7 @@ func (dao *crudDAO[T]) FindOne(ctx context.Context, filter bson.M, opts *options
 
 	res := new(T)
 
-	if err := sr.Decode(res); err != nil {
+	if err = sr.Decode(res); err != nil {
 		log.Error().Fields(map[string]interface{}{
 			"filter": filter,
 			"err":    err,
@@ -215,7 +262,15 @@ func (dao *crudDAO[T]) FindOne(ctx context.Context, filter bson.M, opts *options
 }
 
 func (dao *crudDAO[T]) FindMany(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]T, error) {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	cur, err := dao.collection.Find(ctx, filter, opts)
+	dao.observe("find", start, err)
+
 	if err != nil {
 		log.Error().Fields(map[string]interface{}{
 			"filter": filter,
@@ -259,7 +314,15 @@ func (dao *crudDAO[T]) FindMany(ctx context.Context, filter bson.M, opts *option
 }
 
 func (dao *crudDAO[T]) Aggregate(ctx context.Context, pipeline interface{}) ([]T, error) {
+	pipeline, err := dao.scopePipeline(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
 	cur, err := dao.collection.Aggregate(ctx, pipeline)
+	dao.observe("aggregate", start, err)
+
 	if err != nil {
 		log.Error().Fields(map[string]interface{}{
 			"pipeline": pipeline,
@@ -303,7 +366,15 @@ func (dao *crudDAO[T]) Aggregate(ctx context.Context, pipeline interface{}) ([]T
 }
 
 func (dao *crudDAO[T]) Delete(ctx context.Context, filter bson.M) (bool, error) {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+
+	start := time.Now()
 	dr, err := dao.collection.DeleteOne(ctx, filter)
+	dao.observe("delete_one", start, err)
+
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			log.Warn().Interface("filter", filter).Msgf("Could not delete non-existent %s", dao.modelRef.NameSingular())
@@ -331,7 +402,15 @@ func (dao *crudDAO[T]) Delete(ctx context.Context, filter bson.M) (bool, error)
 }
 
 func (dao *crudDAO[T]) DeleteMany(ctx context.Context, filter bson.M) (int64, error) {
+	filter, err := dao.scope(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
 	dr, err := dao.collection.DeleteMany(ctx, filter)
+	dao.observe("delete_many", start, err)
+
 	if err != nil {
 		log.Error().Fields(map[string]interface{}{
 			"filter": filter,
@@ -405,6 +484,7 @@ func NewCrudDAO[T Document](db *mongo.Database) CrudDAO[T] {
 	dao := &crudDAO[T]{}
 
 	dao.collection = db.Collection(dao.modelRef.CollectionName())
+	_, dao.tenantScoped = any(new(T)).(TenantDocument)
 
 	if len(dao.modelRef.Indexes()) > 0 {
 		// Here we pass a background context because this operation takes time
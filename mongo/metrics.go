@@ -0,0 +1,43 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/m3talux/goauth/metrics"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// observe records an operation on the collection of the DAO in the metrics, from its start. Finding
+// no document is not an error.
+func (dao *crudDAO[T]) observe(operation string, start time.Time, err error) {
+	collection := dao.modelRef.CollectionName()
+
+	metrics.MongoOperations.WithLabelValues(collection, operation).Inc()
+	metrics.MongoOperationDuration.WithLabelValues(collection, operation).Observe(time.Since(start).Seconds())
+
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		metrics.MongoOperationErrors.WithLabelValues(collection, operation).Inc()
+	}
+}
+
+// poolMonitor returns a monitor recording the state of the connection pools in the metrics.
+func poolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(evt *event.PoolEvent) {
+			switch evt.Type {
+			case event.ConnectionCreated:
+				metrics.MongoPoolConnections.WithLabelValues(evt.Address).Inc()
+			case event.ConnectionClosed:
+				metrics.MongoPoolConnections.WithLabelValues(evt.Address).Dec()
+			case event.GetSucceeded:
+				metrics.MongoPoolConnectionsInUse.WithLabelValues(evt.Address).Inc()
+			case event.ConnectionReturned:
+				metrics.MongoPoolConnectionsInUse.WithLabelValues(evt.Address).Dec()
+			case event.GetFailed:
+				metrics.MongoPoolCheckoutFailures.WithLabelValues(evt.Address, evt.Reason).Inc()
+			}
+		},
+	}
+}
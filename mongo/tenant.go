@@ -0,0 +1,93 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrMissingOrganization is returned when querying the documents of organizations without organization
+// in the context, so that a query can't cross organizations by accident.
+var ErrMissingOrganization = errors.New("the context has no organization")
+
+// TenantDocument is implemented by the documents of organizations. Their DAO restricts its queries to the
+// organization of the context, and assigns the documents it creates to it.
+type TenantDocument interface {
+	// TenantField returns the field holding the organization of the document.
+	TenantField() string
+
+	// SetTenant assigns the document to an organization.
+	SetTenant(organizationID string)
+}
+
+// scope restricts a filter to the organization of the context. The documents created before organizations
+// were introduced have none, and belong to the default organization.
+func (dao *crudDAO[T]) scope(ctx context.Context, filter bson.M) (bson.M, error) {
+	if !dao.tenantScoped || tenant.AllOrganizations(ctx) {
+		return filter, nil
+	}
+
+	organizationID := tenant.OrganizationID(ctx)
+	if organizationID == "" {
+		return nil, ErrMissingOrganization
+	}
+
+	field := any(new(T)).(TenantDocument).TenantField()
+
+	tenantFilter := bson.M{field: organizationID}
+	if organizationID == config.DefaultOrganizationID() {
+		tenantFilter = bson.M{field: bson.M{"$in": bson.A{organizationID, nil}}}
+	}
+
+	if len(filter) == 0 {
+		return tenantFilter, nil
+	}
+
+	return bson.M{"$and": bson.A{filter, tenantFilter}}, nil
+}
+
+// scopePipeline restricts an aggregation pipeline to the organization of the context.
+func (dao *crudDAO[T]) scopePipeline(ctx context.Context, pipeline interface{}) (interface{}, error) {
+	filter, err := dao.scope(ctx, bson.M{})
+	if err != nil || len(filter) == 0 {
+		return pipeline, err
+	}
+
+	match := bson.D{{Key: "$match", Value: filter}}
+
+	switch stages := pipeline.(type) {
+	case mongo.Pipeline:
+		return append(mongo.Pipeline{match}, stages...), nil
+	case bson.A:
+		return append(bson.A{match}, stages...), nil
+	case []bson.M:
+		scoped := bson.A{match}
+		for _, stage := range stages {
+			scoped = append(scoped, stage)
+		}
+
+		return scoped, nil
+	}
+
+	return nil, errors.New("the pipeline of documents of organizations must be a list of stages")
+}
+
+// assign assigns a created document to the organization of the context.
+func (dao *crudDAO[T]) assign(ctx context.Context, t *T) error {
+	if !dao.tenantScoped || tenant.AllOrganizations(ctx) {
+		return nil
+	}
+
+	organizationID := tenant.OrganizationID(ctx)
+	if organizationID == "" {
+		return ErrMissingOrganization
+	}
+
+	any(t).(TenantDocument).SetTenant(organizationID)
+
+	return nil
+}
@@ -5,37 +5,102 @@ import (
 
 	"github.com/m3talux/goauth/config"
 	"github.com/m3talux/goauth/handler"
+	"github.com/m3talux/goauth/model"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
 type Router struct {
 	*gin.Engine
-	Handlers Handlers
+	Handlers    Handlers
+	Middlewares Middlewares
 }
 
 type Handlers struct {
-	CheckHandler *handler.CheckHandler
+	CheckHandler               *handler.CheckHandler
+	DiscoveryHandler           *handler.DiscoveryHandler
+	OAuthHandler               *handler.OAuthHandler
+	SessionHandler             *handler.SessionHandler
+	LoginHandler               *handler.LoginHandler
+	AccountHandler             *handler.AccountHandler
+	SAMLHandler                *handler.SAMLHandler
+	SCIMHandler                *handler.SCIMHandler
+	RoleHandler                *handler.RoleHandler
+	OrganizationHandler        *handler.OrganizationHandler
+	InvitationHandler          *handler.InvitationHandler
+	DomainHandler              *handler.DomainHandler
+	APIKeyHandler              *handler.APIKeyHandler
+	PersonalAccessTokenHandler *handler.PersonalAccessTokenHandler
+	PolicyHandler              *handler.PolicyHandler
+	RelationHandler            *handler.RelationHandler
+	GroupHandler               *handler.GroupHandler
+	AuditHandler               *handler.AuditHandler
+	WebhookHandler             *handler.WebhookHandler
 }
 
-func NewRouter(handlers Handlers) Router {
+type Middlewares struct {
+	Tenant              gin.HandlerFunc
+	APIKey              gin.HandlerFunc
+	PersonalAccessToken gin.HandlerFunc
+	AccessToken         gin.HandlerFunc
+	Session             gin.HandlerFunc
+	SCIMToken           gin.HandlerFunc
+	Platform            gin.HandlerFunc
+	// Permission returns a middleware restricting a route to the access tokens granting a permission.
+	Permission func(permission string) gin.HandlerFunc
+	// RateLimit returns a middleware limiting the requests of a route group with a rate limit policy.
+	RateLimit func(policy string) gin.HandlerFunc
+	// AuditSource records the source of the requests in their audit events, Audit records the changes made
+	// through the administration API.
+	AuditSource gin.HandlerFunc
+	Audit       gin.HandlerFunc
+	// Metrics records the count and the duration of the requests in the metrics.
+	Metrics gin.HandlerFunc
+}
+
+func NewRouter(handlers Handlers, middlewares Middlewares) Router {
 	// Use Gin release mode by default
 	if config.GinMode() == "" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	r := Router{
-		Engine:   gin.New(),
-		Handlers: handlers,
+		Engine:      gin.New(),
+		Handlers:    handlers,
+		Middlewares: middlewares,
+	}
+
+	// The client IPs are only read from the headers of the trusted proxies, so that they can't be spoofed
+	// to escape the rate limits
+	if err := r.SetTrustedProxies(config.TLSTrustedProxies()); err != nil {
+		log.Err(err).Msg("Could not set the trusted proxies")
 	}
 
 	// Middlewares
-	r.Use(gin.Recovery(), corsMiddleware())
+	r.Use(r.Middlewares.Metrics, gin.Recovery(), corsMiddleware(), r.Middlewares.AuditSource)
 
 	// Entrypoints
 	r.registerMonitoring()
-	r.registerAPI()
+
+	// The endpoints of the organizations are served at the root, for the default organization and the
+	// organizations resolved from their domain, and under the path of each organization
+	root := r.Group("", r.Middlewares.Tenant)
+	organization := r.Group(config.OrganizationsPath()+"/:organization", r.Middlewares.Tenant)
+
+	for _, base := range []*gin.RouterGroup{root, organization} {
+		r.registerDiscovery(base)
+		r.registerOAuth(base)
+		r.registerLogin(base)
+		r.registerAccount(base)
+		r.registerInvitations(base)
+		r.registerSAML(base)
+		r.registerSCIM(base)
+		r.registerAPI(base)
+	}
+
+	r.registerPlatformAPI(root)
 
 	r.Static("/openapi", "openapi/")
 
@@ -72,6 +137,191 @@ func (r *Router) registerMonitoring() {
 	r.GET("/ready", r.Handlers.CheckHandler.Ready)
 }
 
-func (r *Router) registerAPI() {
-	_ = r.Group(config.APIPath())
+func (r *Router) registerDiscovery(base *gin.RouterGroup) {
+	base.GET("/.well-known/oauth-authorization-server", r.Handlers.DiscoveryHandler.Metadata)
+	base.GET("/.well-known/openid-configuration", r.Handlers.DiscoveryHandler.Metadata)
+	base.GET("/organization", r.Handlers.OrganizationHandler.Organization)
+}
+
+func (r *Router) registerOAuth(base *gin.RouterGroup) {
+	oauth := base.Group(config.OAuthPath(), r.Middlewares.RateLimit(model.RateLimitPolicyOAuth))
+
+	oauth.GET("/authorize", r.Handlers.OAuthHandler.Authorize)
+	oauth.POST("/authorize", r.Handlers.OAuthHandler.Authorize)
+	oauth.POST("/par", r.Handlers.OAuthHandler.PushedAuthorizationRequest)
+	oauth.POST("/token", r.Handlers.OAuthHandler.Token)
+	oauth.POST("/introspect", r.Handlers.OAuthHandler.Introspect)
+	oauth.GET("/jwks", r.Handlers.DiscoveryHandler.JWKS)
+	oauth.GET("/logout", r.Handlers.SessionHandler.Logout)
+	oauth.POST("/logout", r.Handlers.SessionHandler.Logout)
+}
+
+func (r *Router) registerLogin(base *gin.RouterGroup) {
+	login := base.Group(config.SocialLoginPath(), r.Middlewares.RateLimit(model.RateLimitPolicyLogin))
+
+	login.GET("/connections", r.Handlers.LoginHandler.Connections)
+	login.GET("/discovery", r.Handlers.DomainHandler.Discover)
+	login.GET("/:connection", r.Handlers.LoginHandler.Start)
+	login.POST("/:connection", r.Handlers.LoginHandler.Authenticate)
+	login.GET("/:connection/callback", r.Handlers.LoginHandler.Callback)
+	login.POST("/:connection/callback", r.Handlers.LoginHandler.Callback)
+	login.GET("/:connection/metadata", r.Handlers.LoginHandler.Metadata)
+}
+
+func (r *Router) registerAccount(base *gin.RouterGroup) {
+	account := base.Group(config.AccountPath(), r.Middlewares.RateLimit(model.RateLimitPolicyAuthentication), r.Middlewares.Session,
+		r.Middlewares.RateLimit(model.RateLimitPolicyAccount))
+
+	account.GET("/identities", r.Handlers.AccountHandler.Identities)
+	account.GET("/identities/link/:connection", r.Handlers.AccountHandler.LinkIdentity)
+	account.DELETE("/identities/:identity", r.Handlers.AccountHandler.UnlinkIdentity)
+	account.GET("/tokens", r.Handlers.PersonalAccessTokenHandler.Tokens)
+	account.POST("/tokens", r.Handlers.PersonalAccessTokenHandler.CreateToken)
+	account.DELETE("/tokens/:token", r.Handlers.PersonalAccessTokenHandler.RevokeToken)
+}
+
+func (r *Router) registerInvitations(base *gin.RouterGroup) {
+	base.GET(config.InvitationsPath()+"/:token", r.Middlewares.RateLimit(model.RateLimitPolicyLogin), r.Handlers.InvitationHandler.Open)
+}
+
+func (r *Router) registerSAML(base *gin.RouterGroup) {
+	saml := base.Group(config.SAMLPath(), r.Middlewares.RateLimit(model.RateLimitPolicyLogin))
+
+	saml.GET("/metadata", r.Handlers.SAMLHandler.Metadata)
+	saml.GET("/sso", r.Handlers.SAMLHandler.SSO)
+	saml.POST("/sso", r.Handlers.SAMLHandler.SSO)
+	saml.GET("/sso/resume/:request", r.Handlers.SAMLHandler.Resume)
+	saml.GET("/idp", r.Handlers.SAMLHandler.IdPInitiated)
+}
+
+func (r *Router) registerSCIM(base *gin.RouterGroup) {
+	scim := base.Group(config.SCIMPath(), r.Middlewares.RateLimit(model.RateLimitPolicySCIM))
+
+	scim.GET("/ServiceProviderConfig", r.Handlers.SCIMHandler.ServiceProviderConfig)
+	scim.GET("/ResourceTypes", r.Handlers.SCIMHandler.ResourceTypes)
+	scim.GET("/ResourceTypes/:id", r.Handlers.SCIMHandler.ResourceType)
+	scim.GET("/Schemas", r.Handlers.SCIMHandler.Schemas)
+	scim.GET("/Schemas/:id", r.Handlers.SCIMHandler.Schema)
+
+	users := scim.Group("/Users", r.Middlewares.SCIMToken)
+
+	users.GET("", r.Handlers.SCIMHandler.Users)
+	users.POST("", r.Handlers.SCIMHandler.CreateUser)
+	users.GET("/:id", r.Handlers.SCIMHandler.User)
+	users.PUT("/:id", r.Handlers.SCIMHandler.ReplaceUser)
+	users.PATCH("/:id", r.Handlers.SCIMHandler.PatchUser)
+	users.DELETE("/:id", r.Handlers.SCIMHandler.DeleteUser)
+
+	groups := scim.Group("/Groups", r.Middlewares.SCIMToken)
+
+	groups.GET("", r.Handlers.SCIMHandler.Groups)
+	groups.POST("", r.Handlers.SCIMHandler.CreateGroup)
+	groups.GET("/:id", r.Handlers.SCIMHandler.Group)
+	groups.PUT("/:id", r.Handlers.SCIMHandler.ReplaceGroup)
+	groups.PATCH("/:id", r.Handlers.SCIMHandler.PatchGroup)
+	groups.DELETE("/:id", r.Handlers.SCIMHandler.DeleteGroup)
+}
+
+func (r *Router) registerAPI(base *gin.RouterGroup) {
+	api := base.Group(config.APIPath(), r.Middlewares.RateLimit(model.RateLimitPolicyAuthentication),
+		r.Middlewares.APIKey, r.Middlewares.PersonalAccessToken, r.Middlewares.AccessToken,
+		r.Middlewares.RateLimit(model.RateLimitPolicyAPI), r.Middlewares.Audit)
+
+	api.GET("/clients/:client/roles", r.Middlewares.Permission(model.PermissionRolesRead), r.Handlers.RoleHandler.ClientRoles)
+	api.PUT("/clients/:client/roles/:role", r.Middlewares.Permission(model.PermissionRolesWrite), r.Handlers.RoleHandler.AssignClientRole)
+	api.DELETE("/clients/:client/roles/:role", r.Middlewares.Permission(model.PermissionRolesWrite), r.Handlers.RoleHandler.UnassignClientRole)
+
+	api.GET("/members", r.Middlewares.Permission(model.PermissionMembersRead), r.Handlers.OrganizationHandler.Members)
+	api.PUT("/members/:user", r.Middlewares.Permission(model.PermissionMembersWrite), r.Handlers.OrganizationHandler.UpdateMember)
+	api.DELETE("/members/:user", r.Middlewares.Permission(model.PermissionMembersWrite), r.Handlers.OrganizationHandler.RemoveMember)
+
+	api.GET("/invitations", r.Middlewares.Permission(model.PermissionMembersRead), r.Handlers.InvitationHandler.Invitations)
+	api.POST("/invitations", r.Middlewares.Permission(model.PermissionMembersWrite), r.Handlers.InvitationHandler.CreateInvitation)
+	api.DELETE("/invitations/:invitation", r.Middlewares.Permission(model.PermissionMembersWrite), r.Handlers.InvitationHandler.RevokeInvitation)
+
+	api.GET("/domains", r.Middlewares.Permission(model.PermissionDomainsRead), r.Handlers.DomainHandler.Domains)
+	api.POST("/domains", r.Middlewares.Permission(model.PermissionDomainsWrite), r.Handlers.DomainHandler.ClaimDomain)
+	api.PUT("/domains/:domain", r.Middlewares.Permission(model.PermissionDomainsWrite), r.Handlers.DomainHandler.UpdateDomain)
+	api.DELETE("/domains/:domain", r.Middlewares.Permission(model.PermissionDomainsWrite), r.Handlers.DomainHandler.DeleteDomain)
+	api.POST("/domains/:domain/verify", r.Middlewares.Permission(model.PermissionDomainsWrite), r.Handlers.DomainHandler.VerifyDomain)
+
+	api.GET("/api-keys", r.Middlewares.Permission(model.PermissionAPIKeysRead), r.Handlers.APIKeyHandler.APIKeys)
+	api.POST("/api-keys", r.Middlewares.Permission(model.PermissionAPIKeysWrite), r.Handlers.APIKeyHandler.CreateAPIKey)
+	api.DELETE("/api-keys/:key", r.Middlewares.Permission(model.PermissionAPIKeysWrite), r.Handlers.APIKeyHandler.RevokeAPIKey)
+
+	tokens := api.Group("/users/:user/tokens")
+	tokens.GET("", r.Middlewares.Permission(model.PermissionPersonalAccessTokensRead), r.Handlers.PersonalAccessTokenHandler.UserTokens)
+	tokens.DELETE("/:token", r.Middlewares.Permission(model.PermissionPersonalAccessTokensWrite), r.Handlers.PersonalAccessTokenHandler.RevokeUserToken)
+
+	api.GET("/policies", r.Middlewares.Permission(model.PermissionPoliciesRead), r.Handlers.PolicyHandler.Policies)
+	api.POST("/policies", r.Middlewares.Permission(model.PermissionPoliciesWrite), r.Handlers.PolicyHandler.CreatePolicy)
+	api.GET("/policies/:policy", r.Middlewares.Permission(model.PermissionPoliciesRead), r.Handlers.PolicyHandler.Policy)
+	api.PUT("/policies/:policy", r.Middlewares.Permission(model.PermissionPoliciesWrite), r.Handlers.PolicyHandler.UpdatePolicy)
+	api.DELETE("/policies/:policy", r.Middlewares.Permission(model.PermissionPoliciesWrite), r.Handlers.PolicyHandler.DeletePolicy)
+	api.POST("/authorize", r.Middlewares.Permission(model.PermissionPoliciesEvaluate), r.Handlers.PolicyHandler.Authorize)
+	api.POST("/authorize/batch", r.Middlewares.Permission(model.PermissionPoliciesEvaluate), r.Handlers.PolicyHandler.AuthorizeBatch)
+
+	read, write := r.Middlewares.Permission(model.PermissionRelationsRead), r.Middlewares.Permission(model.PermissionRelationsWrite)
+
+	api.GET("/relations/namespaces", read, r.Handlers.RelationHandler.Namespaces)
+	api.PUT("/relations/namespaces/:namespace", write, r.Handlers.RelationHandler.SaveNamespace)
+	api.DELETE("/relations/namespaces/:namespace", write, r.Handlers.RelationHandler.DeleteNamespace)
+	api.POST("/relations/write", write, r.Handlers.RelationHandler.Write)
+	api.POST("/relations/check", read, r.Handlers.RelationHandler.Check)
+	api.POST("/relations/expand", read, r.Handlers.RelationHandler.Expand)
+	api.POST("/relations/list-objects", read, r.Handlers.RelationHandler.ListObjects)
+
+	read, write = r.Middlewares.Permission(model.PermissionGroupsRead), r.Middlewares.Permission(model.PermissionGroupsWrite)
+
+	api.GET("/groups", read, r.Handlers.GroupHandler.Groups)
+	api.POST("/groups", write, r.Handlers.GroupHandler.CreateGroup)
+	api.GET("/groups/:group", read, r.Handlers.GroupHandler.Group)
+	api.PUT("/groups/:group", write, r.Handlers.GroupHandler.UpdateGroup)
+	api.DELETE("/groups/:group", write, r.Handlers.GroupHandler.DeleteGroup)
+	api.GET("/users/:user/groups", read, r.Handlers.GroupHandler.UserGroups)
+
+	api.GET("/audit/events", r.Middlewares.Permission(model.PermissionAuditRead), r.Handlers.AuditHandler.Events)
+
+	read, write = r.Middlewares.Permission(model.PermissionWebhooksRead), r.Middlewares.Permission(model.PermissionWebhooksWrite)
+
+	api.GET("/webhooks", read, r.Handlers.WebhookHandler.Webhooks)
+	api.POST("/webhooks", write, r.Handlers.WebhookHandler.CreateWebhook)
+	api.GET("/webhooks/:webhook", read, r.Handlers.WebhookHandler.Webhook)
+	api.PUT("/webhooks/:webhook", write, r.Handlers.WebhookHandler.UpdateWebhook)
+	api.DELETE("/webhooks/:webhook", write, r.Handlers.WebhookHandler.DeleteWebhook)
+	api.GET("/webhooks/:webhook/deliveries", read, r.Handlers.WebhookHandler.Deliveries)
+	api.POST("/webhooks/:webhook/deliveries/:delivery/replay", write, r.Handlers.WebhookHandler.ReplayDelivery)
+
+	api.GET("/scim/tokens", r.Middlewares.Permission(model.PermissionSCIMTokensRead), r.Handlers.SCIMHandler.Tokens)
+	api.POST("/scim/tokens", r.Middlewares.Permission(model.PermissionSCIMTokensWrite), r.Handlers.SCIMHandler.CreateToken)
+	api.DELETE("/scim/tokens/:token", r.Middlewares.Permission(model.PermissionSCIMTokensWrite), r.Handlers.SCIMHandler.RevokeToken)
+}
+
+// registerPlatformAPI registers the administration endpoints of the whole platform, restricted to the
+// default organization.
+func (r *Router) registerPlatformAPI(base *gin.RouterGroup) {
+	api := base.Group(config.APIPath(), r.Middlewares.RateLimit(model.RateLimitPolicyAuthentication),
+		r.Middlewares.APIKey, r.Middlewares.PersonalAccessToken, r.Middlewares.AccessToken, r.Middlewares.Platform,
+		r.Middlewares.RateLimit(model.RateLimitPolicyAPI), r.Middlewares.Audit)
+	read, write := r.Middlewares.Permission(model.PermissionRolesRead), r.Middlewares.Permission(model.PermissionRolesWrite)
+
+	api.GET("/roles", read, r.Handlers.RoleHandler.Roles)
+	api.POST("/roles", write, r.Handlers.RoleHandler.CreateRole)
+	api.GET("/roles/:role", read, r.Handlers.RoleHandler.Role)
+	api.PUT("/roles/:role", write, r.Handlers.RoleHandler.UpdateRole)
+	api.DELETE("/roles/:role", write, r.Handlers.RoleHandler.DeleteRole)
+	api.GET("/users/:user/roles", read, r.Handlers.RoleHandler.UserRoles)
+	api.PUT("/users/:user/roles/:role", write, r.Handlers.RoleHandler.AssignUserRole)
+	api.DELETE("/users/:user/roles/:role", write, r.Handlers.RoleHandler.UnassignUserRole)
+
+	read, write = r.Middlewares.Permission(model.PermissionOrganizationsRead), r.Middlewares.Permission(model.PermissionOrganizationsWrite)
+
+	api.GET("/organizations", read, r.Handlers.OrganizationHandler.Organizations)
+	api.POST("/organizations", write, r.Handlers.OrganizationHandler.CreateOrganization)
+	api.GET("/organizations/:org", read, r.Handlers.OrganizationHandler.GetOrganization)
+	api.PUT("/organizations/:org", write, r.Handlers.OrganizationHandler.UpdateOrganization)
+	api.DELETE("/organizations/:org", write, r.Handlers.OrganizationHandler.DeleteOrganization)
+	api.GET("/organizations/:org/members", read, r.Handlers.OrganizationHandler.Members)
+	api.PUT("/organizations/:org/members/:user", write, r.Handlers.OrganizationHandler.SetMember)
+	api.DELETE("/organizations/:org/members/:user", write, r.Handlers.OrganizationHandler.RemoveMember)
 }
@@ -0,0 +1,131 @@
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/m3talux/goauth/config"
+)
+
+// Certificate authorities trusted for PKI client authentication, loaded once through ClientCertificateAuthorities().
+var (
+	clientCAPool      *x509.CertPool
+	clientCAPoolError error
+	loadClientCAPool  sync.Once
+)
+
+// PeerCertificate returns the client certificate of the request, either presented during the TLS
+// handshake, or forwarded by a trusted TLS terminating proxy. It returns nil when there is none.
+func PeerCertificate(r *http.Request) (*x509.Certificate, error) {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return r.TLS.PeerCertificates[0], nil
+	}
+
+	header := config.TLSClientCertificateHeader()
+	if header == "" || r.Header.Get(header) == "" || !isTrustedProxy(r.RemoteAddr) {
+		//nolint:nilnil // A request without certificate is not an error
+		return nil, nil
+	}
+
+	return parseForwardedCertificate(r.Header.Get(header))
+}
+
+// PeerIntermediates returns the intermediate certificates presented with the client certificate during
+// the TLS handshake. The certificates forwarded by a proxy come without them.
+func PeerIntermediates(r *http.Request) []*x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) < 2 {
+		return nil
+	}
+
+	return r.TLS.PeerCertificates[1:]
+}
+
+// CertificateThumbprint returns the base64url encoded SHA-256 thumbprint of a certificate (x5t#S256).
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ClientCertificateAuthorities returns the pool of the authorities trusted for PKI client authentication.
+func ClientCertificateAuthorities() (*x509.CertPool, error) {
+	loadClientCAPool.Do(func() {
+		file := config.TLSClientCAFile()
+		if file == "" {
+			clientCAPoolError = errors.New("the client certificate authorities are not configured")
+
+			return
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			clientCAPoolError = err
+
+			return
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			clientCAPoolError = errors.New("the client certificate authorities file contains no certificate")
+
+			return
+		}
+
+		clientCAPool = pool
+	})
+
+	return clientCAPool, clientCAPoolError
+}
+
+// parseForwardedCertificate parses a certificate forwarded as a URL-encoded PEM (like nginx
+// $ssl_client_escaped_cert), or as a base64 encoded DER.
+func parseForwardedCertificate(value string) (*x509.Certificate, error) {
+	unescaped, err := url.QueryUnescape(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if block, _ := pem.Decode([]byte(unescaped)); block != nil {
+		return x509.ParseCertificate(block.Bytes)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCertificate(der)
+}
+
+func isTrustedProxy(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range config.TLSTrustedProxies() {
+		if _, network, parseErr := net.ParseCIDR(proxy); parseErr == nil && network.Contains(ip) {
+			return true
+		}
+
+		if proxyIP := net.ParseIP(proxy); proxyIP != nil && proxyIP.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
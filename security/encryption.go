@@ -0,0 +1,67 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"github.com/m3talux/goauth/config"
+)
+
+// Encrypt encrypts a secret that has to be retrieved later with AES-GCM, using the configured
+// secrets encryption key. The result is base64 encoded, nonce first.
+func Encrypt(plaintext string) (string, error) {
+	aead, err := newAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a secret encrypted with Encrypt.
+func Decrypt(ciphertext string) (string, error) {
+	aead, err := newAEAD()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("the encrypted secret is too short")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+func newAEAD() (cipher.AEAD, error) {
+	key := config.SecretsEncryptionKey()
+	if key == nil {
+		return nil, errors.New("the secrets encryption key is not configured")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,124 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// Size, in bits, of the ephemeral SAML signing keys.
+	samlKeySize = 2048
+	// Size, in bits, of the serial numbers of the self-signed certificates.
+	certificateSerialSize = 128
+	// Validity of the self-signed certificates.
+	certificateLifetime = 10 * 365 * 24 * time.Hour
+)
+
+// SAML signing key and certificate, loaded once through SAMLKeyPair().
+var (
+	samlKey         *rsa.PrivateKey
+	samlCertificate *x509.Certificate
+	samlKeyError    error
+	loadSAMLKey     sync.Once
+)
+
+// SAMLKeyPair returns the RSA key signing the SAML messages and its certificate. When they are not
+// configured, an ephemeral key and a self-signed certificate are generated.
+func SAMLKeyPair() (*rsa.PrivateKey, *x509.Certificate, error) {
+	loadSAMLKey.Do(func() {
+		if config.SAMLKeyFile() == "" || config.SAMLCertificateFile() == "" {
+			log.Warn().Msg("No SAML key pair is configured, an ephemeral one is generated: federated providers must reload the metadata after a restart")
+
+			samlKey, samlKeyError = rsa.GenerateKey(rand.Reader, samlKeySize)
+			if samlKeyError == nil {
+				samlCertificate, samlKeyError = SelfSignedCertificate(samlKey, config.OAuthIssuer())
+			}
+
+			return
+		}
+
+		samlKey, samlCertificate, samlKeyError = readSAMLKeyPair(config.SAMLKeyFile(), config.SAMLCertificateFile())
+	})
+
+	return samlKey, samlCertificate, samlKeyError
+}
+
+// SelfSignedCertificate issues a self-signed certificate for an RSA key. SAML peers trust the
+// certificates published in the metadata, they don't need to be issued by an authority.
+func SelfSignedCertificate(key *rsa.PrivateKey, commonName string) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), certificateSerialSize))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certificateLifetime),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParseCertificate(der)
+}
+
+// readSAMLKeyPair reads a PEM encoded RSA key, in PKCS #1 or PKCS #8, and its PEM encoded certificate.
+func readSAMLKeyPair(keyFile, certificateFile string) (*rsa.PrivateKey, *x509.Certificate, error) {
+	content, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, nil, errors.New("the SAML key file contains no PEM block")
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, pkcs8Err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if pkcs8Err != nil {
+			return nil, nil, pkcs8Err
+		}
+
+		var ok bool
+		if key, ok = parsed.(*rsa.PrivateKey); !ok {
+			return nil, nil, errors.New("the SAML key must be an RSA key")
+		}
+	}
+
+	content, err = os.ReadFile(certificateFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, _ = pem.Decode(content)
+	if block == nil {
+		return nil, nil, errors.New("the SAML certificate file contains no PEM block")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, cert, nil
+}
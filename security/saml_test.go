@@ -0,0 +1,76 @@
+package security
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSAMLKeyPair(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, samlKeySize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := SelfSignedCertificate(key, "https://auth.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certificateFile := writePEM(t, dir, "saml.crt", "CERTIFICATE", cert.Raw)
+
+	for name, block := range map[string]*pem.Block{
+		"PKCS #1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		"PKCS #8": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		t.Run(name, func(t *testing.T) {
+			readKey, readCert, err := readSAMLKeyPair(writePEM(t, dir, "saml.key", block.Type, block.Bytes), certificateFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// What the key signs is verified with the certificate published in the metadata
+			digest := sha256.Sum256([]byte("assertion"))
+
+			signature, err := rsa.SignPKCS1v15(rand.Reader, readKey, crypto.SHA256, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = readCert.CheckSignature(x509.SHA256WithRSA, []byte("assertion"), signature); err != nil {
+				t.Fatal(err)
+			}
+
+			if err = readCert.CheckSignature(x509.SHA256WithRSA, []byte("tampered assertion"), signature); err == nil {
+				t.Fatal("expected the signature of another message to be rejected")
+			}
+		})
+	}
+
+	if _, _, err = readSAMLKeyPair(certificateFile, certificateFile); err == nil {
+		t.Fatal("expected a certificate to be rejected as a key")
+	}
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
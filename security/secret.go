@@ -0,0 +1,20 @@
+package security
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashSecret hashes a low entropy secret (client secret, password...) with bcrypt.
+func HashSecret(secret string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// CheckSecret returns true if the secret matches the given bcrypt hash.
+func CheckSecret(hash, secret string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) == nil
+}
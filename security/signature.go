@@ -0,0 +1,20 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// Sign returns the base64url encoded HMAC-SHA256 of a value.
+func Sign(secret, value string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(value))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CheckSignature returns true if the signature was computed with Sign for the same secret and value.
+func CheckSignature(secret, value, signature string) bool {
+	return EqualTokens(Sign(secret, value), signature)
+}
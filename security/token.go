@@ -0,0 +1,32 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// RandomToken returns a URL-safe random string built from the given number of random bytes.
+func RandomToken(size int) (string, error) {
+	b := make([]byte, size)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the base64url encoded SHA-256 hash of a token.
+// It is used to store high entropy secrets that have to be looked up by value.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// EqualTokens compares two tokens in constant time.
+func EqualTokens(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/handler"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/middleware"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/mongo"
+	"github.com/m3talux/goauth/router"
+	"github.com/rs/zerolog/log"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+)
+
+// DAOs are the data access objects of the collections of goauth.
+type DAOs struct {
+	Client                     mongo.CrudDAO[model.Client]
+	AuthorizationRequest       mongo.CrudDAO[model.AuthorizationRequest]
+	PushedAuthorizationRequest mongo.CrudDAO[model.PushedAuthorizationRequest]
+	AuthorizationCode          mongo.CrudDAO[model.AuthorizationCode]
+	Token                      mongo.CrudDAO[model.Token]
+	ReplayEntry                mongo.CrudDAO[model.ReplayEntry]
+	Session                    mongo.CrudDAO[model.Session]
+	User                       mongo.CrudDAO[model.User]
+	Identity                   mongo.CrudDAO[model.Identity]
+	Connection                 mongo.CrudDAO[model.Connection]
+	SocialLoginState           mongo.CrudDAO[model.SocialLoginState]
+	SAMLServiceProvider        mongo.CrudDAO[model.SAMLServiceProvider]
+	SAMLAuthnRequest           mongo.CrudDAO[model.SAMLAuthnRequest]
+	Group                      mongo.CrudDAO[model.Group]
+	SCIMToken                  mongo.CrudDAO[model.SCIMToken]
+	Role                       mongo.CrudDAO[model.Role]
+	Organization               mongo.CrudDAO[model.Organization]
+	Invitation                 mongo.CrudDAO[model.Invitation]
+	DomainClaim                mongo.CrudDAO[model.DomainClaim]
+	APIKey                     mongo.CrudDAO[model.APIKey]
+	PersonalAccessToken        mongo.CrudDAO[model.PersonalAccessToken]
+	Policy                     mongo.CrudDAO[model.Policy]
+	RelationNamespace          mongo.CrudDAO[model.RelationNamespace]
+	RelationTuple              mongo.CrudDAO[model.RelationTuple]
+	RelationRevision           mongo.CrudDAO[model.RelationRevision]
+	RateLimitBucket            mongo.CrudDAO[model.RateLimitBucket]
+	AuditEvent                 mongo.CrudDAO[model.AuditEvent]
+	Webhook                    mongo.CrudDAO[model.Webhook]
+	WebhookDelivery            mongo.CrudDAO[model.WebhookDelivery]
+}
+
+// NewDAOs returns the DAOs of the collections of a MongoDB database.
+func NewDAOs(db *mongodriver.Database) DAOs {
+	return DAOs{
+		Client:                     mongo.NewCrudDAO[model.Client](db),
+		AuthorizationRequest:       mongo.NewCrudDAO[model.AuthorizationRequest](db),
+		PushedAuthorizationRequest: mongo.NewCrudDAO[model.PushedAuthorizationRequest](db),
+		AuthorizationCode:          mongo.NewCrudDAO[model.AuthorizationCode](db),
+		Token:                      mongo.NewCrudDAO[model.Token](db),
+		ReplayEntry:                mongo.NewCrudDAO[model.ReplayEntry](db),
+		Session:                    mongo.NewCrudDAO[model.Session](db),
+		User:                       mongo.NewCrudDAO[model.User](db),
+		Identity:                   mongo.NewCrudDAO[model.Identity](db),
+		Connection:                 mongo.NewCrudDAO[model.Connection](db),
+		SocialLoginState:           mongo.NewCrudDAO[model.SocialLoginState](db),
+		SAMLServiceProvider:        mongo.NewCrudDAO[model.SAMLServiceProvider](db),
+		SAMLAuthnRequest:           mongo.NewCrudDAO[model.SAMLAuthnRequest](db),
+		Group:                      mongo.NewCrudDAO[model.Group](db),
+		SCIMToken:                  mongo.NewCrudDAO[model.SCIMToken](db),
+		Role:                       mongo.NewCrudDAO[model.Role](db),
+		Organization:               mongo.NewCrudDAO[model.Organization](db),
+		Invitation:                 mongo.NewCrudDAO[model.Invitation](db),
+		DomainClaim:                mongo.NewCrudDAO[model.DomainClaim](db),
+		APIKey:                     mongo.NewCrudDAO[model.APIKey](db),
+		PersonalAccessToken:        mongo.NewCrudDAO[model.PersonalAccessToken](db),
+		Policy:                     mongo.NewCrudDAO[model.Policy](db),
+		RelationNamespace:          mongo.NewCrudDAO[model.RelationNamespace](db),
+		RelationTuple:              mongo.NewCrudDAO[model.RelationTuple](db),
+		RelationRevision:           mongo.NewCrudDAO[model.RelationRevision](db),
+		RateLimitBucket:            mongo.NewCrudDAO[model.RateLimitBucket](db),
+		AuditEvent:                 mongo.NewCrudDAO[model.AuditEvent](db),
+		Webhook:                    mongo.NewCrudDAO[model.Webhook](db),
+		WebhookDelivery:            mongo.NewCrudDAO[model.WebhookDelivery](db),
+	}
+}
+
+// Application is goauth wired on top of its DAOs: the router serving its endpoints, and the managers
+// working outside of the requests.
+type Application struct {
+	Router router.Router
+	// KeyManager signs the JWTs issued by goauth.
+	KeyManager manager.KeyManager
+	// WebhookManager sends the webhook deliveries in the background, once its Run method is started.
+	WebhookManager manager.WebhookManager
+}
+
+// NewApplication creates the managers, the handlers and the middlewares of goauth on top of its DAOs,
+// and creates the documents it requires, like the default organization and the admin role. It is
+// shared by the server and the in-process goauth of the tests.
+func NewApplication(ctx context.Context, daos DAOs) (*Application, error) {
+	// Manager layer initialization
+	keyManager, err := manager.NewKeyManager()
+	if err != nil {
+		log.Err(err).Msg("Could not load the signing key")
+
+		return nil, err
+	}
+
+	webhookManager := manager.NewWebhookManager(daos.Webhook, daos.WebhookDelivery)
+	auditManager := manager.NewAuditManager(daos.AuditEvent, webhookManager)
+	jwksManager := manager.NewJWKSManager()
+	clientManager := manager.NewClientManager(daos.Client, daos.ReplayEntry, jwksManager)
+	authorizationManager := manager.NewAuthorizationManager(clientManager, daos.AuthorizationRequest, daos.PushedAuthorizationRequest, daos.AuthorizationCode)
+	sessionManager := manager.NewSessionManager(daos.Session, daos.Token, daos.PersonalAccessToken, clientManager, keyManager, auditManager)
+	logoutManager := manager.NewLogoutManager(clientManager, sessionManager, keyManager)
+	roleManager := manager.NewRoleManager(daos.Role, daos.User, daos.Client, daos.Group)
+	groupManager := manager.NewGroupManager(daos.Group, daos.User, daos.Role)
+	tokenManager := manager.NewTokenManager(daos.Token, daos.AuthorizationCode, daos.User, sessionManager, keyManager, roleManager, groupManager, auditManager)
+	dpopManager := manager.NewDPoPManager(daos.ReplayEntry)
+	userManager := manager.NewUserManager(daos.User, daos.Identity, daos.DomainClaim, auditManager)
+	socialLoginManager := manager.NewSocialLoginManager(daos.Connection, daos.SocialLoginState, jwksManager)
+	ldapManager := manager.NewLDAPManager(daos.Connection)
+	organizationManager := manager.NewOrganizationManager(daos.Organization, daos.User, daos.Role, daos.Group, sessionManager)
+	invitationManager := manager.NewInvitationManager(daos.Invitation, daos.Role, userManager, organizationManager)
+	domainManager := manager.NewDomainManager(daos.DomainClaim, daos.Connection, daos.Role)
+	apiKeyManager := manager.NewAPIKeyManager(daos.APIKey)
+	personalAccessTokenManager := manager.NewPersonalAccessTokenManager(daos.PersonalAccessToken, daos.User, roleManager)
+	scimManager := manager.NewSCIMManager(daos.SCIMToken, daos.User, daos.Identity, daos.Group, sessionManager, organizationManager, auditManager)
+
+	policyManager, err := manager.NewPolicyManager(daos.Policy, roleManager)
+	if err != nil {
+		log.Err(err).Msg("Could not create the policy engine")
+
+		return nil, err
+	}
+
+	relationManager := manager.NewRelationManager(daos.RelationNamespace, daos.RelationTuple, daos.RelationRevision)
+
+	rateLimitManager := manager.NewMemoryRateLimitManager()
+	if config.RateLimitBackend() == config.RateLimitBackendMongo {
+		rateLimitManager = manager.NewMongoRateLimitManager(daos.RateLimitBucket)
+	}
+
+	rateLimits, err := rateLimitPolicies()
+	if err != nil {
+		log.Err(err).Msg("Could not load the rate limit policies")
+
+		return nil, err
+	}
+
+	samlManager, err := manager.NewSAMLManager(userManager, daos.SAMLServiceProvider, daos.SAMLAuthnRequest, daos.ReplayEntry)
+	if err != nil {
+		log.Err(err).Msg("Could not load the SAML key pair")
+
+		return nil, err
+	}
+
+	if err = organizationManager.EnsureDefaultOrganization(ctx); err != nil {
+		log.Err(err).Msg("Could not initialize the default organization")
+
+		return nil, err
+	}
+
+	if err = roleManager.EnsureAdminRole(ctx); err != nil {
+		log.Err(err).Msg("Could not initialize the admin role")
+
+		return nil, err
+	}
+
+	// Handler layer initialization
+	checkHandler := handler.NewCheckHandler()
+	discoveryHandler := handler.NewDiscoveryHandler(keyManager)
+	oauthHandler := handler.NewOAuthHandler(clientManager, authorizationManager, tokenManager, dpopManager, sessionManager)
+	sessionHandler := handler.NewSessionHandler(logoutManager)
+	loginHandler := handler.NewLoginHandler(socialLoginManager, authorizationManager, userManager, sessionManager, ldapManager, invitationManager, auditManager)
+	accountHandler := handler.NewAccountHandler(userManager, socialLoginManager)
+	samlHandler := handler.NewSAMLHandler(samlManager, authorizationManager, sessionManager)
+	scimHandler := handler.NewSCIMHandler(scimManager, auditManager)
+	roleHandler := handler.NewRoleHandler(roleManager)
+	organizationHandler := handler.NewOrganizationHandler(organizationManager)
+	invitationHandler := handler.NewInvitationHandler(invitationManager, authorizationManager)
+	domainHandler := handler.NewDomainHandler(domainManager)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyManager, auditManager)
+	personalAccessTokenHandler := handler.NewPersonalAccessTokenHandler(personalAccessTokenManager, auditManager)
+	policyHandler := handler.NewPolicyHandler(policyManager)
+	relationHandler := handler.NewRelationHandler(relationManager)
+	groupHandler := handler.NewGroupHandler(groupManager)
+	auditHandler := handler.NewAuditHandler(auditManager)
+	webhookHandler := handler.NewWebhookHandler(webhookManager)
+
+	r := router.NewRouter(
+		router.Handlers{
+			CheckHandler:               checkHandler,
+			DiscoveryHandler:           discoveryHandler,
+			OAuthHandler:               oauthHandler,
+			SessionHandler:             sessionHandler,
+			LoginHandler:               loginHandler,
+			AccountHandler:             accountHandler,
+			SAMLHandler:                samlHandler,
+			SCIMHandler:                scimHandler,
+			RoleHandler:                roleHandler,
+			OrganizationHandler:        organizationHandler,
+			InvitationHandler:          invitationHandler,
+			DomainHandler:              domainHandler,
+			APIKeyHandler:              apiKeyHandler,
+			PersonalAccessTokenHandler: personalAccessTokenHandler,
+			PolicyHandler:              policyHandler,
+			RelationHandler:            relationHandler,
+			GroupHandler:               groupHandler,
+			AuditHandler:               auditHandler,
+			WebhookHandler:             webhookHandler,
+		},
+		router.Middlewares{
+			Tenant:              middleware.Tenant(organizationManager),
+			APIKey:              middleware.APIKey(apiKeyManager),
+			PersonalAccessToken: middleware.PersonalAccessToken(personalAccessTokenManager),
+			AccessToken:         middleware.AccessToken(tokenManager, dpopManager),
+			Session:             middleware.Session(sessionManager),
+			SCIMToken:           middleware.SCIMToken(scimManager),
+			Platform:            middleware.Platform(),
+			Permission:          middleware.Permission,
+			RateLimit:           middleware.RateLimit(rateLimitManager, rateLimits),
+			AuditSource:         middleware.AuditSource(),
+			Audit:               middleware.Audit(auditManager),
+			Metrics:             middleware.Metrics(),
+		},
+	)
+
+	return &Application{
+		Router:         r,
+		KeyManager:     keyManager,
+		WebhookManager: webhookManager,
+	}, nil
+}
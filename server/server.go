@@ -2,10 +2,13 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
 
 	"github.com/m3talux/goauth/config"
 
-	"github.com/m3talux/goauth/handler"
+	"github.com/m3talux/goauth/metrics"
+	"github.com/m3talux/goauth/model"
 	"github.com/m3talux/goauth/mongo"
 	"github.com/m3talux/goauth/router"
 	"github.com/rs/zerolog/log"
@@ -18,27 +21,83 @@ func (s *Server) Run() error {
 	defer cancel()
 
 	// DB layer initialization
-	_, err := mongo.DB(initializationContext)
+	db, err := mongo.DB(initializationContext)
 	if err != nil {
 		log.Err(err).Msg("Could not create the MongoDB database connector")
 
 		return err
 	}
 
-	// DAO layer initialization
+	app, err := NewApplication(initializationContext, NewDAOs(db))
+	if err != nil {
+		return err
+	}
+
+	// The webhook deliveries are sent in the background for as long as the server runs
+	go app.WebhookManager.Run(context.Background())
+
+	if address := config.MetricsAddress(); address != "" {
+		go runMetrics(address)
+	}
 
-	// Manager layer initialization
+	if !config.TLSEnabled() {
+		return app.Router.Run()
+	}
+
+	return runTLS(app.Router)
+}
 
-	// Handler layer initialization
-	checkHandler := handler.NewCheckHandler()
+// rateLimitPolicies parses the rate limit policies of the configuration.
+func rateLimitPolicies() (map[string]model.RateLimitPolicy, error) {
+	policies := make(map[string]model.RateLimitPolicy)
+
+	for name, value := range config.RateLimitPolicies() {
+		policy, err := model.ParseRateLimitPolicy(name, value)
+		if err != nil {
+			return nil, err
+		}
+
+		policies[name] = policy
+	}
 
-	r := router.NewRouter(
-		router.Handlers{
-			CheckHandler: checkHandler,
+	return policies, nil
+}
+
+// runMetrics serves the Prometheus metrics on their own address, so that they are not exposed on the
+// public port.
+func runMetrics(address string) {
+	mux := http.NewServeMux()
+	mux.Handle(config.MetricsPath(), metrics.Handler())
+
+	srv := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: config.ConnectionTimeout(),
+	}
+
+	log.Info().Str("address", address).Msg("The metrics are served")
+
+	if err := srv.ListenAndServe(); err != nil {
+		log.Err(err).Str("address", address).Msg("Could not serve the metrics")
+	}
+}
+
+// runTLS serves the router over TLS. Client certificates are requested but not verified during the
+// handshake: they are verified when authenticating clients, since some of them are self-signed.
+func runTLS(r router.Router) error {
+	srv := &http.Server{
+		Addr:              ":" + config.Port(),
+		Handler:           r,
+		ReadHeaderTimeout: config.ConnectionTimeout(),
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ClientAuth: tls.RequestClientCert,
 		},
-	)
+	}
+
+	log.Info().Str("address", srv.Addr).Msgf("%s is serving over TLS", config.AppName())
 
-	return r.Run()
+	return srv.ListenAndServeTLS(config.TLSCertFile(), config.TLSKeyFile())
 }
 
 func New() *Server {
@@ -0,0 +1,69 @@
+package tenant
+
+import (
+	"context"
+	"strings"
+
+	"github.com/m3talux/goauth/config"
+)
+
+type contextKey int
+
+const (
+	organizationKey contextKey = iota
+	allOrganizationsKey
+)
+
+// WithOrganization returns a context scoped to an organization.
+func WithOrganization(ctx context.Context, organizationID string) context.Context {
+	return context.WithValue(ctx, organizationKey, organizationID)
+}
+
+// WithAllOrganizations returns a context whose queries are not restricted to an organization, for the
+// operations that have to cross organizations on purpose, like the administration of the platform.
+func WithAllOrganizations(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allOrganizationsKey, true)
+}
+
+// OrganizationID returns the organization of a context, or an empty string if it has none.
+func OrganizationID(ctx context.Context) string {
+	organizationID, _ := ctx.Value(organizationKey).(string)
+
+	return organizationID
+}
+
+// Matches returns true if an organization is the organization of a context. The documents without
+// organization belong to the default organization.
+func Matches(ctx context.Context, organizationID string) bool {
+	if organizationID == "" {
+		organizationID = config.DefaultOrganizationID()
+	}
+
+	return organizationID == OrganizationID(ctx)
+}
+
+// AllOrganizations returns true if the queries of the context are not restricted to an organization.
+func AllOrganizations(ctx context.Context) bool {
+	all, _ := ctx.Value(allOrganizationsKey).(bool)
+
+	return all
+}
+
+// Issuer returns the issuer identifier of the organization of a context.
+func Issuer(ctx context.Context) string {
+	return config.OrganizationIssuer(OrganizationID(ctx))
+}
+
+// URL rebases an absolute URL of goauth on the issuer of the organization of a context.
+func URL(ctx context.Context, url string) string {
+	if !strings.HasPrefix(url, config.OAuthIssuer()) {
+		return url
+	}
+
+	return Issuer(ctx) + strings.TrimPrefix(url, config.OAuthIssuer())
+}
+
+// Path prefixes a path with the path of the organization of a context.
+func Path(ctx context.Context, path string) string {
+	return config.OrganizationPath(OrganizationID(ctx)) + path
+}
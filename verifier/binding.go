@@ -0,0 +1,149 @@
+package verifier
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+const dpopProofType = "dpop+jwt"
+
+// dpopAlgorithms are the algorithms accepted for DPoP proofs, whose keys have to be public.
+var dpopAlgorithms = []jose.SignatureAlgorithm{
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.EdDSA,
+}
+
+type dpopClaims struct {
+	ID          string           `json:"jti"`
+	HTTPMethod  string           `json:"htm"`
+	HTTPURI     string           `json:"htu"`
+	IssuedAt    *jwt.NumericDate `json:"iat"`
+	AccessToken string           `json:"ath"`
+}
+
+// verifyDPoPBinding checks the DPoP proof of a request (RFC 9449, section 7), and that it was signed
+// with the key of the token. The jti of the proofs is not tracked: replays are only limited by the
+// maximum age of the proofs.
+func (v *Verifier) verifyDPoPBinding(r *http.Request, claims *Claims, value string) error {
+	proofs := r.Header.Values("DPoP")
+	if len(proofs) != 1 {
+		return invalidDPoPProofError("a single DPoP proof is required")
+	}
+
+	proof, err := jwt.ParseSigned(proofs[0], dpopAlgorithms)
+	if err != nil || len(proof.Headers) != 1 {
+		return invalidDPoPProofError("the DPoP proof is malformed")
+	}
+
+	header := proof.Headers[0]
+	if header.ExtraHeaders[jose.HeaderType] != dpopProofType {
+		return invalidDPoPProofError("the DPoP proof type is invalid")
+	}
+
+	jwk := header.JSONWebKey
+	if jwk == nil || !jwk.Valid() || !jwk.IsPublic() {
+		return invalidDPoPProofError("the DPoP proof key is missing or invalid")
+	}
+
+	var proofClaims dpopClaims
+	if err = proof.Claims(jwk.Key, &proofClaims); err != nil {
+		return invalidDPoPProofError("the DPoP proof signature is invalid")
+	}
+
+	if proofClaims.ID == "" || proofClaims.IssuedAt == nil {
+		return invalidDPoPProofError("the DPoP proof must contain the jti and iat claims")
+	}
+
+	if proofClaims.HTTPMethod != r.Method {
+		return invalidDPoPProofError("the DPoP proof htm claim does not match the request method")
+	}
+
+	if !isSameHTTPURI(proofClaims.HTTPURI, v.requestURI(r)) {
+		return invalidDPoPProofError("the DPoP proof htu claim does not match the request URI")
+	}
+
+	maxAge := v.config.DPoPMaxAge + v.config.Leeway
+	if issuedAt := proofClaims.IssuedAt.Time(); time.Since(issuedAt) > maxAge || time.Until(issuedAt) > maxAge {
+		return invalidDPoPProofError("the DPoP proof iat claim is out of the acceptable window")
+	}
+
+	if proofClaims.AccessToken != hashToken(value) {
+		return invalidDPoPProofError("the DPoP proof ath claim does not match the access token")
+	}
+
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil || base64.RawURLEncoding.EncodeToString(thumbprint) != claims.Confirmation.JKT {
+		return invalidDPoPProofError("the DPoP proof key does not match the access token")
+	}
+
+	return nil
+}
+
+// verifyCertificateBinding checks that certificate-bound tokens are presented over a TLS connection
+// using the same client certificate (RFC 8705, section 3).
+func (v *Verifier) verifyCertificateBinding(r *http.Request, claims *Claims) error {
+	if claims.Confirmation == nil || claims.Confirmation.X5TS256 == "" {
+		return nil
+	}
+
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return invalidTokenError("the client certificate does not match the access token")
+	}
+
+	sum := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != claims.Confirmation.X5TS256 {
+		return invalidTokenError("the client certificate does not match the access token")
+	}
+
+	return nil
+}
+
+// requestURI returns the URI of a request as seen by the client, for the comparison with the htu claim.
+func (v *Verifier) requestURI(r *http.Request) string {
+	if v.config.BaseURL != "" {
+		return strings.TrimSuffix(v.config.BaseURL, "/") + r.URL.Path
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+// isSameHTTPURI compares two URIs without their query and fragment parts (RFC 9449, section 4.3).
+func isSameHTTPURI(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host) && ua.EscapedPath() == ub.EscapedPath()
+}
+
+// hashToken returns the base64url encoded SHA-256 hash of an access token, as in the ath claim.
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func invalidDPoPProofError(description string) Error {
+	return Error{Status: http.StatusUnauthorized, Code: ErrorCodeInvalidDPoPProof, Description: description}
+}
@@ -0,0 +1,116 @@
+package verifier
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// PermissionAll is the permission granting every other permission.
+const PermissionAll = "*"
+
+// Confirmation holds the keys an access token is bound to: the thumbprint of a DPoP key (RFC 9449),
+// or the thumbprint of a client certificate (RFC 8705).
+type Confirmation struct {
+	JKT     string `json:"jkt,omitempty"`
+	X5TS256 string `json:"x5t#S256,omitempty"`
+}
+
+// Claims are the claims of a verified access token, decoded from a JWT access token (RFC 9068) or from
+// the introspection response of an opaque token (RFC 7662).
+type Claims struct {
+	jwt.Claims
+	ClientID       string        `json:"client_id"`
+	Scope          string        `json:"scope,omitempty"`
+	AuthTime       int64         `json:"auth_time,omitempty"`
+	SessionID      string        `json:"sid,omitempty"`
+	Confirmation   *Confirmation `json:"cnf,omitempty"`
+	OrganizationID string        `json:"org_id,omitempty"`
+	Roles          []string      `json:"roles,omitempty"`
+	Permissions    []string      `json:"permissions,omitempty"`
+	Groups         []string      `json:"groups,omitempty"`
+	GroupsOverage  bool          `json:"groups_overage,omitempty"`
+}
+
+// introspectionResponse is a response of the introspection endpoint of goauth (RFC 7662).
+type introspectionResponse struct {
+	Active         bool          `json:"active"`
+	Scope          string        `json:"scope,omitempty"`
+	ClientID       string        `json:"client_id,omitempty"`
+	Subject        string        `json:"sub,omitempty"`
+	TokenType      string        `json:"token_type,omitempty"`
+	IssuedAt       int64         `json:"iat,omitempty"`
+	ExpiresAt      int64         `json:"exp,omitempty"`
+	Issuer         string        `json:"iss,omitempty"`
+	Confirmation   *Confirmation `json:"cnf,omitempty"`
+	OrganizationID string        `json:"org_id,omitempty"`
+	Roles          []string      `json:"roles,omitempty"`
+	Permissions    []string      `json:"permissions,omitempty"`
+	Groups         []string      `json:"groups,omitempty"`
+	GroupsOverage  bool          `json:"groups_overage,omitempty"`
+}
+
+// Scopes returns the scopes granted to the token.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope returns true if the scope was granted to the token.
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes(), scope)
+}
+
+// HasPermission returns true if one of the permissions of the token is the given permission, or a
+// wildcard matching it.
+func (c *Claims) HasPermission(permission string) bool {
+	resource, _, _ := strings.Cut(permission, ":")
+
+	return slices.Contains(c.Permissions, permission) || slices.Contains(c.Permissions, PermissionAll) ||
+		slices.Contains(c.Permissions, resource+":*")
+}
+
+// HasRole returns true if the role was granted to the subject of the token.
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// InGroup returns true if the subject of the token is a member of the group. The groups of the subjects
+// in too many groups are not in the token (GroupsOverage), and have to be fetched from the API.
+func (c *Claims) InGroup(groupID string) bool {
+	return slices.Contains(c.Groups, groupID)
+}
+
+// claims converts an active introspection response to the claims of the token.
+func (r introspectionResponse) claims() *Claims {
+	claims := &Claims{
+		Claims: jwt.Claims{
+			Issuer:  r.Issuer,
+			Subject: r.Subject,
+		},
+		ClientID:       r.ClientID,
+		Scope:          r.Scope,
+		Confirmation:   r.Confirmation,
+		OrganizationID: r.OrganizationID,
+		Roles:          r.Roles,
+		Permissions:    r.Permissions,
+		Groups:         r.Groups,
+		GroupsOverage:  r.GroupsOverage,
+	}
+
+	// Tokens issued to clients themselves have the client as subject
+	if claims.Subject == "" {
+		claims.Subject = r.ClientID
+	}
+
+	if r.IssuedAt != 0 {
+		claims.IssuedAt = jwt.NewNumericDate(time.Unix(r.IssuedAt, 0))
+	}
+
+	if r.ExpiresAt != 0 {
+		claims.Expiry = jwt.NewNumericDate(time.Unix(r.ExpiresAt, 0))
+	}
+
+	return claims
+}
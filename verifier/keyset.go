@@ -0,0 +1,149 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+// Minimum delay between two fetches of the key set, when looking for an unknown key.
+const keySetMinRefreshInterval = 10 * time.Second
+
+// metadata holds the endpoints of the authorization server metadata of goauth (RFC 8414).
+type metadata struct {
+	JWKSURI               string `json:"jwks_uri"`
+	IntrospectionEndpoint string `json:"introspection_endpoint"`
+}
+
+// keySetCache fetches the key set of goauth, and keeps it for the configured lifetime. The metadata of
+// the issuer is fetched once, when the endpoints are not configured.
+type keySetCache struct {
+	config    Config
+	mutex     sync.Mutex
+	metadata  *metadata
+	keySet    *jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// Key returns the key with the given identifier, or the single key of the key set when the identifier
+// is empty. When the key is not in the cached key set, the key set is fetched again since keys may
+// have been rotated. It returns nil if the key does not exist.
+func (c *keySetCache) Key(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	c.mutex.Lock()
+	keySet, fetchedAt := c.keySet, c.fetchedAt
+	c.mutex.Unlock()
+
+	if keySet == nil || time.Since(fetchedAt) >= c.config.KeySetLifetime {
+		var err error
+
+		if keySet, err = c.fetch(ctx); err != nil {
+			return nil, err
+		}
+	} else if findKey(keySet, kid) == nil && time.Since(fetchedAt) >= keySetMinRefreshInterval {
+		var err error
+
+		if keySet, err = c.fetch(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return findKey(keySet, kid), nil
+}
+
+// Metadata returns the authorization server metadata of the issuer.
+func (c *keySetCache) Metadata(ctx context.Context) (*metadata, error) {
+	c.mutex.Lock()
+	cached := c.metadata
+	c.mutex.Unlock()
+
+	if cached != nil {
+		return cached, nil
+	}
+
+	fetched := new(metadata)
+	if err := c.get(ctx, c.config.Issuer+"/.well-known/oauth-authorization-server", fetched); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.metadata = fetched
+	c.mutex.Unlock()
+
+	return fetched, nil
+}
+
+func (c *keySetCache) fetch(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	uri := c.config.JWKSURI
+	if uri == "" {
+		metadata, err := c.Metadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		uri = metadata.JWKSURI
+	}
+
+	keySet := new(jose.JSONWebKeySet)
+	if err := c.get(ctx, uri, keySet); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.keySet = keySet
+	c.fetchedAt = time.Now()
+	c.mutex.Unlock()
+
+	return keySet, nil
+}
+
+// get fetches a JSON document from goauth.
+func (c *keySetCache) get(ctx context.Context, uri string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("the request to %s returned the status %d", uri, res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// findKey returns the signing key with the given identifier, or the single signing key of the key set
+// when the identifier is empty.
+func findKey(keySet *jose.JSONWebKeySet, kid string) *jose.JSONWebKey {
+	if kid != "" {
+		if keys := keySet.Key(kid); len(keys) > 0 {
+			return &keys[0]
+		}
+
+		return nil
+	}
+
+	if len(keySet.Keys) == 1 {
+		return &keySet.Keys[0]
+	}
+
+	return nil
+}
+
+func newKeySetCache(config Config) *keySetCache {
+	return &keySetCache{
+		config: config,
+	}
+}
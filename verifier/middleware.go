@@ -0,0 +1,93 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type contextKey int
+
+const claimsKey contextKey = iota
+
+// errorResponse is the body of the responses of rejected requests.
+type errorResponse struct {
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Handler returns a net/http middleware rejecting the requests without a valid access token. The claims
+// of the token are added to the context of the request, and returned by FromContext.
+func (v *Verifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := v.VerifyRequest(r)
+		if err != nil {
+			status, body := v.reject(w.Header(), err)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(body)
+
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), claims)))
+	})
+}
+
+// Gin returns a gin middleware rejecting the requests without a valid access token. The claims of the
+// token are added to the context of the request, and returned by FromContext.
+func (v *Verifier) Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := v.VerifyRequest(c.Request)
+		if err != nil {
+			status, body := v.reject(c.Writer.Header(), err)
+			c.AbortWithStatusJSON(status, body)
+
+			return
+		}
+
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), claims))
+		c.Next()
+	}
+}
+
+// NewContext returns a context holding the claims of a verified access token.
+func NewContext(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// FromContext returns the claims of the access token verified by the middlewares, or nil if there is none.
+func FromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey).(*Claims)
+
+	return claims
+}
+
+// reject adds the WWW-Authenticate challenges of a rejected request to the response headers, and
+// returns the status and the body of the response. Errors other than Error are not disclosed.
+func (v *Verifier) reject(header http.Header, err error) (int, errorResponse) {
+	var verifierErr Error
+	if !errors.As(err, &verifierErr) {
+		return http.StatusInternalServerError, errorResponse{ErrorDescription: "the access token could not be verified"}
+	}
+
+	params := ""
+	if verifierErr.Code != "" {
+		params = fmt.Sprintf(`, error="%s", error_description="%s"`, verifierErr.Code, verifierErr.Description)
+	}
+
+	if verifierErr.Code == ErrorCodeInsufficientScope {
+		params += fmt.Sprintf(`, scope="%s"`, strings.Join(v.config.Scopes, " "))
+	}
+
+	header.Add("WWW-Authenticate", "Bearer"+strings.TrimPrefix(params, ","))
+	header.Add("WWW-Authenticate", "DPoP"+strings.TrimPrefix(params, ","))
+
+	return verifierErr.Status, errorResponse{Error: verifierErr.Code, ErrorDescription: verifierErr.Description}
+}
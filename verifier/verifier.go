@@ -0,0 +1,273 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// Default values of the configuration.
+const (
+	defaultKeySetLifetime = time.Hour
+	defaultDPoPMaxAge     = time.Minute
+	defaultHTTPTimeout    = 10 * time.Second
+)
+
+// Type (typ header) of the JWT access tokens issued by goauth (RFC 9068, section 2.1).
+const accessTokenType = "at+jwt"
+
+// Error codes of the responses of protected resources (RFC 6750, section 3.1, and RFC 9449, section 7.1).
+const (
+	ErrorCodeInvalidRequest    = "invalid_request"
+	ErrorCodeInvalidToken      = "invalid_token"
+	ErrorCodeInsufficientScope = "insufficient_scope"
+	ErrorCodeInvalidDPoPProof  = "invalid_dpop_proof"
+)
+
+// signatureAlgorithms are the algorithms goauth signs its JWTs with.
+var signatureAlgorithms = []jose.SignatureAlgorithm{jose.RS256, jose.ES256, jose.ES384, jose.ES512}
+
+// Config configures the verification of the access tokens of a resource server.
+type Config struct {
+	// Issuer is the issuer of goauth, or of the organization whose tokens are accepted.
+	Issuer string
+	// Audience, when set, must be one of the audiences of the JWT access tokens. Opaque tokens have no
+	// audience, and are accepted whatever the audience.
+	Audience string
+	// Scopes must all be granted to the tokens.
+	Scopes []string
+	// JWKSURI and IntrospectionEndpoint default to the ones published in the metadata of the issuer.
+	JWKSURI               string
+	IntrospectionEndpoint string
+	// ClientID and ClientSecret authenticate the resource server to the introspection endpoint. Opaque
+	// tokens are rejected when they are not set.
+	ClientID     string
+	ClientSecret string
+	// BaseURL is the external URL of the resource server, compared to the htu claim of the DPoP proofs.
+	// It defaults to the scheme and the host of the requests.
+	BaseURL string
+	// KeySetLifetime is the duration for which the key set of goauth is cached. Defaults to an hour.
+	KeySetLifetime time.Duration
+	// DPoPMaxAge is the maximum age of DPoP proofs. Defaults to a minute.
+	DPoPMaxAge time.Duration
+	// Leeway is the clock skew tolerated when validating the expiration of tokens and proofs.
+	Leeway time.Duration
+	// HTTPClient sends the requests to goauth. Defaults to a client with a 10 seconds timeout.
+	HTTPClient *http.Client
+}
+
+// Verifier verifies the access tokens issued by goauth. JWT access tokens (RFC 9068) are verified with
+// the cached key set of goauth, opaque tokens are introspected (RFC 7662). It is safe for concurrent use.
+type Verifier struct {
+	config Config
+	keys   *keySetCache
+}
+
+// Error is the error returned when an access token is rejected. Status is the HTTP status of the
+// response, and Code the error code of the WWW-Authenticate challenge.
+type Error struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+func (e Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// Verify checks an access token, and returns its claims. It returns an Error when the token is
+// rejected, and another error when the token could not be verified, like when goauth can't be reached.
+// The binding of sender-constrained tokens is not checked, which VerifyRequest does.
+func (v *Verifier) Verify(ctx context.Context, value string) (*Claims, error) {
+	var (
+		claims *Claims
+		err    error
+	)
+
+	if isJWT(value) {
+		claims, err = v.verifyJWT(ctx, value)
+	} else {
+		claims, err = v.introspect(ctx, value)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scope := range v.config.Scopes {
+		if !claims.HasScope(scope) {
+			return nil, Error{Status: http.StatusForbidden, Code: ErrorCodeInsufficientScope, Description: "the " + scope + " scope is required"}
+		}
+	}
+
+	return claims, nil
+}
+
+// VerifyRequest checks the access token of a request, sent with the Bearer scheme (RFC 6750) or the
+// DPoP scheme (RFC 9449), and returns its claims. DPoP-bound tokens must be sent with a valid proof,
+// and certificate-bound tokens over a TLS connection using the same client certificate (RFC 8705).
+func (v *Verifier) VerifyRequest(r *http.Request) (*Claims, error) {
+	scheme, value, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if value == "" {
+		return nil, Error{Status: http.StatusUnauthorized, Description: "the access token is missing"}
+	}
+
+	claims, err := v.Verify(r.Context(), value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = v.verifyCertificateBinding(r, claims); err != nil {
+		return nil, err
+	}
+
+	bound := claims.Confirmation != nil && claims.Confirmation.JKT != ""
+
+	switch {
+	case strings.EqualFold(scheme, "DPoP") && bound:
+		if err = v.verifyDPoPBinding(r, claims, value); err != nil {
+			return nil, err
+		}
+	case strings.EqualFold(scheme, "Bearer") && !bound:
+	default:
+		return nil, invalidTokenError("the authorization scheme does not match the token type")
+	}
+
+	return claims, nil
+}
+
+// verifyJWT checks the signature, the type, the issuer, the audience and the expiration of a JWT
+// access token.
+func (v *Verifier) verifyJWT(ctx context.Context, value string) (*Claims, error) {
+	token, err := jwt.ParseSigned(value, signatureAlgorithms)
+	if err != nil || len(token.Headers) != 1 {
+		return nil, invalidTokenError("the access token is malformed")
+	}
+
+	header := token.Headers[0]
+
+	// Prevents ID tokens or logout tokens from being used as access tokens
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); !strings.EqualFold(typ, accessTokenType) {
+		return nil, invalidTokenError("the token is not an access token")
+	}
+
+	key, err := v.keys.Key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return nil, invalidTokenError("the access token was signed with an unknown key")
+	}
+
+	claims := new(Claims)
+	if err = token.Claims(key.Key, claims); err != nil {
+		return nil, invalidTokenError("the access token signature is invalid")
+	}
+
+	expected := jwt.Expected{Issuer: v.config.Issuer, Time: time.Now()}
+	if v.config.Audience != "" {
+		expected.AnyAudience = jwt.Audience{v.config.Audience}
+	}
+
+	if err = claims.ValidateWithLeeway(expected, v.config.Leeway); err != nil || claims.Expiry == nil {
+		return nil, invalidTokenError("the access token is invalid or expired")
+	}
+
+	return claims, nil
+}
+
+// introspect sends an opaque token to the introspection endpoint of goauth.
+func (v *Verifier) introspect(ctx context.Context, value string) (*Claims, error) {
+	if v.config.ClientID == "" {
+		return nil, invalidTokenError("opaque access tokens are not accepted")
+	}
+
+	endpoint := v.config.IntrospectionEndpoint
+	if endpoint == "" {
+		metadata, err := v.keys.Metadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		endpoint = metadata.IntrospectionEndpoint
+	}
+
+	form := url.Values{"token": {value}, "token_type_hint": {"access_token"}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(v.config.ClientID), url.QueryEscape(v.config.ClientSecret))
+
+	res, err := v.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the introspection request returned the status %d", res.StatusCode)
+	}
+
+	var response introspectionResponse
+	if err = json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	if !response.Active || response.Issuer != v.config.Issuer || (response.ExpiresAt != 0 && time.Now().Unix() >= response.ExpiresAt) {
+		return nil, invalidTokenError("the access token is invalid or expired")
+	}
+
+	return response.claims(), nil
+}
+
+// isJWT returns true if the token value is a compact serialized JWS, rather than an opaque token.
+func isJWT(value string) bool {
+	return strings.Count(value, ".") == 2
+}
+
+func invalidTokenError(description string) Error {
+	return Error{Status: http.StatusUnauthorized, Code: ErrorCodeInvalidToken, Description: description}
+}
+
+// New returns a verifier of the access tokens issued by the configured issuer.
+func New(config Config) (*Verifier, error) {
+	if config.Issuer == "" {
+		return nil, errors.New("the issuer is required")
+	}
+
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	config.Scopes = slices.Clone(config.Scopes)
+
+	if config.KeySetLifetime <= 0 {
+		config.KeySetLifetime = defaultKeySetLifetime
+	}
+
+	if config.DPoPMaxAge <= 0 {
+		config.DPoPMaxAge = defaultDPoPMaxAge
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return &Verifier{
+		config: config,
+		keys:   newKeySetCache(config),
+	}, nil
+}
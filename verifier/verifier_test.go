@@ -0,0 +1,322 @@
+package verifier_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/m3talux/goauth/config"
+	"github.com/m3talux/goauth/internal/goauthtest"
+	"github.com/m3talux/goauth/manager"
+	"github.com/m3talux/goauth/model"
+	"github.com/m3talux/goauth/verifier"
+)
+
+// Clients of the test server: the resource server introspecting the opaque tokens, and the clients
+// obtaining JWT and opaque access tokens with the client credentials grant.
+const (
+	resourceServerID = "resource-server"
+	jwtClientID      = "jwt-client"
+	opaqueClientID   = "opaque-client"
+	clientSecret     = "secret"
+	audience         = "https://api.example.com"
+)
+
+func newServer(t *testing.T) *goauthtest.Server {
+	t.Helper()
+
+	srv := goauthtest.NewServer(t)
+
+	srv.AddClient(t, model.Client{ID: resourceServerID, GrantTypes: []string{model.GrantTypeClientCredentials}}, clientSecret)
+	srv.AddClient(t, model.Client{
+		ID:                   jwtClientID,
+		GrantTypes:           []string{model.GrantTypeClientCredentials},
+		Scopes:               []string{"read", "write"},
+		AccessTokenFormat:    model.AccessTokenFormatJWT,
+		AccessTokenAudiences: []string{audience},
+	}, clientSecret)
+	srv.AddClient(t, model.Client{
+		ID:         opaqueClientID,
+		GrantTypes: []string{model.GrantTypeClientCredentials},
+		Scopes:     []string{"read", "write"},
+	}, clientSecret)
+
+	return srv
+}
+
+func newVerifier(t *testing.T, cfg verifier.Config) *verifier.Verifier {
+	t.Helper()
+
+	v, err := verifier.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+// accessToken requests an access token from the test server with the client credentials grant.
+func accessToken(t *testing.T, srv *goauthtest.Server, clientID, scope string) string {
+	t.Helper()
+
+	form := url.Values{"grant_type": {model.GrantTypeClientCredentials}, "scope": {scope}}
+
+	req, err := http.NewRequest(http.MethodPost, srv.Issuer+"/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, clientSecret)
+
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("the token request returned the status %d", res.StatusCode)
+	}
+
+	var token model.TokenResponse
+	if err = json.NewDecoder(res.Body).Decode(&token); err != nil {
+		t.Fatal(err)
+	}
+
+	return token.AccessToken
+}
+
+// signAccessToken signs JWT access token claims with a key manager, as goauth does.
+func signAccessToken(t *testing.T, keyManager manager.KeyManager, claims jwt.Claims) string {
+	t.Helper()
+
+	value, err := keyManager.Sign(model.AccessTokenClaims{
+		Claims:   claims,
+		ClientID: jwtClientID,
+		Scope:    "read",
+	}, model.JWTTypeAccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return value
+}
+
+// assertRejected checks that a token was rejected with an error code.
+func assertRejected(t *testing.T, err error, code string) {
+	t.Helper()
+
+	var verr verifier.Error
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a verifier error %q, got %v", code, err)
+	}
+
+	if verr.Code != code {
+		t.Fatalf("expected the error code %q, got %q (%s)", code, verr.Code, verr.Description)
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	srv := newServer(t)
+	v := newVerifier(t, verifier.Config{Issuer: srv.Issuer, Audience: audience, Scopes: []string{"read"}})
+
+	claims, err := v.Verify(context.Background(), accessToken(t, srv, jwtClientID, "read write"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.ClientID != jwtClientID || claims.Subject != jwtClientID {
+		t.Errorf("unexpected client %q and subject %q", claims.ClientID, claims.Subject)
+	}
+
+	if !claims.HasScope("write") || claims.OrganizationID != config.DefaultOrganizationID() {
+		t.Errorf("unexpected scope %q and organization %q", claims.Scope, claims.OrganizationID)
+	}
+}
+
+func TestVerifyJWTSignature(t *testing.T) {
+	srv := newServer(t)
+	v := newVerifier(t, verifier.Config{Issuer: srv.Issuer})
+
+	value := accessToken(t, srv, jwtClientID, "read")
+
+	// The payload is replaced by the one of another token
+	parts := strings.Split(value, ".")
+	parts[1] = strings.Split(accessToken(t, srv, jwtClientID, "read write"), ".")[1]
+
+	_, err := v.Verify(context.Background(), strings.Join(parts, "."))
+	assertRejected(t, err, verifier.ErrorCodeInvalidToken)
+
+	// Keys that goauth does not publish are unknown
+	otherKeyManager, err := manager.NewKeyManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = v.Verify(context.Background(), signAccessToken(t, otherKeyManager, jwt.Claims{
+		Issuer: srv.Issuer,
+		Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}))
+	assertRejected(t, err, verifier.ErrorCodeInvalidToken)
+}
+
+func TestVerifyJWTIssuer(t *testing.T) {
+	srv := newServer(t)
+	value := accessToken(t, srv, jwtClientID, "read")
+
+	// The key set is published at the issuer configured in the verifier
+	v := newVerifier(t, verifier.Config{Issuer: "https://other.example.com", JWKSURI: srv.Issuer + "/oauth2/jwks"})
+
+	_, err := v.Verify(context.Background(), value)
+	assertRejected(t, err, verifier.ErrorCodeInvalidToken)
+}
+
+func TestVerifyJWTAudience(t *testing.T) {
+	srv := newServer(t)
+	value := accessToken(t, srv, jwtClientID, "read")
+
+	if _, err := newVerifier(t, verifier.Config{Issuer: srv.Issuer, Audience: audience}).Verify(context.Background(), value); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := newVerifier(t, verifier.Config{Issuer: srv.Issuer, Audience: "https://other.example.com"}).Verify(context.Background(), value)
+	assertRejected(t, err, verifier.ErrorCodeInvalidToken)
+}
+
+func TestVerifyJWTExpiry(t *testing.T) {
+	srv := newServer(t)
+	v := newVerifier(t, verifier.Config{Issuer: srv.Issuer})
+
+	now := time.Now()
+
+	_, err := v.Verify(context.Background(), signAccessToken(t, srv.KeyManager, jwt.Claims{
+		Issuer:   srv.Issuer,
+		IssuedAt: jwt.NewNumericDate(now.Add(-2 * time.Hour)),
+		Expiry:   jwt.NewNumericDate(now.Add(-time.Hour)),
+	}))
+	assertRejected(t, err, verifier.ErrorCodeInvalidToken)
+
+	// Tokens without expiration are rejected too
+	_, err = v.Verify(context.Background(), signAccessToken(t, srv.KeyManager, jwt.Claims{Issuer: srv.Issuer}))
+	assertRejected(t, err, verifier.ErrorCodeInvalidToken)
+
+	// The leeway tolerates the clock skew
+	v = newVerifier(t, verifier.Config{Issuer: srv.Issuer, Leeway: time.Minute})
+
+	if _, err = v.Verify(context.Background(), signAccessToken(t, srv.KeyManager, jwt.Claims{
+		Issuer: srv.Issuer,
+		Expiry: jwt.NewNumericDate(now.Add(-10 * time.Second)),
+	})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyScopes(t *testing.T) {
+	srv := newServer(t)
+	v := newVerifier(t, verifier.Config{
+		Issuer:       srv.Issuer,
+		Scopes:       []string{"write"},
+		ClientID:     resourceServerID,
+		ClientSecret: clientSecret,
+	})
+
+	for _, clientID := range []string{jwtClientID, opaqueClientID} {
+		_, err := v.Verify(context.Background(), accessToken(t, srv, clientID, "read"))
+		assertRejected(t, err, verifier.ErrorCodeInsufficientScope)
+
+		var verr verifier.Error
+		if errors.As(err, &verr) && verr.Status != http.StatusForbidden {
+			t.Errorf("expected the status %d, got %d", http.StatusForbidden, verr.Status)
+		}
+
+		if _, err = v.Verify(context.Background(), accessToken(t, srv, clientID, "read write")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerifyIntrospection(t *testing.T) {
+	srv := newServer(t)
+	v := newVerifier(t, verifier.Config{Issuer: srv.Issuer, ClientID: resourceServerID, ClientSecret: clientSecret})
+
+	claims, err := v.Verify(context.Background(), accessToken(t, srv, opaqueClientID, "read"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.ClientID != opaqueClientID || claims.Subject != opaqueClientID || !claims.HasScope("read") {
+		t.Errorf("unexpected client %q, subject %q and scope %q", claims.ClientID, claims.Subject, claims.Scope)
+	}
+
+	if claims.Expiry == nil || !claims.Expiry.Time().After(time.Now()) {
+		t.Errorf("unexpected expiration %v", claims.Expiry)
+	}
+
+	// Tokens unknown to goauth are inactive
+	_, err = v.Verify(context.Background(), "unknown")
+	assertRejected(t, err, verifier.ErrorCodeInvalidToken)
+
+	// Opaque tokens are rejected when the resource server can't introspect them
+	_, err = newVerifier(t, verifier.Config{Issuer: srv.Issuer}).Verify(context.Background(), accessToken(t, srv, opaqueClientID, "read"))
+	assertRejected(t, err, verifier.ErrorCodeInvalidToken)
+
+	// A failed introspection request is not a rejection of the token
+	_, err = newVerifier(t, verifier.Config{Issuer: srv.Issuer, ClientID: resourceServerID, ClientSecret: "wrong"}).
+		Verify(context.Background(), accessToken(t, srv, opaqueClientID, "read"))
+
+	var verr verifier.Error
+	if err == nil || errors.As(err, &verr) {
+		t.Fatalf("expected an introspection error, got %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	srv := newServer(t)
+	v := newVerifier(t, verifier.Config{Issuer: srv.Issuer, Scopes: []string{"read"}})
+
+	h := v.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims := verifier.FromContext(r.Context()); claims == nil || claims.ClientID != jwtClientID {
+			t.Errorf("unexpected claims %v", claims)
+		}
+	}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{name: "valid token", authorization: "Bearer " + accessToken(t, srv, jwtClientID, "read"), status: http.StatusOK},
+		{name: "missing token", status: http.StatusUnauthorized},
+		{name: "insufficient scope", authorization: "Bearer " + accessToken(t, srv, jwtClientID, "write"), status: http.StatusForbidden},
+		{name: "wrong scheme", authorization: "DPoP " + accessToken(t, srv, jwtClientID, "read"), status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/resource", http.NoBody)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected the status %d, got %d", tt.status, rec.Code)
+			}
+
+			if tt.status != http.StatusOK && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("unexpected challenge %q", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}